@@ -0,0 +1,60 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultAnalysisInterval 未配置分析周期时使用的默认值
+	DefaultAnalysisInterval = 5 * time.Minute
+	// MinAnalysisInterval 分析周期下限，防止过于频繁地调用大模型和可观测后端
+	MinAnalysisInterval = 30 * time.Second
+)
+
+// ParseAnalysisInterval 解析分析周期，空值返回默认值，低于下限时返回错误
+func ParseAnalysisInterval(interval string) (time.Duration, error) {
+	if interval == "" {
+		return DefaultAnalysisInterval, nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid analysisInterval %q: %w", interval, err)
+	}
+	if d < MinAnalysisInterval {
+		return 0, fmt.Errorf("analysisInterval %q is below the minimum of %s", interval, MinAnalysisInterval)
+	}
+	return d, nil
+}
+
+// NormalizeAnalysisInterval 把分析周期换算成能整除的最大单位，例如 120s -> 2m、3600s -> 1h
+func NormalizeAnalysisInterval(interval string) (string, error) {
+	d, err := ParseAnalysisInterval(interval)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour), nil
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute), nil
+	default:
+		return fmt.Sprintf("%ds", d/time.Second), nil
+	}
+}
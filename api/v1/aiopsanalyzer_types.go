@@ -27,7 +27,9 @@ import (
 // +kubebuilder:resource:shortName=aia;aiops
 // +kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.target.selector.matchLabels.app`
 // +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.target.namespace`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.summary`
+// +kubebuilder:printcolumn:name="Noop",type=string,JSONPath=`.status.noopReason`
 // +kubebuilder:printcolumn:name="PR",type=string,JSONPath=`.status.gitOps.pr.number`,priority=10
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
@@ -47,9 +49,10 @@ type AIOpsAnalyzerSpec struct {
 	// +kubebuilder:validation:Required
 	Target TargetSelector `json:"target"`
 
-	// 分析周期
+	// 分析周期，最小 30s：低于下限时 webhook 拒绝创建，控制器也按 30s 处理并记录 Warning 事件
 	// +kubebuilder:default="5m"
 	// +kubebuilder:validation:Pattern=`^(\d+m|\d+h|\d+s)$`
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('30s')",message="analysisInterval must be at least 30s"
 	AnalysisInterval string `json:"analysisInterval,omitempty"`
 
 	// 飞书通知与审批配置
@@ -61,12 +64,230 @@ type AIOpsAnalyzerSpec struct {
 	GitOps GitOpsConfig `json:"gitOps"`
 
 	// 自动修复策略
+	// +kubebuilder:default={}
 	AutoRemediation AutoRemediationSpec `json:"autoRemediation,omitempty"`
 
 	// 阈值配置（可选，AI 可覆盖）
 	Thresholds *Thresholds `json:"thresholds,omitempty"`
+
+	// 运行策略：Once 产出一次修复建议后停止分析，Continuous 持续监控
+	// +kubebuilder:default=Continuous
+	RunPolicy RunPolicy `json:"runPolicy,omitempty"`
+
+	// 大模型配置
+	LLM *LLMSpec `json:"llm,omitempty"`
+
+	// 发送给大模型前的脱敏配置
+	Sanitizer *SanitizerSpec `json:"sanitizer,omitempty"`
+
+	// 大模型请求、响应和处理结果的审计配置
+	// +optional
+	Audit *AuditSpec `json:"audit,omitempty"`
+
+	// 发送给大模型的上下文配置
+	Context *ContextSpec `json:"context,omitempty"`
+
+	// Loki 日志来源配置
+	Loki *LokiConfig `json:"loki,omitempty"`
+
+	// Prometheus 区间查询配置，为大模型提供指标趋势
+	Prometheus *PrometheusConfig `json:"prometheus,omitempty"`
+
+	// Prometheus、Loki 数据源地址
+	Datasources *DatasourcesSpec `json:"datasources,omitempty"`
+
+	// 创建后的观察期，期间只分析并记录结论，不发起修复建议
+	// +kubebuilder:validation:Pattern=`^(\d+m|\d+h|\d+s)$`
+	WarmupPeriod string `json:"warmupPeriod,omitempty"`
+}
+
+type DatasourcesSpec struct {
+	// Prometheus 地址（如 http://prometheus.monitoring:9090），为空时使用 http://127.0.0.1:9090
+	// +kubebuilder:validation:Pattern=`^https?://`
+	PrometheusURL string `json:"prometheusURL,omitempty"`
+
+	// Loki 地址（如 http://loki.monitoring:3100），为空时使用 http://127.0.0.1:3100
+	// +kubebuilder:validation:Pattern=`^https?://`
+	LokiURL string `json:"lokiURL,omitempty"`
+
+	// 多租户 Loki 的 X-Scope-OrgID（如 "1"），为空时不发送该 header，用于单租户 Loki
+	LokiOrgID string `json:"lokiOrgID,omitempty"`
+
+	// 查询 Prometheus 时的认证，为空时不发送 Authorization header
+	PrometheusAuth *DatasourceAuth `json:"prometheusAuth,omitempty"`
+
+	// 查询 Loki 时的认证，为空时不发送 Authorization header
+	LokiAuth *DatasourceAuth `json:"lokiAuth,omitempty"`
+}
+
+// DatasourceAuth 数据源认证，凭据从 CR 所在命名空间的 Secret 或 Vault 读取
+type DatasourceAuth struct {
+	// bearer：使用凭据中的 token；basic：使用凭据中的 username、password
+	// +kubebuilder:default=bearer
+	Type DatasourceAuthType `json:"type,omitempty"`
+
+	// 凭据引用
+	// +kubebuilder:validation:Required
+	CredentialsRef SecretRef `json:"credentialsRef"`
 }
 
+// +kubebuilder:validation:Enum=bearer;basic
+type DatasourceAuthType string
+
+const (
+	DatasourceAuthBearer DatasourceAuthType = "bearer"
+	DatasourceAuthBasic  DatasourceAuthType = "basic"
+)
+
+type LokiConfig struct {
+	// 额外的 LogQL 选择器（如 ingress/代理日志），结果单独标注来源后加入上下文
+	AdditionalStreams []string `json:"additionalStreams,omitempty"`
+
+	// 所有日志流合计最多保留的行数
+	// +kubebuilder:default=200
+	// +kubebuilder:validation:Minimum=1
+	MaxLines int `json:"maxLines,omitempty"`
+
+	// 所有日志流合计最多保留的字节数，避免超出大模型的上下文窗口
+	// +kubebuilder:default=32768
+	// +kubebuilder:validation:Minimum=1
+	MaxBytes int `json:"maxBytes,omitempty"`
+
+	// 查询最近多长时间内的日志
+	// +kubebuilder:default="48m"
+	// +kubebuilder:validation:Pattern=`^(\d+m|\d+h|\d+s)$`
+	Lookback string `json:"lookback,omitempty"`
+
+	// 过滤日志行的正则（RE2 语法），为空时只保留 error/panic/fatal/critical
+	LogFilter string `json:"logFilter,omitempty"`
+
+	// 按错误签名统计日志前替换为 <*> 的正则（RE2 语法），用于去掉时间戳、请求 ID 等每行都不同的内容
+	// 为空时使用内置规则（时间戳、UUID、十六进制 ID、IP 地址、数字）
+	SignatureNormalizers []string `json:"signatureNormalizers,omitempty"`
+
+	// 错误签名统计保留出现次数最多的前几条
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	TopSignatures int `json:"topSignatures,omitempty"`
+}
+
+type PrometheusConfig struct {
+	// 加入上下文的区间查询，结果按名称分段展示
+	// +listType=map
+	// +listMapKey=name
+	RangeQueries []PrometheusRangeQuery `json:"rangeQueries,omitempty"`
+}
+
+type PrometheusRangeQuery struct {
+	// 展示名称（如 cpu、memory）
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// PromQL 表达式，$namespace 替换为目标命名空间，$pod 替换为匹配目标 Pod 名称的正则
+	// 如 sum by (pod) (rate(container_cpu_usage_seconds_total{namespace="$namespace",pod=~"$pod"}[5m]))
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Expr string `json:"expr"`
+
+	// 查询最近多长时间
+	// +kubebuilder:default="30m"
+	// +kubebuilder:validation:Pattern=`^(\d+m|\d+h|\d+s)$`
+	Window string `json:"window,omitempty"`
+
+	// 采样步长
+	// +kubebuilder:default="1m"
+	// +kubebuilder:validation:Pattern=`^(\d+m|\d+h|\d+s)$`
+	Step string `json:"step,omitempty"`
+}
+
+type ContextSpec struct {
+	// 只把未就绪或最近重启过的Pod作为上下文，默认包含所有匹配的Pod
+	UnhealthyOnly bool `json:"unhealthyOnly,omitempty"`
+
+	// event string 的 token 预算，超出时依次截断日志、指标趋势和资源 YAML，告警始终保留
+	// 为空时使用 24000
+	// +kubebuilder:validation:Minimum=1000
+	MaxTokens int `json:"maxTokens,omitempty"`
+}
+
+type SanitizerSpec struct {
+	// 关闭内置规则（邮箱、token、IP、内部域名）
+	DisableDefaultRules bool `json:"disableDefaultRules,omitempty"`
+
+	// 额外的脱敏正则，命中内容替换为 [REDACTED]
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+type AuditSpec struct {
+	// 把审计记录（已脱敏）保存到 CR 所属的 ConfigMap <name>-audit 中，CR 删除时一并删除
+	ConfigMapRetention bool `json:"configMapRetention,omitempty"`
+
+	// ConfigMap 中保留的最近记录数，超出或 ConfigMap 过大时丢弃最旧的记录
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	MaxRecords int32 `json:"maxRecords,omitempty"`
+}
+
+type LLMSpec struct {
+	// 大模型 API Key 所在的凭据（键 api_key）
+	CredentialsRef *SecretRef `json:"credentialsRef,omitempty"`
+
+	// 替换默认的系统提示词，为空时使用内置提示词
+	// 按 Go 模板渲染，{{.Now}} 为当前北京时间（YYYYMMDD-HHMMSS）；必须要求大模型只输出 JSON
+	// +optional
+	SystemPromptOverride string `json:"systemPromptOverride,omitempty"`
+
+	// 用 CR 所在命名空间中 ConfigMap 的模板替换内置的分析提示词模板
+	// 按 Go 模板渲染，可用变量：.AppInfo、.EventString、.CurrentTime、.Thresholds、.AllowedActions
+	// +optional
+	PromptTemplateRef *ConfigMapKeyRef `json:"promptTemplateRef,omitempty"`
+}
+
+// ConfigMapKeyRef 引用 CR 所在命名空间中 ConfigMap 的一个键
+type ConfigMapKeyRef struct {
+	// ConfigMap 名称
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// 键名
+	// +kubebuilder:default="analysis.tmpl"
+	Key string `json:"key,omitempty"`
+}
+
+// SecretRef 凭据引用，Provider 决定从 Kubernetes Secret 还是 Vault 读取
+type SecretRef struct {
+	// 凭据后端
+	// +kubebuilder:default=kubernetes
+	Provider SecretProvider `json:"provider,omitempty"`
+
+	// kubernetes：CR 所在命名空间中的 Secret 名称
+	// vault：KV v2 引擎中 <mount>/data/<CR命名空间>/ 之后的路径，每段不能以 . 开头，不能引用其他命名空间的凭据
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-][A-Za-z0-9_.-]*(/[A-Za-z0-9_-][A-Za-z0-9_.-]*)*$`
+	Name string `json:"name"`
+}
+
+// +kubebuilder:validation:Enum=kubernetes;vault
+type SecretProvider string
+
+const (
+	SecretProviderKubernetes SecretProvider = "kubernetes"
+	SecretProviderVault      SecretProvider = "vault"
+)
+
+// +kubebuilder:validation:Enum=Once;Continuous
+type RunPolicy string
+
+const (
+	RunPolicyOnce       RunPolicy = "Once"
+	RunPolicyContinuous RunPolicy = "Continuous"
+)
+
+// RerunAnnotation 用于重置 Once 模式的终止状态，设置后会重新分析一次
+const RerunAnnotation = "autofix.aiops.com/rerun"
+
 type TargetSelector struct {
 	Namespace string               `json:"namespace,omitempty"`
 	Selector  metav1.LabelSelector `json:"selector"`
@@ -79,16 +300,50 @@ type FeishuNotification struct {
 	// +kubebuilder:validation:Required
 	ReceiveID string `json:"receiveId"`
 
-	// 可选：@指定的审批人（支持多个）
+	// 可选：@指定的审批人（支持多个），填写 open_id、user_id 或邮箱
+	// 卡片模板中通过变量 mentions 展示
 	MentionUsers []string `json:"mentionUsers,omitempty"`
 	MentionRoles []string `json:"mentionRoles,omitempty"` // 如 "oncall-sre"
 
+	// 角色到审批人的映射，用于解析 mentionRoles，如 oncall-sre: [ou_xxx, ou_yyy]
+	RoleMembers map[string][]string `json:"roleMembers,omitempty"`
+
 	// 审批超时时间
 	// +kubebuilder:default="10m"
 	ApprovalTimeout string `json:"approvalTimeout,omitempty"`
+
+	// 飞书应用凭据（键 app_id、app_secret），为空时使用控制器 --feishu-credentials-secret 指定的凭据
+	CredentialsRef *SecretRef `json:"credentialsRef,omitempty"`
+
+	// 按风险等级路由到不同的接收者，未匹配时使用默认接收者
+	Routes []FeishuRoute `json:"routes,omitempty"`
+
+	// 审批卡片的模板 ID，为空时依次使用控制器 --config 中的 feishu.templateID 和内置模板
+	TemplateID string `json:"templateID,omitempty"`
+
+	// 审批卡片的模板版本，为空时依次使用控制器 --config 中的 feishu.templateVersion 和内置模板
+	TemplateVersion string `json:"templateVersion,omitempty"`
 }
 
-// +kubebuilder:validation:Enum=user_id;open_id;union_id;user_open_id;chat_id;email
+// 未配置 templateID、templateVersion 时使用的内置审批卡片模板
+const (
+	DefaultFeishuTemplateID      = "AAqhGHg0Wgux8"
+	DefaultFeishuTemplateVersion = "0.0.9"
+)
+
+// FeishuRoute 某个风险等级的修复建议发送给指定接收者
+type FeishuRoute struct {
+	// 风险等级
+	// +kubebuilder:validation:Enum=low;medium;high
+	RiskLevel string `json:"riskLevel"`
+
+	// +kubebuilder:validation:Required
+	ReceiveIDType FeishuReceiveIDType `json:"receiveIdType"`
+	// +kubebuilder:validation:Required
+	ReceiveID string `json:"receiveId"`
+}
+
+// +kubebuilder:validation:Enum=user_id;open_id;union_id;chat_id;email
 type FeishuReceiveIDType string
 
 const (
@@ -112,13 +367,41 @@ type GitOpsConfig struct {
 	// +kubebuilder:validation:Required
 	Path string `json:"path"`
 
-	// Git 认证 Secret（包含 token 或 ssh key）
+	// Git 认证凭据（包含 token 或 ssh key）
 	// +kubebuilder:validation:Required
-	TokenSecretRef corev1.LocalObjectReference `json:"tokenSecretRef"`
+	TokenSecretRef SecretRef `json:"tokenSecretRef"`
 
 	// 可选：提交者信息
 	CommitAuthorName  string `json:"commitAuthorName,omitempty"`
 	CommitAuthorEmail string `json:"commitAuthorEmail,omitempty"`
+
+	// 预览模式：不新建 PR，而是把修复建议以评论形式发到跟踪 PR/MR 上
+	PreviewMode *PreviewModeSpec `json:"previewMode,omitempty"`
+
+	// PR 未合并或关闭前查询其状态的间隔
+	// +kubebuilder:default="5m"
+	// +kubebuilder:validation:Pattern=`^(\d+m|\d+h|\d+s)$`
+	PRPollInterval string `json:"prPollInterval,omitempty"`
+
+	// 可选：PR 合并后跟踪 ArgoCD Application 的同步与健康状态，按 prPollInterval 查询
+	ArgoCD *ArgoCDSpec `json:"argoCD,omitempty"`
+}
+
+type ArgoCDSpec struct {
+	// 部署该应用的 ArgoCD Application 名称
+	// +kubebuilder:validation:Required
+	ApplicationName string `json:"applicationName"`
+
+	// Application 所在的命名空间
+	// +kubebuilder:default="argocd"
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type PreviewModeSpec struct {
+	// 接收评论的跟踪 PR/MR 编号
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	TrackingPR int `json:"trackingPR"`
 }
 
 type AutoRemediationSpec struct {
@@ -133,9 +416,37 @@ type AutoRemediationSpec struct {
 	// 允许的修复类型（可多选）
 	// +kubebuilder:validation:ItemsEnum=scale;restart;config;traffic;resource;feature-toggle
 	AllowedActions []string `json:"allowedActions,omitempty"`
+
+	// 安全模式：拒绝 remove、副本数改为 0、删除资源 limits 等会降低可用性的操作
+	SafeMode bool `json:"safeMode,omitempty"`
+
+	// 资源调整的上限（如 cpu: "8"、memory: 16Gi），相对值换算后的结果不会超过该值
+	MaxResources corev1.ResourceList `json:"maxResources,omitempty"`
+
+	// 单个修复建议最多包含的补丁数，不能超过生产硬上限（10）
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	MaxPatches int `json:"maxPatches,omitempty"`
+
+	// 合并后的修复未能解决问题时自动创建回滚 PR：冷却期结束后大模型仍给出修复建议即视为验证失败，
+	// 此时用提出修复建议时记录的撤销补丁创建回滚 PR，不再发起新的修复建议
+	AutoRollback bool `json:"autoRollback,omitempty"`
+
+	// 只把修复建议写入 status.proposedRemediation 并记录 Event，不发送飞书卡片、不创建 PR
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// 最低置信度（0~1，如 "0.7"），大模型给出的 confidence 低于该值时只记录结论，不发起修复建议
+	// 为空或 0 时不检查；设置后未给出 confidence 的修复建议按 0 处理
+	// +kubebuilder:validation:Pattern=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	MinConfidence string `json:"minConfidence,omitempty"`
+
+	// 冷却期：上一次修复生效（PR 合并或 ArgoCD 同步）后的这段时间内暂停分析、不调用大模型，避免指标震荡时反复扩缩容
+	// +kubebuilder:validation:Pattern=`^(\d+m|\d+h|\d+s)$`
+	Cooldown string `json:"cooldown,omitempty"`
 }
 
 type Thresholds struct {
+	// 为资源量（如 "4"、"8Gi"）时同时作为修复建议中 CPU、内存的上限，百分比等其他取值不作为上限
 	CPU               string `json:"cpu,omitempty"`
 	Memory            string `json:"memory,omitempty"`
 	RestartCount      *int32 `json:"restartCount,omitempty"`
@@ -144,16 +455,81 @@ type Thresholds struct {
 
 // ==================== Status ====================
 
+// Summary 取值
+const (
+	SummaryHealthy = "Healthy"
+	// 大模型给出了修复建议，等待审批
+	SummaryRemediationProposed = "RemediationProposed"
+	// Once 模式下已产出修复建议，不再继续分析
+	SummaryCompleted = "Completed"
+	// 修复建议包含破坏性操作，被安全模式拒绝
+	SummaryBlockedBySafeMode = "BlockedBySafeMode"
+	// dry-run 模式下只记录了修复建议
+	SummaryDryRun = "DryRun"
+	// 未启用自动修复，只记录分析结论
+	SummaryRemediationDisabled = "RemediationDisabled"
+	// 大模型给出了修复建议，但被 allowedActions、取值上限、minConfidence 等策略拒绝，未发起修复
+	SummaryPolicyRejected = "PolicyRejected"
+	// Ready condition 为 False：协调失败或依赖的大模型、数据源不可用
+	SummaryDegraded = "Degraded"
+	// 大模型响应无法解析或校验不通过
+	SummaryAnalysisFailed = "AnalysisFailed"
+	// 修复建议已批准，但无法提交到 GitOps 仓库
+	SummaryPullRequestFailed = "PullRequestFailed"
+	// 上一次修复生效后的冷却期内，暂停分析、不调用大模型
+	SummaryCoolingDown = "CoolingDown"
+)
+
+// NoopReason 本轮没有产出修复建议的原因
+// +kubebuilder:validation:Enum=NoSelector;RunCompleted;LLMNoop;PolicyRejected;WarmingUp;RemediationDisabled;LowConfidence;InCooldown;AwaitingApproval;Unchanged;LLMUnavailable
+type NoopReason string
+
+const (
+	// 未配置 target.selector
+	NoopReasonNoSelector NoopReason = "NoSelector"
+	// Once 模式已产出过修复建议
+	NoopReasonRunCompleted NoopReason = "RunCompleted"
+	// 大模型判断无需处理
+	NoopReasonLLMNoop NoopReason = "LLMNoop"
+	// 修复建议被策略（如安全模式）拒绝
+	NoopReasonPolicyRejected NoopReason = "PolicyRejected"
+	// 仍在创建后的观察期内
+	NoopReasonWarmingUp NoopReason = "WarmingUp"
+	// autoRemediation.enabled 为 false
+	NoopReasonRemediationDisabled NoopReason = "RemediationDisabled"
+	// 修复建议的置信度低于 autoRemediation.minConfidence
+	NoopReasonLowConfidence NoopReason = "LowConfidence"
+	// 仍在上一次修复生效后的冷却期内
+	NoopReasonInCooldown NoopReason = "InCooldown"
+	// 上一次的修复建议仍在等待审批
+	NoopReasonAwaitingApproval NoopReason = "AwaitingApproval"
+	// 分析周期内情况与上一次分析相比没有变化
+	NoopReasonUnchanged NoopReason = "Unchanged"
+	// 大模型连续调用失败，熔断期间暂停分析
+	NoopReasonLLMUnavailable NoopReason = "LLMUnavailable"
+)
+
 type AIOpsAnalyzerStatus struct {
 	// 最近分析时间
 	LastAnalysisTime *metav1.Time `json:"lastAnalysisTime,omitempty"`
 
+	// 最近一次发送给大模型的 event string 指纹，分析周期内情况未变化时不再重复分析
+	LastEventHash string `json:"lastEventHash,omitempty"`
+
 	// 简要状态
 	// +kubebuilder:default="Healthy"
 	Summary string `json:"summary,omitempty"`
 
 	// AI 分析结论
 	Insights string `json:"insights,omitempty"`
+
+	// 观察期结束时间（创建时间 + warmupPeriod）
+	WarmupEndsAt *metav1.Time `json:"warmupEndsAt,omitempty"`
+
+	// 最近一次没有产出修复建议的原因，产出修复建议时清空
+	NoopReason NoopReason `json:"noopReason,omitempty"`
+	// 原因的详细说明
+	NoopMessage string `json:"noopMessage,omitempty"`
 	// AI patch补丁
 	ProposedRemediation *RemediationProposal `json:"proposedRemediation,omitempty"`
 	// 当前待审批请求
@@ -162,10 +538,94 @@ type AIOpsAnalyzerStatus struct {
 	// GitOps PR 状态
 	GitOps GitOpsStatus `json:"gitOps,omitempty"`
 
+	// 最近一次协调失败的错误，下一次成功协调后清空
+	LastError *ReconcileError `json:"lastError,omitempty"`
+
+	// 最近的修复记录（按时间先后，只保留最近若干条）
+	History []RemediationRecord `json:"history,omitempty"`
+
+	// 标准 Condition：Ready、ApprovalPending、LLMAvailable、DatasourcesReachable
+	// Summary 是在此基础上的可读汇总，可使用 kubectl wait --for=condition=Ready
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
 	// 标准字段
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+// Condition 类型与原因
+const (
+	// Prometheus 和 Loki 是否可访问
+	ConditionDatasourcesReachable = "DatasourcesReachable"
+
+	ReasonDatasourcesReachable         = "Reachable"
+	ReasonInvalidDatasourceURL         = "InvalidURL"
+	ReasonDatasourceUnreachable        = "Unreachable"
+	ReasonInvalidDatasourceCredentials = "InvalidCredentials"
+
+	// 大模型是否可用，连续调用失败后暂停分析
+	ConditionLLMAvailable = "LLMAvailable"
+
+	ReasonLLMAvailable   = "Available"
+	ReasonLLMUnavailable = "LLMUnavailable"
+
+	// 最近一次协调成功且大模型、数据源均可用
+	ConditionReady = "Ready"
+
+	ReasonReconciled     = "Reconciled"
+	ReasonReconcileError = "ReconcileError"
+
+	// 是否有修复建议在等待飞书审批
+	ConditionApprovalPending = "ApprovalPending"
+
+	ReasonAwaitingApproval  = "AwaitingApproval"
+	ReasonNoPendingApproval = "NoPendingApproval"
+)
+
+type RemediationRecord struct {
+	// 对应的审批请求 ID
+	RequestID string `json:"requestID"`
+
+	// 修复动作类型
+	ActionType string `json:"actionType,omitempty"`
+
+	// 风险等级
+	RiskLevel string `json:"riskLevel,omitempty"`
+
+	// AI 给出的理由
+	Reason string `json:"reason,omitempty"`
+
+	// 提出修复建议的时间
+	ProposedAt metav1.Time `json:"proposedAt"`
+
+	// 审批结果，未决定时为空
+	Approved *bool `json:"approved,omitempty"`
+
+	// 对应的 PR 编号与是否已合并
+	PRNumber int  `json:"prNumber,omitempty"`
+	PRMerged bool `json:"prMerged,omitempty"`
+
+	// 审批卡片的消息 ID，PR 合并后用于更新卡片
+	MessageID string `json:"messageID,omitempty"`
+
+	// 审批通过后无法创建 PR 的原因（如仓库中找不到目标资源），重试也无法成功
+	Failure string `json:"failure,omitempty"`
+
+	// 撤销本次修复的补丁（开启 autoRollback 时记录）
+	RollbackPatches []PatchOperation `json:"rollbackPatches,omitempty"`
+	// 本条记录回滚的修复对应的 RequestID
+	RollbackOf string `json:"rollbackOf,omitempty"`
+}
+
+type ReconcileError struct {
+	// 错误信息（过长时截断）
+	Message string `json:"message"`
+	// 发生时间
+	Time metav1.Time `json:"time"`
+}
+
 type RemediationProposal struct {
 	// AI 建议执行的动作类型
 	// +kubebuilder:validation:Enum=scale;restart;feature-toggle;traffic-shift;resource-adjust;config-change
@@ -221,8 +681,14 @@ type GitOpsStatus struct {
 	// 最后一次提交的 commit hash
 	LastCommitSHA string `json:"lastCommitSHA,omitempty"`
 
-	// 最后同步时间（ArgoCD 同步后可通过 event 更新）
+	// 最后同步时间（配置 spec.gitOps.argoCD 时，合并的修复提交被 ArgoCD 同步后更新）
 	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+
+	// ArgoCD Application 的同步状态：Synced / OutOfSync / Unknown
+	SyncStatus string `json:"syncStatus,omitempty"`
+
+	// ArgoCD Application 的健康状态：Healthy / Progressing / Degraded / Suspended / Missing / Unknown
+	Health string `json:"health,omitempty"`
 }
 
 type PRStatus struct {
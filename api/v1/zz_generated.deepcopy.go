@@ -22,6 +22,7 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -89,13 +90,48 @@ func (in *AIOpsAnalyzerSpec) DeepCopyInto(out *AIOpsAnalyzerSpec) {
 	*out = *in
 	in.Target.DeepCopyInto(&out.Target)
 	in.Feishu.DeepCopyInto(&out.Feishu)
-	out.GitOps = in.GitOps
+	in.GitOps.DeepCopyInto(&out.GitOps)
 	in.AutoRemediation.DeepCopyInto(&out.AutoRemediation)
 	if in.Thresholds != nil {
 		in, out := &in.Thresholds, &out.Thresholds
 		*out = new(Thresholds)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.LLM != nil {
+		in, out := &in.LLM, &out.LLM
+		*out = new(LLMSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sanitizer != nil {
+		in, out := &in.Sanitizer, &out.Sanitizer
+		*out = new(SanitizerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Audit != nil {
+		in, out := &in.Audit, &out.Audit
+		*out = new(AuditSpec)
+		**out = **in
+	}
+	if in.Context != nil {
+		in, out := &in.Context, &out.Context
+		*out = new(ContextSpec)
+		**out = **in
+	}
+	if in.Loki != nil {
+		in, out := &in.Loki, &out.Loki
+		*out = new(LokiConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(PrometheusConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Datasources != nil {
+		in, out := &in.Datasources, &out.Datasources
+		*out = new(DatasourcesSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIOpsAnalyzerSpec.
@@ -115,6 +151,10 @@ func (in *AIOpsAnalyzerStatus) DeepCopyInto(out *AIOpsAnalyzerStatus) {
 		in, out := &in.LastAnalysisTime, &out.LastAnalysisTime
 		*out = (*in).DeepCopy()
 	}
+	if in.WarmupEndsAt != nil {
+		in, out := &in.WarmupEndsAt, &out.WarmupEndsAt
+		*out = (*in).DeepCopy()
+	}
 	if in.ProposedRemediation != nil {
 		in, out := &in.ProposedRemediation, &out.ProposedRemediation
 		*out = new(RemediationProposal)
@@ -126,6 +166,25 @@ func (in *AIOpsAnalyzerStatus) DeepCopyInto(out *AIOpsAnalyzerStatus) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.GitOps.DeepCopyInto(&out.GitOps)
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(ReconcileError)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]RemediationRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AIOpsAnalyzerStatus.
@@ -160,6 +219,36 @@ func (in *ApprovalRequest) DeepCopy() *ApprovalRequest {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDSpec) DeepCopyInto(out *ArgoCDSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDSpec.
+func (in *ArgoCDSpec) DeepCopy() *ArgoCDSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuditSpec) DeepCopyInto(out *AuditSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuditSpec.
+func (in *AuditSpec) DeepCopy() *AuditSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AuditSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AutoRemediationSpec) DeepCopyInto(out *AutoRemediationSpec) {
 	*out = *in
@@ -168,6 +257,13 @@ func (in *AutoRemediationSpec) DeepCopyInto(out *AutoRemediationSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MaxResources != nil {
+		in, out := &in.MaxResources, &out.MaxResources
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoRemediationSpec.
@@ -180,6 +276,77 @@ func (in *AutoRemediationSpec) DeepCopy() *AutoRemediationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyRef) DeepCopyInto(out *ConfigMapKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyRef.
+func (in *ConfigMapKeyRef) DeepCopy() *ConfigMapKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContextSpec) DeepCopyInto(out *ContextSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContextSpec.
+func (in *ContextSpec) DeepCopy() *ContextSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ContextSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatasourceAuth) DeepCopyInto(out *DatasourceAuth) {
+	*out = *in
+	out.CredentialsRef = in.CredentialsRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatasourceAuth.
+func (in *DatasourceAuth) DeepCopy() *DatasourceAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(DatasourceAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatasourcesSpec) DeepCopyInto(out *DatasourcesSpec) {
+	*out = *in
+	if in.PrometheusAuth != nil {
+		in, out := &in.PrometheusAuth, &out.PrometheusAuth
+		*out = new(DatasourceAuth)
+		**out = **in
+	}
+	if in.LokiAuth != nil {
+		in, out := &in.LokiAuth, &out.LokiAuth
+		*out = new(DatasourceAuth)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatasourcesSpec.
+func (in *DatasourcesSpec) DeepCopy() *DatasourcesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatasourcesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FeishuNotification) DeepCopyInto(out *FeishuNotification) {
 	*out = *in
@@ -193,6 +360,32 @@ func (in *FeishuNotification) DeepCopyInto(out *FeishuNotification) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RoleMembers != nil {
+		in, out := &in.RoleMembers, &out.RoleMembers
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.CredentialsRef != nil {
+		in, out := &in.CredentialsRef, &out.CredentialsRef
+		*out = new(SecretRef)
+		**out = **in
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]FeishuRoute, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeishuNotification.
@@ -205,10 +398,35 @@ func (in *FeishuNotification) DeepCopy() *FeishuNotification {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeishuRoute) DeepCopyInto(out *FeishuRoute) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeishuRoute.
+func (in *FeishuRoute) DeepCopy() *FeishuRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(FeishuRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitOpsConfig) DeepCopyInto(out *GitOpsConfig) {
 	*out = *in
 	out.TokenSecretRef = in.TokenSecretRef
+	if in.PreviewMode != nil {
+		in, out := &in.PreviewMode, &out.PreviewMode
+		*out = new(PreviewModeSpec)
+		**out = **in
+	}
+	if in.ArgoCD != nil {
+		in, out := &in.ArgoCD, &out.ArgoCD
+		*out = new(ArgoCDSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsConfig.
@@ -241,6 +459,56 @@ func (in *GitOpsStatus) DeepCopy() *GitOpsStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LLMSpec) DeepCopyInto(out *LLMSpec) {
+	*out = *in
+	if in.CredentialsRef != nil {
+		in, out := &in.CredentialsRef, &out.CredentialsRef
+		*out = new(SecretRef)
+		**out = **in
+	}
+	if in.PromptTemplateRef != nil {
+		in, out := &in.PromptTemplateRef, &out.PromptTemplateRef
+		*out = new(ConfigMapKeyRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LLMSpec.
+func (in *LLMSpec) DeepCopy() *LLMSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LLMSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LokiConfig) DeepCopyInto(out *LokiConfig) {
+	*out = *in
+	if in.AdditionalStreams != nil {
+		in, out := &in.AdditionalStreams, &out.AdditionalStreams
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SignatureNormalizers != nil {
+		in, out := &in.SignatureNormalizers, &out.SignatureNormalizers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LokiConfig.
+func (in *LokiConfig) DeepCopy() *LokiConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LokiConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PRStatus) DeepCopyInto(out *PRStatus) {
 	*out = *in
@@ -281,6 +549,72 @@ func (in *PatchOperation) DeepCopy() *PatchOperation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreviewModeSpec) DeepCopyInto(out *PreviewModeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewModeSpec.
+func (in *PreviewModeSpec) DeepCopy() *PreviewModeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PreviewModeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusConfig) DeepCopyInto(out *PrometheusConfig) {
+	*out = *in
+	if in.RangeQueries != nil {
+		in, out := &in.RangeQueries, &out.RangeQueries
+		*out = make([]PrometheusRangeQuery, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusConfig.
+func (in *PrometheusConfig) DeepCopy() *PrometheusConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRangeQuery) DeepCopyInto(out *PrometheusRangeQuery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRangeQuery.
+func (in *PrometheusRangeQuery) DeepCopy() *PrometheusRangeQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRangeQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcileError) DeepCopyInto(out *ReconcileError) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcileError.
+func (in *ReconcileError) DeepCopy() *ReconcileError {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcileError)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemediationProposal) DeepCopyInto(out *RemediationProposal) {
 	*out = *in
@@ -304,6 +638,69 @@ func (in *RemediationProposal) DeepCopy() *RemediationProposal {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationRecord) DeepCopyInto(out *RemediationRecord) {
+	*out = *in
+	in.ProposedAt.DeepCopyInto(&out.ProposedAt)
+	if in.Approved != nil {
+		in, out := &in.Approved, &out.Approved
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RollbackPatches != nil {
+		in, out := &in.RollbackPatches, &out.RollbackPatches
+		*out = make([]PatchOperation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationRecord.
+func (in *RemediationRecord) DeepCopy() *RemediationRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SanitizerSpec) DeepCopyInto(out *SanitizerSpec) {
+	*out = *in
+	if in.Patterns != nil {
+		in, out := &in.Patterns, &out.Patterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SanitizerSpec.
+func (in *SanitizerSpec) DeepCopy() *SanitizerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SanitizerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRef) DeepCopyInto(out *SecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRef.
+func (in *SecretRef) DeepCopy() *SecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetSelector) DeepCopyInto(out *TargetSelector) {
 	*out = *in
@@ -17,26 +17,40 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	lark "github.com/larksuite/oapi-sdk-go/v3"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
 	"github.com/boqier/AIOpsAnalyzer/internal/controller"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/audit"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/secret"
+	webhookautofixv1 "github.com/boqier/AIOpsAnalyzer/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -58,6 +72,18 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var vaultAddr string
+	var vaultMountPath string
+	var vaultKVVersion int
+	var maxConcurrentGitOps int
+	var maxConcurrentReconciles int
+	var datasourceTimeout time.Duration
+	var feishuCredentialsSecret string
+	var feishuCallbackAddr string
+	var auditLogPath string
+	var configFile string
+	var simulateAddr string
+	var readyzCheckDependencies bool
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -69,6 +95,38 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&vaultAddr, "vault-address", "",
+		"The address of the HashiCorp Vault server used to resolve secretRefs with provider vault. "+
+			"The token is read from the VAULT_TOKEN environment variable. Leave empty to disable the vault provider.")
+	flag.StringVar(&vaultMountPath, "vault-kv-mount", "secret", "The mount path of the Vault KV secrets engine.")
+	flag.IntVar(&vaultKVVersion, "vault-kv-version", 2, "The version of the Vault KV secrets engine, 1 or 2.")
+	flag.IntVar(&maxConcurrentGitOps, "max-concurrent-git-ops", gitops.DefaultMaxConcurrentOps,
+		"The maximum number of git/PR operations running at the same time across all reconciles.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 4,
+		"The maximum number of AIOpsAnalyzers reconciled at the same time. "+
+			"Each AIOpsAnalyzer is always reconciled by one worker at a time and events received meanwhile are coalesced.")
+	flag.StringVar(&feishuCredentialsSecret, "feishu-credentials-secret", "",
+		"The <namespace>/<name> of a Secret with the default Feishu app_id and app_secret, "+
+			"used by AIOpsAnalyzers without spec.feishu.credentialsRef.")
+	flag.StringVar(&feishuCallbackAddr, "feishu-callback-bind-address", ":8082",
+		"The address the Feishu card callback endpoint binds to. Set FEISHU_VERIFICATION_TOKEN to enable it.")
+	flag.DurationVar(&datasourceTimeout, "datasource-timeout", 15*time.Second,
+		"The timeout of a single Prometheus or Loki query.")
+	flag.StringVar(&auditLogPath, "audit-log-file", "",
+		"The file that LLM audit records are appended to as JSON lines. Leave empty to write them to the controller log.")
+	flag.StringVar(&configFile, "config", "",
+		"The ControllerConfig YAML file with controller-wide defaults for datasources, the LLM and Feishu. "+
+			"Flags set explicitly, environment variables and AIOpsAnalyzer fields take precedence.")
+	flag.StringVar(&simulateAddr, "simulate-bind-address", "0",
+		"The address the simulate endpoint binds to. POST an AIOpsAnalyzer (or its name) and an event string to "+
+			"/simulate to preview the LLM action and approval card without touching status, Feishu or Git. "+
+			"Callers are authenticated with TokenReviews and need the simulate-caller ClusterRole. "+
+			"Leave as 0 to disable it.")
+	flag.BoolVar(&readyzCheckDependencies, "readyz-check-dependencies", false,
+		"If set, /readyz fails while the default LLM, Prometheus or Loki from --config is unreachable. "+
+			"The result of the background check (every 30s) is cached, probes never call the dependencies. "+
+			"The webhooks are served by the same Pod with failurePolicy Fail, so AIOpsAnalyzers cannot be "+
+			"created or updated while it is not ready.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -77,6 +135,25 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// 控制器配置文件只提供默认值，显式指定的命令行参数优先
+	var controllerConfig config.ControllerConfig
+	if configFile != "" {
+		loaded, err := config.Load(configFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load controller config", "path", configFile)
+			os.Exit(1)
+		}
+		controllerConfig = *loaded
+		explicit := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+		if !explicit["feishu-credentials-secret"] && controllerConfig.Feishu.CredentialsSecret != "" {
+			feishuCredentialsSecret = controllerConfig.Feishu.CredentialsSecret
+		}
+		if !explicit["datasource-timeout"] {
+			datasourceTimeout = controllerConfig.DatasourceTimeout(datasourceTimeout)
+		}
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -142,20 +219,143 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.AIOpsAnalyzerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	// Secret 通过 APIReader 直接读取，避免在缓存中 watch 全集群的 Secret
+	secretResolvers := secret.Resolvers{
+		autofixv1.SecretProviderKubernetes: &secret.KubernetesResolver{Client: mgr.GetAPIReader()},
+	}
+	if vaultAddr != "" {
+		vaultResolver, err := secret.NewVaultResolver(vaultAddr, os.Getenv("VAULT_TOKEN"), vaultMountPath, vaultKVVersion)
+		if err != nil {
+			setupLog.Error(err, "unable to create vault secret resolver")
+			os.Exit(1)
+		}
+		secretResolvers[autofixv1.SecretProviderVault] = vaultResolver
+	}
+
+	// 未设置 LLM_API_KEY 时只能分析配置了 spec.llm.credentialsRef 的 CR，本地 Ollama 不需要 API Key
+	var llmClient llm.LLMClient
+	if llmConfig := controllerConfig.ApplyLLM(llm.ConfigFromEnv()); llmConfig.APIKey != "" || llmConfig.Provider == llm.ProviderOllama {
+		llmClient, err = llm.NewLLMClient(llmConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to create llm client", "provider", llmConfig.Provider)
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("LLM_API_KEY is not set, only AIOpsAnalyzers with spec.llm.credentialsRef can be analyzed")
+	}
+
+	// 默认的飞书凭据在启动时读取，Secret 不存在或缺少键时直接退出
+	var feishuClient *lark.Client
+	if feishuCredentialsSecret != "" {
+		namespace, name, ok := strings.Cut(feishuCredentialsSecret, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(nil, "--feishu-credentials-secret must be <namespace>/<name>", "value", feishuCredentialsSecret)
+			os.Exit(1)
+		}
+		feishuClient, err = controller.NewFeishuClientFromSecret(context.Background(), mgr.GetAPIReader(),
+			types.NamespacedName{Namespace: namespace, Name: name})
+		if err != nil {
+			setupLog.Error(err, "unable to load feishu credentials", "secret", feishuCredentialsSecret)
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("--feishu-credentials-secret is not set, only AIOpsAnalyzers with spec.feishu.credentialsRef can send approval cards")
+	}
+
+	// 审计记录默认输出到控制器日志，指定文件时按 JSON Lines 追加写入
+	var auditSink audit.Sink
+	if auditLogPath != "" {
+		if auditSink, err = audit.NewFileSink(auditLogPath); err != nil {
+			setupLog.Error(err, "unable to open audit log", "path", auditLogPath)
+			os.Exit(1)
+		}
+	}
+
+	reconciler := &controller.AIOpsAnalyzerReconciler{
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		APIReader:  mgr.GetAPIReader(),
+		Secrets:    secretResolvers,
+		GitLimiter: gitops.NewLimiter(maxConcurrentGitOps),
+		LLM:        llmClient,
+		Feishu:     feishuClient,
+		Audit:      auditSink,
+		Defaults:   controllerConfig,
+
+		DatasourceTimeout:       datasourceTimeout,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AIOpsAnalyzer")
 		os.Exit(1)
 	}
+
+	// 飞书卡片回调：校验签名后把审批结果写回对应的 AIOpsAnalyzer
+	if verificationToken := os.Getenv("FEISHU_VERIFICATION_TOKEN"); verificationToken != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/feishu/card", feishu.NewCardActionHandler(verificationToken, os.Getenv("FEISHU_ENCRYPT_KEY"), reconciler.ApplyCardDecision))
+		if err := mgr.Add(&manager.Server{
+			Name:   "feishu-callback",
+			Server: &http.Server{Addr: feishuCallbackAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		}); err != nil {
+			setupLog.Error(err, "unable to add feishu callback server")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("FEISHU_VERIFICATION_TOKEN is not set, approval card callbacks are disabled")
+	}
+	// 模拟分析：用 CR 和粘贴的监控数据调用大模型，只返回结果，便于调试提示词和阈值
+	// 返回的提示词包含 Pod、日志和事件，与 metrics 一样经过 authn/authz，调用方需要 /simulate 的 post 权限
+	if simulateAddr != "0" {
+		filter, err := filters.WithAuthenticationAndAuthorization(mgr.GetConfig(), mgr.GetHTTPClient())
+		if err != nil {
+			setupLog.Error(err, "unable to create simulate authn/authz filter")
+			os.Exit(1)
+		}
+		handler, err := filter(ctrl.Log.WithName("simulate"), reconciler.SimulateHandler())
+		if err != nil {
+			setupLog.Error(err, "unable to protect simulate endpoint")
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/simulate", handler)
+		if err := mgr.Add(&manager.Server{
+			Name:   "simulate",
+			Server: &http.Server{Addr: simulateAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		}); err != nil {
+			setupLog.Error(err, "unable to add simulate server")
+			os.Exit(1)
+		}
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookautofixv1.SetupAIOpsAnalyzerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AIOpsAnalyzer")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	// 默认的大模型和数据源是否可达通过 aiops_dependency_up 指标上报，默认不影响就绪：
+	// webhook 的 failurePolicy 为 Fail，外部服务故障时不能让 CR 无法提交
+	dependencyHealth := &controller.DependencyHealth{
+		LLM:         llmClient,
+		Datasources: controllerConfig.Datasources,
+		HTTPClient:  &http.Client{Timeout: datasourceTimeout},
+	}
+	if err := mgr.Add(dependencyHealth); err != nil {
+		setupLog.Error(err, "unable to set up dependency health check")
+		os.Exit(1)
+	}
+	readyzCheck := healthz.Ping
+	if readyzCheckDependencies {
+		readyzCheck = dependencyHealth.ReadyzCheck
+	}
+	if err := mgr.AddReadyzCheck("readyz", readyzCheck); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
@@ -0,0 +1,177 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// disallowedActions 按补丁路径判断修复类型，返回不在 AllowedActions 中的补丁说明
+// 未配置 AllowedActions 时只允许 scale 和 resource；修改 ConfigMap 时附带的重启补丁属于 config
+func disallowedActions(remediation autofixv1.AutoRemediationSpec, ops []llm.PatchOp) []string {
+	allowed := remediation.AllowedActions
+	if len(allowed) == 0 {
+		allowed = llm.DefaultAllowedActions
+	}
+	configChange := llm.HasConfigMapPatches(ops)
+	var rejected []string
+	for _, op := range ops {
+		actions := llm.ActionsForPath(op.Path)
+		if configChange && op.Path == llm.RestartAnnotationPath {
+			actions = []string{"config"}
+		}
+		if slices.ContainsFunc(actions, func(action string) bool { return slices.Contains(allowed, action) }) {
+			continue
+		}
+		rejected = append(rejected, fmt.Sprintf("%s %s (%s)", op.Op, op.Path, strings.Join(actions, "/")))
+	}
+	return rejected
+}
+
+// minConfidence 解析 autoRemediation.minConfidence，为空或无法解析时返回 0（不检查）
+func minConfidence(remediation autofixv1.AutoRemediationSpec) float64 {
+	if remediation.MinConfidence == "" {
+		return 0
+	}
+	threshold, err := strconv.ParseFloat(remediation.MinConfidence, 64)
+	if err != nil {
+		return 0
+	}
+	return threshold
+}
+
+// formatConfidence 以百分比展示置信度，大模型未给出时返回 "未知"
+func formatConfidence(confidence *float64) string {
+	if confidence == nil {
+		return "未知"
+	}
+	return fmt.Sprintf("%.0f%%", *confidence*100)
+}
+
+// belowConfidence 修复建议的置信度低于 minConfidence 时降级为 noop 并返回 true
+func (r *AIOpsAnalyzerReconciler) belowConfidence(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, error) {
+	threshold := minConfidence(aiopsAnalyzer.Spec.AutoRemediation)
+	if threshold <= 0 {
+		return false, nil
+	}
+	confidence := 0.0
+	if heal.Confidence != nil {
+		confidence = *heal.Confidence
+	}
+	if confidence >= threshold {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("置信度 %s 低于 minConfidence %s", formatConfidence(heal.Confidence), aiopsAnalyzer.Spec.AutoRemediation.MinConfidence)
+	log.FromContext(ctx).Info("修复建议置信度不足，只记录结论", "confidence", confidence, "minConfidence", threshold)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonLowConfidence, "%s，未发起修复建议: %s", message, heal.Reason)
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryPolicyRejected
+		status.Insights = fmt.Sprintf("%s（%s）", heal.Reason, message)
+		status.NoopReason = autofixv1.NoopReasonLowConfidence
+		status.NoopMessage = message
+	})
+}
+
+// rejectedByPolicy 自动修复未启用或修复类型不在 AllowedActions 中时记录为 noop 并返回 true
+func (r *AIOpsAnalyzerReconciler) rejectedByPolicy(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, error) {
+	log := log.FromContext(ctx)
+	remediation := aiopsAnalyzer.Spec.AutoRemediation
+
+	if !remediation.Enabled {
+		log.Info("未启用自动修复，只记录分析结论", "reason", heal.Reason)
+		return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			now := metav1.Now()
+			status.LastAnalysisTime = &now
+			status.Summary = autofixv1.SummaryRemediationDisabled
+			status.Insights = heal.Reason
+			if heal.Detail != "" {
+				status.Insights = fmt.Sprintf("%s：%s", heal.Reason, heal.Detail)
+			}
+			status.NoopReason = autofixv1.NoopReasonRemediationDisabled
+			status.NoopMessage = "autoRemediation.enabled 为 false"
+		})
+	}
+
+	rejected := disallowedActions(remediation, heal.PatchContent)
+	if len(rejected) == 0 {
+		return false, nil
+	}
+	log.Info("修复类型不在 allowedActions 中", "ops", rejected)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonActionNotAllowed,
+		"修复建议包含未允许的修复类型，已忽略: %s", strings.Join(rejected, "; "))
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryPolicyRejected
+		status.Insights = fmt.Sprintf("%s（修复类型未允许：%s）", heal.Reason, strings.Join(rejected, "; "))
+		status.NoopReason = autofixv1.NoopReasonPolicyRejected
+		status.NoopMessage = "修复类型未允许: " + strings.Join(rejected, "; ")
+	})
+}
+
+// healLimits 在生产硬上限的基础上叠加 maxResources、maxPatches 和 thresholds 中配置的 cpu、memory
+// thresholds 中不是资源量的值（如 "80%"）不作为上限
+func healLimits(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) llm.HealLimits {
+	limits := llm.DefaultHealLimits.Tighten(llm.HealLimits{MaxPatches: aiopsAnalyzer.Spec.AutoRemediation.MaxPatches})
+	if maxResources := aiopsAnalyzer.Spec.AutoRemediation.MaxResources; maxResources != nil {
+		limits = limits.Tighten(llm.HealLimits{MaxCPU: maxResources[corev1.ResourceCPU], MaxMemory: maxResources[corev1.ResourceMemory]})
+	}
+	if thresholds := aiopsAnalyzer.Spec.Thresholds; thresholds != nil {
+		var configured llm.HealLimits
+		if cpu, err := resource.ParseQuantity(thresholds.CPU); err == nil {
+			configured.MaxCPU = cpu
+		}
+		if memory, err := resource.ParseQuantity(thresholds.Memory); err == nil {
+			configured.MaxMemory = memory
+		}
+		limits = limits.Tighten(configured)
+	}
+	return limits
+}
+
+// exceedsLimits 副本数或资源量超过上限时记录为 noop 并返回 true，需在相对值换算后调用
+func (r *AIOpsAnalyzerReconciler) exceedsLimits(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, error) {
+	err := llm.ValidateHealAction(heal, healLimits(aiopsAnalyzer))
+	if err == nil {
+		return false, nil
+	}
+	log.FromContext(ctx).Info("修复建议的取值超过上限", "error", err.Error())
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonValueOutOfRange, "修复建议的取值超过上限，已忽略: %v", err)
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryPolicyRejected
+		status.Insights = fmt.Sprintf("%s（取值超过上限：%v）", heal.Reason, err)
+		status.NoopReason = autofixv1.NoopReasonPolicyRejected
+		status.NoopMessage = err.Error()
+	})
+}
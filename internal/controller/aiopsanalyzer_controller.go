@@ -18,43 +18,91 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	yaml "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/audit"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
 	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
 	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
-	lark "github.com/larksuite/oapi-sdk-go/v3"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/sanitize"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/secret"
 )
 
 // AIOpsAnalyzerReconciler reconciles a AIOpsAnalyzer object
 type AIOpsAnalyzerReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// APIReader 绕过缓存直接读取 API Server，用于 Event 等无需 watch 的资源，为空时使用 Client
+	APIReader client.Reader
+
+	// Secrets 按 provider 解析 CR 中引用的凭据
+	Secrets secret.Resolvers
+	// Recorder 记录分析、提议、审批等 Kubernetes Event
+	Recorder record.EventRecorder
+	// GitLimiter 限制所有协调共享的 git/PR 并发操作数
+	GitLimiter *gitops.Limiter
+	// DatasourceTimeout 单次查询 Prometheus、Loki 的超时时间，为 0 时使用 15s
+	DatasourceTimeout time.Duration
+	// NewGitProvider 创建 PR/MR 托管平台客户端，为空时使用 gitops.NewProvider
+	NewGitProvider func(repoURL, token string) (gitops.Provider, error)
+	// UpdateCardStatus 把审批卡片更新为审批结果，为空时使用 feishu.UpdateCardStatus
+	UpdateCardStatus func(ctx context.Context, client *lark.Client, messageID string, decision feishu.ApprovalDecision) error
+	// LLM 默认的大模型客户端，CR 配置了 spec.llm.credentialsRef 时按 CR 的凭据单独创建
+	LLM llm.LLMClient
+	// Feishu 默认的飞书客户端，CR 配置了 spec.feishu.credentialsRef 时按 CR 的凭据单独创建
+	Feishu *lark.Client
+	// Audit 大模型请求和响应的审计日志，为空时输出到控制器日志
+	Audit audit.Sink
+	// Defaults 控制器级别的默认配置（--config），CR 中配置的字段优先
+	Defaults config.ControllerConfig
+	// MaxConcurrentReconciles 同时协调的 CR 数，同一个 CR 始终串行协调；为 0 时使用 4
+	MaxConcurrentReconciles int
+
+	// decisions 审批结果写入后通知控制器立即协调
+	decisions chan event.GenericEvent
+	// llmBreaker 大模型连续失败时暂停对应 CR 的分析
+	llmBreaker llmBreaker
+	// intervalWarnings 无效的 analysisInterval 每个 generation 只提示一次
+	intervalWarnings intervalWarnings
+	// podLabelsIndexed 缓存中已按 podLabelsField 建立索引
+	podLabelsIndexed bool
 }
 
-// 常量定义
-const (
-	prometheusQueryEndpoint = "http://127.0.0.1:9090/api/v1/query"
-	lokiQueryEndpoint       = "http://127.0.0.1:3100/loki/api/v1/query"
-)
-
 // +kubebuilder:rbac:groups=autofix.aiops.com,resources=aiopsanalyzers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=autofix.aiops.com,resources=aiopsanalyzers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=autofix.aiops.com,resources=aiopsanalyzers/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -70,153 +118,518 @@ func (r *AIOpsAnalyzerReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	// 1. 获取AIOpsAnalyzer实例
 	var aiopsAnalyzer autofixv1.AIOpsAnalyzer
 	if err := r.Get(ctx, req.NamespacedName, &aiopsAnalyzer); err != nil {
+		if apierrors.IsNotFound(err) {
+			forgetAnalyzerMetrics(req.Namespace, req.Name)
+			r.llmBreaker.reset(req.NamespacedName)
+			r.intervalWarnings.forget(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
 		log.Error(err, "获取AIOpsAnalyzer资源失败")
 		return ctrl.Result{}, err
 	}
 
+	// 跟踪已创建 PR 的合并状态和 ArgoCD 同步状态，未结束前按 prPollInterval 重新入队
+	pollAfter := r.pollPullRequest(ctx, &aiopsAnalyzer)
+	if syncAfter := r.trackArgoCDSync(ctx, &aiopsAnalyzer); pollAfter == 0 || (syncAfter > 0 && syncAfter < pollAfter) {
+		pollAfter = syncAfter
+	}
+
+	result, err := r.reconcile(ctx, &aiopsAnalyzer)
+	recordApprovalPending(&aiopsAnalyzer)
+	if err != nil {
+		analysisTotal.WithLabelValues(analysisResultError).Inc()
+		r.recordEvent(&aiopsAnalyzer, corev1.EventTypeWarning, EventReasonFailed, "分析失败: %v", err)
+	} else if result.IsZero() {
+		// 按 analysisInterval 周期性重新分析
+		result.RequeueAfter = r.nextAnalysisAfter(&aiopsAnalyzer)
+	}
+	if err == nil && pollAfter > 0 && (result.RequeueAfter == 0 || pollAfter < result.RequeueAfter) {
+		result.RequeueAfter = pollAfter
+	}
+
+	// 把本次协调的错误写入status，成功时清空
+	if statusErr := r.recordLastError(ctx, &aiopsAnalyzer, err); statusErr != nil {
+		log.Error(statusErr, "更新lastError失败")
+	}
+	if statusErr := r.recordConditions(ctx, &aiopsAnalyzer, err); statusErr != nil {
+		log.Error(statusErr, "更新conditions失败")
+	}
+	return result, err
+}
+
+// reconcile 执行一次完整的分析流程
+func (r *AIOpsAnalyzerReconciler) reconcile(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// 超时无人响应的审批请求按拒绝处理
+	if err := r.expireApproval(ctx, aiopsAnalyzer); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 审批通过的修复建议先提交 PR，本轮不再分析（dry-run 模式下不做任何 Git 操作）
+	if opened, err := r.openApprovedPullRequest(ctx, aiopsAnalyzer); err != nil || opened {
+		return ctrl.Result{}, err
+	}
+
+	// Once 模式已产出修复建议时不再分析，也不再重新入队
+	completed, err := r.isRunCompleted(ctx, aiopsAnalyzer)
+	if err != nil || completed {
+		if err == nil {
+			err = r.recordNoop(ctx, aiopsAnalyzer, autofixv1.NoopReasonRunCompleted, "Once 模式已产出修复建议，设置重置注解可重新分析")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// 已有修复建议在等待审批时不再分析，避免重复发送卡片；到期后由 expireApproval 处理
+	if pending := awaitingApproval(aiopsAnalyzer); pending != nil {
+		log.Info("修复建议等待审批中，跳过本轮分析", "requestID", pending.RequestID, "expiresAt", pending.ExpiresAt)
+		err := r.recordNoop(ctx, aiopsAnalyzer, autofixv1.NoopReasonAwaitingApproval, fmt.Sprintf("修复建议 %s 等待审批中", pending.RequestID))
+		if remaining := time.Until(pending.ExpiresAt.Time); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, err
+		}
+		return ctrl.Result{}, err
+	}
+
 	// 2. 检查是否有TargetSelector配置
 	if aiopsAnalyzer.Spec.Target.Selector.MatchLabels == nil && aiopsAnalyzer.Spec.Target.Selector.MatchExpressions == nil {
 		log.Info("未配置TargetSelector，跳过Pod获取")
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.recordNoop(ctx, aiopsAnalyzer, autofixv1.NoopReasonNoSelector, "未配置 target.selector")
+	}
+
+	// 上一次修复生效后的冷却期内不查询数据源、不调用大模型，冷却期结束后重新分析
+	if hold, remaining, err := r.holdDuringCooldown(ctx, aiopsAnalyzer); err != nil || hold {
+		if hold {
+			log.Info("仍在冷却期内，跳过本轮分析", "remaining", remaining)
+		}
+		return ctrl.Result{RequeueAfter: remaining}, err
 	}
 
-	// 3. 直接使用GetTargetPods函数获取匹配的Pod列表
-	targetPods, err := r.GetTargetPods(ctx, &aiopsAnalyzer.Spec.Target)
+	// 3. 获取需要分析的Pod列表（unhealthyOnly 时只保留异常Pod）
+	targetPods, err := r.GetContextPods(ctx, aiopsAnalyzer)
 	if err != nil {
 		log.Error(err, "获取目标Pod失败")
 		return ctrl.Result{}, err
 	}
 
 	log.Info("成功获取匹配的Pod", "count", len(targetPods))
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonAnalysisStarted, "开始分析 %d 个目标Pod", len(targetPods))
 
-	// 4. 构建event string
-	eventString, err := r.BuildEventString(ctx, &aiopsAnalyzer.Spec.Target)
+	// 4. 构建event string，数据源地址、凭据无效或无法访问时记录到 condition
+	var eventString string
+	datasources, err := r.resolveDatasources(ctx, aiopsAnalyzer)
+	if err == nil {
+		eventString, err = r.BuildEventString(ctx, aiopsAnalyzer, datasources, targetPods)
+	}
+	if condErr := r.recordDatasourcesCondition(ctx, aiopsAnalyzer, err); condErr != nil {
+		log.Error(condErr, "更新数据源状态失败")
+	}
+	// 只有一个数据源失败时仍然继续分析
+	if err != nil && eventString != "" {
+		log.Info("部分数据源不可用，使用其余数据继续分析", "error", err.Error())
+		err = nil
+	}
 	if err != nil {
 		log.Error(err, "构建event string失败")
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 	}
 
-	// 5. 处理event string（根据您的业务逻辑）
+	// 5. 脱敏后再发送给大模型
+	eventString, err = r.SanitizeEventString(ctx, aiopsAnalyzer, eventString)
+	if err != nil {
+		log.Error(err, "脱敏event string失败")
+		return ctrl.Result{}, err
+	}
+	// 分析周期内情况没有变化时不再重复调用大模型
+	fingerprint := eventFingerprint(aiopsAnalyzer, eventString)
+	if remaining, unchanged := r.unchangedWithinInterval(aiopsAnalyzer, fingerprint, time.Now()); unchanged {
+		log.Info("与上次分析相比情况没有变化，跳过本轮分析", "remaining", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, r.recordNoop(ctx, aiopsAnalyzer, autofixv1.NoopReasonUnchanged, "与上次分析相比情况没有变化")
+	}
+
+	// 6. 超出 token 预算时截断，避免超出大模型的上下文窗口
+	maxTokens := contextMaxTokens(aiopsAnalyzer.Spec.Context)
+	if tokens := estimateTokens(eventString); tokens > maxTokens {
+		eventString = TrimEventString(eventString, maxTokens)
+		log.Info("event string超出token预算，已截断", "estimatedTokens", tokens, "maxTokens", maxTokens, "trimmedTokens", estimateTokens(eventString))
+	}
 	log.Info("成功构建event string", "length", len(eventString))
 	log.Info("event string内容", "content", eventString)
 
-	// 6. 调用大模型生成修复方案
-	llmClient, err := llm.NewOpenAIClient()
+	// 7. 调用大模型生成修复方案
+	return r.analyze(ctx, aiopsAnalyzer, eventString, fingerprint)
+}
+
+// analyze 把 event string 发送给大模型，并根据结论发起修复建议或记录无需处理
+// 大模型调用成功后记录 fingerprint，为空时不记录
+func (r *AIOpsAnalyzerReconciler) analyze(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, eventString, fingerprint string) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// 大模型连续失败后在退避时间内不再调用
+	key := client.ObjectKeyFromObject(aiopsAnalyzer)
+	if remaining := r.llmBreaker.remaining(key, time.Now()); remaining > 0 {
+		log.Info("大模型熔断中，跳过本轮分析", "remaining", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, r.recordNoop(ctx, aiopsAnalyzer, autofixv1.NoopReasonLLMUnavailable, "大模型连续调用失败，熔断期间暂停分析")
+	}
+
+	llmClient, err := r.llmClientFor(ctx, aiopsAnalyzer)
 	if err != nil {
 		log.Error(err, "创建大模型客户端失败")
 		return ctrl.Result{}, err
 	}
 
-	// 构建大模型请求内容
-	currentTime := time.Now().Format("20060102-150405")
-	content := fmt.Sprintf(`### 当前应用信息（请原样使用）：
-- 应用标签选择器：app.kubernetes.io/name=order-service
-- 命名空间：product-a
-- 当前副本数：1
-- 当前 CPU limits：2000m
-- 当前 CPU requests：1000m
-- 当前内存 limits：4Gi
-- 当前时间: %s
-
-### 告警/监控数据：
-%s
-
-请立即决定是否需要自愈，如果需要，按以下 JSON 格式输出（只能输出这个 JSON）：
-
-{
-  "action": "heal" | "noop",
-  "namespace": "order-prod",
-  "reason": "一句话中文原因，用于 git commit（≤50字）",
-  "detail": "详细技术说明，包含问题说明，以及解决方案简述，用于 PR body（≤300字）",
-  "patch_file": "20251126-204555-cpu-spike.yaml",
-  "patch_content": [
-    {
-      "op": "replace",
-      "path": "/spec/replicas",
-      "value": 20
-    }
-  ],
-  "target": {
-    "kind": "Deployment",
-    "labelSelector": "app.kubernetes.io/name=order-service"
-  },
-  "suggested_duration": "30m",
-  "risk_level": "low" | "medium" | "high"
-}
-
-如果不需要自愈，输出：
-{
-  "action": "noop",
-  "reason": "当前指标正常，无需干预"
-}`, currentTime, eventString)
+	// 用线上工作负载的实际配置构建大模型请求内容
+	workload, err := r.describeTargetWorkload(ctx, aiopsAnalyzer)
+	if err != nil {
+		log.Error(err, "获取目标工作负载失败")
+		return ctrl.Result{}, err
+	}
+	content, err := r.buildAnalysisPrompt(ctx, aiopsAnalyzer, workload, eventString, time.Now())
+	if err != nil {
+		log.Error(err, "渲染提示词模板失败")
+		return ctrl.Result{}, err
+	}
 
-	response, err := llmClient.SendMessage(content)
+	sent, err := sendAnalysis(ctx, llmClient, content)
 	if err != nil {
 		log.Error(err, "调用大模型失败")
-		return ctrl.Result{}, err
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonLLMCallFailed, "调用大模型失败: %v", err)
+		failures, backoff := r.llmBreaker.failure(key, time.Now())
+		// 调用失败多是网络、限流等暂时性错误，熔断前返回错误由控制器尽快重试
+		if backoff == 0 {
+			return ctrl.Result{}, err
+		}
+		// 熔断打开后按退避时间重新入队，不返回错误，避免控制器按自身的限速立即重试
+		log.Info("大模型连续调用失败，暂停分析", "failures", failures, "backoff", backoff)
+		return ctrl.Result{RequeueAfter: backoff}, r.recordLLMCondition(ctx, aiopsAnalyzer, failures, backoff, err)
+	}
+	r.llmBreaker.reset(key)
+	if err := r.recordLLMCondition(ctx, aiopsAnalyzer, 0, 0, nil); err != nil {
+		log.Error(err, "更新大模型状态失败")
 	}
+	if fingerprint != "" {
+		if err := r.recordEventHash(ctx, aiopsAnalyzer, fingerprint); err != nil {
+			log.Error(err, "记录event string指纹失败")
+		}
+	}
+	recordLLMUsage(aiopsAnalyzer, sent.Usage)
+	log.Info("大模型 token 用量", "prompt", sent.Usage.PromptTokens, "completion", sent.Usage.CompletionTokens, "total", sent.Usage.TotalTokens)
+
+	// 请求、原始响应和处理结果按 RequestID 写入审计日志
+	requestID := GenerateRequestID(aiopsAnalyzer)
+	auditRecord := newAuditRecord(aiopsAnalyzer, requestID, content, sent.Content)
+	result, err := r.handleResponse(ctx, aiopsAnalyzer, requestID, sent.Content, &auditRecord)
+	r.writeAudit(ctx, aiopsAnalyzer, auditRecord, err)
+	return result, err
+}
 
-	// 7. 解析大模型响应
-	result, err := llm.ParseAutoHealResponse(response)
+// handleResponse 解析大模型响应，根据结论发起修复建议或记录无需处理
+// 解析出的动作写入 auditRecord，供审计使用
+func (r *AIOpsAnalyzerReconciler) handleResponse(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID, response string, auditRecord *audit.Record) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// 7. 解析大模型响应，修复类型是否允许在下面按 allowedActions 检查
+	result, err := llm.ParseAutoHealResponse(response, llm.AllActionsAllowlist())
 	if err != nil {
+		// 同一份响应重新解析仍会失败，记录失败和原始响应后按分析间隔重新分析，不返回错误避免反复重试
 		log.Error(err, "解析大模型响应失败")
-		return ctrl.Result{}, err
+		auditRecord.Error = err.Error()
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonInvalidLLMResponse, "大模型响应无法解析: %v", err)
+		return ctrl.Result{RequeueAfter: analysisInterval(aiopsAnalyzer)},
+			r.recordAnalysisFailure(ctx, aiopsAnalyzer, invalidResponseInsights(err, response))
 	}
+	auditRecord.SetAction(result)
 
 	// 8. 根据响应类型执行不同操作
 	switch v := result.(type) {
 	case *llm.HealAction:
+		analysisTotal.WithLabelValues(analysisResultHeal).Inc()
 		log.Info("自愈动作")
 		log.Info("原因:", "reason", v.Reason)
 		log.Info("风险:", "risk_level", v.RiskLevel)
 		log.Info("补丁文件:", "patch_file", v.PatchFile)
 
-		// 9. 构造卡片变量并发送卡片
-		// 初始化飞书客户端（暂时使用硬编码值，后续可从配置或Secret中获取）
-		client := lark.NewClient("cli_a9a95e30b7f85bc9", "1tzulFiDFgLlw3AbR3eCQeYZRl08g0Xs")
-
-		// 将 []llm.PatchOp 转换为 []feishu.PatchOp
-		patches := make([]feishu.PatchOp, len(v.PatchContent))
-		for i, op := range v.PatchContent {
-			patches[i] = feishu.PatchOp{
-				Op:    op.Op,
-				Path:  op.Path,
-				Value: op.Value,
+		// 未启用自动修复或修复类型不在 allowedActions 中时只记录结论
+		if rejected, err := r.rejectedByPolicy(ctx, aiopsAnalyzer, v); err != nil || rejected {
+			return ctrl.Result{}, err
+		}
+
+		// 修改的 ConfigMap 未被目标工作负载引用时只记录结论
+		if rejected, err := r.rejectedConfigMap(ctx, aiopsAnalyzer, v); err != nil || rejected {
+			return ctrl.Result{}, err
+		}
+
+		// 置信度低于 minConfidence 时只记录结论
+		if low, err := r.belowConfidence(ctx, aiopsAnalyzer, v); err != nil || low {
+			return ctrl.Result{}, err
+		}
+
+		// 观察期内只记录结论，观察期结束后重新分析
+		if hold, remaining, err := r.holdDuringWarmup(ctx, aiopsAnalyzer, v); err != nil || hold {
+			if hold {
+				log.Info("仍在观察期内，不发起修复建议", "remaining", remaining)
 			}
+			return ctrl.Result{RequeueAfter: remaining}, err
 		}
 
-		// 构造卡片变量
-		cardMsg := feishu.NewCardMessage(
-			aiopsAnalyzer.Spec.Feishu.ReceiveID,             // 接收者ID
-			string(aiopsAnalyzer.Spec.Feishu.ReceiveIDType), // 接收类型
-			"AAqhGHg0Wgux8", // 模板ID（暂时硬编码）
-			"0.0.9",         // 模板版本（暂时硬编码）
-			&feishu.CardVariables{
-				Reason:          v.Reason,
-				Patch:           fmt.Sprintf("%v", v.PatchContent),
-				Patches:         patches,
-				ResolveFunction: v.Detail,
-				Namespace:       v.Namespace,
-				Name:            v.Target.LabelSelector,
-				RequestID:       fmt.Sprintf("%s-%d", v.PatchFile, time.Now().Unix()),
-			},
-		)
-
-		// 发送卡片
-		err := feishu.SendTemplateCard(ctx, client, cardMsg)
-		if err != nil {
-			log.Error(err, "发送卡片失败")
+		// 合并的修复在冷却期结束后问题仍在时创建回滚 PR，不再发起新的修复建议
+		if rolledBack, err := r.rollBackFailedRemediation(ctx, aiopsAnalyzer, requestID, v); err != nil || rolledBack {
+			return ctrl.Result{}, err
+		}
+
+		// 按名称引用的容器不存在时只记录结论；名称引用保留到创建 PR 时再换算为下标
+		if rejected, err := r.rejectUnknownContainers(ctx, aiopsAnalyzer, v); err != nil || rejected {
+			return ctrl.Result{}, err
+		}
+
+		// 把相对值（如 "+50%"）换算为具体的资源量
+		if err := r.resolveRelativeResources(ctx, aiopsAnalyzer, v); err != nil {
+			log.Error(err, "换算相对资源值失败")
+			return ctrl.Result{}, err
+		}
+
+		// 副本数和资源量超过 thresholds、maxResources 或生产硬上限时只记录结论
+		if exceeded, err := r.exceedsLimits(ctx, aiopsAnalyzer, v); err != nil || exceeded {
+			return ctrl.Result{}, err
+		}
+
+		// 安全模式下拒绝破坏性操作，不发送卡片
+		if blocked, err := r.blockedBySafeMode(ctx, aiopsAnalyzer, v); err != nil || blocked {
+			return ctrl.Result{}, err
+		}
+
+		// dry-run 模式下只把修复建议写入status，不发送卡片、不评论或创建 PR
+		if dryRun, err := r.recordDryRun(ctx, aiopsAnalyzer, v); err != nil || dryRun {
+			return ctrl.Result{}, err
+		}
+
+		// 预览模式下把修复建议评论到跟踪 PR
+		if aiopsAnalyzer.Spec.GitOps.PreviewMode != nil {
+			if err := r.postPreviewComment(ctx, aiopsAnalyzer, v); err != nil {
+				log.Error(err, "发布预览评论失败")
+			} else {
+				log.Info("预览评论发布成功", "trackingPR", aiopsAnalyzer.Spec.GitOps.PreviewMode.TrackingPR)
+			}
+		}
+
+		// 9. 需要审批时发送审批卡片并等待回调，否则直接批准
+		// critical 严重程度的高风险修复即使未要求审批也升级为人工审批
+		var result ctrl.Result
+		var proposed bool
+		requireApproval := aiopsAnalyzer.Spec.AutoRemediation.RequireApproval
+		if escalation := EscalationPolicy(proposalSeverity(v), v.RiskLevel); escalation.RequireApproval && !requireApproval {
+			log.Info("严重程度和风险等级较高，升级为人工审批", "severity", proposalSeverity(v), "riskLevel", v.RiskLevel)
+			r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonApprovalEscalated,
+				"严重程度 %s、风险等级 %s 的修复建议需要人工审批", proposalSeverity(v), v.RiskLevel)
+			requireApproval = true
+		}
+		if requireApproval {
+			if err := r.sendApprovalCard(ctx, aiopsAnalyzer, v, requestID); err != nil {
+				log.Error(err, "发送卡片失败")
+			} else {
+				log.Info("卡片发送成功", "requestID", requestID)
+				proposed = true
+				// 审批超时后重新协调，处理无人响应的请求
+				result.RequeueAfter = approvalTimeout(aiopsAnalyzer)
+			}
 		} else {
-			log.Info("卡片发送成功")
+			if err := r.autoApprove(ctx, aiopsAnalyzer, requestID); err != nil {
+				log.Error(err, "自动批准修复建议失败")
+				return ctrl.Result{}, err
+			}
+			log.Info("未要求审批，已自动批准", "requestID", requestID)
+			proposed = true
+			// 立即重新协调以创建 PR
+			result.Requeue = true
+		}
+		if proposed {
+			record := newRemediationRecord(requestID, v)
+			// 升级为人工审批的修复建议仍在等待审批，不能记为已批准
+			if !requireApproval {
+				approved := true
+				record.Approved = &approved
+			}
+			if aiopsAnalyzer.Spec.AutoRemediation.AutoRollback {
+				if record.RollbackPatches, err = r.rollbackPatchesFor(ctx, aiopsAnalyzer, v); err != nil {
+					log.Error(err, "生成回滚补丁失败")
+				}
+			}
+			if err := r.recordHistory(ctx, aiopsAnalyzer, record); err != nil {
+				log.Error(err, "记录修复历史失败")
+			}
+			r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonRemediationProposed,
+				"提出修复建议 %s（风险: %s）: %s", requestID, v.RiskLevel, v.Reason)
 		}
+
+		// 把结论和完整的修复建议写入status，审批通过后据此创建 PR
+		if err := r.recordProposal(ctx, aiopsAnalyzer, v); err != nil {
+			log.Error(err, "记录修复建议失败")
+			return ctrl.Result{}, err
+		}
+
+		// Once 模式下产出修复建议后进入终止状态
+		if err := r.markRunCompleted(ctx, aiopsAnalyzer, v.Reason); err != nil {
+			log.Error(err, "更新终止状态失败")
+			return ctrl.Result{}, err
+		}
+		return result, nil
 	case *llm.NoopAction:
-		// 更新status，然后return
-		log.Info("无需操作:", "reason", v.Reason)
+		analysisTotal.WithLabelValues(analysisResultNoop).Inc()
+		log.Info("无需操作:", "reason", v.Reason, "detail", v.Detail, "severity", v.Severity)
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonNoActionNeeded, "无需处理: %s", v.Reason)
+		if err := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			now := metav1.Now()
+			status.LastAnalysisTime = &now
+			status.Summary = autofixv1.SummaryHealthy
+			status.Insights = v.Reason
+			if v.Detail != "" {
+				status.Insights = fmt.Sprintf("%s：%s", v.Reason, v.Detail)
+			}
+			if v.Severity != "" {
+				status.Insights = fmt.Sprintf("[%s] %s", v.Severity, status.Insights)
+			}
+			status.NoopReason = autofixv1.NoopReasonLLMNoop
+			status.NoopMessage = v.Reason
+		}); err != nil {
+			log.Error(err, "更新noopReason失败")
+			return ctrl.Result{}, err
+		}
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// sendApprovalCard 构造审批卡片，先持久化待审批请求再发送
+func (r *AIOpsAnalyzerReconciler) sendApprovalCard(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, v *llm.HealAction, requestID string) error {
+	client, err := r.newFeishuClient(ctx, aiopsAnalyzer)
+	if err != nil {
+		return fmt.Errorf("create feishu client failed: %w", err)
+	}
+
+	cardMsg, vars, err := r.buildApprovalCard(ctx, aiopsAnalyzer, v, requestID)
+	if err != nil {
+		return fmt.Errorf("build approval card failed: %w", err)
+	}
+
+	approval := newApprovalRequest(aiopsAnalyzer, requestID)
+	return r.requestApproval(ctx, aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+		return r.sendCardWithFallback(ctx, aiopsAnalyzer, client, cardMsg, vars)
+	})
+}
+
+// buildApprovalCard 按接收者路由和 spec.feishu 中的模板构造审批卡片
+func (r *AIOpsAnalyzerReconciler) buildApprovalCard(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, v *llm.HealAction, requestID string) (*feishu.CardMessage, *feishu.CardVariables, error) {
+	// 将 []llm.PatchOp 转换为 []feishu.PatchOp
+	patches := make([]feishu.PatchOp, len(v.PatchContent))
+	for i, op := range v.PatchContent {
+		patches[i] = feishu.PatchOp{
+			Op:    op.Op,
+			Path:  op.Path,
+			Value: op.Value,
+		}
+	}
+
+	// 构造卡片变量，补丁按目标资源渲染，并展示线上的当前值
+	diff := r.renderPatchDiff(ctx, aiopsAnalyzer, v)
+	receiveIDType, receiveID := feishuReceiver(&aiopsAnalyzer.Spec.Feishu, v.RiskLevel)
+	escalation := EscalationPolicy(proposalSeverity(v), v.RiskLevel)
+	vars := &feishu.CardVariables{
+		Reason:            v.Reason,
+		Patch:             diff,
+		Patches:           patches,
+		PatchDiff:         diff,
+		ResolveFunction:   v.Detail,
+		Namespace:         healNamespace(aiopsAnalyzer, v),
+		Name:              v.Target.LabelSelector,
+		RequestID:         requestID,
+		RiskLevel:         v.RiskLevel,
+		Severity:          v.Severity,
+		SuggestedDuration: v.SuggestedDuration,
+		Confidence:        formatConfidence(v.Confidence),
+		Mentions:          feishuMentions(ctx, &aiopsAnalyzer.Spec.Feishu, escalation.MentionRoles),
+	}
+	templateID, templateVersion := feishuTemplate(&aiopsAnalyzer.Spec.Feishu, r.Defaults.Feishu)
+	cardMsg, err := feishu.NewCardMessage(
+		receiveID,             // 接收者ID（按风险等级路由）
+		string(receiveIDType), // 接收类型
+		templateID,            // 模板ID
+		templateVersion,       // 模板版本
+		vars,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cardMsg, vars, nil
+}
+
+// errCardFallback 审批卡片改发为富文本或纯文本通知，通知中没有审批按钮，不能保留待审批请求
+var errCardFallback = errors.New("approval card fell back to a rich-text or plain-text notification")
+
+// sendCardWithFallback 发送模板卡片，模板相关的错误时改发富文本摘要，富文本也失败时再改发纯文本，保证仍能通知到人
+// 兜底消息无法审批，改发后返回 errCardFallback，由 requestApproval 撤销待审批请求
+func (r *AIOpsAnalyzerReconciler) sendCardWithFallback(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, client *lark.Client, msg *feishu.CardMessage, vars *feishu.CardVariables) (string, error) {
+	messageID, err := feishu.SendTemplateCard(ctx, client, msg)
+	if err == nil || !feishu.IsTemplateError(err) {
+		return messageID, err
+	}
+
+	log.FromContext(ctx).Error(err, "发送审批卡片失败，改发富文本通知", "templateID", msg.TemplateID, "version", msg.Version)
+	fallback := "富文本"
+	post, postErr := vars.Post()
+	if postErr == nil {
+		_, postErr = feishu.SendPostMessage(ctx, client, msg.ReceiveID, msg.ReceiveType, post)
+	}
+	if postErr != nil {
+		// 富文本也发不出去时改发纯文本，纯文本消息没有格式，最不容易被拒绝
+		log.FromContext(ctx).Error(postErr, "发送富文本通知失败，改发纯文本通知")
+		if _, textErr := feishu.SendTextMessage(ctx, client, msg.ReceiveID, msg.ReceiveType, vars.Text()); textErr != nil {
+			return "", fmt.Errorf("%w; fallback post message failed: %v; fallback text message failed: %v", err, postErr, textErr)
+		}
+		fallback = "纯文本"
+	}
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonCardFallback, "审批卡片发送失败，已改发%s通知，修复建议未进入审批: %v", fallback, err)
+	return "", fmt.Errorf("%w: %v", errCardFallback, err)
+}
+
+// streamProgressBytes 流式响应每收到这么多字节记录一次进度
+const streamProgressBytes = 1024
+
+// sendAnalysis 调用大模型，客户端支持流式响应时边接收边记录进度
+func sendAnalysis(ctx context.Context, llmClient llm.LLMClient, content string) (llm.SendMessageResult, error) {
+	defer observeDuration(llmRequestDuration, time.Now())
+	streaming, ok := llmClient.(llm.StreamingLLMClient)
+	if !ok {
+		return llmClient.SendMessage(ctx, content)
+	}
+
+	logger := log.FromContext(ctx)
+	start := time.Now()
+	received, logged := 0, 0
+	sent, err := streaming.SendMessageStream(ctx, content, func(delta string) {
+		received += len(delta)
+		if received-logged >= streamProgressBytes {
+			logged = received
+			logger.V(1).Info("正在接收大模型响应", "bytes", received, "elapsed", time.Since(start).Round(time.Millisecond))
+		}
+	})
+	if err == nil {
+		logger.Info("大模型响应完成", "bytes", len(sent.Content), "elapsed", time.Since(start).Round(time.Millisecond))
+	}
+	return sent, err
+}
+
+// renderPatchDiff 渲染修复建议的补丁，找不到目标工作负载或 ConfigMap 时只展示新值
+func (r *AIOpsAnalyzerReconciler) renderPatchDiff(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) string {
+	ops, live, err := r.healPatches(ctx, aiopsAnalyzer, heal)
+	if err == nil {
+		return patch.RenderDiff(ops, live...)
+	}
+	log.FromContext(ctx).Error(err, "获取目标资源失败，补丁中不展示当前值")
+	if ops, err = toPatchOperations(heal.PatchContent); err != nil {
+		return fmt.Sprintf("%v", heal.PatchContent)
+	}
+	return patch.RenderDiff(ops)
+}
+
 // GetTargetPods 根据TargetSelector获取对应的Pod列表
 func (r *AIOpsAnalyzerReconciler) GetTargetPods(ctx context.Context, target *autofixv1.TargetSelector) ([]corev1.Pod, error) {
 	log := log.FromContext(ctx)
@@ -240,6 +653,12 @@ func (r *AIOpsAnalyzerReconciler) GetTargetPods(ctx context.Context, target *aut
 		}
 		listOptions.LabelSelector = selector
 		log.V(1).Info("应用标签选择器", "selector", selector.String())
+		// 缓存中建立了标签索引时先按索引缩小范围，避免大命名空间中逐个匹配所有 Pod
+		if r.podLabelsIndexed {
+			if fields := podLabelIndexSelector(&target.Selector); fields != nil {
+				fields.ApplyToList(listOptions)
+			}
+		}
 	} else {
 		log.V(1).Info("未配置标签选择器，将获取命名空间内所有 Pod")
 	}
@@ -269,23 +688,50 @@ func BuildLabelSelector(labels map[string]string) (*metav1.LabelSelector, error)
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AIOpsAnalyzerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// 按节点名索引Pod，用于统计节点资源分配情况
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podNodeNameField,
+		func(obj client.Object) []string {
+			pod := obj.(*corev1.Pod)
+			if pod.Spec.NodeName == "" {
+				return nil
+			}
+			return []string{pod.Spec.NodeName}
+		}); err != nil {
+		return err
+	}
+	// 按标签索引Pod，GetTargetPods 从缓存读取时按索引查询目标Pod
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podLabelsField, indexPodLabels); err != nil {
+		return err
+	}
+	r.podLabelsIndexed = true
+
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("aiopsanalyzer-controller")
+	}
+
+	// 不同 CR 并行协调；同一个 CR 的事件由 workqueue 合并，见 defaultMaxConcurrentReconciles
+	maxConcurrentReconciles := r.MaxConcurrentReconciles
+	if maxConcurrentReconciles <= 0 {
+		maxConcurrentReconciles = defaultMaxConcurrentReconciles
+	}
+
+	// 只在 spec 或注解变化时触发，避免写 status 后再次触发分析
+	// 审批结果通过 decisions 单独触发，以便尽快创建 PR
+	r.decisions = make(chan event.GenericEvent, decisionQueueSize)
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&autofixv1.AIOpsAnalyzer{}).
+		For(&autofixv1.AIOpsAnalyzer{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		WatchesRawSource(source.Channel(r.decisions, &handler.EnqueueRequestForObject{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}).
 		Named("aiopsanalyzer").
 		Complete(r)
 }
 
-// GetTargetResourceYAML 根据TargetSelector获取资源YAML并过滤不重要的字段
-func (r *AIOpsAnalyzerReconciler) GetTargetResourceYAML(ctx context.Context, target *autofixv1.TargetSelector) (string, error) {
+// GetTargetResourceYAML 把目标Pod序列化为YAML并过滤不重要的字段
+func (r *AIOpsAnalyzerReconciler) GetTargetResourceYAML(ctx context.Context, pods []corev1.Pod) (string, error) {
 	log := log.FromContext(ctx)
 
-	// 1. 获取目标Pod列表
-	pods, err := r.GetTargetPods(ctx, target)
-	if err != nil {
-		log.Error(err, "获取目标Pod失败")
-		return "", err
-	}
-
+	// 1. 没有目标Pod时返回空
 	if len(pods) == 0 {
 		return "", nil
 	}
@@ -329,208 +775,376 @@ func FilterPodFields(pod *corev1.Pod) *corev1.Pod {
 	filtered.ObjectMeta.Finalizers = nil
 	filtered.ObjectMeta.OwnerReferences = nil
 
-	// 过滤status中的字段
-	filtered.Status = corev1.PodStatus{
+	// 过滤status中的字段（Pending 的Pod可能还没有 conditions 和容器状态）
+	status := corev1.PodStatus{
 		Phase: filtered.Status.Phase,
-		Conditions: []corev1.PodCondition{
-			{
-				Type:   corev1.PodReady,
-				Status: filtered.Status.Conditions[len(filtered.Status.Conditions)-1].Status,
-			},
-		},
-		ContainerStatuses: []corev1.ContainerStatus{
-			{
-				Name:  filtered.Status.ContainerStatuses[0].Name,
-				Ready: filtered.Status.ContainerStatuses[0].Ready,
-				State: filtered.Status.ContainerStatuses[0].State,
-			},
-		},
 	}
+	for _, condition := range filtered.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: condition.Status}}
+		}
+	}
+	// 保留所有容器（包括 sidecar）的状态，去掉镜像 ID 等冗长字段
+	for _, container := range filtered.Status.ContainerStatuses {
+		status.ContainerStatuses = append(status.ContainerStatuses, corev1.ContainerStatus{
+			Name:         container.Name,
+			Ready:        container.Ready,
+			RestartCount: container.RestartCount,
+			State:        container.State,
+		})
+	}
+	filtered.Status = status
 
 	return filtered
 }
 
 // GetPrometheusAlerts 从Prometheus获取告警信息
-func (r *AIOpsAnalyzerReconciler) GetPrometheusAlerts(ctx context.Context, target *autofixv1.TargetSelector) (string, error) {
+func (r *AIOpsAnalyzerReconciler) GetPrometheusAlerts(ctx context.Context, datasources datasources, target *autofixv1.TargetSelector) (string, error) {
 	log := log.FromContext(ctx)
 
+	defer observeDuration(prometheusQueryDuration, time.Now())
+
 	// 构建Prometheus查询
-	query := fmt.Sprintf("ALERTS{namespace='%s'}", target.Namespace)
-	if target.Selector.MatchLabels != nil {
-		for k, v := range target.Selector.MatchLabels {
-			query += fmt.Sprintf(",%s='%s'", k, v)
-		}
-	}
-	query += " and ALERTS.state='firing'"
+	query := prometheusAlertsQuery(target)
 
 	// 发送请求
-	resp, err := http.Get(fmt.Sprintf("%s?query=%s", prometheusQueryEndpoint, url.QueryEscape(query)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s%s?query=%s", datasources.PrometheusURL, prometheusQueryPath, url.QueryEscape(query)), nil)
 	if err != nil {
-		log.Error(err, "发送Prometheus查询请求失败")
 		return "", err
 	}
+	datasources.PrometheusAuth.apply(req)
+	resp, err := r.datasourceHTTPClient().Do(req)
+	if err != nil {
+		log.Error(err, "发送Prometheus查询请求失败")
+		return "", unreachableDatasource("prometheus", datasources.PrometheusURL, err)
+	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Error(nil, "Prometheus返回非200", "status", resp.StatusCode, "body", string(body))
+		return "", fmt.Errorf("prometheus returned %d: %s", resp.StatusCode, string(body))
+	}
+
 	// 解析响应
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	alerts, err := parsePrometheusAlerts(resp.Body)
+	if err != nil {
 		log.Error(err, "解析Prometheus响应失败")
 		return "", err
 	}
 
 	// 格式化告警信息
 	var alertsBuilder strings.Builder
-	if data, ok := result["data"].(map[string]interface{}); ok {
-		if resultType, ok := data["resultType"].(string); ok && resultType == "vector" {
-			if results, ok := data["result"].([]interface{}); ok {
-				for _, item := range results {
-					if alert, ok := item.(map[string]interface{}); ok {
-						if metric, ok := alert["metric"].(map[string]interface{}); ok {
-							alertsBuilder.WriteString(fmt.Sprintf("Alert: %s\n", metric["alertname"]))
-							alertsBuilder.WriteString(fmt.Sprintf("  Namespace: %s\n", metric["namespace"]))
-							if pod, ok := metric["pod"].(string); ok {
-								alertsBuilder.WriteString(fmt.Sprintf("  Pod: %s\n", pod))
-							}
-							alertsBuilder.WriteString("\n")
-						}
-					}
-				}
-			}
-		}
+	for _, alert := range alerts {
+		alertsBuilder.WriteString(alert.Format())
+		alertsBuilder.WriteString("\n")
 	}
 
 	return alertsBuilder.String(), nil
 }
 
-// GetLokiLogs 从Loki获取日志信息
-func (r *AIOpsAnalyzerReconciler) GetLokiLogs(ctx context.Context, target *autofixv1.TargetSelector) (string, error) {
+// QueryRange 调用 Prometheus /api/v1/query_range，返回 [start, end] 内按 step 采样的序列
+func (r *AIOpsAnalyzerReconciler) QueryRange(ctx context.Context, datasources datasources, query string, start, end time.Time, step time.Duration) ([]PrometheusSeries, error) {
+	log := log.FromContext(ctx)
+	defer observeDuration(prometheusQueryDuration, time.Now())
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s%s?%s", datasources.PrometheusURL, prometheusQueryRangePath, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	datasources.PrometheusAuth.apply(req)
+	resp, err := r.datasourceHTTPClient().Do(req)
+	if err != nil {
+		log.Error(err, "发送Prometheus区间查询请求失败")
+		return nil, unreachableDatasource("prometheus", datasources.PrometheusURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Error(nil, "Prometheus返回非200", "status", resp.StatusCode, "body", string(body))
+		return nil, fmt.Errorf("prometheus returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	return parsePrometheusSeries(resp.Body)
+}
+
+// GetPrometheusTrends 执行 spec.prometheus.rangeQueries，按名称分段输出各序列的趋势
+// 单个查询失败只记录在对应分段中，不影响其它查询
+func (r *AIOpsAnalyzerReconciler) GetPrometheusTrends(ctx context.Context, datasources datasources, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, pods []corev1.Pod) string {
+	log := log.FromContext(ctx)
+	cfg := aiopsAnalyzer.Spec.Prometheus
+	if cfg == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	now := time.Now()
+	for _, rangeQuery := range cfg.RangeQueries {
+		fmt.Fprintf(&b, "--- %s ---\n", rangeQuery.Name)
+		query, err := prometheusRangeQueryFor(rangeQuery, &aiopsAnalyzer.Spec.Target, pods, now)
+		var series []PrometheusSeries
+		if err == nil {
+			series, err = r.QueryRange(ctx, datasources, query.Expr, query.Start, query.End, query.Step)
+		}
+		switch {
+		case err != nil:
+			log.Error(err, "Prometheus区间查询失败", "name", rangeQuery.Name)
+			fmt.Fprintf(&b, "Unavailable: %v\n", err)
+		case len(series) == 0:
+			b.WriteString("No data\n")
+		default:
+			for _, s := range series {
+				b.WriteString(s.Format())
+			}
+		}
+	}
+	return b.String()
+}
+
+// GetLokiLogs 从Loki获取目标及 spec.loki.additionalStreams 的错误日志，按来源分段输出
+// 同时返回按错误签名统计的出现次数，统计在截断前进行，覆盖查询到的所有日志
+func (r *AIOpsAnalyzerReconciler) GetLokiLogs(ctx context.Context, datasources datasources, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (logs, signatures string, err error) {
 	log := log.FromContext(ctx)
+	target := &aiopsAnalyzer.Spec.Target
 
-	// 构建 LogQL 查询：关键修复点是将所有标签值从单引号 ' 更改为双引号 "
-	query := fmt.Sprintf("{namespace=\"%s\"", target.Namespace)
+	selector := lokiTargetSelector(target)
 	log.Info("查询命名空间", "namespace", target.Namespace)
 
-	if target.Selector.MatchLabels != nil {
-		for k, v := range target.Selector.MatchLabels {
-			// 使用双引号 " 包裹标签值
-			query += fmt.Sprintf(",%s=\"%s\"", k, v)
+	query, err := lokiQueryFor(aiopsAnalyzer.Spec.Loki, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	// 归一化正则由 webhook 校验，绕过 webhook 写入的无效正则只跳过签名统计，原始日志照常返回
+	normalizer, normalizerErr := signatureNormalizerFor(aiopsAnalyzer.Spec.Loki)
+	if normalizerErr != nil {
+		log.Error(normalizerErr, "错误签名归一化正则无效，跳过签名统计")
+	}
+	log.Info("查询时间范围", "start", query.Start.Format("2006-01-02 15:04:05"), "end", query.End.Format("2006-01-02 15:04:05"))
+
+	// 目标自身的日志失败时直接返回错误，额外日志流失败只记录在结果中
+	lines, err := r.queryLokiLines(ctx, datasources, query, selector)
+	if err != nil {
+		return "", "", err
+	}
+	streams := []lokiStreamLogs{{Selector: selector, Lines: lines}}
+
+	var additional []string
+	if cfg := aiopsAnalyzer.Spec.Loki; cfg != nil {
+		additional = cfg.AdditionalStreams
+	}
+	for _, stream := range additional {
+		lines, err := r.queryLokiLines(ctx, datasources, query, stream)
+		if err != nil {
+			log.Error(err, "查询额外日志流失败", "stream", stream)
 		}
+		streams = append(streams, lokiStreamLogs{Selector: stream, Lines: lines, Err: err})
+	}
+
+	logs = formatLokiStreams(streams, lokiLimitsFor(aiopsAnalyzer.Spec.Loki))
+	if normalizerErr != nil {
+		return logs, "", nil
 	}
-	// 正则表达式部分保持不变，使用反引号 `
-	// 直接用 or 连接多个字面量匹配（大小写分开写，覆盖所有常见变体）
-	query += "} |~ \"(?i)(error|panic|fatal|critical)\""
+	return logs, normalizer.FormatHistogram(streams), nil
+}
+
+// queryLokiLines 按查询范围和过滤条件查询 selector 对应的日志流，返回 "时间戳: 日志" 形式的行
+func (r *AIOpsAnalyzerReconciler) queryLokiLines(ctx context.Context, datasources datasources, query lokiQuery, selector string) ([]string, error) {
+	log := log.FromContext(ctx)
+	defer observeDuration(lokiQueryDuration, time.Now())
+	logQL := query.LogQL(selector)
+	log.Info("query 语句", "query", logQL)
 
-	// 这一行计算的是毫秒时间戳
-	timeRange := time.Now().Add(-48*time.Minute).UnixNano() / int64(time.Millisecond)
-	log.Info("查询起始时间", "timeRange", time.Now().Add(-48*time.Minute).Format("2006-01-02 15:04:05"))
-	log.Info("query 语句", "query", query)
-	log.Info("查询时间范围", "timeRange", timeRange)
-	// 对完整的 LogQL query 进行 URL 编码
-	url := fmt.Sprintf("%s?query=%s&start=%d", lokiQueryEndpoint, url.QueryEscape(query), timeRange)
+	// 对完整的 LogQL query 进行 URL 编码，start/end 使用纳秒时间戳
+	url := fmt.Sprintf("%s%s?query=%s&start=%d&end=%d", datasources.LokiURL, lokiQueryPath,
+		url.QueryEscape(logQL), query.Start.UnixNano(), query.End.UnixNano())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// 关键行：设置 X-Scope-OrgID header
-	req.Header.Set("X-Scope-OrgID", "1")
+	// 多租户 Loki 按 X-Scope-OrgID 区分租户，单租户 Loki 可能拒绝该 header，未配置时不发送
+	if datasources.LokiOrgID != "" {
+		req.Header.Set("X-Scope-OrgID", datasources.LokiOrgID)
+	}
+	datasources.LokiAuth.apply(req)
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := r.datasourceHTTPClient().Do(req)
 	if err != nil {
 		log.Error(err, "发送Loki查询请求失败")
-		return "", err
+		return nil, unreachableDatasource("loki", datasources.LokiURL, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		log.Error(nil, "Loki返回非200", "status", resp.StatusCode, "body", string(body))
-		return "", fmt.Errorf("loki returned %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("loki returned %d: %s", resp.StatusCode, string(body))
 	}
 
-	// 注意：这里打印 resp.Body 是错误的，因为它是一个 io.ReadCloser，需要先读取才能打印内容
-	// 但为了保持原意，我们继续往下解析。
 	log.Info("Loki查询响应", "status", resp.StatusCode)
 
 	// 解析响应
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	lines, err := parseLokiLines(resp.Body)
+	if err != nil {
 		log.Error(err, "解析Loki响应失败")
-		return "", err
-	}
-	log.Info("Loki查询响应", "result", result)
-	// 格式化日志信息
-	var logsBuilder strings.Builder
-	if data, ok := result["data"].(map[string]interface{}); ok {
-		if resultType, ok := data["resultType"].(string); ok && resultType == "streams" {
-			if streams, ok := data["result"].([]interface{}); ok {
-				for _, stream := range streams {
-					if streamData, ok := stream.(map[string]interface{}); ok {
-						if values, ok := streamData["values"].([]interface{}); ok {
-							for _, value := range values {
-								if logEntry, ok := value.([]interface{}); ok && len(logEntry) >= 2 {
-									// logEntry[0] 是时间戳，logEntry[1] 是日志行内容
-									logsBuilder.WriteString(fmt.Sprintf("%s: %s\n", logEntry[0], logEntry[1]))
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+		return nil, err
 	}
+	return lines, nil
+}
 
-	return logsBuilder.String(), nil
+// newSanitizer 按 spec.sanitizer 创建脱敏器
+func newSanitizer(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (*sanitize.Sanitizer, error) {
+	var patterns []string
+	includeDefaults := true
+	if cfg := aiopsAnalyzer.Spec.Sanitizer; cfg != nil {
+		patterns = cfg.Patterns
+		includeDefaults = !cfg.DisableDefaultRules
+	}
+	return sanitize.New(patterns, includeDefaults)
 }
 
-// BuildEventString 组装event string
-func (r *AIOpsAnalyzerReconciler) BuildEventString(ctx context.Context, target *autofixv1.TargetSelector) (string, error) {
+// SanitizeEventString 按 spec.sanitizer 对event string脱敏，并记录替换次数
+func (r *AIOpsAnalyzerReconciler) SanitizeEventString(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, eventString string) (string, error) {
 	log := log.FromContext(ctx)
 
-	// 1. 获取资源YAML
-	resourceYAML, err := r.GetTargetResourceYAML(ctx, target)
+	sanitizer, err := newSanitizer(aiopsAnalyzer)
 	if err != nil {
-		log.Error(err, "获取资源YAML失败")
 		return "", err
 	}
+	sanitized, redactions := sanitizer.Sanitize(eventString)
+	log.Info("event string脱敏完成", "redactions", redactions)
+	return sanitized, nil
+}
 
-	// 2. 获取Prometheus告警
-	prometheusAlerts, err := r.GetPrometheusAlerts(ctx, target)
-	if err != nil {
-		log.Error(err, "获取Prometheus告警失败")
+// BuildEventString 根据需要分析的Pod组装event string，各部分并发获取
+// 资源YAML读取失败时返回错误；Prometheus、Loki 只有一个失败时用占位段落代替，
+// 同时返回完整的 event string 和该数据源的错误，调用方据此更新 condition 并继续分析；
+// 节点压力和 Warning Event 只是补充信息，读取失败时用占位段落代替，不返回错误
+func (r *AIOpsAnalyzerReconciler) BuildEventString(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, datasources datasources, pods []corev1.Pod) (string, error) {
+	log := log.FromContext(ctx)
+	target := &aiopsAnalyzer.Spec.Target
+
+	var (
+		resourceYAML, nodePressure, prometheusAlerts, prometheusTrends, lokiLogs, lokiSignatures, targetEvents string
+		prometheusErr, lokiErr, eventsErr, nodeErr                                                             error
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	// 1. 获取资源YAML
+	g.Go(func() (err error) {
+		if resourceYAML, err = r.GetTargetResourceYAML(gctx, pods); err != nil {
+			log.Error(err, "获取资源YAML失败")
+		}
+		return err
+	})
+	// 2. 获取目标Pod所在节点的压力情况，读取失败时用占位段落代替
+	g.Go(func() error {
+		if nodePressure, nodeErr = r.GetNodePressure(gctx, pods); nodeErr != nil {
+			log.Error(nodeErr, "获取节点压力信息失败")
+		}
+		return nil
+	})
+	// 3. 获取Prometheus告警
+	g.Go(func() error {
+		if prometheusAlerts, prometheusErr = r.GetPrometheusAlerts(gctx, datasources, target); prometheusErr != nil {
+			log.Error(prometheusErr, "获取Prometheus告警失败")
+		} else {
+			log.Info("Prometheus告警信息", "alerts", prometheusAlerts)
+		}
+		return nil
+	})
+	// 4. 获取Prometheus指标趋势
+	g.Go(func() error {
+		prometheusTrends = r.GetPrometheusTrends(gctx, datasources, aiopsAnalyzer, pods)
+		return nil
+	})
+	// 5. 获取Loki日志
+	g.Go(func() error {
+		if lokiLogs, lokiSignatures, lokiErr = r.GetLokiLogs(gctx, datasources, aiopsAnalyzer); lokiErr != nil {
+			log.Error(lokiErr, "获取Loki日志失败")
+		}
+		return nil
+	})
+	// 6. 获取目标Pod的 Warning Event，读取失败时用占位段落代替
+	g.Go(func() error {
+		if targetEvents, eventsErr = r.GetTargetEvents(gctx, aiopsAnalyzer, pods); eventsErr != nil {
+			log.Error(eventsErr, "获取Warning Event失败")
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
 		return "", err
 	}
-	log.Info("Prometheus告警信息", "alerts", prometheusAlerts)
-	// 3. 获取Loki日志
-	lokiLogs, err := r.GetLokiLogs(ctx, target)
-	if err != nil {
-		log.Error(err, "获取Loki日志失败")
-		return "", err
+	// 两个数据源都失败时没有可供分析的监控数据
+	if prometheusErr != nil && lokiErr != nil {
+		return "", errors.Join(prometheusErr, lokiErr)
 	}
 
-	// 4. 组装event string
+	// 7. 组装event string
 	var eventBuilder strings.Builder
 
 	eventBuilder.WriteString("=== Target Resource Information ===\n")
 	eventBuilder.WriteString(resourceYAML)
 
+	eventBuilder.WriteString("\n=== Node Pressure ===\n")
+	switch {
+	case nodeErr != nil:
+		fmt.Fprintf(&eventBuilder, "Unavailable: %v\n", nodeErr)
+	case nodePressure == "":
+		eventBuilder.WriteString("No scheduled nodes\n")
+	default:
+		eventBuilder.WriteString(nodePressure)
+	}
+
+	eventBuilder.WriteString("\n=== Kubernetes Warning Events ===\n")
+	switch {
+	case eventsErr != nil:
+		fmt.Fprintf(&eventBuilder, "Unavailable: %v\n", eventsErr)
+	case targetEvents == "":
+		eventBuilder.WriteString("No warning events\n")
+	default:
+		eventBuilder.WriteString(targetEvents)
+	}
+
 	eventBuilder.WriteString("\n=== Prometheus Alerts ===\n")
-	if prometheusAlerts == "" {
+	switch {
+	case prometheusErr != nil:
+		fmt.Fprintf(&eventBuilder, "Unavailable: %v\n", prometheusErr)
+	case prometheusAlerts == "":
 		eventBuilder.WriteString("No firing alerts\n")
-	} else {
+	default:
 		eventBuilder.WriteString(prometheusAlerts)
 	}
 
+	if prometheusTrends != "" {
+		eventBuilder.WriteString("\n=== Prometheus Trends ===\n")
+		eventBuilder.WriteString(prometheusTrends)
+	}
+
 	eventBuilder.WriteString("\n=== Loki Error Logs ===\n")
-	if lokiLogs == "" {
+	switch {
+	case lokiErr != nil:
+		fmt.Fprintf(&eventBuilder, "Unavailable: %v\n", lokiErr)
+	case lokiLogs == "":
 		eventBuilder.WriteString("No error logs\n")
-	} else {
+	default:
 		eventBuilder.WriteString(lokiLogs)
 	}
 
-	return eventBuilder.String(), nil
+	if lokiSignatures != "" {
+		fmt.Fprintf(&eventBuilder, "\n=== %s ===\n", lokiSignaturesSection)
+		eventBuilder.WriteString(lokiSignatures)
+	}
+
+	return eventBuilder.String(), errors.Join(prometheusErr, lokiErr)
 }
 
 //发送飞书请求
@@ -0,0 +1,53 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// rawResponseMaxLength insights 中保留的原始响应长度
+const rawResponseMaxLength = 512
+
+// invalidResponseInsights 说明响应无法解析的原因，并附上截断后的原始响应
+func invalidResponseInsights(err error, response string) string {
+	if len(response) > rawResponseMaxLength {
+		// 按字符截断，避免截断半个中文字符
+		cut := rawResponseMaxLength
+		for cut > 0 && !utf8.RuneStart(response[cut]) {
+			cut--
+		}
+		response = response[:cut] + "...(truncated)"
+	}
+	return fmt.Sprintf("大模型响应无法解析：%v；原始响应：%s", err, response)
+}
+
+// recordAnalysisFailure 大模型响应无法使用时记录失败：Summary 为 AnalysisFailed，insights 记录原因和原始响应
+func (r *AIOpsAnalyzerReconciler) recordAnalysisFailure(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, insights string) error {
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryAnalysisFailed
+		status.Insights = insights
+	})
+}
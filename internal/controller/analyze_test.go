@@ -0,0 +1,469 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("Analyze", func() {
+	const (
+		noopResponse          = `{"action":"noop","reason":"指标正常"}`
+		healResponse          = `{"action":"heal","reason":"CPU 飙高","patch_file":"cpu.yaml","patch_content":[{"op":"replace","path":"/spec/replicas","value":3}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low"}`
+		lowConfidenceResponse = `{"action":"heal","reason":"CPU 飙高","patch_file":"cpu.yaml","patch_content":[{"op":"replace","path":"/spec/replicas","value":3}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low","confidence":0.4}`
+		memoryResponse        = `{"action":"heal","reason":"OOM","patch_file":"mem.yaml","patch_content":[{"op":"replace","path":"/spec/template/spec/containers/0/resources/limits/memory","value":"4Gi"}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low"}`
+		scaleUpResponse       = `{"action":"heal","reason":"OOM","patch_file":"scale.yaml","patch_content":[{"op":"replace","path":"/spec/replicas","value":3},{"op":"replace","path":"/spec/template/spec/containers/0/resources/limits/memory","value":"4Gi"}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low"}`
+		restartResponse       = `{"action":"restart","reason":"连接池耗尽","target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low"}`
+		overCapResponse       = `{"action":"heal","reason":"扩容","patch_file":"scale.yaml","patch_content":[{"op":"replace","path":"/spec/replicas","value":5000}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low"}`
+		destructiveResponse   = `{"action":"heal","reason":"缩容","patch_file":"scale.yaml","patch_content":[{"op":"replace","path":"/spec/replicas","value":0}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"high"}`
+	)
+
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("2Gi")},
+				},
+			}}}},
+		},
+	}
+	target := autofixv1.TargetSelector{
+		Namespace: "default",
+		Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+	}
+
+	type analyzeCase struct {
+		spec        autofixv1.AIOpsAnalyzerSpec
+		disabled    bool
+		fake        *llmtest.FakeLLMClient
+		expectErr   string
+		requeue     bool
+		summary     string
+		noopReason  autofixv1.NoopReason
+		noopMessage string
+	}
+
+	DescribeTable("dispatching on the LLM response",
+		func(tc analyzeCase) {
+			aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+				ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default", CreationTimestamp: metav1.Now()},
+				Spec:       tc.spec,
+			}
+			aiopsAnalyzer.Spec.Target = target
+			// CRD 默认开启自动修复
+			aiopsAnalyzer.Spec.AutoRemediation.Enabled = !tc.disabled
+			reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+			reconciler.LLM = tc.fake
+
+			result, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "=== Prometheus Alerts ===\nNo firing alerts\n", "")
+			if tc.expectErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(tc.expectErr)))
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(result.RequeueAfter > 0).To(Equal(tc.requeue))
+			Expect(tc.fake.Requests).To(HaveLen(1))
+			Expect(tc.fake.Requests[0]).To(ContainSubstring("No firing alerts"))
+			Expect(tc.fake.Requests[0]).To(ContainSubstring("- 当前副本数：2\n"))
+			Expect(tc.fake.Requests[0]).To(ContainSubstring("- 容器 app：CPU requests 500m，CPU limits 1，内存 requests 未设置，内存 limits 2Gi\n"))
+
+			var updated autofixv1.AIOpsAnalyzer
+			Expect(reconciler.Get(context.Background(), types.NamespacedName{Name: "analyze", Namespace: "default"}, &updated)).To(Succeed())
+			Expect(updated.Status.Summary).To(Equal(tc.summary))
+			Expect(updated.Status.NoopReason).To(Equal(tc.noopReason))
+			Expect(updated.Status.NoopMessage).To(ContainSubstring(tc.noopMessage))
+		},
+		Entry("noop records a healthy summary", analyzeCase{
+			fake:        llmtest.NewFakeLLMClient(noopResponse),
+			summary:     autofixv1.SummaryHealthy,
+			noopReason:  autofixv1.NoopReasonLLMNoop,
+			noopMessage: "指标正常",
+		}),
+		Entry("heal only records insights when remediation is disabled", analyzeCase{
+			disabled:    true,
+			fake:        llmtest.NewFakeLLMClient(healResponse),
+			summary:     autofixv1.SummaryRemediationDisabled,
+			noopReason:  autofixv1.NoopReasonRemediationDisabled,
+			noopMessage: "enabled",
+		}),
+		Entry("heal with an action outside allowedActions is rejected", analyzeCase{
+			spec:        autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{AllowedActions: []string{"restart"}}},
+			fake:        llmtest.NewFakeLLMClient(healResponse),
+			summary:     autofixv1.SummaryPolicyRejected,
+			noopReason:  autofixv1.NoopReasonPolicyRejected,
+			noopMessage: "replace /spec/replicas (scale)",
+		}),
+		Entry("heal below minConfidence is downgraded to a noop", analyzeCase{
+			spec:        autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{MinConfidence: "0.7"}},
+			fake:        llmtest.NewFakeLLMClient(lowConfidenceResponse),
+			summary:     autofixv1.SummaryPolicyRejected,
+			noopReason:  autofixv1.NoopReasonLowConfidence,
+			noopMessage: "置信度 40% 低于 minConfidence 0.7",
+		}),
+		Entry("heal without confidence is downgraded when minConfidence is set", analyzeCase{
+			spec:        autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{MinConfidence: "0.5"}},
+			fake:        llmtest.NewFakeLLMClient(healResponse),
+			summary:     autofixv1.SummaryPolicyRejected,
+			noopReason:  autofixv1.NoopReasonLowConfidence,
+			noopMessage: "置信度 未知",
+		}),
+		Entry("heal at minConfidence is proposed", analyzeCase{
+			spec:    autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{MinConfidence: "0.4"}},
+			fake:    llmtest.NewFakeLLMClient(lowConfidenceResponse),
+			summary: autofixv1.SummaryRemediationProposed,
+		}),
+		Entry("heal above the memory threshold is rejected", analyzeCase{
+			spec:        autofixv1.AIOpsAnalyzerSpec{Thresholds: &autofixv1.Thresholds{Memory: "3Gi"}},
+			fake:        llmtest.NewFakeLLMClient(memoryResponse),
+			summary:     autofixv1.SummaryPolicyRejected,
+			noopReason:  autofixv1.NoopReasonPolicyRejected,
+			noopMessage: "4Gi exceeds the maximum of 3Gi",
+		}),
+		Entry("heal above the production cap is rejected like a configured threshold", analyzeCase{
+			fake:        llmtest.NewFakeLLMClient(overCapResponse),
+			summary:     autofixv1.SummaryPolicyRejected,
+			noopReason:  autofixv1.NoopReasonPolicyRejected,
+			noopMessage: "replicas 5000 exceeds the maximum of 100",
+		}),
+		Entry("heal with more patches than maxPatches is rejected", analyzeCase{
+			spec:        autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{MaxPatches: 1}},
+			fake:        llmtest.NewFakeLLMClient(scaleUpResponse),
+			summary:     autofixv1.SummaryPolicyRejected,
+			noopReason:  autofixv1.NoopReasonPolicyRejected,
+			noopMessage: "too many patches: 2 exceeds the maximum of 1",
+		}),
+		Entry("heal without requireApproval is approved without a card", analyzeCase{
+			fake:    llmtest.NewFakeLLMClient(healResponse),
+			summary: autofixv1.SummaryRemediationProposed,
+		}),
+		Entry("restart is rejected unless allowed", analyzeCase{
+			fake:        llmtest.NewFakeLLMClient(restartResponse),
+			summary:     autofixv1.SummaryPolicyRejected,
+			noopReason:  autofixv1.NoopReasonPolicyRejected,
+			noopMessage: "kubectl.kubernetes.io~1restartedAt (restart)",
+		}),
+		Entry("restart is proposed when allowed", analyzeCase{
+			spec:    autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{AllowedActions: []string{"restart"}}},
+			fake:    llmtest.NewFakeLLMClient(restartResponse),
+			summary: autofixv1.SummaryRemediationProposed,
+		}),
+		Entry("heal is held during the warmup period", analyzeCase{
+			spec:       autofixv1.AIOpsAnalyzerSpec{WarmupPeriod: "1h"},
+			fake:       llmtest.NewFakeLLMClient(healResponse),
+			requeue:    true,
+			noopReason: autofixv1.NoopReasonWarmingUp,
+		}),
+		Entry("destructive heal is blocked by safe mode", analyzeCase{
+			spec:        autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{SafeMode: true}},
+			fake:        llmtest.NewFakeLLMClient(destructiveResponse),
+			summary:     autofixv1.SummaryBlockedBySafeMode,
+			noopReason:  autofixv1.NoopReasonPolicyRejected,
+			noopMessage: "/spec/replicas",
+		}),
+		Entry("heal in dry-run only records the proposal", analyzeCase{
+			spec:    autofixv1.AIOpsAnalyzerSpec{AutoRemediation: autofixv1.AutoRemediationSpec{DryRun: true}},
+			fake:    llmtest.NewFakeLLMClient(healResponse),
+			summary: autofixv1.SummaryDryRun,
+		}),
+		Entry("LLM errors are returned", analyzeCase{
+			fake:      &llmtest.FakeLLMClient{Err: errors.New("rate limited")},
+			expectErr: "rate limited",
+		}),
+		Entry("unparseable responses are recorded and retried after the analysis interval", analyzeCase{
+			fake:    llmtest.NewFakeLLMClient("not json at all"),
+			requeue: true,
+			summary: autofixv1.SummaryAnalysisFailed,
+		}),
+		Entry("responses with an unknown action are recorded and retried after the analysis interval", analyzeCase{
+			fake:    llmtest.NewFakeLLMClient(`{"action":"delete","reason":"清理"}`),
+			requeue: true,
+			summary: autofixv1.SummaryAnalysisFailed,
+		}),
+	)
+
+	It("should fail instead of guessing when the target workload is missing", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: target},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+		fake := llmtest.NewFakeLLMClient(noopResponse)
+		reconciler.LLM = fake
+
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).To(MatchError(ContainSubstring(`target workload not found: no Deployment or StatefulSet matches "app=order" in namespace default`)))
+		Expect(fake.Requests).To(BeEmpty())
+	})
+
+	It("should write the would-be remediation to status in dry-run", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target:          target,
+				AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true, DryRun: true},
+			},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+		reconciler.LLM = llmtest.NewFakeLLMClient(healResponse)
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), types.NamespacedName{Name: "analyze", Namespace: "default"}, &updated)).To(Succeed())
+		Expect(updated.Status.Insights).To(Equal("[dry-run] CPU 飙高"))
+		Expect(updated.Status.PendingApproval).To(BeNil())
+		Expect(updated.Status.History).To(BeEmpty())
+		Expect(updated.Status.ProposedRemediation).NotTo(BeNil())
+		Expect(updated.Status.ProposedRemediation.ActionType).To(Equal("scale"))
+		Expect(updated.Status.ProposedRemediation.Patches[0].TargetRef.Name).To(Equal("order"))
+		Expect(recorder.Events).To(Receive(Equal("Normal DryRun dry-run：将提出 scale 修复建议（风险: low）: CPU 飙高；补丁: replace /spec/replicas 3")))
+	})
+
+	It("should emit events for the analysis outcome", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: target, AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true}},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		reconciler.LLM = llmtest.NewFakeLLMClient(noopResponse)
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(Equal("Normal NoActionNeeded 无需处理: 指标正常")))
+
+		reconciler.LLM = llmtest.NewFakeLLMClient(healResponse)
+		_, err = reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal RemediationProposed 提出修复建议 default.analyze.")))
+		// 改名前的 reason 仍然同时记录
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal Proposed 提出修复建议 default.analyze.")))
+
+		reconciler.LLM = &llmtest.FakeLLMClient{Err: errors.New("rate limited")}
+		_, err = reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).To(HaveOccurred())
+		Expect(recorder.Events).To(Receive(Equal("Warning LLMCallFailed 调用大模型失败: rate limited")))
+	})
+
+	It("should record an unusable response with its raw content instead of failing the reconcile", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: target, AnalysisInterval: "10m"},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		reconciler.LLM = llmtest.NewFakeLLMClient(`{"action":"heal","reason":"CPU 飙高","risk_level":"extreme"}`)
+
+		result, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+		Expect(recorder.Events).To(Receive(Equal("Warning InvalidLLMResponse 大模型响应无法解析: invalid risk_level: extreme")))
+
+		var updated autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), types.NamespacedName{Name: "analyze", Namespace: "default"}, &updated)).To(Succeed())
+		Expect(updated.Status.Summary).To(Equal(autofixv1.SummaryAnalysisFailed))
+		Expect(updated.Status.Insights).To(Equal(
+			`大模型响应无法解析：invalid risk_level: extreme；原始响应：{"action":"heal","reason":"CPU 飙高","risk_level":"extreme"}`))
+		Expect(updated.Status.LastAnalysisTime).NotTo(BeNil())
+	})
+
+	It("should truncate long raw responses without splitting characters", func() {
+		insights := invalidResponseInsights(errors.New("parse base failed"), "{"+strings.Repeat("飙", rawResponseMaxLength))
+		Expect(insights).To(HaveSuffix("...(truncated)"))
+		Expect(utf8.ValidString(insights)).To(BeTrue())
+	})
+
+	It("should pause analysis after repeated LLM failures and resume on success", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: target},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+		fake := &llmtest.FakeLLMClient{Err: errors.New("connection refused")}
+		reconciler.LLM = fake
+
+		for range llmFailureThreshold - 1 {
+			result, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+			Expect(err).To(MatchError(ContainSubstring("connection refused")))
+			Expect(result.RequeueAfter).To(BeZero())
+		}
+		result, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(llmBackoffBase))
+
+		var updated autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), types.NamespacedName{Name: "analyze", Namespace: "default"}, &updated)).To(Succeed())
+		condition := meta.FindStatusCondition(updated.Status.Conditions, autofixv1.ConditionLLMAvailable)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(autofixv1.ReasonLLMUnavailable))
+
+		// 熔断期间不调用大模型
+		result, err = reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(fake.Requests).To(HaveLen(llmFailureThreshold))
+		Expect(aiopsAnalyzer.Status.NoopReason).To(Equal(autofixv1.NoopReasonLLMUnavailable))
+
+		// 退避时间过后恢复调用，成功一次即清零
+		key := types.NamespacedName{Name: "analyze", Namespace: "default"}
+		reconciler.llmBreaker.failures[key] = llmFailures{count: llmFailureThreshold, last: time.Now().Add(-time.Hour)}
+		reconciler.LLM = llmtest.NewFakeLLMClient(noopResponse)
+		_, err = reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.llmBreaker.failures).NotTo(HaveKey(key))
+		Expect(reconciler.Get(context.Background(), key, &updated)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, autofixv1.ConditionLLMAvailable)).To(BeTrue())
+	})
+
+	It("should double the LLM backoff up to the cap", func() {
+		Expect(llmFailures{count: llmFailureThreshold - 1}.backoff()).To(BeZero())
+		Expect(llmFailures{count: llmFailureThreshold + 1}.backoff()).To(Equal(2 * llmBackoffBase))
+		Expect(llmFailures{count: llmFailureThreshold + 20}.backoff()).To(Equal(llmBackoffMax))
+	})
+
+	It("should count LLM tokens per analyzer", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "tokens", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: target},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+		fake := llmtest.NewFakeLLMClient(noopResponse)
+		fake.Usage = llm.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+		reconciler.LLM = fake
+		DeferCleanup(forgetAnalyzerMetrics, "default", "tokens")
+
+		for range 2 {
+			_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(testutil.ToFloat64(llmPromptTokens.WithLabelValues("default", "tokens"))).To(Equal(200.0))
+		Expect(testutil.ToFloat64(llmCompletionTokens.WithLabelValues("default", "tokens"))).To(Equal(40.0))
+	})
+
+	It("should count analyses by result and time LLM requests", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: target, AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true}},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+		heals := testutil.ToFloat64(analysisTotal.WithLabelValues(analysisResultHeal))
+		noops := testutil.ToFloat64(analysisTotal.WithLabelValues(analysisResultNoop))
+		requests := histogramSampleCount(llmRequestDuration)
+
+		reconciler.LLM = llmtest.NewFakeLLMClient(noopResponse)
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		reconciler.LLM = llmtest.NewFakeLLMClient(healResponse)
+		_, err = reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(testutil.ToFloat64(analysisTotal.WithLabelValues(analysisResultHeal))).To(Equal(heals + 1))
+		Expect(testutil.ToFloat64(analysisTotal.WithLabelValues(analysisResultNoop))).To(Equal(noops + 1))
+		Expect(histogramSampleCount(llmRequestDuration)).To(Equal(requests + 2))
+	})
+
+	It("should send the CR's system prompt override", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: target,
+				LLM:    &autofixv1.LLMSpec{SystemPromptOverride: "副本数不超过 10，只输出 JSON"},
+			},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment.DeepCopy())
+		fake := llmtest.NewFakeLLMClient(noopResponse)
+		reconciler.LLM = fake
+
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.SystemPrompt).To(Equal("副本数不超过 10，只输出 JSON"))
+
+		aiopsAnalyzer.Spec.LLM.SystemPromptOverride = "随便说说"
+		_, err = reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).To(MatchError(ContainSubstring("invalid spec.llm.systemPromptOverride")))
+	})
+
+	DescribeTable("matching patches against allowedActions",
+		func(allowed []string, path string, expected []string) {
+			ops := []llm.PatchOp{{Op: "replace", Path: path, Value: "x"}}
+			Expect(disallowedActions(autofixv1.AutoRemediationSpec{AllowedActions: allowed}, ops)).To(Equal(expected))
+		},
+		Entry("scale is allowed by default", nil, "/spec/replicas", nil),
+		Entry("resource is allowed by default", nil, "/spec/template/spec/containers/0/resources/limits/cpu", nil),
+		Entry("restart is not allowed by default", nil, "/spec/template/metadata/annotations/restartedAt",
+			[]string{"replace /spec/template/metadata/annotations/restartedAt (restart)"}),
+		Entry("env matches either config or feature-toggle", []string{"feature-toggle"}, "/spec/template/spec/containers/0/env", nil),
+		Entry("scale is rejected when only restart is allowed", []string{"restart"}, "/spec/replicas",
+			[]string{"replace /spec/replicas (scale)"}),
+	)
+
+	It("should fail without an injected client or credentialsRef", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"}}
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).To(MatchError(ContainSubstring("llm client is not configured")))
+	})
+
+	It("should return canned responses in order", func() {
+		fake := llmtest.NewFakeLLMClient("first", "second")
+		for _, want := range []string{"first", "second", "second"} {
+			result, err := fake.SendMessage(context.Background(), "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Content).To(Equal(want))
+		}
+	})
+})
+
+// histogramSampleCount 返回直方图已记录的样本数
+func histogramSampleCount(histogram prometheus.Histogram) uint64 {
+	var metric dto.Metric
+	Expect(histogram.Write(&metric)).To(Succeed())
+	return metric.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+)
+
+// autoApprover 未要求审批时记录的批准人
+const autoApprover = "auto-approved"
+
+// approvalExpiredReason 审批超时无人响应时写入的拒绝原因
+const approvalExpiredReason = "timeout"
+
+// decisionQueueSize 等待协调的审批结果数，队列满时依靠周期性协调兜底
+const decisionQueueSize = 64
+
+// defaultApprovalTimeout 未配置或无法解析 approvalTimeout 时的审批超时时间
+const defaultApprovalTimeout = 10 * time.Minute
+
+// approvalTimeout 解析 spec.feishu.approvalTimeout
+func approvalTimeout(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) time.Duration {
+	timeout, err := time.ParseDuration(aiopsAnalyzer.Spec.Feishu.ApprovalTimeout)
+	if err != nil || timeout <= 0 {
+		return defaultApprovalTimeout
+	}
+	return timeout
+}
+
+// requestIDSuffixLength RequestID 随机后缀的长度，字符集为 27 个字符，同一 CR 内重复的概率可以忽略
+const requestIDSuffixLength = 10
+
+// GenerateRequestID 生成修复建议的请求 ID，用于飞书回调匹配、审计记录和 PR 分支名
+// 格式为 <namespace>.<name>.<随机后缀>：命名空间不含 "."，后缀不含 "."，
+// 回调时可以据此直接找到对应的 CR
+func GenerateRequestID(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) string {
+	return fmt.Sprintf("%s.%s.%s", aiopsAnalyzer.Namespace, aiopsAnalyzer.Name, utilrand.String(requestIDSuffixLength))
+}
+
+// parseRequestID 从 GenerateRequestID 生成的 ID 中取出 CR 的命名空间和名称，旧格式的 ID 返回 false
+func parseRequestID(requestID string) (types.NamespacedName, bool) {
+	namespace, rest, ok := strings.Cut(requestID, ".")
+	if !ok {
+		return types.NamespacedName{}, false
+	}
+	i := strings.LastIndex(rest, ".")
+	if namespace == "" || i <= 0 || len(rest)-i-1 != requestIDSuffixLength {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: namespace, Name: rest[:i]}, true
+}
+
+// newApprovalRequest 构造待审批请求
+func newApprovalRequest(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID string) *autofixv1.ApprovalRequest {
+	now := metav1.Now()
+	return &autofixv1.ApprovalRequest{
+		RequestID:   requestID,
+		RequestedAt: now,
+		ExpiresAt:   metav1.NewTime(now.Add(approvalTimeout(aiopsAnalyzer))),
+	}
+}
+
+// requestApproval 先把待审批请求写入status并确认成功，再发送卡片
+// 这样审批人即使立刻点击按钮，回调也一定能找到对应的 RequestID
+// 卡片发送失败时撤销本次待审批请求，发送成功后记录卡片的消息 ID
+func (r *AIOpsAnalyzerReconciler) requestApproval(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer,
+	approval *autofixv1.ApprovalRequest, send func(ctx context.Context) (string, error)) error {
+	log := log.FromContext(ctx)
+
+	if err := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.PendingApproval = approval.DeepCopy()
+	}); err != nil {
+		return fmt.Errorf("persist pending approval failed: %w", err)
+	}
+
+	messageID, err := send(ctx)
+	if err != nil {
+		if rollbackErr := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			if status.PendingApproval != nil && status.PendingApproval.RequestID == approval.RequestID {
+				status.PendingApproval = nil
+			}
+		}); rollbackErr != nil {
+			log.Error(rollbackErr, "撤销待审批请求失败", "requestID", approval.RequestID)
+		}
+		return err
+	}
+	if messageID == "" {
+		return nil
+	}
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		if status.PendingApproval != nil && status.PendingApproval.RequestID == approval.RequestID {
+			status.PendingApproval.MessageID = messageID
+		}
+	})
+}
+
+// autoApprove 未要求审批时直接写入已批准的请求，下一次协调据此创建 PR
+func (r *AIOpsAnalyzerReconciler) autoApprove(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID string) error {
+	approved := true
+	approval := newApprovalRequest(aiopsAnalyzer, requestID)
+	approval.Approved = &approved
+	approval.ApprovedBy = autoApprover
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.PendingApproval = approval
+	})
+}
+
+// ApplyCardDecision 处理飞书卡片按钮回调，回调响应本身会替换卡片，无需再更新消息
+func (r *AIOpsAnalyzerReconciler) ApplyCardDecision(ctx context.Context, decision feishu.ApprovalDecision) error {
+	log.FromContext(ctx).Info("收到审批回调", "requestID", decision.RequestID, "approved", decision.Approved, "operator", decision.Operator)
+	return r.applyDecision(ctx, decision, false)
+}
+
+// notifyDecision 通知控制器立即协调，队列满或控制器未启动时跳过
+func (r *AIOpsAnalyzerReconciler) notifyDecision(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) {
+	if r.decisions == nil {
+		return
+	}
+	select {
+	case r.decisions <- event.GenericEvent{Object: aiopsAnalyzer.DeepCopy()}:
+	default:
+	}
+}
+
+// ApplyApprovalDecision 把审批结果写入 RequestID 匹配的 AIOpsAnalyzer，并更新已发出的审批卡片
+// 只处理仍未决定的请求，重复回调不会覆盖已有结果
+func (r *AIOpsAnalyzerReconciler) ApplyApprovalDecision(ctx context.Context, requestID string, approved bool, approvedBy, reason string) error {
+	return r.applyDecision(ctx, feishu.ApprovalDecision{
+		RequestID: requestID,
+		Approved:  approved,
+		Operator:  approvedBy,
+		Reason:    reason,
+		DecidedAt: time.Now(),
+	}, true)
+}
+
+// applyDecision 写入审批结果，updateCard 时把原卡片替换为审批结果
+func (r *AIOpsAnalyzerReconciler) applyDecision(ctx context.Context, decision feishu.ApprovalDecision, updateCard bool) error {
+	requestID, approved, approvedBy := decision.RequestID, decision.Approved, decision.Operator
+	candidates, err := r.approvalCandidates(ctx, requestID)
+	if err != nil {
+		return err
+	}
+
+	for i := range candidates {
+		aiopsAnalyzer := &candidates[i]
+		pending := aiopsAnalyzer.Status.PendingApproval
+		if pending == nil || pending.RequestID != requestID {
+			continue
+		}
+
+		decided := false
+		if err := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			decided = false
+			if status.PendingApproval == nil || status.PendingApproval.RequestID != requestID ||
+				status.PendingApproval.Approved != nil {
+				return
+			}
+			status.PendingApproval.Approved = &approved
+			status.PendingApproval.ApprovedBy = approvedBy
+			status.PendingApproval.Reason = decision.Reason
+			if record := findHistory(status, requestID); record != nil {
+				record.Approved = &approved
+			}
+			decision.MessageID = status.PendingApproval.MessageID
+			decided = true
+		}); err != nil {
+			return err
+		}
+		if !decided {
+			return nil
+		}
+		if approved {
+			r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonApproved, "修复建议 %s 已由 %s 批准", requestID, approvedBy)
+		}
+		if updateCard && decision.MessageID != "" {
+			r.updateApprovalCard(ctx, aiopsAnalyzer, decision)
+		}
+		r.notifyDecision(aiopsAnalyzer)
+		return nil
+	}
+
+	return fmt.Errorf("no pending approval found for request %q", requestID)
+}
+
+// approvalCandidates 返回可能持有 requestID 的 CR：ID 中带有命名空间和名称时直接读取该 CR，
+// 升级前发出的旧格式 ID 需要遍历所有 CR
+func (r *AIOpsAnalyzerReconciler) approvalCandidates(ctx context.Context, requestID string) ([]autofixv1.AIOpsAnalyzer, error) {
+	if key, ok := parseRequestID(requestID); ok {
+		var aiopsAnalyzer autofixv1.AIOpsAnalyzer
+		if err := r.Get(ctx, key, &aiopsAnalyzer); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []autofixv1.AIOpsAnalyzer{aiopsAnalyzer}, nil
+	}
+	var list autofixv1.AIOpsAnalyzerList
+	if err := r.List(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// expireApproval 待审批请求超过 ExpiresAt 仍无人响应时按超时拒绝：记录历史、发出告警事件、
+// 把卡片更新为已过期并清空 pendingApproval
+// 写入前在最新的 status 上重新检查，已决定或已清空的请求不会重复处理
+func (r *AIOpsAnalyzerReconciler) expireApproval(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) error {
+	now := time.Now()
+	expired := func(pending *autofixv1.ApprovalRequest) bool {
+		return pending != nil && pending.Approved == nil && !pending.ExpiresAt.IsZero() && now.After(pending.ExpiresAt.Time)
+	}
+	if !expired(aiopsAnalyzer.Status.PendingApproval) {
+		return nil
+	}
+
+	var decision feishu.ApprovalDecision
+	if err := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		decision = feishu.ApprovalDecision{}
+		if !expired(status.PendingApproval) {
+			return
+		}
+		rejected := false
+		status.PendingApproval.Approved = &rejected
+		status.PendingApproval.Reason = approvalExpiredReason
+		if record := findHistory(status, status.PendingApproval.RequestID); record != nil {
+			record.Approved = &rejected
+		}
+		decision = feishu.ApprovalDecision{
+			RequestID: status.PendingApproval.RequestID,
+			Reason:    approvalExpiredReason,
+			MessageID: status.PendingApproval.MessageID,
+			DecidedAt: status.PendingApproval.ExpiresAt.Time,
+			Expired:   true,
+		}
+		status.PendingApproval = nil
+	}); err != nil {
+		return err
+	}
+	if decision.RequestID == "" {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("审批超时", "requestID", decision.RequestID)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonApprovalExpired, "修复建议 %s 审批超时，已按拒绝处理", decision.RequestID)
+	if decision.MessageID != "" {
+		r.updateApprovalCard(ctx, aiopsAnalyzer, decision)
+	}
+	return nil
+}
+
+// updateApprovalCard 更新审批卡片，失败只记录日志，不影响已写入的审批结果
+func (r *AIOpsAnalyzerReconciler) updateApprovalCard(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, decision feishu.ApprovalDecision) {
+	logger := log.FromContext(ctx)
+	updateCardStatus := r.UpdateCardStatus
+	if updateCardStatus == nil {
+		updateCardStatus = feishu.UpdateCardStatus
+	}
+	client, err := r.newFeishuClient(ctx, aiopsAnalyzer)
+	if err == nil {
+		err = updateCardStatus(ctx, client, decision.MessageID, decision)
+	}
+	if err != nil {
+		logger.Error(err, "更新审批卡片失败", "requestID", decision.RequestID, "messageID", decision.MessageID)
+	}
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu/feishutest"
+)
+
+// newFakeReconciler 使用 fake client 构造 reconciler，不依赖 envtest
+func newFakeReconciler(objs ...client.Object) *AIOpsAnalyzerReconciler {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(autofixv1.AddToScheme(scheme)).To(Succeed())
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&autofixv1.AIOpsAnalyzer{}).
+		// API Server 原生支持 Event 的 involvedObject.name 字段选择器，假客户端需要注册索引
+		WithIndex(&corev1.Event{}, eventInvolvedObjectNameField, func(obj client.Object) []string {
+			return []string{obj.(*corev1.Event).InvolvedObject.Name}
+		}).
+		WithIndex(&corev1.Pod{}, podNodeNameField, func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+	return &AIOpsAnalyzerReconciler{Client: fakeClient, Scheme: scheme}
+}
+
+var _ = Describe("Approval request", func() {
+	var (
+		reconciler    *AIOpsAnalyzerReconciler
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		updatedCards  []feishu.ApprovalDecision
+	)
+
+	BeforeEach(func() {
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "approval", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Feishu: autofixv1.FeishuNotification{ApprovalTimeout: "15m"},
+			},
+		}
+		reconciler = newFakeReconciler(aiopsAnalyzer)
+		reconciler.Feishu = lark.NewClient("cli_test", "secret")
+		updatedCards = nil
+		reconciler.UpdateCardStatus = func(_ context.Context, _ *lark.Client, messageID string, decision feishu.ApprovalDecision) error {
+			Expect(messageID).To(Equal(decision.MessageID))
+			updatedCards = append(updatedCards, decision)
+			return nil
+		}
+	})
+
+	It("should persist the pending approval before the card is sent", func() {
+		approval := newApprovalRequest(aiopsAnalyzer, "req-1")
+		Expect(approval.ExpiresAt.Sub(approval.RequestedAt.Time)).To(BeNumerically("~", 15*60*1e9, 1e9))
+
+		// 模拟审批人在卡片发出的同时立刻点击了按钮
+		err := reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "om_1", reconciler.ApplyApprovalDecision(ctx, "req-1", true, "alice", "")
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval).NotTo(BeNil())
+		Expect(latest.Status.PendingApproval.Approved).To(HaveValue(BeTrue()))
+		Expect(latest.Status.PendingApproval.ApprovedBy).To(Equal("alice"))
+		Expect(latest.Status.PendingApproval.MessageID).To(Equal("om_1"))
+	})
+
+	It("should roll back the pending approval when the card fails to send", func() {
+		approval := newApprovalRequest(aiopsAnalyzer, "req-2")
+		err := reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "", errors.New("feishu unavailable")
+		})
+		Expect(err).To(MatchError("feishu unavailable"))
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval).To(BeNil())
+	})
+
+	It("should not overwrite a decision that was already made", func() {
+		approval := newApprovalRequest(aiopsAnalyzer, "req-3")
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "", nil
+		})).To(Succeed())
+
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), "req-3", false, "bob", "too risky")).To(Succeed())
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), "req-3", true, "alice", "")).To(Succeed())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval.Approved).To(HaveValue(BeFalse()))
+		Expect(latest.Status.PendingApproval.ApprovedBy).To(Equal("bob"))
+	})
+
+	It("should emit an Approved event on the analyzer", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		approval := newApprovalRequest(aiopsAnalyzer, "req-4")
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "", nil
+		})).To(Succeed())
+
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), "req-4", true, "alice", "")).To(Succeed())
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal " + EventReasonApproved)))
+	})
+
+	It("should approve right away when approval is not required", func() {
+		requestID := GenerateRequestID(aiopsAnalyzer)
+		Expect(requestID).To(MatchRegexp(`^default\.approval\.[a-z0-9]{10}$`))
+		Expect(reconciler.autoApprove(context.Background(), aiopsAnalyzer, requestID)).To(Succeed())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval.RequestID).To(Equal(requestID))
+		Expect(latest.Status.PendingApproval.Approved).To(HaveValue(BeTrue()))
+		Expect(latest.Status.PendingApproval.ApprovedBy).To(Equal(autoApprover))
+	})
+
+	It("should generate unique request IDs that route back to the analyzer", func() {
+		seen := make(map[string]struct{})
+		for i := 0; i < 10000; i++ {
+			requestID := GenerateRequestID(aiopsAnalyzer)
+			Expect(seen).NotTo(HaveKey(requestID))
+			seen[requestID] = struct{}{}
+		}
+
+		dotted := &autofixv1.AIOpsAnalyzer{ObjectMeta: metav1.ObjectMeta{Name: "order.v2", Namespace: "shop"}}
+		key, ok := parseRequestID(GenerateRequestID(dotted))
+		Expect(ok).To(BeTrue())
+		Expect(key).To(Equal(types.NamespacedName{Namespace: "shop", Name: "order.v2"}))
+		for _, legacy := range []string{"req-1", "approval-k7x2m9qp", ".approval.k7x2m9qp4b", "default.approval.short"} {
+			_, ok := parseRequestID(legacy)
+			Expect(ok).To(BeFalse(), legacy)
+		}
+
+		requestID := GenerateRequestID(aiopsAnalyzer)
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, newApprovalRequest(aiopsAnalyzer, requestID),
+			func(context.Context) (string, error) { return "om_1", nil })).To(Succeed())
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), requestID, true, "alice", "")).To(Succeed())
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval.Approved).To(HaveValue(BeTrue()))
+
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), "default.missing.k7x2m9qp4b", true, "alice", "")).
+			To(MatchError(ContainSubstring("no pending approval found")))
+	})
+
+	It("should enqueue the analyzer when a card callback decides", func() {
+		reconciler.decisions = make(chan event.GenericEvent, 1)
+		approval := newApprovalRequest(aiopsAnalyzer, "req-5")
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "om_5", nil
+		})).To(Succeed())
+
+		Expect(reconciler.ApplyCardDecision(context.Background(), feishu.ApprovalDecision{
+			RequestID: "req-5", Approved: true, Operator: "ou_1",
+		})).To(Succeed())
+
+		var queued event.GenericEvent
+		Expect(reconciler.decisions).To(Receive(&queued))
+		Expect(queued.Object.GetName()).To(Equal(aiopsAnalyzer.Name))
+	})
+
+	It("should replace the card with the decision", func() {
+		approval := newApprovalRequest(aiopsAnalyzer, "req-6")
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "om_6", nil
+		})).To(Succeed())
+
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), "req-6", false, "bob", "too risky")).To(Succeed())
+		Expect(updatedCards).To(HaveLen(1))
+		Expect(updatedCards[0].MessageID).To(Equal("om_6"))
+		Expect(updatedCards[0].Approved).To(BeFalse())
+		Expect(updatedCards[0].Operator).To(Equal("bob"))
+
+		// 重复的决定不会再次更新卡片
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), "req-6", true, "alice", "")).To(Succeed())
+		Expect(updatedCards).To(HaveLen(1))
+	})
+
+	It("should leave the card to the callback response", func() {
+		approval := newApprovalRequest(aiopsAnalyzer, "req-7")
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "om_7", nil
+		})).To(Succeed())
+
+		Expect(reconciler.ApplyCardDecision(context.Background(), feishu.ApprovalDecision{
+			RequestID: "req-7", Approved: true, Operator: "ou_1",
+		})).To(Succeed())
+		Expect(updatedCards).To(BeEmpty())
+	})
+
+	It("should expire an unanswered request once and mark the card expired", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		approval := newApprovalRequest(aiopsAnalyzer, "req-8")
+		approval.ExpiresAt = metav1.NewTime(time.Now().Add(-time.Minute))
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "om_8", nil
+		})).To(Succeed())
+		Expect(reconciler.updateStatus(context.Background(), aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.History = append(status.History, autofixv1.RemediationRecord{RequestID: "req-8"})
+		})).To(Succeed())
+		stale := aiopsAnalyzer.DeepCopy()
+
+		Expect(reconciler.expireApproval(context.Background(), aiopsAnalyzer)).To(Succeed())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval).To(BeNil())
+		Expect(latest.Status.History[0].Approved).To(HaveValue(BeFalse()))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning " + EventReasonApprovalExpired)))
+		Expect(updatedCards).To(HaveLen(1))
+		Expect(updatedCards[0].MessageID).To(Equal("om_8"))
+		Expect(updatedCards[0].Expired).To(BeTrue())
+		Expect(updatedCards[0].Reason).To(Equal(approvalExpiredReason))
+
+		// 使用过期前读到的旧对象再次处理也不会重复发出事件或更新卡片
+		Expect(reconciler.expireApproval(context.Background(), stale)).To(Succeed())
+		Expect(recorder.Events).NotTo(Receive())
+		Expect(updatedCards).To(HaveLen(1))
+	})
+
+	It("should leave unexpired and decided requests alone", func() {
+		approval := newApprovalRequest(aiopsAnalyzer, "req-9")
+		Expect(reconciler.requestApproval(context.Background(), aiopsAnalyzer, approval, func(ctx context.Context) (string, error) {
+			return "om_9", nil
+		})).To(Succeed())
+		Expect(reconciler.expireApproval(context.Background(), aiopsAnalyzer)).To(Succeed())
+		Expect(aiopsAnalyzer.Status.PendingApproval).NotTo(BeNil())
+
+		Expect(reconciler.ApplyApprovalDecision(context.Background(), "req-9", true, "alice", "")).To(Succeed())
+		Expect(reconciler.updateStatus(context.Background(), aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.PendingApproval.ExpiresAt = metav1.NewTime(time.Now().Add(-time.Minute))
+		})).To(Succeed())
+		Expect(reconciler.expireApproval(context.Background(), aiopsAnalyzer)).To(Succeed())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval.Approved).To(HaveValue(BeTrue()))
+		Expect(updatedCards).To(HaveLen(1))
+	})
+
+	It("should report unknown request IDs", func() {
+		err := reconciler.ApplyApprovalDecision(context.Background(), "missing", true, "alice", "")
+		Expect(err).To(MatchError(ContainSubstring("no pending approval")))
+	})
+})
+
+var _ = Describe("Approval card fallback", func() {
+	const chatID = "oc_a0553eda9014c201e6969b478895c230"
+
+	var (
+		server     *feishutest.Server
+		recorder   *record.FakeRecorder
+		reconciler *AIOpsAnalyzerReconciler
+	)
+	aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "shop"}}
+
+	BeforeEach(func() {
+		server = feishutest.NewServer()
+		DeferCleanup(server.Close)
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &AIOpsAnalyzerReconciler{Recorder: recorder}
+	})
+
+	send := func() (string, error) {
+		vars := &feishu.CardVariables{RequestID: "req-1", Reason: "CPU 打满"}
+		msg, err := feishu.NewCardMessage(chatID, feishu.ReceiveIDTypeChatID, "tpl", "1.0.0", vars)
+		Expect(err).NotTo(HaveOccurred())
+		return reconciler.sendCardWithFallback(context.Background(), aiopsAnalyzer, server.LarkClient(), msg, vars)
+	}
+
+	It("should return the card message ID when the card is sent", func() {
+		Expect(send()).To(Equal("om_interactive"))
+		Expect(server.MsgTypes()).To(Equal([]string{"interactive"}))
+	})
+
+	It("should fall back to rich text and not leave an approval open when the template fails", func() {
+		server.RespondTo("interactive", `{"code":230099,"msg":"Failed to create card content"}`)
+		_, err := send()
+		Expect(err).To(MatchError(errCardFallback))
+		Expect(server.MsgTypes()).To(Equal([]string{"interactive", "post"}))
+		Expect(server.Messages()[1].Content).To(ContainSubstring("本消息无法审批"))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning " + EventReasonCardFallback)))
+
+		// 改发富文本后撤销待审批请求，不会阻塞之后的分析
+		analyzer := aiopsAnalyzer.DeepCopy()
+		reconciler = newFakeReconciler(analyzer)
+		reconciler.Recorder = recorder
+		err = reconciler.requestApproval(context.Background(), analyzer, newApprovalRequest(analyzer, "req-1"), func(context.Context) (string, error) {
+			return send()
+		})
+		Expect(err).To(MatchError(errCardFallback))
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(analyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval).To(BeNil())
+	})
+
+	It("should fall back to plain text when the rich-text message also fails", func() {
+		server.RespondTo("interactive", `{"code":230099,"msg":"Failed to create card content"}`)
+		server.RespondTo("post", `{"code":230001,"msg":"invalid message content"}`)
+		_, err := send()
+		Expect(err).To(MatchError(errCardFallback))
+		Expect(server.MsgTypes()).To(Equal([]string{"interactive", "post", "text"}))
+		Expect(server.Messages()[2].Content).To(ContainSubstring("本消息无法审批"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("已改发纯文本通知")))
+
+		server.RespondTo("text", `{"code":230001,"msg":"invalid message content"}`)
+		_, err = send()
+		Expect(err).NotTo(MatchError(errCardFallback))
+		Expect(err).To(MatchError(ContainSubstring("fallback text message failed")))
+	})
+
+	It("should not fall back on other errors", func() {
+		server.RespondTo("interactive", `{"code":99991663,"msg":"tenant access token invalid"}`)
+		_, err := send()
+		Expect(err).To(MatchError(ContainSubstring("code=99991663")))
+		Expect(server.MsgTypes()).To(Equal([]string{"interactive"}))
+	})
+})
@@ -0,0 +1,157 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// +kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=get;list;watch
+
+// applicationGVK ArgoCD Application，以 unstructured 读取，避免依赖 ArgoCD 的 Go 模块
+var applicationGVK = schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Application"}
+
+// 表示同步完成的 ArgoCD 状态
+const (
+	argoSyncStatusSynced  = "Synced"
+	argoHealthProgressing = "Progressing"
+)
+
+// applicationStatus ArgoCD Application 中与修复闭环相关的状态
+type applicationStatus struct {
+	// SyncStatus status.sync.status
+	SyncStatus string
+	// Revision 最近一次同步的 commit，即 status.sync.revision
+	Revision string
+	// Health status.health.status
+	Health string
+	// SyncedAt 最近一次同步操作完成的时间，没有时使用 status.reconciledAt
+	SyncedAt *time.Time
+}
+
+// readApplicationStatus 从 Application 中读取同步和健康状态
+func readApplicationStatus(app *unstructured.Unstructured) applicationStatus {
+	var status applicationStatus
+	status.SyncStatus, _, _ = unstructured.NestedString(app.Object, "status", "sync", "status")
+	status.Revision, _, _ = unstructured.NestedString(app.Object, "status", "sync", "revision")
+	status.Health, _, _ = unstructured.NestedString(app.Object, "status", "health", "status")
+
+	syncedAt, _, _ := unstructured.NestedString(app.Object, "status", "operationState", "finishedAt")
+	if syncedAt == "" {
+		syncedAt, _, _ = unstructured.NestedString(app.Object, "status", "reconciledAt")
+	}
+	if t, err := time.Parse(time.RFC3339, syncedAt); err == nil {
+		status.SyncedAt = &t
+	}
+	return status
+}
+
+// syncedMerge 判断 Application 是否已同步到合并的修复提交
+// 合并后仓库可能又有新提交，因此同步时间晚于合并时间也视为已同步
+func (s applicationStatus) syncedMerge(gitOps autofixv1.GitOpsStatus) bool {
+	if s.SyncStatus != argoSyncStatusSynced {
+		return false
+	}
+	if gitOps.LastCommitSHA != "" && s.Revision == gitOps.LastCommitSHA {
+		return true
+	}
+	return s.SyncedAt != nil && gitOps.PR.MergedAt != nil && !s.SyncedAt.Before(gitOps.PR.MergedAt.Time)
+}
+
+// argoCDSyncDone 合并的修复已同步且健康状态稳定，不再需要查询
+func argoCDSyncDone(gitOps autofixv1.GitOpsStatus) bool {
+	return gitOps.LastSyncedTime != nil && gitOps.SyncStatus == argoSyncStatusSynced &&
+		(gitOps.PR.MergedAt == nil || !gitOps.LastSyncedTime.Before(gitOps.PR.MergedAt)) &&
+		gitOps.Health != "" && gitOps.Health != argoHealthProgressing
+}
+
+// trackArgoCDSync 在修复 PR 合并后读取 spec.gitOps.argoCD 指定的 Application，
+// 把同步状态和健康状态写入 status.gitOps，同步到合并的提交后更新 lastSyncedTime
+// 返回下次查询前的等待时间，未配置、PR 未合并或已同步完成时返回 0；查询失败只记录日志，按间隔重试
+func (r *AIOpsAnalyzerReconciler) trackArgoCDSync(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) time.Duration {
+	logger := log.FromContext(ctx)
+
+	argoCD := aiopsAnalyzer.Spec.GitOps.ArgoCD
+	gitOps := aiopsAnalyzer.Status.GitOps
+	if argoCD == nil || !gitOps.PR.Merged || argoCDSyncDone(gitOps) {
+		return 0
+	}
+	interval := prPollInterval(aiopsAnalyzer)
+
+	app, err := r.getApplication(ctx, argoCD)
+	if err != nil {
+		logger.Error(err, "查询 ArgoCD Application 失败", "application", argoCD.ApplicationName)
+		return interval
+	}
+	appStatus := readApplicationStatus(app)
+	synced := appStatus.syncedMerge(gitOps)
+
+	var firstSync bool
+	if err := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.GitOps.SyncStatus = appStatus.SyncStatus
+		status.GitOps.Health = appStatus.Health
+		if !synced {
+			return
+		}
+		syncedAt := metav1.Now()
+		if appStatus.SyncedAt != nil {
+			syncedAt = metav1.NewTime(*appStatus.SyncedAt)
+		}
+		last := status.GitOps.LastSyncedTime
+		firstSync = last == nil || (status.GitOps.PR.MergedAt != nil && last.Before(status.GitOps.PR.MergedAt))
+		status.GitOps.LastSyncedTime = &syncedAt
+	}); err != nil {
+		logger.Error(err, "更新 ArgoCD 同步状态失败", "application", argoCD.ApplicationName)
+		return interval
+	}
+
+	if firstSync {
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonSynced,
+			"ArgoCD Application %s 已同步 PR #%d 的修复，健康状态 %s", argoCD.ApplicationName, gitOps.PR.Number, appStatus.Health)
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonApplied,
+			"PR #%d 的修复已由 ArgoCD Application %s 应用到集群", gitOps.PR.Number, argoCD.ApplicationName)
+	}
+	if argoCDSyncDone(aiopsAnalyzer.Status.GitOps) {
+		return 0
+	}
+	return interval
+}
+
+// getApplication 读取 ArgoCD Application
+func (r *AIOpsAnalyzerReconciler) getApplication(ctx context.Context, argoCD *autofixv1.ArgoCDSpec) (*unstructured.Unstructured, error) {
+	namespace := argoCD.Namespace
+	if namespace == "" {
+		namespace = "argocd"
+	}
+	app := &unstructured.Unstructured{}
+	app.SetGroupVersionKind(applicationGVK)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: argoCD.ApplicationName}, app); err != nil {
+		return nil, fmt.Errorf("get ArgoCD application %s/%s failed: %w", namespace, argoCD.ApplicationName, err)
+	}
+	return app, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("ArgoCD sync tracking", func() {
+	var (
+		ctx           context.Context
+		reconciler    *AIOpsAnalyzerReconciler
+		recorder      *record.FakeRecorder
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		mergedAt      time.Time
+	)
+
+	newApplication := func(sync, revision, health, finishedAt string) *unstructured.Unstructured {
+		app := &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"sync":           map[string]interface{}{"status": sync, "revision": revision},
+				"health":         map[string]interface{}{"status": health},
+				"operationState": map[string]interface{}{"phase": "Succeeded", "finishedAt": finishedAt},
+			},
+		}}
+		app.SetGroupVersionKind(applicationGVK)
+		app.SetNamespace("argocd")
+		app.SetName("order")
+		return app
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mergedAt = time.Date(2025, 11, 26, 12, 45, 55, 0, time.UTC)
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				GitOps: autofixv1.GitOpsConfig{
+					PRPollInterval: "1m",
+					ArgoCD:         &autofixv1.ArgoCDSpec{ApplicationName: "order"},
+				},
+			},
+		}
+		reconciler = newFakeReconciler(aiopsAnalyzer)
+		recorder = record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		merged := metav1.NewTime(mergedAt)
+		Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.GitOps.PR = autofixv1.PRStatus{Number: 8, Status: "merged", Merged: true, MergedAt: &merged}
+			status.GitOps.LastCommitSHA = "abc123"
+		})).To(Succeed())
+	})
+
+	latest := func() autofixv1.GitOpsStatus {
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		return latest.Status.GitOps
+	}
+
+	It("should do nothing unless tracking is enabled and the PR is merged", func() {
+		aiopsAnalyzer.Spec.GitOps.ArgoCD = nil
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(BeZero())
+
+		aiopsAnalyzer.Spec.GitOps.ArgoCD = &autofixv1.ArgoCDSpec{ApplicationName: "order"}
+		aiopsAnalyzer.Status.GitOps.PR.Merged = false
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(BeZero())
+	})
+
+	It("should keep polling while the Application is missing or out of sync", func() {
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(Equal(time.Minute))
+
+		Expect(reconciler.Create(ctx, newApplication("OutOfSync", "old", "Healthy", "2025-11-26T12:00:00Z"))).To(Succeed())
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(Equal(time.Minute))
+		status := latest()
+		Expect(status.SyncStatus).To(Equal("OutOfSync"))
+		Expect(status.Health).To(Equal("Healthy"))
+		Expect(status.LastSyncedTime).To(BeNil())
+	})
+
+	It("should record the sync of the merged commit and wait for the rollout to become healthy", func() {
+		app := newApplication("Synced", "abc123", "Progressing", "2025-11-26T12:50:00Z")
+		Expect(reconciler.Create(ctx, app)).To(Succeed())
+
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(Equal(time.Minute))
+		status := latest()
+		Expect(status.SyncStatus).To(Equal("Synced"))
+		Expect(status.Health).To(Equal("Progressing"))
+		Expect(status.LastSyncedTime.Time.Equal(time.Date(2025, 11, 26, 12, 50, 0, 0, time.UTC))).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonSynced)))
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonApplied)))
+
+		Expect(unstructured.SetNestedField(app.Object, "Healthy", "status", "health", "status")).To(Succeed())
+		Expect(reconciler.Update(ctx, app)).To(Succeed())
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(BeZero())
+		Expect(latest().Health).To(Equal("Healthy"))
+		Expect(recorder.Events).NotTo(Receive())
+
+		// 同步完成后不再查询
+		Expect(reconciler.Delete(ctx, app)).To(Succeed())
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(BeZero())
+	})
+
+	It("should treat a later sync of a newer revision as synced", func() {
+		Expect(reconciler.Create(ctx, newApplication("Synced", "def456", "Healthy", "2025-11-26T13:00:00Z"))).To(Succeed())
+
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(BeZero())
+		Expect(latest().LastSyncedTime).NotTo(BeNil())
+	})
+
+	It("should not treat a sync from before the merge as synced", func() {
+		Expect(reconciler.Create(ctx, newApplication("Synced", "old", "Healthy", "2025-11-26T12:00:00Z"))).To(Succeed())
+
+		Expect(reconciler.trackArgoCDSync(ctx, aiopsAnalyzer)).To(Equal(time.Minute))
+		Expect(latest().LastSyncedTime).To(BeNil())
+	})
+})
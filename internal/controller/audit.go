@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/audit"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/sanitize"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+
+const (
+	// defaultAuditMaxRecords 未配置 spec.audit.maxRecords 时 ConfigMap 中保留的记录数
+	defaultAuditMaxRecords = 10
+	// auditConfigMapMaxBytes ConfigMap 最大 1MiB，为元数据留出余量
+	auditConfigMapMaxBytes = 900 * 1024
+	// auditDecisionError 处理大模型响应出错时记录的处理结果
+	auditDecisionError = "Error"
+)
+
+// newAuditRecord 构造审计记录，处理结果在 writeAudit 中补充
+func newAuditRecord(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID, prompt, response string) audit.Record {
+	return audit.Record{
+		RequestID: requestID,
+		Analyzer:  client.ObjectKeyFromObject(aiopsAnalyzer).String(),
+		Time:      time.Now().UTC(),
+		Prompt:    prompt,
+		Response:  response,
+	}
+}
+
+// auditConfigMapName 保存审计记录的 ConfigMap 名称
+func auditConfigMapName(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) string {
+	return aiopsAnalyzer.Name + "-audit"
+}
+
+// writeAudit 用 status 补充处理结果，脱敏后写入审计日志，开启 configMapRetention 时同时保存到 ConfigMap
+// 审计失败只记录日志，不影响分析
+func (r *AIOpsAnalyzerReconciler) writeAudit(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, record audit.Record, handleErr error) {
+	log := log.FromContext(ctx)
+
+	status := aiopsAnalyzer.Status
+	record.Decision = status.Summary
+	switch status.Summary {
+	case autofixv1.SummaryRemediationProposed, autofixv1.SummaryCompleted, autofixv1.SummaryAnalysisFailed:
+	default:
+		record.NoopReason = string(status.NoopReason)
+	}
+	if handleErr != nil {
+		record.Decision = auditDecisionError
+		record.Error = handleErr.Error()
+	}
+
+	// 自定义脱敏规则不合法时退回内置规则，凭据仍然会被替换
+	sanitizer, err := newSanitizer(aiopsAnalyzer)
+	if err != nil {
+		sanitizer, _ = sanitize.New(nil, true)
+	}
+	record = record.Redacted(func(content string) string {
+		redacted, _ := sanitizer.Sanitize(content)
+		return redacted
+	})
+
+	sink := r.Audit
+	if sink == nil {
+		sink = audit.LogSink{}
+	}
+	if err := sink.Write(ctx, record); err != nil {
+		log.Error(err, "写入审计日志失败", "requestID", record.RequestID)
+	}
+	if cfg := aiopsAnalyzer.Spec.Audit; cfg != nil && cfg.ConfigMapRetention {
+		if err := r.retainAudit(ctx, aiopsAnalyzer, record); err != nil {
+			log.Error(err, "保存审计记录到 ConfigMap 失败", "requestID", record.RequestID)
+		}
+	}
+}
+
+// retainAudit 把审计记录以 RequestID 为键保存到 CR 所属的 ConfigMap，只保留最近的 maxRecords 条
+func (r *AIOpsAnalyzerReconciler) retainAudit(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, record audit.Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	maxRecords := int(aiopsAnalyzer.Spec.Audit.MaxRecords)
+	if maxRecords <= 0 {
+		maxRecords = defaultAuditMaxRecords
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      auditConfigMapName(aiopsAnalyzer),
+		Namespace: aiopsAnalyzer.Namespace,
+	}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		if err := controllerutil.SetControllerReference(aiopsAnalyzer, configMap, r.Scheme); err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[record.RequestID] = string(line)
+		trimAuditRecords(configMap.Data, maxRecords, auditConfigMapMaxBytes)
+		return nil
+	})
+	return err
+}
+
+// trimAuditRecords 按记录时间丢弃最旧的记录，直到不超过 maxRecords 条且总大小不超过 maxBytes
+// 最新的一条始终保留
+func trimAuditRecords(data map[string]string, maxRecords, maxBytes int) {
+	type entry struct {
+		key  string
+		time time.Time
+	}
+	entries := make([]entry, 0, len(data))
+	size := 0
+	for key, value := range data {
+		var record audit.Record
+		// 无法解析的记录时间为零值，最先被丢弃
+		_ = json.Unmarshal([]byte(value), &record)
+		entries = append(entries, entry{key: key, time: record.Time})
+		size += len(key) + len(value)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].time.Equal(entries[j].time) {
+			return entries[i].time.Before(entries[j].time)
+		}
+		return entries[i].key < entries[j].key
+	})
+	for _, e := range entries[:max(len(entries)-1, 0)] {
+		if len(data) <= maxRecords && size <= maxBytes {
+			return
+		}
+		size -= len(e.key) + len(data[e.key])
+		delete(data, e.key)
+	}
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Record 一次大模型分析的审计记录，用于事后复盘自动修复的决策过程
+type Record struct {
+	// RequestID 本次分析的请求 ID，与审批卡片、修复历史中的 ID 一致
+	RequestID string `json:"requestID"`
+	// Analyzer AIOpsAnalyzer 的 namespace/name
+	Analyzer string    `json:"analyzer"`
+	Time     time.Time `json:"time"`
+	// Prompt 发送给大模型的完整请求内容（已脱敏）
+	Prompt string `json:"prompt"`
+	// Response 大模型返回的原始内容（已脱敏）
+	Response string `json:"response"`
+	// Action 解析后的 heal/noop 结构（JSON），解析失败时为空
+	Action json.RawMessage `json:"action,omitempty"`
+	// Decision Operator 对修复建议的处理结果，取 status.summary
+	Decision string `json:"decision"`
+	// NoopReason 没有产出修复建议的原因
+	NoopReason string `json:"noopReason,omitempty"`
+	// Error 解析或处理过程中的错误
+	Error string `json:"error,omitempty"`
+}
+
+// SetAction 以 JSON 记录解析后的动作，无法编码时记录其文本形式
+func (r *Record) SetAction(action any) {
+	raw, err := json.Marshal(action)
+	if err != nil {
+		raw, _ = json.Marshal(fmt.Sprintf("%+v", action))
+	}
+	r.Action = raw
+}
+
+// Redacted 返回用 redact 处理过请求、响应、动作和错误的副本，避免凭据写入审计日志
+func (r Record) Redacted(redact func(string) string) Record {
+	r.Prompt = redact(r.Prompt)
+	r.Response = redact(r.Response)
+	r.Error = redact(r.Error)
+	if len(r.Action) > 0 {
+		action := redact(string(r.Action))
+		// 自定义规则可能破坏 JSON 结构，此时按字符串保存
+		if !json.Valid([]byte(action)) {
+			quoted, _ := json.Marshal(action)
+			action = string(quoted)
+		}
+		r.Action = json.RawMessage(action)
+	}
+	return r
+}
+
+// Sink 审计记录的写入目标
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+// LogSink 把审计记录作为结构化日志输出到控制器日志
+type LogSink struct{}
+
+// Write 以 audit 为 logger 名称输出一条日志
+func (LogSink) Write(ctx context.Context, record Record) error {
+	log.FromContext(ctx).WithName("audit").Info("大模型分析审计记录",
+		"requestID", record.RequestID,
+		"analyzer", record.Analyzer,
+		"prompt", record.Prompt,
+		"response", record.Response,
+		"action", string(record.Action),
+		"decision", record.Decision,
+		"noopReason", record.NoopReason,
+		"error", record.Error)
+	return nil
+}
+
+// JSONSink 把审计记录按 JSON Lines 写入 w，多个协调并发写入时加锁
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink 创建写入 w 的 JSONSink
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// NewFileSink 以追加方式打开 path，审计记录按 JSON Lines 写入该文件
+func NewFileSink(path string) (*JSONSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log %s failed: %w", path, err)
+	}
+	return NewJSONSink(f), nil
+}
+
+// Write 写入一行 JSON
+func (s *JSONSink) Write(_ context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode audit record %s failed: %w", record.RequestID, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit record %s failed: %w", record.RequestID, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit records", func() {
+	redactToken := func(content string) string {
+		return strings.ReplaceAll(content, "s3cr3t", "[REDACTED]")
+	}
+
+	It("should append one JSON line per record", func() {
+		var buf bytes.Buffer
+		sink := NewJSONSink(&buf)
+		record := Record{RequestID: "order-abc", Analyzer: "shop/order", Time: time.Date(2025, 11, 26, 12, 0, 0, 0, time.UTC), Decision: "DryRun"}
+		record.SetAction(map[string]any{"action": "noop"})
+		Expect(sink.Write(context.Background(), record)).To(Succeed())
+		Expect(sink.Write(context.Background(), Record{RequestID: "order-def"})).To(Succeed())
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		Expect(lines).To(HaveLen(2))
+		var decoded Record
+		Expect(json.Unmarshal([]byte(lines[0]), &decoded)).To(Succeed())
+		Expect(decoded.RequestID).To(Equal("order-abc"))
+		Expect(decoded.Decision).To(Equal("DryRun"))
+		Expect(string(decoded.Action)).To(Equal(`{"action":"noop"}`))
+	})
+
+	It("should redact the prompt, response, action and error", func() {
+		record := Record{Prompt: "token=s3cr3t", Response: `{"detail":"s3cr3t"}`, Error: "auth s3cr3t failed"}
+		record.SetAction(map[string]string{"value": "s3cr3t"})
+
+		redacted := record.Redacted(redactToken)
+		Expect(redacted.Prompt).To(Equal("token=[REDACTED]"))
+		Expect(redacted.Response).To(Equal(`{"detail":"[REDACTED]"}`))
+		Expect(redacted.Error).To(Equal("auth [REDACTED] failed"))
+		Expect(string(redacted.Action)).To(Equal(`{"value":"[REDACTED]"}`))
+		Expect(record.Prompt).To(Equal("token=s3cr3t"))
+	})
+
+	It("should keep the action as a string when redaction breaks the JSON", func() {
+		record := Record{}
+		record.SetAction(map[string]string{"value": "s3cr3t"})
+		redacted := record.Redacted(func(content string) string { return strings.ReplaceAll(content, `"`, "") })
+		Expect(json.Valid(redacted.Action)).To(BeTrue())
+		Expect(string(redacted.Action)).To(Equal(`"{value:s3cr3t}"`))
+	})
+})
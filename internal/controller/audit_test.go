@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/audit"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("LLM audit log", func() {
+	var (
+		ctx           context.Context
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		deployment    *appsv1.Deployment
+		buf           *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default", UID: "uid-1"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+				AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true, DryRun: true},
+			},
+		}
+		replicas := int32(2)
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+		buf = &bytes.Buffer{}
+	})
+
+	analyzeWith := func(response string) (*AIOpsAnalyzerReconciler, audit.Record, error) {
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment)
+		reconciler.LLM = llmtest.NewFakeLLMClient(response)
+		reconciler.Audit = audit.NewJSONSink(buf)
+		_, err := reconciler.analyze(ctx, aiopsAnalyzer, "=== Prometheus Alerts ===\nAuthorization: Bearer abcdef123456\n", "")
+
+		var record audit.Record
+		Expect(json.Unmarshal(buf.Bytes(), &record)).To(Succeed())
+		return reconciler, record, err
+	}
+
+	It("should record the prompt, raw response, parsed action and decision with secrets redacted", func() {
+		response := `{"action":"heal","reason":"CPU 飙高","detail":"password=hunter2","patch_content":[{"op":"replace","path":"/spec/replicas","value":3}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low"}`
+		_, record, err := analyzeWith(response)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(record.RequestID).To(HavePrefix("default.analyze."))
+		Expect(record.Analyzer).To(Equal("default/analyze"))
+		Expect(record.Prompt).To(ContainSubstring("### 告警/监控数据："))
+		Expect(record.Prompt).To(ContainSubstring("Bearer [REDACTED_TOKEN]"))
+		Expect(record.Response).To(ContainSubstring("password=[REDACTED_SECRET]"))
+		Expect(record.Response).NotTo(ContainSubstring("hunter2"))
+		Expect(string(record.Action)).To(ContainSubstring(`"path":"/spec/replicas"`))
+		Expect(record.Decision).To(Equal(autofixv1.SummaryDryRun))
+		Expect(record.Error).To(BeEmpty())
+	})
+
+	It("should record noop reasons and parse failures", func() {
+		_, record, err := analyzeWith(`{"action":"noop","reason":"指标正常"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.Decision).To(Equal(autofixv1.SummaryHealthy))
+		Expect(record.NoopReason).To(Equal(string(autofixv1.NoopReasonLLMNoop)))
+
+		buf.Reset()
+		_, record, err = analyzeWith(`{"action":"reboot"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.Decision).To(Equal(autofixv1.SummaryAnalysisFailed))
+		Expect(record.NoopReason).To(BeEmpty())
+		Expect(record.Error).To(ContainSubstring("unknown action: reboot"))
+		Expect(record.Action).To(BeEmpty())
+		Expect(record.Response).To(Equal(`{"action":"reboot"}`))
+	})
+
+	It("should retain the latest records in an owned ConfigMap", func() {
+		aiopsAnalyzer.Spec.Audit = &autofixv1.AuditSpec{ConfigMapRetention: true, MaxRecords: 2}
+		reconciler, record, err := analyzeWith(`{"action":"noop","reason":"指标正常"}`)
+		Expect(err).NotTo(HaveOccurred())
+
+		var configMap corev1.ConfigMap
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: "default", Name: "analyze-audit"}, &configMap)).To(Succeed())
+		Expect(configMap.OwnerReferences).To(HaveLen(1))
+		Expect(configMap.OwnerReferences[0].Name).To(Equal("analyze"))
+		Expect(configMap.Data).To(HaveKey(record.RequestID))
+
+		for i := range 2 {
+			Expect(reconciler.retainAudit(ctx, aiopsAnalyzer, audit.Record{
+				RequestID: fmt.Sprintf("later-%d", i),
+				Time:      record.Time.Add(time.Duration(i+1) * time.Minute),
+			})).To(Succeed())
+		}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Namespace: "default", Name: "analyze-audit"}, &configMap)).To(Succeed())
+		Expect(configMap.Data).To(HaveLen(2))
+		Expect(configMap.Data).To(HaveKey("later-0"))
+		Expect(configMap.Data).To(HaveKey("later-1"))
+	})
+
+	It("should drop the oldest records when the ConfigMap grows too large", func() {
+		entry := func(minute int, padding int) string {
+			line, _ := json.Marshal(audit.Record{Time: time.Date(2025, 11, 26, 12, minute, 0, 0, time.UTC), Prompt: string(make([]byte, padding))})
+			return string(line)
+		}
+		data := map[string]string{"a": entry(1, 100), "b": entry(2, 100), "c": entry(3, 100)}
+		trimAuditRecords(data, 10, len(data["c"])+len(data["b"])+2)
+		Expect(data).To(HaveLen(2))
+		Expect(data).NotTo(HaveKey("a"))
+
+		trimAuditRecords(data, 10, 1)
+		Expect(data).To(HaveLen(1))
+		Expect(data).To(HaveKey("c"))
+	})
+})
@@ -0,0 +1,24 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+// defaultMaxConcurrentReconciles 未配置 MaxConcurrentReconciles 时同时协调的 CR 数
+//
+// 同一个 CR 的事件合并由 controller-runtime 的 workqueue 完成：队列按 key 去重，
+// 一个 key 在被 worker 处理期间不会交给其他 worker，期间重复入队的 key 只保留一个，
+// 处理结束后再协调一次。MaxConcurrentReconciles 只限制不同 CR 之间的并行度
+const defaultMaxConcurrentReconciles = 4
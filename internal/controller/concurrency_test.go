@@ -0,0 +1,110 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Reconcile concurrency", func() {
+	guarded := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "guarded"}}
+	other := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "other"}}
+
+	It("should coalesce events for a CR being reconciled into one follow-up through the workqueue", func() {
+		var (
+			mu        sync.Mutex
+			running   = map[string]int{}
+			maxSame   int
+			calls     = map[string]int{}
+			started   = make(chan string, 10)
+			release   = make(chan struct{})
+			processed atomic.Int32
+		)
+		reconciler := newFakeReconciler()
+		reconciler.Client = interceptor.NewClient(reconciler.Client.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, k client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				mu.Lock()
+				running[k.Name]++
+				maxSame = max(maxSame, running[k.Name])
+				calls[k.Name]++
+				first := k.Name == guarded.Name && calls[k.Name] == 1
+				mu.Unlock()
+				started <- k.Name
+				if first {
+					<-release
+				}
+				mu.Lock()
+				running[k.Name]--
+				mu.Unlock()
+				return c.Get(ctx, k, obj, opts...)
+			},
+		})
+
+		// 与 controller-runtime 创建的队列相同
+		queue := workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[reconcile.Request](),
+			workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{Name: "aiopsanalyzer"})
+		defer queue.ShutDown()
+		for range defaultMaxConcurrentReconciles {
+			go func() {
+				defer GinkgoRecover()
+				for {
+					req, shutdown := queue.Get()
+					if shutdown {
+						return
+					}
+					_, err := reconciler.Reconcile(context.Background(), req)
+					Expect(err).NotTo(HaveOccurred())
+					queue.Forget(req)
+					queue.Done(req)
+					processed.Add(1)
+				}
+			}()
+		}
+
+		queue.Add(guarded)
+		Eventually(started).Should(Receive(Equal(guarded.Name)))
+
+		// 协调进行中又收到三次同一个 CR 的事件，其他 CR 不受影响
+		for range 3 {
+			queue.Add(guarded)
+		}
+		queue.Add(other)
+		Eventually(started).Should(Receive(Equal(other.Name)))
+		Eventually(processed.Load).Should(BeEquivalentTo(1))
+		Consistently(started).ShouldNot(Receive())
+
+		close(release)
+		Eventually(processed.Load).Should(BeEquivalentTo(3))
+		Consistently(processed.Load).Should(BeEquivalentTo(3))
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(calls).To(Equal(map[string]int{guarded.Name: 2, other.Name: 1}))
+		Expect(maxSame).To(Equal(1))
+	})
+})
@@ -0,0 +1,109 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// readinessDependencies Ready 依赖的 condition，按顺序取第一个为 False 的作为 Ready 的原因
+var readinessDependencies = []string{autofixv1.ConditionLLMAvailable, autofixv1.ConditionDatasourcesReachable}
+
+// readyCondition 根据本次协调的错误和依赖的 condition 计算 Ready
+func readyCondition(status *autofixv1.AIOpsAnalyzerStatus, generation int64, reconcileErr error) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               autofixv1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             autofixv1.ReasonReconciled,
+		Message:            "最近一次协调成功",
+		ObservedGeneration: generation,
+	}
+	if reconcileErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = autofixv1.ReasonReconcileError
+		condition.Message = truncateError(reconcileErr)
+		return condition
+	}
+	for _, conditionType := range readinessDependencies {
+		if dependency := meta.FindStatusCondition(status.Conditions, conditionType); dependency != nil &&
+			dependency.Status == metav1.ConditionFalse {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = dependency.Reason
+			condition.Message = fmt.Sprintf("%s: %s", conditionType, dependency.Message)
+			return condition
+		}
+	}
+	return condition
+}
+
+// approvalPendingCondition 有修复建议在等待审批时为 True
+func approvalPendingCondition(status *autofixv1.AIOpsAnalyzerStatus, generation int64) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               autofixv1.ConditionApprovalPending,
+		Status:             metav1.ConditionFalse,
+		Reason:             autofixv1.ReasonNoPendingApproval,
+		Message:            "没有等待审批的修复建议",
+		ObservedGeneration: generation,
+	}
+	if pending := status.PendingApproval; pending != nil && pending.Approved == nil {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = autofixv1.ReasonAwaitingApproval
+		condition.Message = fmt.Sprintf("修复建议 %s 等待审批，%s 过期", pending.RequestID, pending.ExpiresAt.Format(time.RFC3339))
+	}
+	return condition
+}
+
+// summaryFromConditions 根据 condition 汇总 Summary：Ready 为 False 时为 Degraded，
+// 等待审批时为 RemediationProposed，恢复后从 Degraded 回到 Healthy，其余情况保留分析结论；
+// Once 模式在当前 generation 已结束时保留 Completed，否则 isRunCompleted 不再匹配，会重新分析
+func summaryFromConditions(status *autofixv1.AIOpsAnalyzerStatus, generation int64) string {
+	switch {
+	case status.Summary == autofixv1.SummaryCompleted && status.ObservedGeneration == generation:
+		return autofixv1.SummaryCompleted
+	case meta.IsStatusConditionFalse(status.Conditions, autofixv1.ConditionReady):
+		return autofixv1.SummaryDegraded
+	case meta.IsStatusConditionTrue(status.Conditions, autofixv1.ConditionApprovalPending):
+		return autofixv1.SummaryRemediationProposed
+	case status.Summary == autofixv1.SummaryDegraded:
+		return autofixv1.SummaryHealthy
+	}
+	return status.Summary
+}
+
+// recordConditions 在每次协调结束时更新 Ready、ApprovalPending 和由此汇总的 Summary，没有变化时不写 status
+func (r *AIOpsAnalyzerReconciler) recordConditions(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, reconcileErr error) error {
+	generation := aiopsAnalyzer.Generation
+	mutate := func(status *autofixv1.AIOpsAnalyzerStatus) {
+		meta.SetStatusCondition(&status.Conditions, approvalPendingCondition(status, generation))
+		meta.SetStatusCondition(&status.Conditions, readyCondition(status, generation, reconcileErr))
+		status.Summary = summaryFromConditions(status, generation)
+	}
+	status := aiopsAnalyzer.Status.DeepCopy()
+	mutate(status)
+	if equality.Semantic.DeepEqual(*status, aiopsAnalyzer.Status) {
+		return nil
+	}
+	return r.updateStatus(ctx, aiopsAnalyzer, mutate)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Status conditions", func() {
+	var (
+		ctx           context.Context
+		reconciler    *AIOpsAnalyzerReconciler
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "conditions", Namespace: "default", Generation: 2},
+			Status:     autofixv1.AIOpsAnalyzerStatus{Summary: autofixv1.SummaryHealthy},
+		}
+		reconciler = newFakeReconciler(aiopsAnalyzer)
+	})
+
+	It("should mark the analyzer ready after a successful reconcile", func() {
+		Expect(reconciler.recordConditions(ctx, aiopsAnalyzer, nil)).To(Succeed())
+
+		ready := meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+		Expect(ready.Reason).To(Equal(autofixv1.ReasonReconciled))
+		Expect(ready.ObservedGeneration).To(Equal(int64(2)))
+		Expect(meta.IsStatusConditionFalse(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionApprovalPending)).To(BeTrue())
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryHealthy))
+	})
+
+	It("should report a reconcile error as not ready and degraded, then recover", func() {
+		Expect(reconciler.recordConditions(ctx, aiopsAnalyzer, errors.New("list pods failed"))).To(Succeed())
+
+		ready := meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(autofixv1.ReasonReconcileError))
+		Expect(ready.Message).To(Equal("list pods failed"))
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryDegraded))
+
+		Expect(reconciler.recordConditions(ctx, aiopsAnalyzer, nil)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionReady)).To(BeTrue())
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryHealthy))
+	})
+
+	It("should not be ready while the LLM is unavailable", func() {
+		Expect(reconciler.recordLLMCondition(ctx, aiopsAnalyzer, 3, time.Minute, errors.New("timeout"))).To(Succeed())
+		Expect(reconciler.recordConditions(ctx, aiopsAnalyzer, nil)).To(Succeed())
+
+		ready := meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(autofixv1.ReasonLLMUnavailable))
+		Expect(ready.Message).To(HavePrefix(autofixv1.ConditionLLMAvailable + ": "))
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryDegraded))
+	})
+
+	It("should report a pending approval", func() {
+		Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.PendingApproval = &autofixv1.ApprovalRequest{
+				RequestID: "req-1", ExpiresAt: metav1.NewTime(time.Date(2025, 11, 26, 13, 0, 0, 0, time.UTC)),
+			}
+		})).To(Succeed())
+		Expect(reconciler.recordConditions(ctx, aiopsAnalyzer, nil)).To(Succeed())
+
+		pending := meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionApprovalPending)
+		Expect(pending.Status).To(Equal(metav1.ConditionTrue))
+		Expect(pending.Reason).To(Equal(autofixv1.ReasonAwaitingApproval))
+		Expect(pending.Message).To(ContainSubstring("req-1"))
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryRemediationProposed))
+	})
+
+	It("should keep the analysis outcome in the summary and skip unchanged writes", func() {
+		Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.Summary = autofixv1.SummaryDryRun
+		})).To(Succeed())
+		Expect(reconciler.recordConditions(ctx, aiopsAnalyzer, nil)).To(Succeed())
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryDryRun))
+
+		resourceVersion := aiopsAnalyzer.ResourceVersion
+		Expect(reconciler.recordConditions(ctx, aiopsAnalyzer, nil)).To(Succeed())
+		Expect(aiopsAnalyzer.ResourceVersion).To(Equal(resourceVersion))
+	})
+})
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// GroupVersion 配置文件的 apiVersion
+var GroupVersion = schema.GroupVersion{Group: "config.aiops.com", Version: "v1alpha1"}
+
+// Kind 配置文件的 kind
+const Kind = "ControllerConfig"
+
+// ControllerConfig 控制器级别的默认配置，启动时从 --config 指定的 YAML 文件加载
+// 只提供默认值：CR 中配置的字段、显式指定的命令行参数和环境变量优先
+type ControllerConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Datasources 未配置 spec.datasources 时使用的 Prometheus、Loki
+	Datasources DatasourcesConfig `json:"datasources,omitempty"`
+
+	// LLM 默认大模型客户端的配置，API Key 仍从环境变量 LLM_API_KEY 读取
+	LLM LLMConfig `json:"llm,omitempty"`
+
+	// Feishu 默认的飞书应用凭据和审批卡片模板
+	Feishu FeishuConfig `json:"feishu,omitempty"`
+}
+
+// DatasourcesConfig 默认的可观测数据源
+type DatasourcesConfig struct {
+	// PrometheusURL 如 http://prometheus.monitoring:9090
+	PrometheusURL string `json:"prometheusURL,omitempty"`
+	// LokiURL 如 http://loki.monitoring:3100
+	LokiURL string `json:"lokiURL,omitempty"`
+	// LokiOrgID 多租户 Loki 的 X-Scope-OrgID
+	LokiOrgID string `json:"lokiOrgID,omitempty"`
+	// Timeout 单次查询的超时时间，对应 --datasource-timeout
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// LLMConfig 默认的大模型服务，字段含义与环境变量 LLM_PROVIDER、LLM_BASE_URL 等相同
+type LLMConfig struct {
+	Provider        llm.Provider    `json:"provider,omitempty"`
+	BaseURL         string          `json:"baseURL,omitempty"`
+	Model           string          `json:"model,omitempty"`
+	APIVersion      string          `json:"apiVersion,omitempty"`
+	Timeout         metav1.Duration `json:"timeout,omitempty"`
+	DisableJSONMode bool            `json:"disableJSONMode,omitempty"`
+}
+
+// FeishuConfig 默认的飞书配置
+type FeishuConfig struct {
+	// CredentialsSecret 飞书应用凭据（键 app_id、app_secret）所在的 Secret，格式 <namespace>/<name>，
+	// 对应 --feishu-credentials-secret
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	// TemplateID、TemplateVersion 未配置 spec.feishu.templateID、templateVersion 时使用的审批卡片模板
+	TemplateID      string `json:"templateID,omitempty"`
+	TemplateVersion string `json:"templateVersion,omitempty"`
+}
+
+// Load 读取并校验配置文件，文件中出现未知字段时报错
+func Load(path string) (*ControllerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read controller config %s failed: %w", path, err)
+	}
+	var cfg ControllerConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse controller config %s failed: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid controller config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// validate 校验 apiVersion、kind 和取值范围，apiVersion、kind 可以省略
+func (c *ControllerConfig) validate() error {
+	if c.APIVersion != "" && c.APIVersion != GroupVersion.String() {
+		return fmt.Errorf("unsupported apiVersion %q, expected %q", c.APIVersion, GroupVersion)
+	}
+	if c.Kind != "" && c.Kind != Kind {
+		return fmt.Errorf("unsupported kind %q, expected %q", c.Kind, Kind)
+	}
+	if c.Datasources.Timeout.Duration < 0 {
+		return fmt.Errorf("datasources.timeout must not be negative")
+	}
+	if c.LLM.Timeout.Duration < 0 {
+		return fmt.Errorf("llm.timeout must not be negative")
+	}
+	return nil
+}
+
+// ApplyLLM 用配置文件中的值补全 base 中未设置的字段，base 通常来自 llm.ConfigFromEnv
+func (c *ControllerConfig) ApplyLLM(base llm.OpenAIConfig) llm.OpenAIConfig {
+	if base.Provider == "" {
+		base.Provider = c.LLM.Provider
+	}
+	if base.BaseURL == "" {
+		base.BaseURL = c.LLM.BaseURL
+	}
+	if base.Model == "" {
+		base.Model = c.LLM.Model
+	}
+	if base.APIVersion == "" {
+		base.APIVersion = c.LLM.APIVersion
+	}
+	if base.Timeout == 0 {
+		base.Timeout = c.LLM.Timeout.Duration
+	}
+	base.DisableJSONMode = base.DisableJSONMode || c.LLM.DisableJSONMode
+	return base
+}
+
+// DatasourceTimeout 返回 datasources.timeout，未配置时返回 fallback
+func (c *ControllerConfig) DatasourceTimeout(fallback time.Duration) time.Duration {
+	if c.Datasources.Timeout.Duration > 0 {
+		return c.Datasources.Timeout.Duration
+	}
+	return fallback
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+var _ = Describe("ControllerConfig", func() {
+	write := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		return path
+	}
+
+	It("should load the defaults from a YAML file", func() {
+		cfg, err := Load(write(`apiVersion: config.aiops.com/v1alpha1
+kind: ControllerConfig
+datasources:
+  prometheusURL: http://prometheus.monitoring:9090
+  lokiURL: http://loki.monitoring:3100
+  timeout: 30s
+llm:
+  provider: azure
+  baseURL: https://my-resource.openai.azure.com
+  model: gpt-4o
+feishu:
+  credentialsSecret: aiops-system/feishu
+  templateID: ctp_team_card
+  templateVersion: 1.2.0
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Datasources.PrometheusURL).To(Equal("http://prometheus.monitoring:9090"))
+		Expect(cfg.Datasources.LokiURL).To(Equal("http://loki.monitoring:3100"))
+		Expect(cfg.DatasourceTimeout(15 * time.Second)).To(Equal(30 * time.Second))
+		Expect(cfg.LLM.Provider).To(Equal(llm.ProviderAzure))
+		Expect(cfg.Feishu.CredentialsSecret).To(Equal("aiops-system/feishu"))
+		Expect(cfg.Feishu.TemplateID).To(Equal("ctp_team_card"))
+		Expect(cfg.Feishu.TemplateVersion).To(Equal("1.2.0"))
+	})
+
+	DescribeTable("rejecting invalid files",
+		func(content, message string) {
+			_, err := Load(write(content))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown field", "datasources:\n  prometheus: http://prometheus:9090\n", `unknown field "prometheus"`),
+		Entry("wrong kind", "kind: Deployment\n", `unsupported kind "Deployment"`),
+		Entry("wrong apiVersion", "apiVersion: v1\n", `unsupported apiVersion "v1"`),
+		Entry("negative timeout", "llm:\n  timeout: -1s\n", "llm.timeout must not be negative"),
+	)
+
+	It("should fail when the file does not exist", func() {
+		_, err := Load(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+		Expect(err).To(MatchError(ContainSubstring("read controller config")))
+	})
+
+	It("should only fill the LLM fields not set in the environment", func() {
+		cfg := &ControllerConfig{LLM: LLMConfig{Provider: llm.ProviderOllama, BaseURL: "http://ollama:11434", Model: "qwen2.5"}}
+		merged := cfg.ApplyLLM(llm.OpenAIConfig{APIKey: "sk-test", Model: "deepseek-v3"})
+		Expect(merged).To(Equal(llm.OpenAIConfig{
+			Provider: llm.ProviderOllama, APIKey: "sk-test", BaseURL: "http://ollama:11434", Model: "deepseek-v3",
+		}))
+	})
+
+	It("should keep the fallback timeout when none is configured", func() {
+		Expect((&ControllerConfig{}).DatasourceTimeout(15 * time.Second)).To(Equal(15 * time.Second))
+	})
+})
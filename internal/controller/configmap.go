@@ -0,0 +1,175 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// configMapReferences 返回 Pod 模板通过卷、env 和 envFrom 引用的 ConfigMap 名称，按名称排序
+func configMapReferences(podSpec *corev1.PodSpec) []string {
+	refs := map[string]bool{}
+	for _, volume := range podSpec.Volumes {
+		if volume.ConfigMap != nil {
+			refs[volume.ConfigMap.Name] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					refs[source.ConfigMap.Name] = true
+				}
+			}
+		}
+	}
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				refs[env.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				refs[envFrom.ConfigMapRef.Name] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(refs))
+}
+
+// workloadConfigMaps 返回工作负载引用的 ConfigMap 名称
+func workloadConfigMaps(workload *unstructured.Unstructured) ([]string, error) {
+	raw, found, err := unstructured.NestedMap(workload.Object, "spec", "template", "spec")
+	if err != nil || !found {
+		return nil, fmt.Errorf("%s/%s has no pod template", workload.GetKind(), workload.GetName())
+	}
+	var podSpec corev1.PodSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &podSpec); err != nil {
+		return nil, fmt.Errorf("decode pod template of %s/%s failed: %w", workload.GetKind(), workload.GetName(), err)
+	}
+	return configMapReferences(&podSpec), nil
+}
+
+// rejectedConfigMap 修改的 ConfigMap 没有被目标工作负载引用时记录为 noop 并返回 true
+// 否则修改不会对目标工作负载生效，重启也无济于事
+func (r *AIOpsAnalyzerReconciler) rejectedConfigMap(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, error) {
+	if heal.ConfigMap == "" {
+		return false, nil
+	}
+	workload, err := r.getTargetWorkload(ctx, healNamespace(aiopsAnalyzer, heal), heal.Target)
+	if err != nil {
+		return false, err
+	}
+	referenced, err := workloadConfigMaps(workload)
+	if err != nil {
+		return false, err
+	}
+	if slices.Contains(referenced, heal.ConfigMap) {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("ConfigMap %s 未被 %s/%s 引用", heal.ConfigMap, workload.GetKind(), workload.GetName())
+	log.FromContext(ctx).Info("修复建议修改的 ConfigMap 未被目标工作负载引用", "configMap", heal.ConfigMap, "referenced", referenced)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonConfigMapNotReferenced,
+		"%s（引用的 ConfigMap：%s），已忽略修复建议: %s", message, strings.Join(referenced, ", "), heal.Reason)
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryPolicyRejected
+		status.Insights = fmt.Sprintf("%s（%s）", heal.Reason, message)
+		status.NoopReason = autofixv1.NoopReasonPolicyRejected
+		status.NoopMessage = message
+	})
+}
+
+// getConfigMap 以 unstructured 形式获取 ConfigMap，便于和工作负载一起渲染、撤销补丁
+func (r *AIOpsAnalyzerReconciler) getConfigMap(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	configMap := &unstructured.Unstructured{}
+	configMap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+		return nil, fmt.Errorf("get ConfigMap %s/%s failed: %w", namespace, name, err)
+	}
+	return configMap, nil
+}
+
+// objectReference 补丁的 TargetRef，创建 PR 时按它找到仓库中对应的清单
+func objectReference(object *unstructured.Unstructured) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: object.GetAPIVersion(),
+		Kind:       object.GetKind(),
+		Namespace:  object.GetNamespace(),
+		Name:       object.GetName(),
+	}
+}
+
+// healPatches 把修复建议转换为带 TargetRef 的补丁，并返回补丁涉及的线上资源
+// /data、/binaryData 下的补丁指向 heal.ConfigMap，其余补丁指向目标工作负载
+func (r *AIOpsAnalyzerReconciler) healPatches(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) ([]autofixv1.PatchOperation, []*unstructured.Unstructured, error) {
+	ops, err := toPatchOperations(heal.PatchContent)
+	if err != nil {
+		return nil, nil, err
+	}
+	namespace := healNamespace(aiopsAnalyzer, heal)
+	workload, err := r.getTargetWorkload(ctx, namespace, heal.Target)
+	if err != nil {
+		return nil, nil, err
+	}
+	live := []*unstructured.Unstructured{workload}
+	var configMap *unstructured.Unstructured
+	if heal.ConfigMap != "" {
+		if configMap, err = r.getConfigMap(ctx, namespace, heal.ConfigMap); err != nil {
+			return nil, nil, err
+		}
+		live = append(live, configMap)
+	}
+
+	for i := range ops {
+		target := workload
+		if configMap != nil && llm.IsConfigMapPath(ops[i].Path) {
+			target = configMap
+		}
+		ops[i].TargetRef = objectReference(target)
+	}
+	return ops, live, nil
+}
+
+// patchesFor 返回 TargetRef 指向 object 的补丁
+func patchesFor(ops []autofixv1.PatchOperation, object *unstructured.Unstructured) []autofixv1.PatchOperation {
+	var matched []autofixv1.PatchOperation
+	for _, op := range ops {
+		if patch.TargetMatches(op.TargetRef, patch.ManifestObject{Kind: object.GetKind(), Name: object.GetName()}) {
+			matched = append(matched, op)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("ConfigMap remediation", func() {
+	const configResponse = `{"action":"heal","reason":"连接池过小","patch_content":[{"op":"replace","path":"/data/DB_POOL_SIZE","value":"50"}],"target":{"kind":"Deployment","labelSelector":"app=order"},"config_map":"%s","restart_workload":true,"risk_level":"medium"}`
+
+	var (
+		ctx           context.Context
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		deployment    *appsv1.Deployment
+		configMap     *corev1.ConfigMap
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+				AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true, DryRun: true, AllowedActions: []string{"config"}},
+			},
+		}
+		deployment = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "app",
+				EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "order-config"},
+				}}},
+			}}}}},
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "order-config", Namespace: "default"},
+			Data:       map[string]string{"DB_POOL_SIZE": "20"},
+		}
+	})
+
+	It("should collect ConfigMaps referenced by volumes, env and envFrom", func() {
+		keyRef := func(name string) *corev1.EnvVarSource {
+			return &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "k",
+			}}
+		}
+		podSpec := &corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{Name: "conf", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "nginx-conf"},
+				}}},
+				{Name: "all", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+					{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected"}}},
+				}}}},
+			},
+			InitContainers: []corev1.Container{{Name: "init", Env: []corev1.EnvVar{{Name: "A", ValueFrom: keyRef("init-config")}}}},
+			Containers:     deployment.Spec.Template.Spec.Containers,
+		}
+		Expect(configMapReferences(podSpec)).To(Equal([]string{"init-config", "nginx-conf", "order-config", "projected"}))
+		Expect(configMapReferences(&corev1.PodSpec{})).To(BeEmpty())
+	})
+
+	It("should target the ConfigMap and restart the Deployment", func() {
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment, configMap)
+		heal := &llm.HealAction{
+			Reason:    "连接池过小",
+			Target:    llm.Target{Kind: "Deployment", LabelSelector: "app=order"},
+			ConfigMap: "order-config",
+			PatchContent: []llm.PatchOp{
+				{Op: "replace", Path: "/data/DB_POOL_SIZE", Value: "50"},
+				llm.RestartPatch(time.Now()),
+			},
+			RiskLevel: "medium",
+		}
+
+		proposal, err := reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposal.ActionType).To(Equal("config-change"))
+		Expect(proposal.Patches).To(HaveLen(2))
+		Expect(proposal.Patches[0].TargetRef).To(Equal(&corev1.ObjectReference{
+			APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "order-config",
+		}))
+		Expect(proposal.Patches[1].TargetRef.Kind).To(Equal("Deployment"))
+
+		Expect(reconciler.renderPatchDiff(ctx, aiopsAnalyzer, heal)).To(HavePrefix(
+			"ConfigMap/order-config:\n  replace /data/DB_POOL_SIZE: 20 -> 50\n\nDeployment/order:\n  add " + llm.RestartAnnotationPath + " = "))
+
+		rollback, err := reconciler.rollbackPatchesFor(ctx, aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rollback).To(HaveLen(2))
+		Expect(rollback[0].Op).To(Equal("replace"))
+		Expect(rollback[0].Path).To(Equal("/data/DB_POOL_SIZE"))
+		Expect(rollback[0].Value).To(Equal(runtime.RawExtension{Raw: []byte(`"20"`)}))
+		Expect(rollback[1].Op).To(Equal("remove"))
+		Expect(rollback[1].TargetRef.Kind).To(Equal("Deployment"))
+	})
+
+	It("should propose a change to a referenced ConfigMap", func() {
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment, configMap)
+		reconciler.LLM = llmtest.NewFakeLLMClient(fmt.Sprintf(configResponse, "order-config"))
+
+		_, err := reconciler.analyze(ctx, aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "analyze", Namespace: "default"}, &updated)).To(Succeed())
+		Expect(updated.Status.ProposedRemediation).NotTo(BeNil())
+		Expect(updated.Status.ProposedRemediation.Patches).To(HaveLen(2))
+		Expect(updated.Status.ProposedRemediation.Patches[1].Path).To(Equal(llm.RestartAnnotationPath))
+	})
+
+	It("should reject a ConfigMap the workload does not reference", func() {
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment, configMap)
+		reconciler.LLM = llmtest.NewFakeLLMClient(fmt.Sprintf(configResponse, "payment-config"))
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		_, err := reconciler.analyze(ctx, aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "analyze", Namespace: "default"}, &updated)).To(Succeed())
+		Expect(updated.Status.ProposedRemediation).To(BeNil())
+		Expect(updated.Status.NoopReason).To(Equal(autofixv1.NoopReasonPolicyRejected))
+		Expect(updated.Status.NoopMessage).To(Equal("ConfigMap payment-config 未被 Deployment/order 引用"))
+		Eventually(recorder.Events).Should(Receive(HavePrefix("Warning ConfigMapNotReferenced ConfigMap payment-config 未被 Deployment/order 引用（引用的 ConfigMap：order-config）")))
+	})
+})
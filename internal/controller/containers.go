@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+)
+
+// unknownContainerRefs 返回补丁中按名称引用、但线上工作负载中不存在的容器
+// 路径保留名称引用，创建 PR 时再按被修改的清单换算为下标，避免线上注入的 sidecar 改变下标
+func (r *AIOpsAnalyzerReconciler) unknownContainerRefs(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) ([]string, error) {
+	var workload map[string]any
+	var unknown []string
+	for _, op := range heal.PatchContent {
+		name, ok := patch.ContainerNameRef(op.Path)
+		if !ok {
+			continue
+		}
+
+		// 只有存在名称引用时才读取线上工作负载
+		if workload == nil {
+			obj, err := r.getTargetWorkload(ctx, healNamespace(aiopsAnalyzer, heal), heal.Target)
+			if err != nil {
+				return nil, err
+			}
+			workload = obj.Object
+		}
+
+		if _, err := patch.ResolveContainerIndex(workload, name); err != nil {
+			unknown = append(unknown, fmt.Sprintf("%s: %v", op.Path, err))
+		}
+	}
+	return unknown, nil
+}
+
+// rejectUnknownContainers 补丁引用的容器不存在时只记录结论，不返回错误，避免重新协调时再次调用大模型
+func (r *AIOpsAnalyzerReconciler) rejectUnknownContainers(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, error) {
+	unknown, err := r.unknownContainerRefs(ctx, aiopsAnalyzer, heal)
+	if err != nil || len(unknown) == 0 {
+		return false, err
+	}
+	message := "引用的容器不存在: " + strings.Join(unknown, "; ")
+	log.FromContext(ctx).Info("修复建议引用的容器不存在", "containers", unknown)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonContainerNotFound, "修复建议%s，已忽略", message)
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryPolicyRejected
+		status.Insights = fmt.Sprintf("%s（%s）", heal.Reason, message)
+		status.NoopReason = autofixv1.NoopReasonPolicyRejected
+		status.NoopMessage = message
+	})
+}
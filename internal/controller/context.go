@@ -0,0 +1,83 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// recentRestartWindow 在此时间窗口内重启过的容器视为异常
+const recentRestartWindow = time.Hour
+
+// GetContextPods 获取需要发送给大模型的Pod，spec.context.unhealthyOnly 时只保留异常Pod
+func (r *AIOpsAnalyzerReconciler) GetContextPods(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) ([]corev1.Pod, error) {
+	log := log.FromContext(ctx)
+
+	pods, err := r.GetTargetPods(ctx, &aiopsAnalyzer.Spec.Target)
+	if err != nil {
+		return nil, err
+	}
+	if aiopsAnalyzer.Spec.Context == nil || !aiopsAnalyzer.Spec.Context.UnhealthyOnly {
+		return pods, nil
+	}
+
+	unhealthy := FilterUnhealthyPods(pods, time.Now().Add(-recentRestartWindow))
+	log.Info("只保留异常Pod", "total", len(pods), "unhealthy", len(unhealthy))
+	return unhealthy, nil
+}
+
+// FilterUnhealthyPods 返回未就绪或在 since 之后重启过的Pod，已成功结束的Pod视为健康
+func FilterUnhealthyPods(pods []corev1.Pod, since time.Time) []corev1.Pod {
+	var unhealthy []corev1.Pod
+	for _, pod := range pods {
+		if isPodUnhealthy(&pod, since) {
+			unhealthy = append(unhealthy, pod)
+		}
+	}
+	return unhealthy
+}
+
+// isPodUnhealthy 判断单个Pod是否异常
+func isPodUnhealthy(pod *corev1.Pod, since time.Time) bool {
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return false
+	}
+
+	ready := false
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+	if !ready {
+		return true
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.LastTerminationState.Terminated
+		if status.RestartCount > 0 && terminated != nil && terminated.FinishedAt.After(since) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// newContextPod 构造测试用Pod
+func newContextPod(name string, ready bool, restartedAt *time.Time) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+	containerStatus := corev1.ContainerStatus{Name: "app", Ready: ready}
+	if restartedAt != nil {
+		containerStatus.RestartCount = 1
+		containerStatus.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+			ExitCode:   137,
+			FinishedAt: metav1.NewTime(*restartedAt),
+		}
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "demo"}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: readyStatus}},
+			ContainerStatuses: []corev1.ContainerStatus{containerStatus},
+		},
+	}
+}
+
+var _ = Describe("Context pods", func() {
+	recent := time.Now().Add(-5 * time.Minute)
+	old := time.Now().Add(-48 * time.Hour)
+
+	pods := []*corev1.Pod{
+		newContextPod("healthy", true, nil),
+		newContextPod("not-ready", false, nil),
+		newContextPod("restarted-recently", true, &recent),
+		newContextPod("restarted-long-ago", true, &old),
+	}
+
+	newReconciler := func() *AIOpsAnalyzerReconciler {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		builder := fake.NewClientBuilder().WithScheme(scheme)
+		for _, pod := range pods {
+			builder = builder.WithObjects(pod.DeepCopy())
+		}
+		return &AIOpsAnalyzerReconciler{Client: builder.Build(), Scheme: scheme}
+	}
+
+	newAnalyzer := func(unhealthyOnly bool) *autofixv1.AIOpsAnalyzer {
+		return &autofixv1.AIOpsAnalyzer{
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+				},
+				Context: &autofixv1.ContextSpec{UnhealthyOnly: unhealthyOnly},
+			},
+		}
+	}
+
+	podNames := func(pods []corev1.Pod) []string {
+		names := make([]string, 0, len(pods))
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		return names
+	}
+
+	It("should keep all pods by default", func() {
+		result, err := newReconciler().GetContextPods(context.Background(), newAnalyzer(false))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(HaveLen(4))
+	})
+
+	It("should keep only not-ready and recently restarted pods when unhealthyOnly is set", func() {
+		result, err := newReconciler().GetContextPods(context.Background(), newAnalyzer(true))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podNames(result)).To(ConsistOf("not-ready", "restarted-recently"))
+	})
+
+	It("should serialize pods that have no status yet", func() {
+		pending := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pending"}, Status: corev1.PodStatus{Phase: corev1.PodPending}}
+		Expect(FilterUnhealthyPods([]corev1.Pod{pending}, recent)).To(HaveLen(1))
+
+		yaml, err := newReconciler().GetTargetResourceYAML(context.Background(), []corev1.Pod{pending})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(yaml).To(ContainSubstring("name: pending"))
+	})
+})
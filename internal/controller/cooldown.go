@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// lastAppliedAt 返回上一次修复生效的时间：ArgoCD 同步时间或 PR 合并时间中较晚的一个，没有时返回 nil
+func lastAppliedAt(gitOps autofixv1.GitOpsStatus) *time.Time {
+	var applied *time.Time
+	for _, t := range []*metav1.Time{gitOps.PR.MergedAt, gitOps.LastSyncedTime} {
+		if t != nil && (applied == nil || t.After(*applied)) {
+			applied = &t.Time
+		}
+	}
+	return applied
+}
+
+// cooldownEndsAt 返回冷却期结束时间，未配置 cooldown 或还没有生效过的修复时返回 nil
+func cooldownEndsAt(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (*time.Time, error) {
+	cooldown := aiopsAnalyzer.Spec.AutoRemediation.Cooldown
+	if cooldown == "" {
+		return nil, nil
+	}
+	period, err := time.ParseDuration(cooldown)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cooldown %q: %w", cooldown, err)
+	}
+	applied := lastAppliedAt(aiopsAnalyzer.Status.GitOps)
+	if applied == nil {
+		return nil, nil
+	}
+	end := applied.Add(period)
+	return &end, nil
+}
+
+// holdDuringCooldown 上一次修复生效后的冷却期内暂停分析，在构建提示词和调用大模型之前检查
+// 返回 true 时调用方应在返回的时间后重新分析
+func (r *AIOpsAnalyzerReconciler) holdDuringCooldown(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (bool, time.Duration, error) {
+	end, err := cooldownEndsAt(aiopsAnalyzer)
+	if err != nil || end == nil {
+		return false, 0, err
+	}
+	remaining := time.Until(*end)
+	if remaining <= 0 {
+		return false, 0, nil
+	}
+
+	return true, remaining, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.Summary = autofixv1.SummaryCoolingDown
+		status.NoopReason = autofixv1.NoopReasonInCooldown
+		status.NoopMessage = fmt.Sprintf("上一次修复生效后的冷却期到 %s 结束，暂停分析", end.Format(time.RFC3339))
+	})
+}
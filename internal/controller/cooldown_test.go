@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("Remediation cooldown", func() {
+	newAnalyzer := func(mergedAgo time.Duration) *autofixv1.AIOpsAnalyzer {
+		mergedAt := metav1.NewTime(time.Now().Add(-mergedAgo))
+		return &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "cooldown", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+				AutoRemediation: autofixv1.AutoRemediationSpec{Cooldown: "30m"},
+			},
+			Status: autofixv1.AIOpsAnalyzerStatus{
+				GitOps: autofixv1.GitOpsStatus{
+					PR: autofixv1.PRStatus{Number: 8, Status: "merged", Merged: true, MergedAt: &mergedAt},
+				},
+			},
+		}
+	}
+
+	It("should pause analysis within the cooldown after the last merge", func() {
+		aiopsAnalyzer := newAnalyzer(10 * time.Minute)
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		hold, remaining, err := reconciler.holdDuringCooldown(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hold).To(BeTrue())
+		Expect(remaining).To(BeNumerically("~", 20*time.Minute, time.Minute))
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryCoolingDown))
+		Expect(aiopsAnalyzer.Status.NoopReason).To(Equal(autofixv1.NoopReasonInCooldown))
+		Expect(aiopsAnalyzer.Status.NoopMessage).To(ContainSubstring("冷却期"))
+	})
+
+	It("should not call the LLM within the cooldown", func() {
+		aiopsAnalyzer := newAnalyzer(10 * time.Minute)
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+		fake := llmtest.NewFakeLLMClient(`{"action":"noop","reason":"指标正常"}`)
+		reconciler.LLM = fake
+
+		result, err := reconciler.reconcile(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 20*time.Minute, time.Minute))
+		Expect(fake.Requests).To(BeEmpty())
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryCoolingDown))
+	})
+
+	It("should start the cooldown from the ArgoCD sync when it is later than the merge", func() {
+		aiopsAnalyzer := newAnalyzer(time.Hour)
+		syncedAt := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+		aiopsAnalyzer.Status.GitOps.LastSyncedTime = &syncedAt
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		hold, remaining, err := reconciler.holdDuringCooldown(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hold).To(BeTrue())
+		Expect(remaining).To(BeNumerically("~", 25*time.Minute, time.Minute))
+	})
+
+	It("should propose again once the cooldown has passed", func() {
+		aiopsAnalyzer := newAnalyzer(time.Hour)
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		hold, _, err := reconciler.holdDuringCooldown(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hold).To(BeFalse())
+		Expect(aiopsAnalyzer.Status.NoopReason).To(BeEmpty())
+	})
+
+	It("should not hold before any remediation was applied", func() {
+		aiopsAnalyzer := newAnalyzer(0)
+		aiopsAnalyzer.Status.GitOps = autofixv1.GitOpsStatus{}
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		hold, _, err := reconciler.holdDuringCooldown(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hold).To(BeFalse())
+	})
+})
@@ -0,0 +1,195 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/secret"
+)
+
+// 凭据中约定的键
+const (
+	llmAPIKeyKey       = "api_key"
+	feishuAppIDKey     = "app_id"
+	feishuAppSecretKey = "app_secret"
+	gitTokenKey        = "token"
+	gitSSHKeyKey       = "ssh-privatekey"
+	datasourceTokenKey = "token"
+	datasourceUserKey  = "username"
+	datasourcePassKey  = "password"
+)
+
+// gitCredential GitOps 仓库的凭据，token 用于 HTTPS 和托管平台 API，ssh-privatekey 用于 SSH 地址
+type gitCredential struct {
+	Token         string
+	SSHPrivateKey []byte
+}
+
+// String 避免凭据被打印到日志中
+func (c gitCredential) String() string {
+	return fmt.Sprintf("gitCredential{token: %t, sshPrivateKey: %t}", c.Token != "", len(c.SSHPrivateKey) > 0)
+}
+
+// GoString 与 String 相同，%#v 时也不输出凭据内容
+func (c gitCredential) GoString() string {
+	return c.String()
+}
+
+// secretResolvers 返回凭据解析器，未注入时只支持 CR 所在命名空间的 Kubernetes Secret
+func (r *AIOpsAnalyzerReconciler) secretResolvers() secret.Resolvers {
+	if r.Secrets != nil {
+		return r.Secrets
+	}
+	return secret.Resolvers{
+		autofixv1.SecretProviderKubernetes: &secret.KubernetesResolver{Client: r.Client},
+	}
+}
+
+// resolveLLMAPIKey 解析大模型 API Key，未配置 credentialsRef 时返回空字符串（使用 LLM_API_KEY）
+func (r *AIOpsAnalyzerReconciler) resolveLLMAPIKey(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (string, error) {
+	if aiopsAnalyzer.Spec.LLM == nil || aiopsAnalyzer.Spec.LLM.CredentialsRef == nil {
+		return "", nil
+	}
+	apiKey, err := r.secretResolvers().ResolveKey(ctx, aiopsAnalyzer.Namespace, *aiopsAnalyzer.Spec.LLM.CredentialsRef, llmAPIKeyKey)
+	if err != nil {
+		return "", fmt.Errorf("resolve llm credentials failed: %w", err)
+	}
+	return apiKey, nil
+}
+
+// llmClientFor 返回本次分析使用的大模型客户端
+// 配置了 spec.llm.credentialsRef 时用其中的 API Key 创建客户端，否则使用注入的 LLM
+// 配置了 spec.llm.systemPromptOverride 时替换系统提示词
+func (r *AIOpsAnalyzerReconciler) llmClientFor(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (llm.LLMClient, error) {
+	apiKey, err := r.resolveLLMAPIKey(ctx, aiopsAnalyzer)
+	if err != nil {
+		return nil, err
+	}
+	var client llm.LLMClient = r.LLM
+	if apiKey != "" {
+		cfg := llm.ConfigFromEnv()
+		cfg.APIKey = apiKey
+		if client, err = llm.NewLLMClient(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if client == nil {
+		return nil, errors.New("llm client is not configured: set spec.llm.credentialsRef or LLM_API_KEY")
+	}
+	return withSystemPrompt(aiopsAnalyzer, client)
+}
+
+// withSystemPrompt 配置了 spec.llm.systemPromptOverride 时替换系统提示词
+func withSystemPrompt(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, client llm.LLMClient) (llm.LLMClient, error) {
+	if aiopsAnalyzer.Spec.LLM == nil || aiopsAnalyzer.Spec.LLM.SystemPromptOverride == "" {
+		return client, nil
+	}
+	override := aiopsAnalyzer.Spec.LLM.SystemPromptOverride
+	if err := llm.ValidateSystemPrompt(override); err != nil {
+		return nil, fmt.Errorf("invalid spec.llm.systemPromptOverride: %w", err)
+	}
+	prompter, ok := client.(llm.SystemPrompter)
+	if !ok {
+		return nil, errors.New("llm client does not support spec.llm.systemPromptOverride")
+	}
+	return prompter.WithSystemPrompt(override), nil
+}
+
+// resolveGitToken 读取 spec.gitOps.tokenSecretRef 中的 Git 凭据，至少需要 token 或 ssh-privatekey 之一
+func (r *AIOpsAnalyzerReconciler) resolveGitToken(ctx context.Context, ref autofixv1.SecretRef, namespace string) (gitCredential, error) {
+	data, err := r.secretResolvers().Resolve(ctx, namespace, ref)
+	if err != nil {
+		return gitCredential{}, fmt.Errorf("resolve git credentials failed: %w", err)
+	}
+	credential := gitCredential{Token: string(data[gitTokenKey]), SSHPrivateKey: data[gitSSHKeyKey]}
+	if credential.Token == "" && len(credential.SSHPrivateKey) == 0 {
+		return gitCredential{}, fmt.Errorf("git credentials %q must contain %q or %q", ref.Name, gitTokenKey, gitSSHKeyKey)
+	}
+	return credential, nil
+}
+
+// resolveDatasourceAuth 读取数据源凭据并生成 Authorization header，auth 为空时不认证
+func (r *AIOpsAnalyzerReconciler) resolveDatasourceAuth(ctx context.Context, namespace string, auth *autofixv1.DatasourceAuth) (datasourceAuth, error) {
+	if auth == nil {
+		return datasourceAuth{}, nil
+	}
+	data, err := r.secretResolvers().Resolve(ctx, namespace, auth.CredentialsRef)
+	if err != nil {
+		return datasourceAuth{}, err
+	}
+	switch auth.Type {
+	case autofixv1.DatasourceAuthBasic:
+		username, password := string(data[datasourceUserKey]), string(data[datasourcePassKey])
+		if username == "" || password == "" {
+			return datasourceAuth{}, fmt.Errorf("datasource credentials %q must contain %q and %q", auth.CredentialsRef.Name, datasourceUserKey, datasourcePassKey)
+		}
+		return datasourceAuth{header: "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))}, nil
+	case "", autofixv1.DatasourceAuthBearer:
+		token := string(data[datasourceTokenKey])
+		if token == "" {
+			return datasourceAuth{}, fmt.Errorf("datasource credentials %q must contain %q", auth.CredentialsRef.Name, datasourceTokenKey)
+		}
+		return datasourceAuth{header: "Bearer " + token}, nil
+	default:
+		return datasourceAuth{}, fmt.Errorf("unsupported datasource auth type %q", auth.Type)
+	}
+}
+
+// newFeishuClient 使用 spec.feishu.credentialsRef 中的应用凭据创建飞书客户端，未配置时使用控制器默认的客户端
+func (r *AIOpsAnalyzerReconciler) newFeishuClient(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (*lark.Client, error) {
+	ref := aiopsAnalyzer.Spec.Feishu.CredentialsRef
+	if ref == nil {
+		if r.Feishu == nil {
+			return nil, errors.New("spec.feishu.credentialsRef is not set and the controller has no default feishu credentials (--feishu-credentials-secret)")
+		}
+		return r.Feishu, nil
+	}
+
+	data, err := r.secretResolvers().Resolve(ctx, aiopsAnalyzer.Namespace, *ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolve feishu credentials failed: %w", err)
+	}
+	return feishuClientFrom(data, ref.Name)
+}
+
+// NewFeishuClientFromSecret 读取控制器级别的飞书应用凭据，创建默认的飞书客户端
+func NewFeishuClientFromSecret(ctx context.Context, reader client.Reader, key types.NamespacedName) (*lark.Client, error) {
+	data, err := (&secret.KubernetesResolver{Client: reader}).Resolve(ctx, key.Namespace, key.Name)
+	if err != nil {
+		return nil, err
+	}
+	return feishuClientFrom(data, key.Name)
+}
+
+// feishuClientFrom 用凭据中的 app_id、app_secret 创建飞书客户端
+func feishuClientFrom(data map[string][]byte, name string) (*lark.Client, error) {
+	appID, appSecret := string(data[feishuAppIDKey]), string(data[feishuAppSecretKey])
+	if appID == "" || appSecret == "" {
+		return nil, fmt.Errorf("feishu credentials %q must contain %q and %q", name, feishuAppIDKey, feishuAppSecretKey)
+	}
+	return lark.NewClient(appID, appSecret), nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Git credentials", func() {
+	ref := autofixv1.SecretRef{Provider: autofixv1.SecretProviderKubernetes, Name: "git"}
+
+	newReconcilerWithSecret := func(data map[string][]byte) *AIOpsAnalyzerReconciler {
+		return newFakeReconciler(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "git", Namespace: "default"},
+			Data:       data,
+		})
+	}
+
+	It("should read the https token", func() {
+		reconciler := newReconcilerWithSecret(map[string][]byte{"token": []byte("t0ken")})
+		credential, err := reconciler.resolveGitToken(context.Background(), ref, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credential.Token).To(Equal("t0ken"))
+		Expect(credential.SSHPrivateKey).To(BeEmpty())
+	})
+
+	It("should read the ssh private key", func() {
+		reconciler := newReconcilerWithSecret(map[string][]byte{"ssh-privatekey": []byte("-----BEGIN KEY-----")})
+		credential, err := reconciler.resolveGitToken(context.Background(), ref, "default")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credential.SSHPrivateKey).To(Equal([]byte("-----BEGIN KEY-----")))
+	})
+
+	It("should report a missing secret", func() {
+		_, err := newFakeReconciler().resolveGitToken(context.Background(), ref, "default")
+		Expect(err).To(MatchError(ContainSubstring("get secret default/git failed")))
+	})
+
+	It("should report a secret without either key", func() {
+		reconciler := newReconcilerWithSecret(map[string][]byte{"password": []byte("x")})
+		_, err := reconciler.resolveGitToken(context.Background(), ref, "default")
+		Expect(err).To(MatchError(`git credentials "git" must contain "token" or "ssh-privatekey"`))
+	})
+
+	It("should never print the secret values", func() {
+		credential := gitCredential{Token: "t0ken", SSHPrivateKey: []byte("-----BEGIN KEY-----")}
+		for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+			Expect(fmt.Sprintf(format, credential)).NotTo(Or(ContainSubstring("t0ken"), ContainSubstring("BEGIN")))
+		}
+	})
+})
+
+var _ = Describe("Feishu credentials", func() {
+	feishuSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "feishu", Namespace: "aiops-system"},
+		Data:       map[string][]byte{"app_id": []byte("cli_test"), "app_secret": []byte("s3cret")},
+	}
+
+	It("should load the controller-level credentials at startup", func() {
+		reconciler := newFakeReconciler(feishuSecret)
+		feishuClient, err := NewFeishuClientFromSecret(context.Background(), reconciler.Client, types.NamespacedName{Namespace: "aiops-system", Name: "feishu"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(feishuClient).NotTo(BeNil())
+	})
+
+	It("should fail when the controller-level secret is missing or incomplete", func() {
+		_, err := NewFeishuClientFromSecret(context.Background(), newFakeReconciler().Client, types.NamespacedName{Namespace: "aiops-system", Name: "feishu"})
+		Expect(err).To(MatchError(ContainSubstring("get secret aiops-system/feishu failed")))
+
+		incomplete := feishuSecret.DeepCopy()
+		delete(incomplete.Data, "app_secret")
+		_, err = NewFeishuClientFromSecret(context.Background(), newFakeReconciler(incomplete).Client, types.NamespacedName{Namespace: "aiops-system", Name: "feishu"})
+		Expect(err).To(MatchError(`feishu credentials "feishu" must contain "app_id" and "app_secret"`))
+	})
+
+	It("should use the default client only when the analyzer has no credentialsRef", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default"}}
+		reconciler := newFakeReconciler()
+		_, err := reconciler.newFeishuClient(context.Background(), aiopsAnalyzer)
+		Expect(err).To(MatchError(ContainSubstring("--feishu-credentials-secret")))
+
+		reconciler.Feishu = lark.NewClient("cli_default", "secret")
+		Expect(reconciler.newFeishuClient(context.Background(), aiopsAnalyzer)).To(BeIdenticalTo(reconciler.Feishu))
+
+		aiopsAnalyzer.Spec.Feishu.CredentialsRef = &autofixv1.SecretRef{Provider: autofixv1.SecretProviderKubernetes, Name: "feishu"}
+		_, err = reconciler.newFeishuClient(context.Background(), aiopsAnalyzer)
+		Expect(err).To(MatchError(ContainSubstring("get secret default/feishu failed")))
+	})
+})
@@ -0,0 +1,209 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
+)
+
+// 未配置 spec.datasources 和控制器配置 datasources 时使用的默认地址
+const (
+	defaultPrometheusURL = "http://127.0.0.1:9090"
+	defaultLokiURL       = "http://127.0.0.1:3100"
+
+	// defaultDatasourceTimeout 未配置 DatasourceTimeout 时单次查询的超时时间
+	defaultDatasourceTimeout = 15 * time.Second
+
+	prometheusQueryPath      = "/api/v1/query"
+	prometheusQueryRangePath = "/api/v1/query_range"
+	lokiQueryPath            = "/loki/api/v1/query_range"
+)
+
+// datasources 本次分析使用的数据源
+type datasources struct {
+	PrometheusURL  string
+	LokiURL        string
+	LokiOrgID      string
+	PrometheusAuth datasourceAuth
+	LokiAuth       datasourceAuth
+}
+
+// datasourceAuth 查询数据源时发送的 Authorization header，为空时不发送
+type datasourceAuth struct {
+	header string
+}
+
+// String 避免凭据被打印到日志中
+func (a datasourceAuth) String() string {
+	return fmt.Sprintf("datasourceAuth{configured: %t}", a.header != "")
+}
+
+// GoString 与 String 相同，%#v 时也不输出凭据内容
+func (a datasourceAuth) GoString() string {
+	return a.String()
+}
+
+// apply 为请求设置 Authorization header
+func (a datasourceAuth) apply(req *http.Request) {
+	if a.header != "" {
+		req.Header.Set("Authorization", a.header)
+	}
+}
+
+// datasourceHTTPClient 返回查询数据源使用的 HTTP 客户端
+func (r *AIOpsAnalyzerReconciler) datasourceHTTPClient() *http.Client {
+	timeout := r.DatasourceTimeout
+	if timeout <= 0 {
+		timeout = defaultDatasourceTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// datasourceError 数据源地址无效或无法访问，Reason 对应 condition 的原因
+type datasourceError struct {
+	Reason     string
+	Datasource string
+	URL        string
+	Err        error
+}
+
+func (e *datasourceError) Error() string {
+	switch e.Reason {
+	case autofixv1.ReasonInvalidDatasourceURL:
+		return fmt.Sprintf("invalid %s url %q: %v", e.Datasource, e.URL, e.Err)
+	case autofixv1.ReasonInvalidDatasourceCredentials:
+		return fmt.Sprintf("invalid %s credentials: %v", e.Datasource, e.Err)
+	}
+	return fmt.Sprintf("%s at %s is unreachable: %v", e.Datasource, e.URL, e.Err)
+}
+
+func (e *datasourceError) Unwrap() error {
+	return e.Err
+}
+
+// unreachableDatasource 包装请求数据源时的网络错误
+func unreachableDatasource(name, rawURL string, err error) error {
+	return &datasourceError{Reason: autofixv1.ReasonDatasourceUnreachable, Datasource: name, URL: rawURL, Err: err}
+}
+
+// datasourcesFor 读取 spec.datasources，未配置的字段依次使用控制器配置 defaults 和内置默认值，并校验地址
+func datasourcesFor(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, defaults config.DatasourcesConfig) (datasources, error) {
+	ds := datasources{
+		PrometheusURL: defaultPrometheusURL,
+		LokiURL:       defaultLokiURL,
+		LokiOrgID:     defaults.LokiOrgID,
+	}
+	if defaults.PrometheusURL != "" {
+		ds.PrometheusURL = defaults.PrometheusURL
+	}
+	if defaults.LokiURL != "" {
+		ds.LokiURL = defaults.LokiURL
+	}
+	if spec := aiopsAnalyzer.Spec.Datasources; spec != nil {
+		if spec.PrometheusURL != "" {
+			ds.PrometheusURL = spec.PrometheusURL
+		}
+		if spec.LokiURL != "" {
+			ds.LokiURL = spec.LokiURL
+		}
+		if spec.LokiOrgID != "" {
+			ds.LokiOrgID = spec.LokiOrgID
+		}
+	}
+
+	var err error
+	if ds.PrometheusURL, err = validateDatasourceURL("prometheus", ds.PrometheusURL); err != nil {
+		return datasources{}, err
+	}
+	if ds.LokiURL, err = validateDatasourceURL("loki", ds.LokiURL); err != nil {
+		return datasources{}, err
+	}
+	return ds, nil
+}
+
+// resolveDatasources 在 datasourcesFor 的基础上读取 spec.datasources 中配置的认证凭据
+func (r *AIOpsAnalyzerReconciler) resolveDatasources(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (datasources, error) {
+	ds, err := datasourcesFor(aiopsAnalyzer, r.Defaults.Datasources)
+	if err != nil || aiopsAnalyzer.Spec.Datasources == nil {
+		return ds, err
+	}
+	spec := aiopsAnalyzer.Spec.Datasources
+	if ds.PrometheusAuth, err = r.resolveDatasourceAuth(ctx, aiopsAnalyzer.Namespace, spec.PrometheusAuth); err != nil {
+		return datasources{}, &datasourceError{Reason: autofixv1.ReasonInvalidDatasourceCredentials, Datasource: "prometheus", URL: ds.PrometheusURL, Err: err}
+	}
+	if ds.LokiAuth, err = r.resolveDatasourceAuth(ctx, aiopsAnalyzer.Namespace, spec.LokiAuth); err != nil {
+		return datasources{}, &datasourceError{Reason: autofixv1.ReasonInvalidDatasourceCredentials, Datasource: "loki", URL: ds.LokiURL, Err: err}
+	}
+	return ds, nil
+}
+
+// validateDatasourceURL 校验地址为带 host 的 http(s) URL，并去掉末尾的 /
+func validateDatasourceURL(name, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err == nil && u.Scheme != "http" && u.Scheme != "https" {
+		err = errors.New("scheme must be http or https")
+	}
+	if err == nil && u.Host == "" {
+		err = errors.New("host is empty")
+	}
+	if err != nil {
+		return "", &datasourceError{Reason: autofixv1.ReasonInvalidDatasourceURL, Datasource: name, URL: rawURL, Err: err}
+	}
+	return strings.TrimRight(rawURL, "/"), nil
+}
+
+// recordDatasourcesCondition 根据本次查询结果更新 DatasourcesReachable condition
+// err 与数据源无关时不更新，condition 没有变化时不写 status
+func (r *AIOpsAnalyzerReconciler) recordDatasourcesCondition(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, err error) error {
+	condition := metav1.Condition{
+		Type:               autofixv1.ConditionDatasourcesReachable,
+		Status:             metav1.ConditionTrue,
+		Reason:             autofixv1.ReasonDatasourcesReachable,
+		Message:            "Prometheus 和 Loki 均可访问",
+		ObservedGeneration: aiopsAnalyzer.Generation,
+	}
+	var dsErr *datasourceError
+	switch {
+	case errors.As(err, &dsErr):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = dsErr.Reason
+		condition.Message = dsErr.Error()
+	case err != nil:
+		return nil
+	}
+
+	if existing := meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, condition.Type); existing != nil &&
+		existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		meta.SetStatusCondition(&status.Conditions, condition)
+	})
+}
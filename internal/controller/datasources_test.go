@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
+)
+
+var _ = Describe("Datasources", func() {
+	newAnalyzer := func(spec *autofixv1.DatasourcesSpec) *autofixv1.AIOpsAnalyzer {
+		return &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "datasources", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Datasources: spec},
+		}
+	}
+
+	It("should fall back to the local endpoints when not configured", func() {
+		ds, err := datasourcesFor(newAnalyzer(nil), config.DatasourcesConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds).To(Equal(datasources{PrometheusURL: defaultPrometheusURL, LokiURL: defaultLokiURL}))
+	})
+
+	It("should fall back to the controller config before the local endpoints", func() {
+		ds, err := datasourcesFor(newAnalyzer(&autofixv1.DatasourcesSpec{LokiURL: "https://loki.example.com"}), config.DatasourcesConfig{
+			PrometheusURL: "http://prometheus.monitoring:9090",
+			LokiURL:       "http://loki.monitoring:3100",
+			LokiOrgID:     "platform",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds).To(Equal(datasources{
+			PrometheusURL: "http://prometheus.monitoring:9090",
+			LokiURL:       "https://loki.example.com",
+			LokiOrgID:     "platform",
+		}))
+	})
+
+	It("should use the endpoints from the spec", func() {
+		ds, err := datasourcesFor(newAnalyzer(&autofixv1.DatasourcesSpec{
+			PrometheusURL: "http://prometheus.monitoring:9090/",
+			LokiURL:       "https://loki.example.com",
+			LokiOrgID:     "team-a",
+		}), config.DatasourcesConfig{PrometheusURL: "http://prometheus.default:9090"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds).To(Equal(datasources{
+			PrometheusURL: "http://prometheus.monitoring:9090",
+			LokiURL:       "https://loki.example.com",
+			LokiOrgID:     "team-a",
+		}))
+	})
+
+	DescribeTable("rejecting invalid urls",
+		func(spec *autofixv1.DatasourcesSpec) {
+			_, err := datasourcesFor(newAnalyzer(spec), config.DatasourcesConfig{})
+			var dsErr *datasourceError
+			Expect(errors.As(err, &dsErr)).To(BeTrue())
+			Expect(dsErr.Reason).To(Equal(autofixv1.ReasonInvalidDatasourceURL))
+		},
+		Entry("unsupported scheme", &autofixv1.DatasourcesSpec{PrometheusURL: "ftp://prometheus:9090"}),
+		Entry("missing host", &autofixv1.DatasourcesSpec{LokiURL: "http://"}),
+		Entry("unparseable", &autofixv1.DatasourcesSpec{LokiURL: "http://loki:port"}),
+	)
+
+	It("should surface unreachable datasources as a condition", func() {
+		server := httptest.NewServer(nil)
+		server.Close()
+
+		aiopsAnalyzer := newAnalyzer(&autofixv1.DatasourcesSpec{PrometheusURL: server.URL})
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+		ds, err := datasourcesFor(aiopsAnalyzer, config.DatasourcesConfig{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = reconciler.GetPrometheusAlerts(context.Background(), ds, &aiopsAnalyzer.Spec.Target)
+		Expect(err).To(HaveOccurred())
+		Expect(reconciler.recordDatasourcesCondition(context.Background(), aiopsAnalyzer, err)).To(Succeed())
+		condition := meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionDatasourcesReachable)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(autofixv1.ReasonDatasourceUnreachable))
+		Expect(condition.Message).To(ContainSubstring(server.URL))
+
+		Expect(reconciler.recordDatasourcesCondition(context.Background(), aiopsAnalyzer, nil)).To(Succeed())
+		condition = meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionDatasourcesReachable)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("should leave the condition alone for unrelated errors", func() {
+		aiopsAnalyzer := newAnalyzer(nil)
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		Expect(reconciler.recordDatasourcesCondition(context.Background(), aiopsAnalyzer, errors.New("list pods failed"))).To(Succeed())
+		Expect(aiopsAnalyzer.Status.Conditions).To(BeEmpty())
+	})
+
+	Describe("authentication", func() {
+		var (
+			headers map[string]string
+			server  *httptest.Server
+		)
+		BeforeEach(func() {
+			headers = map[string]string{}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				headers[req.URL.Path] = req.Header.Get("Authorization")
+				resultType := "vector"
+				if req.URL.Path == lokiQueryPath {
+					resultType = "streams"
+				}
+				fmt.Fprintf(w, `{"status":"success","data":{"resultType":%q,"result":[]}}`, resultType)
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		query := func(objs ...client.Object) (datasources, error) {
+			aiopsAnalyzer := newAnalyzer(&autofixv1.DatasourcesSpec{
+				PrometheusURL:  server.URL,
+				LokiURL:        server.URL,
+				PrometheusAuth: &autofixv1.DatasourceAuth{Type: autofixv1.DatasourceAuthBearer, CredentialsRef: autofixv1.SecretRef{Name: "prometheus"}},
+				LokiAuth:       &autofixv1.DatasourceAuth{Type: autofixv1.DatasourceAuthBasic, CredentialsRef: autofixv1.SecretRef{Name: "loki"}},
+			})
+			reconciler := newFakeReconciler(append(objs, aiopsAnalyzer)...)
+			ds, err := reconciler.resolveDatasources(context.Background(), aiopsAnalyzer)
+			if err != nil {
+				return ds, err
+			}
+			_, err = reconciler.GetPrometheusAlerts(context.Background(), ds, &aiopsAnalyzer.Spec.Target)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = reconciler.GetLokiLogs(context.Background(), ds, aiopsAnalyzer)
+			Expect(err).NotTo(HaveOccurred())
+			return ds, nil
+		}
+		secret := func(name string, data map[string]string) *corev1.Secret {
+			s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Data: map[string][]byte{}}
+			for k, v := range data {
+				s.Data[k] = []byte(v)
+			}
+			return s
+		}
+
+		It("should send the bearer token and basic credentials", func() {
+			ds, err := query(
+				secret("prometheus", map[string]string{"token": "prom-t0ken"}),
+				secret("loki", map[string]string{"username": "aiops", "password": "l0ki-pass"}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(headers).To(Equal(map[string]string{
+				prometheusQueryPath: "Bearer prom-t0ken",
+				lokiQueryPath:       "Basic " + base64.StdEncoding.EncodeToString([]byte("aiops:l0ki-pass")),
+			}))
+			Expect(fmt.Sprintf("%v %+v %#v", ds, ds, ds)).NotTo(Or(ContainSubstring("prom-t0ken"), ContainSubstring("l0ki-pass"), ContainSubstring("Basic ")))
+		})
+
+		It("should not send credentials when auth is not configured", func() {
+			aiopsAnalyzer := newAnalyzer(&autofixv1.DatasourcesSpec{PrometheusURL: server.URL})
+			reconciler := newFakeReconciler(aiopsAnalyzer)
+			ds, err := reconciler.resolveDatasources(context.Background(), aiopsAnalyzer)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = reconciler.GetPrometheusAlerts(context.Background(), ds, &aiopsAnalyzer.Spec.Target)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(headers).To(HaveKeyWithValue(prometheusQueryPath, ""))
+		})
+
+		It("should report missing credentials without querying", func() {
+			_, err := query(
+				secret("prometheus", map[string]string{"token": "prom-t0ken"}),
+				secret("loki", map[string]string{"username": "aiops"}),
+			)
+			var dsErr *datasourceError
+			Expect(errors.As(err, &dsErr)).To(BeTrue())
+			Expect(dsErr.Reason).To(Equal(autofixv1.ReasonInvalidDatasourceCredentials))
+			Expect(err).To(MatchError(`invalid loki credentials: datasource credentials "loki" must contain "username" and "password"`))
+			Expect(headers).To(BeEmpty())
+
+			_, err = query()
+			Expect(err).To(MatchError(ContainSubstring("invalid prometheus credentials")))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// fingerprintSections 参与指纹计算的分段，指标趋势和日志每次查询都会变化，不代表情况发生了变化
+var fingerprintSections = map[string]bool{
+	"Target Resource Information": true,
+	"Node Pressure":               true,
+	"Prometheus Alerts":           true,
+}
+
+// signatureCountSuffix 错误签名行末尾的出现次数
+var signatureCountSuffix = regexp.MustCompile(` \(x\d+\)\n?$`)
+
+// eventFingerprint 计算 event string 中目标资源、节点压力、告警分段以及错误签名集合的 sha256
+// 错误签名只计入签名本身，出现次数和排序每次查询都会变化，出现新的错误才代表情况发生了变化
+// generation 一并计入，修改 spec 后总会重新分析
+func eventFingerprint(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, eventString string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "generation=%d\n", aiopsAnalyzer.Generation)
+	for _, section := range splitEventSections(eventString) {
+		if section.Title == lokiSignaturesSection {
+			for _, signature := range fingerprintSignatures(section.Lines) {
+				fmt.Fprintf(h, "signature=%s\n", signature)
+			}
+			continue
+		}
+		if !fingerprintSections[section.Title] {
+			continue
+		}
+		for _, line := range section.Lines {
+			h.Write([]byte(line))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintSignatures 返回错误签名分段中按名称排序的签名，去掉出现次数和合并的其余签名
+func fingerprintSignatures(lines []string) []string {
+	var signatures []string
+	for _, line := range lines {
+		if !signatureCountSuffix.MatchString(line) {
+			continue
+		}
+		signatures = append(signatures, signatureCountSuffix.ReplaceAllString(line, ""))
+	}
+	sort.Strings(signatures)
+	return signatures
+}
+
+// awaitingApproval 返回仍在等待审批人响应的请求，没有时返回 nil
+func awaitingApproval(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) *autofixv1.ApprovalRequest {
+	pending := aiopsAnalyzer.Status.PendingApproval
+	if pending == nil || pending.Approved != nil {
+		return nil
+	}
+	return pending
+}
+
+// unchangedWithinInterval 指纹与上次分析相同且仍在 analysisInterval 内时返回距下一次分析的剩余时间
+func (r *AIOpsAnalyzerReconciler) unchangedWithinInterval(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, fingerprint string, now time.Time) (time.Duration, bool) {
+	status := &aiopsAnalyzer.Status
+	if status.LastEventHash == "" || status.LastEventHash != fingerprint || status.LastAnalysisTime == nil {
+		return 0, false
+	}
+	remaining := analysisInterval(aiopsAnalyzer) - now.Sub(status.LastAnalysisTime.Time)
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, true
+}
+
+// recordEventHash 记录本次发送给大模型的 event string 指纹
+func (r *AIOpsAnalyzerReconciler) recordEventHash(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, fingerprint string) error {
+	if aiopsAnalyzer.Status.LastEventHash == fingerprint {
+		return nil
+	}
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.LastEventHash = fingerprint
+	})
+}
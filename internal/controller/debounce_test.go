@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("Analysis debounce", func() {
+	const eventString = "=== Target Resource Information ===\nrestartCount: 3\n\n" +
+		"=== Prometheus Alerts ===\nAlert: HighCPU\n\n" +
+		"=== Prometheus Trends ===\n--- cpu ---\n{pod=order-0} min=0.1 max=0.9 last=0.9\n\n" +
+		"=== Loki Error Logs ===\n2025-01-01T00:00:00Z connection refused\n"
+
+	var aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+
+	BeforeEach(func() {
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "debounce", Namespace: "default", Generation: 1},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				AnalysisInterval: "10m",
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+			},
+		}
+	})
+
+	It("should ignore trends and logs but not alerts or spec changes", func() {
+		fingerprint := eventFingerprint(aiopsAnalyzer, eventString)
+		Expect(eventFingerprint(aiopsAnalyzer, eventString+"2025-01-01T00:01:00Z connection refused\n")).To(Equal(fingerprint))
+		Expect(eventFingerprint(aiopsAnalyzer, strings.Replace(eventString, "last=0.9", "last=0.7", 1))).To(Equal(fingerprint))
+
+		Expect(eventFingerprint(aiopsAnalyzer, "=== Prometheus Alerts ===\nNo firing alerts\n")).NotTo(Equal(fingerprint))
+		aiopsAnalyzer.Generation = 2
+		Expect(eventFingerprint(aiopsAnalyzer, eventString)).NotTo(Equal(fingerprint))
+	})
+
+	It("should take new error signatures into account but not their counts", func() {
+		withSignatures := func(signatures string) string {
+			return eventString + "\n=== Loki Error Signatures ===\n" + signatures
+		}
+		fingerprint := eventFingerprint(aiopsAnalyzer, withSignatures(
+			"ERROR dial tcp <*>: connection refused (x8)\npanic: nil map (x3)\n"))
+		Expect(fingerprint).NotTo(Equal(eventFingerprint(aiopsAnalyzer, eventString)))
+
+		Expect(eventFingerprint(aiopsAnalyzer, withSignatures(
+			"panic: nil map (x30)\nERROR dial tcp <*>: connection refused (x9)\n... 1 more signatures (1 lines)\n"))).To(Equal(fingerprint))
+		Expect(eventFingerprint(aiopsAnalyzer, withSignatures(
+			"ERROR dial tcp <*>: connection refused (x8)\nFATAL out of memory (x1)\n"))).NotTo(Equal(fingerprint))
+	})
+
+	It("should skip only an unchanged situation within the analysis interval", func() {
+		reconciler := &AIOpsAnalyzerReconciler{}
+		now := time.Now()
+		fingerprint := eventFingerprint(aiopsAnalyzer, eventString)
+		aiopsAnalyzer.Status.LastEventHash = fingerprint
+		aiopsAnalyzer.Status.LastAnalysisTime = &metav1.Time{Time: now.Add(-4 * time.Minute)}
+
+		remaining, unchanged := reconciler.unchangedWithinInterval(aiopsAnalyzer, fingerprint, now)
+		Expect(unchanged).To(BeTrue())
+		Expect(remaining).To(Equal(6 * time.Minute))
+
+		_, unchanged = reconciler.unchangedWithinInterval(aiopsAnalyzer, "changed", now)
+		Expect(unchanged).To(BeFalse())
+		_, unchanged = reconciler.unchangedWithinInterval(aiopsAnalyzer, fingerprint, now.Add(6*time.Minute))
+		Expect(unchanged).To(BeFalse())
+
+		aiopsAnalyzer.Status.LastEventHash = ""
+		_, unchanged = reconciler.unchangedWithinInterval(aiopsAnalyzer, "", now)
+		Expect(unchanged).To(BeFalse())
+	})
+
+	It("should not analyze again while an approval is pending", func() {
+		aiopsAnalyzer.Status.PendingApproval = &autofixv1.ApprovalRequest{
+			RequestID: "debounce-1",
+			ExpiresAt: metav1.NewTime(time.Now().Add(5 * time.Minute)),
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+		fake := llmtest.NewFakeLLMClient()
+		reconciler.LLM = fake
+
+		result, err := reconciler.reconcile(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 5*time.Minute, time.Second))
+		Expect(fake.Requests).To(BeEmpty())
+		Expect(aiopsAnalyzer.Status.NoopReason).To(Equal(autofixv1.NoopReasonAwaitingApproval))
+		Expect(aiopsAnalyzer.Status.NoopMessage).To(ContainSubstring("debounce-1"))
+	})
+
+	It("should record the fingerprint once the LLM has answered", func() {
+		replicas := int32(2)
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment)
+		reconciler.LLM = llmtest.NewFakeLLMClient(`{"action":"noop","reason":"指标正常"}`)
+
+		fingerprint := eventFingerprint(aiopsAnalyzer, eventString)
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, eventString, fingerprint)
+		Expect(err).NotTo(HaveOccurred())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.LastEventHash).To(Equal(fingerprint))
+		Expect(latest.Status.LastAnalysisTime).NotTo(BeNil())
+		_, unchanged := reconciler.unchangedWithinInterval(&latest, fingerprint, time.Now())
+		Expect(unchanged).To(BeTrue())
+	})
+})
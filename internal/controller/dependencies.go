@@ -0,0 +1,211 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+const (
+	// defaultDependencyCheckInterval 未配置 Interval 时两次检查的间隔
+	defaultDependencyCheckInterval = 30 * time.Second
+	// defaultDependencyCheckTimeout 未配置 Timeout 时单个依赖的检查超时时间
+	defaultDependencyCheckTimeout = 5 * time.Second
+
+	prometheusReadyPath = "/-/ready"
+	lokiReadyPath       = "/ready"
+
+	// aiops_dependency_up 的 dependency 取值
+	dependencyLLM        = "llm"
+	dependencyPrometheus = "prometheus"
+	dependencyLoki       = "loki"
+)
+
+// DependencyHealth 在后台定期检查默认的大模型和数据源是否可达，结果通过 aiops_dependency_up 指标上报，
+// 并缓存给 ReadyzCheck 使用，探针不会直接请求依赖
+//
+// 默认不接入 /readyz：manager 同时提供 failurePolicy 为 Fail 的 webhook，外部服务短暂不可用时
+// Pod 退出就绪会导致 CR 无法创建和更新，需要时用 --readyz-check-dependencies 开启。只检查控制器级别配置的依赖：
+// 未配置默认大模型或不支持 Ping 时跳过大模型，控制器配置中未填写的数据源地址也跳过，CR 中单独配置的数据源不在检查范围内
+type DependencyHealth struct {
+	LLM         llm.LLMClient
+	Datasources config.DatasourcesConfig
+	// HTTPClient 请求数据源使用的客户端，为空时使用 http.DefaultClient
+	HTTPClient *http.Client
+	// Timeout 单个依赖的检查超时时间，为零时使用 defaultDependencyCheckTimeout
+	Timeout time.Duration
+	// Interval 两次检查的间隔，为零时使用 defaultDependencyCheckInterval
+	Interval time.Duration
+
+	// healthy 上次检查的结果，只在状态变化时打印日志
+	healthy map[string]bool
+
+	// mu 保护 results，ReadyzCheck 在探针的 goroutine 中读取
+	mu sync.Mutex
+	// results 最近一次检查的结果，还没有检查过时为 nil
+	results map[string]error
+}
+
+var _ manager.LeaderElectionRunnable = (*DependencyHealth)(nil)
+
+// NeedLeaderElection 每个副本都上报自己能否访问依赖
+func (d *DependencyHealth) NeedLeaderElection() bool {
+	return false
+}
+
+// Start 实现 manager.Runnable，立即检查一次，之后按 Interval 定期检查，直到 ctx 结束
+func (d *DependencyHealth) Start(ctx context.Context) error {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = defaultDependencyCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.checkOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkOnce 并发检查所有已配置的依赖并更新指标，返回每个依赖的检查结果
+func (d *DependencyHealth) checkOnce(ctx context.Context) map[string]error {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = defaultDependencyCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{}
+	if pinger, ok := d.LLM.(llm.Pinger); ok {
+		checks[dependencyLLM] = func(ctx context.Context) error {
+			if err := pinger.Ping(ctx); err != nil {
+				return fmt.Errorf("llm is unreachable: %w", err)
+			}
+			return nil
+		}
+	}
+	if d.Datasources.PrometheusURL != "" {
+		checks[dependencyPrometheus] = func(ctx context.Context) error {
+			return d.checkEndpoint(ctx, dependencyPrometheus, d.Datasources.PrometheusURL, prometheusReadyPath, "")
+		}
+	}
+	if d.Datasources.LokiURL != "" {
+		checks[dependencyLoki] = func(ctx context.Context) error {
+			return d.checkEndpoint(ctx, dependencyLoki, d.Datasources.LokiURL, lokiReadyPath, d.Datasources.LokiOrgID)
+		}
+	}
+
+	results := make(map[string]error, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := check(ctx)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	d.results = results
+	d.mu.Unlock()
+
+	logger := log.FromContext(ctx)
+	if d.healthy == nil {
+		d.healthy = map[string]bool{}
+	}
+	for name, err := range results {
+		up := 1.0
+		if err != nil {
+			up = 0
+		}
+		dependencyUp.WithLabelValues(name).Set(up)
+
+		healthy, checked := d.healthy[name]
+		switch {
+		case err != nil && (!checked || healthy):
+			logger.Error(err, "依赖不可达", "dependency", name)
+		case err == nil && checked && !healthy:
+			logger.Info("依赖已恢复", "dependency", name)
+		}
+		d.healthy[name] = err == nil
+	}
+	return results
+}
+
+// ReadyzCheck 实现 healthz.Checker，返回最近一次后台检查的结果，任何依赖不可达或还没有检查过时未就绪
+func (d *DependencyHealth) ReadyzCheck(_ *http.Request) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.results == nil {
+		return errors.New("dependencies have not been checked yet")
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(d.results)) {
+		if err := d.results[name]; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkEndpoint 请求数据源的就绪接口，非 200 时视为不可用
+func (d *DependencyHealth) checkEndpoint(ctx context.Context, name, baseURL, path, orgID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("%s is unreachable: %w", name, err)
+	}
+	if orgID != "" {
+		req.Header.Set("X-Scope-OrgID", orgID)
+	}
+	httpClient := d.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is unreachable: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s is not ready: %s returned %d", name, path, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+// pingingLLM 支持 Ping 的假大模型客户端
+type pingingLLM struct {
+	*llmtest.FakeLLMClient
+	err   error
+	calls atomic.Int32
+}
+
+func (p *pingingLLM) Ping(context.Context) error {
+	p.calls.Add(1)
+	return p.err
+}
+
+var _ = Describe("DependencyHealth", func() {
+	var (
+		prometheusStatus, lokiStatus atomic.Int32
+		prometheusHits               atomic.Int32
+		lokiOrgID                    atomic.Value
+		datasources                  config.DatasourcesConfig
+	)
+
+	BeforeEach(func() {
+		prometheusStatus.Store(http.StatusOK)
+		lokiStatus.Store(http.StatusOK)
+		prometheusHits.Store(0)
+		lokiOrgID.Store("")
+
+		prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/-/ready"))
+			prometheusHits.Add(1)
+			w.WriteHeader(int(prometheusStatus.Load()))
+		}))
+		DeferCleanup(prometheus.Close)
+		loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/ready"))
+			lokiOrgID.Store(r.Header.Get("X-Scope-OrgID"))
+			w.WriteHeader(int(lokiStatus.Load()))
+		}))
+		DeferCleanup(loki.Close)
+		datasources = config.DatasourcesConfig{PrometheusURL: prometheus.URL + "/", LokiURL: loki.URL, LokiOrgID: "team-a"}
+	})
+
+	up := func(dependency string) float64 {
+		return testutil.ToFloat64(dependencyUp.WithLabelValues(dependency))
+	}
+
+	It("should report reachable dependencies as up", func() {
+		fake := &pingingLLM{FakeLLMClient: llmtest.NewFakeLLMClient()}
+		health := &DependencyHealth{LLM: fake, Datasources: datasources}
+		results := health.checkOnce(ctx)
+		Expect(results).To(HaveLen(3))
+		for name, err := range results {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(up(name)).To(Equal(1.0))
+		}
+		Expect(fake.calls.Load()).To(BeEquivalentTo(1))
+		Expect(prometheusHits.Load()).To(BeEquivalentTo(1))
+		Expect(lokiOrgID.Load()).To(Equal("team-a"))
+	})
+
+	It("should report every unreachable dependency as down", func() {
+		lokiStatus.Store(http.StatusServiceUnavailable)
+		fake := &pingingLLM{FakeLLMClient: llmtest.NewFakeLLMClient(), err: errors.New("401 unauthorized")}
+		health := &DependencyHealth{LLM: fake, Datasources: datasources}
+		results := health.checkOnce(ctx)
+		Expect(results[dependencyLLM]).To(MatchError(ContainSubstring("llm is unreachable: 401 unauthorized")))
+		Expect(results[dependencyLoki]).To(MatchError(ContainSubstring("loki is not ready: /ready returned 503")))
+		Expect(results[dependencyPrometheus]).NotTo(HaveOccurred())
+		Expect(up(dependencyLLM)).To(Equal(0.0))
+		Expect(up(dependencyLoki)).To(Equal(0.0))
+		Expect(up(dependencyPrometheus)).To(Equal(1.0))
+
+		datasources.PrometheusURL = "http://127.0.0.1:1"
+		health = &DependencyHealth{Datasources: datasources}
+		Expect(health.checkOnce(ctx)[dependencyPrometheus]).To(MatchError(ContainSubstring("prometheus is unreachable")))
+		Expect(up(dependencyPrometheus)).To(Equal(0.0))
+	})
+
+	It("should serve the cached result as the readiness check", func() {
+		fake := &pingingLLM{FakeLLMClient: llmtest.NewFakeLLMClient()}
+		health := &DependencyHealth{LLM: fake, Datasources: datasources}
+		Expect(health.ReadyzCheck(nil)).To(MatchError("dependencies have not been checked yet"))
+
+		health.checkOnce(ctx)
+		Expect(health.ReadyzCheck(nil)).To(Succeed())
+		Expect(health.ReadyzCheck(nil)).To(Succeed())
+		Expect(fake.calls.Load()).To(BeEquivalentTo(1))
+		Expect(prometheusHits.Load()).To(BeEquivalentTo(1))
+
+		lokiStatus.Store(http.StatusServiceUnavailable)
+		health.checkOnce(ctx)
+		Expect(health.ReadyzCheck(nil)).To(MatchError(ContainSubstring("loki is not ready: /ready returned 503")))
+	})
+
+	It("should skip dependencies that are not configured", func() {
+		health := &DependencyHealth{LLM: llmtest.NewFakeLLMClient()}
+		Expect(health.checkOnce(ctx)).To(BeEmpty())
+	})
+
+	It("should keep checking in the background until stopped", func() {
+		health := &DependencyHealth{Datasources: datasources, Interval: 10 * time.Millisecond}
+		Expect(health.NeedLeaderElection()).To(BeFalse())
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- health.Start(runCtx) }()
+		Eventually(prometheusHits.Load).Should(BeNumerically(">=", 2))
+
+		prometheusStatus.Store(http.StatusServiceUnavailable)
+		Eventually(func() float64 { return up(dependencyPrometheus) }).Should(Equal(0.0))
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
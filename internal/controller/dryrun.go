@@ -0,0 +1,63 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// recordDryRun 开启 dry-run 时只把修复建议写入status并记录 Event，不发送卡片、不做任何 Git 操作
+func (r *AIOpsAnalyzerReconciler) recordDryRun(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if !aiopsAnalyzer.Spec.AutoRemediation.DryRun {
+		return false, nil
+	}
+	proposal, err := r.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+	if err != nil {
+		return true, err
+	}
+
+	ops := make([]string, 0, len(proposal.Patches))
+	for _, op := range proposal.Patches {
+		ops = append(ops, strings.TrimSpace(fmt.Sprintf("%s %s %s", op.Op, op.Path, string(op.Value.Raw))))
+	}
+	log.Info("dry-run 模式，只记录修复建议", "actionType", proposal.ActionType, "ops", ops)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonDryRun,
+		"dry-run：将提出 %s 修复建议（风险: %s）: %s；补丁: %s", proposal.ActionType, heal.RiskLevel, heal.Reason, strings.Join(ops, "; "))
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryDryRun
+		status.Insights = fmt.Sprintf("[dry-run] %s", heal.Reason)
+		if heal.Detail != "" {
+			status.Insights = fmt.Sprintf("%s：%s", status.Insights, heal.Detail)
+		}
+		status.ProposedRemediation = proposal
+		status.NoopReason = ""
+		status.NoopMessage = ""
+	})
+}
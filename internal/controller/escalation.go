@@ -0,0 +1,69 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// 严重程度（severity）描述问题本身有多紧急：low / medium / high / critical
+// 风险等级（risk_level）描述修复操作本身有多危险：low / medium / high
+// 两者由大模型分别给出、互不推导；大模型没有给出严重程度时按同名等级由风险等级映射，
+// 映射结果不会是 critical，因此只有大模型明确判断为 critical 时才会触发升级
+var severityForRisk = map[string]string{
+	"low":    "low",
+	"medium": "medium",
+	"high":   "high",
+}
+
+// validSeverities 修复建议允许的严重程度，与 RemediationProposal.Severity 的枚举一致
+var validSeverities = map[string]bool{
+	"low":      true,
+	"medium":   true,
+	"high":     true,
+	"critical": true,
+}
+
+// proposalSeverity 返回修复建议的严重程度：优先使用大模型给出的 severity，缺失或不合法时按风险等级映射
+func proposalSeverity(heal *llm.HealAction) string {
+	if validSeverities[heal.Severity] {
+		return heal.Severity
+	}
+	return severityForRisk[heal.RiskLevel]
+}
+
+// Escalation 严重程度和风险等级组合后强制的审批行为
+type Escalation struct {
+	// RequireApproval 即使 autoRemediation.requireApproval 为 false 也必须人工审批
+	RequireApproval bool
+	// MentionRoles 审批卡片 @ roleMembers 中配置的所有角色，而不只是 mentionRoles
+	MentionRoles bool
+}
+
+// EscalationPolicy 按严重程度和风险等级决定是否升级审批：
+//
+//	severity \ risk   low   medium   high
+//	low               -     -        -
+//	medium            -     -        -
+//	high              -     -        -
+//	critical          -     -        强制审批并 @ 所有角色
+func EscalationPolicy(severity, risk string) Escalation {
+	if severity == "critical" && risk == "high" {
+		return Escalation{RequireApproval: true, MentionRoles: true}
+	}
+	return Escalation{}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu/feishutest"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("Escalation policy", func() {
+	DescribeTable("severity and risk matrix",
+		func(severity, risk string, escalated bool) {
+			Expect(EscalationPolicy(severity, risk)).To(Equal(Escalation{RequireApproval: escalated, MentionRoles: escalated}))
+		},
+		Entry("low/low", "low", "low", false),
+		Entry("low/high", "low", "high", false),
+		Entry("medium/high", "medium", "high", false),
+		Entry("high/high", "high", "high", false),
+		Entry("critical/low", "critical", "low", false),
+		Entry("critical/medium", "critical", "medium", false),
+		Entry("critical/high", "critical", "high", true),
+		Entry("missing severity", "", "high", false),
+	)
+
+	DescribeTable("severity of a proposal",
+		func(severity, risk, expected string) {
+			Expect(proposalSeverity(&llm.HealAction{Severity: severity, RiskLevel: risk})).To(Equal(expected))
+		},
+		Entry("uses the severity from the LLM", "critical", "low", "critical"),
+		Entry("maps the risk level when severity is missing", "", "medium", "medium"),
+		Entry("maps the risk level when severity is invalid", "none", "high", "high"),
+		Entry("leaves unknown risk levels empty", "", "", ""),
+	)
+
+	It("should mention every configured role when escalated", func() {
+		feishu := &autofixv1.FeishuNotification{
+			MentionRoles: []string{"oncall-sre"},
+			RoleMembers: map[string][]string{
+				"oncall-sre": {"ou_alice"},
+				"dba":        {"ou_carol"},
+				"app-owner":  {"ou_bob", "ou_alice"},
+			},
+		}
+		Expect(feishuMentions(context.Background(), feishu, false)).To(Equal([]string{"ou_alice"}))
+		Expect(feishuMentions(context.Background(), feishu, true)).To(Equal([]string{"ou_alice", "ou_bob", "ou_carol"}))
+	})
+
+	It("should require approval for a critical high-risk heal even without requireApproval", func() {
+		replicas := int32(2)
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "escalate", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+				AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true},
+			},
+		}
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment)
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		reconciler.LLM = llmtest.NewFakeLLMClient(`{"action":"heal","reason":"订单服务不可用","patch_file":"scale.yaml",` +
+			`"patch_content":[{"op":"replace","path":"/spec/replicas","value":6}],"target":{"kind":"Deployment","labelSelector":"app=order"},` +
+			`"risk_level":"high","severity":"critical"}`)
+
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning " + EventReasonApprovalEscalated)))
+
+		// 没有配置飞书凭证时卡片发送失败，但也不会被自动批准
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval).To(BeNil())
+		Expect(latest.Status.ProposedRemediation.Severity).To(Equal("critical"))
+	})
+
+	It("should record an escalated proposal as awaiting approval", func() {
+		replicas := int32(2)
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "escalate", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+				Feishu: autofixv1.FeishuNotification{
+					ReceiveIDType: autofixv1.FeishuChatID, ReceiveID: "oc_a0553eda9014c201e6969b478895c230",
+					TemplateID: "tpl", TemplateVersion: "1.0.0",
+				},
+				AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true},
+			},
+		}
+		server := feishutest.NewServer()
+		DeferCleanup(server.Close)
+		reconciler := newFakeReconciler(aiopsAnalyzer, deployment)
+		reconciler.Recorder = record.NewFakeRecorder(10)
+		reconciler.Feishu = server.LarkClient()
+		reconciler.LLM = llmtest.NewFakeLLMClient(`{"action":"heal","reason":"订单服务不可用","patch_file":"scale.yaml",` +
+			`"patch_content":[{"op":"replace","path":"/spec/replicas","value":6}],"target":{"kind":"Deployment","labelSelector":"app=order"},` +
+			`"risk_level":"high","severity":"critical"}`)
+
+		_, err := reconciler.analyze(context.Background(), aiopsAnalyzer, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.MsgTypes()).To(Equal([]string{"interactive"}))
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.PendingApproval).NotTo(BeNil())
+		Expect(latest.Status.PendingApproval.Approved).To(BeNil())
+		Expect(latest.Status.History).To(HaveLen(1))
+		Expect(latest.Status.History[0].Approved).To(BeNil())
+	})
+})
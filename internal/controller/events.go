@@ -0,0 +1,81 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// Kubernetes Event 的 reason，下游工具（kube-state-metrics、Argo Events 等）按此触发
+const (
+	EventReasonAnalysisStarted     = "AnalysisStarted"
+	EventReasonNoActionNeeded      = "NoActionNeeded"
+	EventReasonRemediationProposed = "RemediationProposed"
+	EventReasonApproved            = "Approved"
+	EventReasonApplied             = "Applied" // 修复已合并到 GitOps 仓库，配置了 ArgoCD 时为已同步到集群
+	EventReasonFailed              = "Failed"
+	EventReasonLLMCallFailed       = "LLMCallFailed"
+	// 已弃用：AnalysisStarted、RemediationProposed 之前的 reason，仍随新 reason 一起记录，
+	// 按旧 reason 触发的下游工具迁移后移除
+	EventReasonAnalyzing = "Analyzing"
+	EventReasonProposed  = "Proposed"
+
+	// 大模型响应无法解析或校验不通过
+	EventReasonInvalidLLMResponse = "InvalidLLMResponse"
+	// 审批超时无人响应
+	EventReasonApprovalExpired = "ApprovalExpired"
+	// 严重程度和风险等级触发升级，未要求审批也需要人工审批
+	EventReasonApprovalEscalated = "ApprovalEscalated"
+
+	EventReasonPullRequestOpened = "PullRequestOpened"
+	EventReasonPullRequestMerged = "PullRequestMerged"
+	// 已批准的修复建议无法提交 PR，且重试也无法成功
+	EventReasonPullRequestFailed = "PullRequestFailed"
+	EventReasonCardFallback      = "CardFallback"
+	EventReasonDryRun            = "DryRun"
+	EventReasonActionNotAllowed  = "ActionNotAllowed"
+	EventReasonLowConfidence     = "LowConfidence"
+	EventReasonValueOutOfRange   = "ValueOutOfRange"
+	// 修改的 ConfigMap 未被目标工作负载引用
+	EventReasonConfigMapNotReferenced = "ConfigMapNotReferenced"
+	// 补丁按名称引用的容器在目标工作负载中不存在
+	EventReasonContainerNotFound = "ContainerNotFound"
+	// 合并的修复未能解决问题，已创建回滚 PR
+	EventReasonRolledBack = "RolledBack"
+	// 合并的修复已被 ArgoCD 同步
+	EventReasonSynced = "Synced"
+
+	EventReasonInvalidAnalysisInterval = "InvalidAnalysisInterval"
+)
+
+// legacyEventReasons 改名后的 reason 对应的旧 reason
+var legacyEventReasons = map[string]string{
+	EventReasonAnalysisStarted:     EventReasonAnalyzing,
+	EventReasonRemediationProposed: EventReasonProposed,
+}
+
+// recordEvent 以 AIOpsAnalyzer 为 involved object 记录事件，未注入 Recorder 时忽略
+// 改名的 reason 同时以旧 reason 再记录一次，兼容按旧 reason 触发的下游工具
+func (r *AIOpsAnalyzerReconciler) recordEvent(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(aiopsAnalyzer, eventType, reason, messageFmt, args...)
+	if legacy, ok := legacyEventReasons[reason]; ok {
+		r.Recorder.Eventf(aiopsAnalyzer, eventType, legacy, messageFmt, args...)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("BuildEventString", func() {
+	const (
+		alertsBody = `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"alertname":"HighCPU","namespace":"default","pod":"order-0"},"value":[1700000000,"1"]}]}}`
+		logsBody = `{"status":"success","data":{"resultType":"streams","result":[{"stream":{},"values":[["1","boom"]]}]}}`
+		delay    = 300 * time.Millisecond
+	)
+
+	aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+		ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+		Spec: autofixv1.AIOpsAnalyzerSpec{Target: autofixv1.TargetSelector{
+			Namespace: "default",
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+		}},
+	}
+
+	// source 返回固定响应的数据源，处理前等待 wait
+	source := func(wait time.Duration, status int, body string) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			time.Sleep(wait)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		DeferCleanup(server.Close)
+		return server.URL
+	}
+
+	It("should query the datasources concurrently", func() {
+		ds := datasources{
+			PrometheusURL: source(delay, http.StatusOK, alertsBody),
+			LokiURL:       source(delay, http.StatusOK, logsBody),
+		}
+
+		start := time.Now()
+		eventString, err := newFakeReconciler().BuildEventString(context.Background(), aiopsAnalyzer, ds, nil)
+		elapsed := time.Since(start)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventString).To(ContainSubstring("Alert: HighCPU"))
+		Expect(eventString).To(ContainSubstring("1: boom"))
+		Expect(eventString).To(HaveSuffix("\n=== Loki Error Signatures ===\nboom (x1)\n"))
+		Expect(elapsed).To(BeNumerically("<", 2*delay-50*time.Millisecond))
+	})
+
+	It("should keep the alerts when Loki fails", func() {
+		ds := datasources{
+			PrometheusURL: source(0, http.StatusOK, alertsBody),
+			LokiURL:       source(0, http.StatusBadGateway, "loki is down"),
+		}
+
+		eventString, err := newFakeReconciler().BuildEventString(context.Background(), aiopsAnalyzer, ds, nil)
+		Expect(err).To(MatchError(ContainSubstring("loki returned 502")))
+		Expect(eventString).To(ContainSubstring("Alert: HighCPU"))
+		Expect(eventString).To(ContainSubstring("=== Loki Error Logs ===\nUnavailable: loki returned 502: loki is down\n"))
+		Expect(eventString).NotTo(ContainSubstring("Loki Error Signatures"))
+	})
+
+	It("should fail when both Prometheus and Loki fail", func() {
+		ds := datasources{
+			PrometheusURL: source(0, http.StatusServiceUnavailable, "prometheus is restarting"),
+			LokiURL:       source(0, http.StatusBadGateway, "loki is down"),
+		}
+
+		eventString, err := newFakeReconciler().BuildEventString(context.Background(), aiopsAnalyzer, ds, nil)
+		Expect(err).To(MatchError(ContainSubstring("prometheus returned 503")))
+		Expect(err).To(MatchError(ContainSubstring("loki returned 502")))
+		Expect(eventString).To(BeEmpty())
+	})
+})
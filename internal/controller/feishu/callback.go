@@ -0,0 +1,134 @@
+package feishu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	"github.com/larksuite/oapi-sdk-go/v3/core/httpserverext"
+)
+
+// 审批卡片按钮 value 中约定的键和取值
+const (
+	ActionValueRequestID = "request_id"
+	ActionValueAction    = "action"
+	ActionValueReason    = "reason"
+
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+)
+
+// ApprovalDecision 审批人在卡片上做出的决定
+type ApprovalDecision struct {
+	RequestID string
+	Approved  bool
+	// Operator 点击按钮的用户（open_id）
+	Operator  string
+	Reason    string
+	MessageID string
+	DecidedAt time.Time
+	// Expired 审批超时无人响应，视为拒绝
+	Expired bool
+	// Merged 修复 PR 已合并，DecidedAt 为合并时间
+	Merged bool
+	// RolledBack 合并的修复未能解决问题，已创建回滚 PR，PRURL 为回滚 PR 的地址
+	RolledBack bool
+	PRURL      string
+}
+
+// DecisionFunc 把审批结果写回对应的 AIOpsAnalyzer
+type DecisionFunc func(ctx context.Context, decision ApprovalDecision) error
+
+// NewCardActionHandler 接收审批卡片的按钮回调
+// 使用 verificationToken 校验请求签名（配置了 encryptKey 时先解密），
+// 调用 decide 写回审批结果后返回更新后的卡片，替换掉原来的按钮
+func NewCardActionHandler(verificationToken, encryptKey string, decide DecisionFunc) http.HandlerFunc {
+	handler := larkcard.NewCardActionHandler(verificationToken, encryptKey,
+		func(ctx context.Context, action *larkcard.CardAction) (interface{}, error) {
+			decision, err := parseCardAction(action)
+			if err != nil {
+				return nil, err
+			}
+			if err := decide(ctx, decision); err != nil {
+				return nil, err
+			}
+			return DecisionCard(decision), nil
+		})
+	return httpserverext.NewCardActionHandlerFunc(handler)
+}
+
+// parseCardAction 从按钮 value 中读取 request_id 和 approve/reject
+func parseCardAction(action *larkcard.CardAction) (ApprovalDecision, error) {
+	if action.Action == nil {
+		return ApprovalDecision{}, errors.New("card callback has no action")
+	}
+	value := action.Action.Value
+	requestID, _ := value[ActionValueRequestID].(string)
+	if requestID == "" {
+		return ApprovalDecision{}, fmt.Errorf("card callback has no %s", ActionValueRequestID)
+	}
+
+	decision := ApprovalDecision{
+		RequestID: requestID,
+		Operator:  action.OpenID,
+		MessageID: action.OpenMessageID,
+		DecidedAt: time.Now(),
+	}
+	switch value[ActionValueAction] {
+	case ActionApprove:
+		decision.Approved = true
+	case ActionReject:
+	default:
+		return ApprovalDecision{}, fmt.Errorf("unknown card action %v", value[ActionValueAction])
+	}
+	if reason, ok := value[ActionValueReason].(string); ok {
+		decision.Reason = reason
+	} else if reason, ok := action.Action.FormValue[ActionValueReason].(string); ok {
+		decision.Reason = reason
+	}
+	return decision, nil
+}
+
+// DecisionCard 审批结束后的卡片：绿色（批准）、红色（拒绝）或灰色（超时）横幅，不再包含按钮
+func DecisionCard(decision ApprovalDecision) *larkcard.MessageCard {
+	template, title := larkcard.TemplateRed, "已拒绝"
+	switch {
+	case decision.RolledBack:
+		template, title = larkcard.TemplateOrange, "已回滚"
+	case decision.Merged:
+		template, title = larkcard.TemplateBlue, "已合并"
+	case decision.Expired:
+		template, title = larkcard.TemplateGrey, "已过期"
+	case decision.Approved:
+		template, title = larkcard.TemplateGreen, "已批准"
+	}
+
+	content := fmt.Sprintf("**请求 ID**：%s\n**审批人**：<at id=%s></at>\n**时间**：%s",
+		decision.RequestID, decision.Operator, decision.DecidedAt.Format(time.DateTime))
+	switch {
+	case decision.RolledBack:
+		content = fmt.Sprintf("**请求 ID**：%s\n**回滚 PR**：%s\n**时间**：%s",
+			decision.RequestID, decision.PRURL, decision.DecidedAt.Format(time.DateTime))
+	case decision.Merged:
+		content = fmt.Sprintf("**请求 ID**：%s\n**PR**：%s\n**合并时间**：%s",
+			decision.RequestID, decision.PRURL, decision.DecidedAt.Format(time.DateTime))
+	case decision.Expired:
+		content = fmt.Sprintf("**请求 ID**：%s\n**过期时间**：%s", decision.RequestID, decision.DecidedAt.Format(time.DateTime))
+	}
+	if decision.Reason != "" {
+		content += "\n**说明**：" + decision.Reason
+	}
+
+	return larkcard.NewMessageCard().
+		Config(larkcard.NewMessageCardConfig().WideScreenMode(true).UpdateMulti(true).Build()).
+		Header(larkcard.NewMessageCardHeader().
+			Template(template).
+			Title(larkcard.NewMessageCardPlainText().Content("修复建议" + title).Build()).
+			Build()).
+		Elements([]larkcard.MessageCardElement{
+			larkcard.NewMessageCardDiv().Text(larkcard.NewMessageCardLarkMd().Content(content).Build()).Build(),
+		})
+}
@@ -0,0 +1,135 @@
+package feishu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testVerificationToken = "v-token"
+
+// cardRequest 构造带签名的卡片回调请求
+func cardRequest(body, token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/feishu/card", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(larkevent.EventRequestTimestamp, "1700000000")
+	req.Header.Set(larkevent.EventRequestNonce, "nonce")
+	req.Header.Set(larkevent.EventSignature, larkcard.Signature("1700000000", "nonce", token, body))
+	return req
+}
+
+var _ = Describe("Card action callback", func() {
+	var decisions []ApprovalDecision
+	var decide DecisionFunc
+
+	BeforeEach(func() {
+		decisions = nil
+		decide = func(_ context.Context, decision ApprovalDecision) error {
+			decisions = append(decisions, decision)
+			return nil
+		}
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		NewCardActionHandler(testVerificationToken, "", decide).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("should record an approval and return the approved card", func() {
+		body := `{"open_id":"ou_1","open_message_id":"om_1","token":"t",` +
+			`"action":{"tag":"button","value":{"request_id":"demo-abc","action":"approve"}}}`
+
+		rec := serve(cardRequest(body, testVerificationToken))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(decisions).To(HaveLen(1))
+		Expect(decisions[0].RequestID).To(Equal("demo-abc"))
+		Expect(decisions[0].Approved).To(BeTrue())
+		Expect(decisions[0].Operator).To(Equal("ou_1"))
+		Expect(decisions[0].MessageID).To(Equal("om_1"))
+		Expect(rec.Body.String()).To(ContainSubstring("修复建议已批准"))
+		Expect(rec.Body.String()).To(ContainSubstring(`"template":"green"`))
+	})
+
+	It("should record a rejection with its reason", func() {
+		body := `{"open_id":"ou_2","action":{"tag":"button",` +
+			`"value":{"request_id":"demo-abc","action":"reject","reason":"业务高峰"}}}`
+
+		rec := serve(cardRequest(body, testVerificationToken))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(decisions).To(HaveLen(1))
+		Expect(decisions[0].Approved).To(BeFalse())
+		Expect(decisions[0].Reason).To(Equal("业务高峰"))
+		Expect(rec.Body.String()).To(ContainSubstring(`"template":"red"`))
+	})
+
+	It("should reject requests with a bad signature", func() {
+		body := `{"open_id":"ou_1","action":{"tag":"button","value":{"request_id":"demo-abc","action":"approve"}}}`
+
+		rec := serve(cardRequest(body, "other-token"))
+		Expect(rec.Code).NotTo(Equal(http.StatusOK))
+		Expect(decisions).To(BeEmpty())
+	})
+
+	It("should not decide on unknown actions or missing request IDs", func() {
+		for _, body := range []string{
+			`{"open_id":"ou_1","action":{"tag":"button","value":{"request_id":"demo-abc","action":"merge"}}}`,
+			`{"open_id":"ou_1","action":{"tag":"button","value":{"action":"approve"}}}`,
+		} {
+			rec := serve(cardRequest(body, testVerificationToken))
+			Expect(rec.Code).NotTo(Equal(http.StatusOK))
+		}
+		Expect(decisions).To(BeEmpty())
+	})
+
+	It("should fail the callback when the decision cannot be recorded", func() {
+		decide = func(context.Context, ApprovalDecision) error { return errors.New("conflict") }
+		body := `{"open_id":"ou_1","action":{"tag":"button","value":{"request_id":"demo-abc","action":"approve"}}}`
+
+		rec := serve(cardRequest(body, testVerificationToken))
+		Expect(rec.Code).NotTo(Equal(http.StatusOK))
+	})
+
+	It("should render an expired card without an operator", func() {
+		content, err := DecisionCard(ApprovalDecision{RequestID: "demo-abc", Expired: true}).String()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(ContainSubstring("修复建议已过期"))
+		Expect(content).To(ContainSubstring(`"template":"grey"`))
+		Expect(content).NotTo(ContainSubstring("审批人"))
+	})
+
+	It("should render a merged card with the PR link", func() {
+		content, err := DecisionCard(ApprovalDecision{
+			RequestID: "demo-abc",
+			Merged:    true,
+			PRURL:     "https://github.com/boqier/deploy/pull/8",
+			DecidedAt: time.Date(2025, 11, 26, 12, 45, 55, 0, time.Local),
+		}).String()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(ContainSubstring("修复建议已合并"))
+		Expect(content).To(ContainSubstring(`"template":"blue"`))
+		Expect(content).To(ContainSubstring("https://github.com/boqier/deploy/pull/8"))
+		Expect(content).To(ContainSubstring("2025-11-26 12:45:55"))
+		Expect(content).NotTo(ContainSubstring("审批人"))
+	})
+	It("should render a rolled back card with the rollback PR link", func() {
+		content, err := DecisionCard(ApprovalDecision{
+			RequestID:  "demo-abc",
+			Merged:     true,
+			RolledBack: true,
+			PRURL:      "https://github.com/boqier/deploy/pull/9",
+		}).String()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(ContainSubstring("修复建议已回滚"))
+		Expect(content).To(ContainSubstring(`"template":"orange"`))
+		Expect(content).To(ContainSubstring("回滚 PR"))
+	})
+})
@@ -0,0 +1,131 @@
+// Package feishutest 提供模拟飞书开放平台的 HTTP 服务，用于单元测试
+package feishutest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+)
+
+const messagesPath = "/open-apis/im/v1/messages"
+
+// Message 服务收到的一次发送消息请求
+type Message struct {
+	ReceiveIDType string
+	ReceiveID     string
+	MsgType       string
+	Content       string
+}
+
+// Server 模拟 tenant_access_token、发送、更新和回复消息接口，其他路径返回 404
+type Server struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// responses 按 msg_type 设置的发送消息响应体，未设置时返回成功和消息 ID om_<msg_type>
+	responses map[string]string
+	// patchResponse 更新消息的响应体，未设置时返回成功
+	patchResponse string
+	messages      []Message
+	requests      []string
+}
+
+// NewServer 启动模拟服务，调用方负责 Close
+func NewServer() *Server {
+	s := &Server{responses: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// LarkClient 返回请求该服务的飞书客户端，不缓存 token
+func (s *Server) LarkClient() *lark.Client {
+	return lark.NewClient("cli_test", "secret", lark.WithOpenBaseUrl(s.URL), lark.WithEnableTokenCache(false))
+}
+
+// RespondTo 设置发送 msgType 类型消息时返回的响应体
+func (s *Server) RespondTo(msgType, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[msgType] = body
+}
+
+// RespondToPatch 设置更新消息时返回的响应体
+func (s *Server) RespondToPatch(body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patchResponse = body
+}
+
+// Requests 返回按顺序收到的请求，格式为 "<method> <path>"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Messages 返回按顺序收到的发送消息请求
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// MsgTypes 返回收到的消息类型，便于断言是否发生了降级
+func (s *Server) MsgTypes() []string {
+	var msgTypes []string
+	for _, msg := range s.Messages() {
+		msgTypes = append(msgTypes, msg.MsgType)
+	}
+	return msgTypes
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	request := r.Method + " " + r.URL.Path
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	patchResponse := s.patchResponse
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case request == "POST /open-apis/auth/v3/tenant_access_token/internal":
+		_, _ = w.Write([]byte(`{"code":0,"tenant_access_token":"t-1","expire":7200}`))
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, messagesPath+"/"):
+		if patchResponse == "" {
+			patchResponse = `{"code":0,"msg":"success"}`
+		}
+		_, _ = w.Write([]byte(patchResponse))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, messagesPath+"/") && strings.HasSuffix(r.URL.Path, "/reply"):
+		_, _ = w.Write([]byte(`{"code":0,"msg":"success","data":{"message_id":"om_reply"}}`))
+	case request == "POST "+messagesPath:
+		var body struct {
+			ReceiveID string `json:"receive_id"`
+			MsgType   string `json:"msg_type"`
+			Content   string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, Message{
+			ReceiveIDType: r.URL.Query().Get("receive_id_type"),
+			ReceiveID:     body.ReceiveID,
+			MsgType:       body.MsgType,
+			Content:       body.Content,
+		})
+		response, ok := s.responses[body.MsgType]
+		s.mu.Unlock()
+		if !ok {
+			response = fmt.Sprintf(`{"code":0,"msg":"success","data":{"message_id":"om_%s"}}`, body.MsgType)
+		}
+		_, _ = w.Write([]byte(response))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
@@ -3,7 +3,11 @@ package feishu
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
 
 	lark "github.com/larksuite/oapi-sdk-go/v3"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
@@ -18,13 +22,163 @@ type PatchOp struct {
 
 // 方便后续不同的卡片模板变量
 type CardVariables struct {
-	Reason          string    `json:"reason"`
-	Patch           string    `json:"patch"`
-	Patches         []PatchOp `json:"patches"`
-	ResolveFunction string    `json:"resolve_fuction"`
-	Namespace       string    `json:"namespace"`
-	Name            string    `json:"name"`
-	RequestID       string    `json:"request_id"`
+	Reason  string    `json:"reason"`
+	Patch   string    `json:"patch"`
+	Patches []PatchOp `json:"patches"`
+	// PatchDiff 按目标资源逐行展示补丁和当前值
+	PatchDiff         string `json:"patch_diff"`
+	ResolveFunction   string `json:"resolve_function"`
+	Namespace         string `json:"namespace"`
+	Name              string `json:"name"`
+	RequestID         string `json:"request_id"`
+	RiskLevel         string `json:"risk_level"`
+	Severity          string `json:"severity"`
+	SuggestedDuration string `json:"suggested_duration"`
+	// Confidence 大模型给出的置信度，例如 85%
+	Confidence string `json:"confidence"`
+	// Mentions 需要 @ 的审批人（open_id、user_id 或邮箱），模板变量 mentions 为渲染后的 <at> 标签
+	Mentions []string `json:"-"`
+}
+
+// resolveFunctionAlias 已发布的卡片模板使用的拼写错误的变量名，模板更新前同时下发
+const resolveFunctionAlias = "resolve_fuction"
+
+// ToMap 按 json tag 把结构体转成模板变量
+func (v *CardVariables) ToMap() map[string]any {
+	if v == nil {
+		return map[string]any{}
+	}
+	return map[string]any{
+		"reason":             v.Reason,
+		"patch":              v.Patch,
+		"patches":            v.Patches,
+		"patch_diff":         v.PatchDiff,
+		"resolve_function":   v.ResolveFunction,
+		resolveFunctionAlias: v.ResolveFunction,
+		"namespace":          v.Namespace,
+		"name":               v.Name,
+		"request_id":         v.RequestID,
+		"risk_level":         v.RiskLevel,
+		"severity":           v.Severity,
+		"suggested_duration": v.SuggestedDuration,
+		"confidence":         v.Confidence,
+		"mentions":           MentionTags(v.Mentions),
+	}
+}
+
+// MentionTags 把审批人渲染为卡片 markdown 中的 <at> 标签，邮箱使用 email 属性
+func MentionTags(ids []string) string {
+	tags := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if strings.Contains(id, "@") {
+			tags = append(tags, fmt.Sprintf("<at email=%s></at>", id))
+		} else {
+			tags = append(tags, fmt.Sprintf("<at id=%s></at>", id))
+		}
+	}
+	return strings.Join(tags, " ")
+}
+
+// postElement 富文本消息段落中的元素，tag 为 text 或 at
+type postElement struct {
+	Tag    string `json:"tag"`
+	Text   string `json:"text,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// postText 返回只有一段文本的段落
+func postText(text string) []postElement {
+	return []postElement{{Tag: "text", Text: text}}
+}
+
+// summaryLines 兜底通知的正文，每行一段，富文本和纯文本消息共用
+func (v *CardVariables) summaryLines() []string {
+	lines := []string{
+		"审批卡片发送失败，本消息无法审批。请检查卡片模板配置，下次分析时会重新发送审批卡片",
+		fmt.Sprintf("目标：%s/%s", v.Namespace, v.Name),
+		fmt.Sprintf("原因：%s", v.Reason),
+	}
+	if v.ResolveFunction != "" {
+		lines = append(lines, fmt.Sprintf("修复方案：%s", v.ResolveFunction))
+	}
+	risk := "风险等级：" + v.RiskLevel
+	if v.Severity != "" {
+		risk += "，严重程度：" + v.Severity
+	}
+	if v.Confidence != "" {
+		risk += "，置信度：" + v.Confidence
+	}
+	lines = append(lines, risk)
+	if v.PatchDiff != "" {
+		lines = append(lines, "补丁：")
+		lines = append(lines, strings.Split(strings.TrimRight(v.PatchDiff, "\n"), "\n")...)
+	}
+	return append(lines, fmt.Sprintf("请求 ID：%s", v.RequestID))
+}
+
+// splitMentions 把审批人分为可以按 ID @ 的用户和只能以纯文本列出的邮箱
+func (v *CardVariables) splitMentions() (ids, emails []string) {
+	for _, id := range v.Mentions {
+		if strings.Contains(id, "@") {
+			emails = append(emails, id)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+	return ids, emails
+}
+
+// Post 把卡片变量渲染为富文本（post）消息的 content，卡片发送失败时作为兜底通知
+// 兜底通知没有审批按钮，提示审批人修复模板，修复建议会在下次分析时重新发出审批卡片
+func (v *CardVariables) Post() (string, error) {
+	var paragraphs [][]postElement
+	// 富文本消息只支持按 ID @ 用户，邮箱审批人以纯文本列出
+	ids, emails := v.splitMentions()
+	var mentions []postElement
+	for _, id := range ids {
+		mentions = append(mentions, postElement{Tag: "at", UserID: id})
+	}
+	if len(emails) > 0 {
+		text := "审批人：" + strings.Join(emails, ", ")
+		if len(mentions) > 0 {
+			text = " " + text
+		}
+		mentions = append(mentions, postElement{Tag: "text", Text: text})
+	}
+	if len(mentions) > 0 {
+		paragraphs = append(paragraphs, mentions)
+	}
+	for _, line := range v.summaryLines() {
+		paragraphs = append(paragraphs, postText(line))
+	}
+
+	content, err := json.Marshal(map[string]any{
+		"zh_cn": map[string]any{
+			"title":   "【AIOps 修复建议】",
+			"content": paragraphs,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal post content failed: %w", err)
+	}
+	return string(content), nil
+}
+
+// Text 把卡片变量渲染为纯文本，富文本消息也发送失败时作为最后的兜底通知
+func (v *CardVariables) Text() string {
+	lines := []string{"【AIOps 修复建议】"}
+	ids, emails := v.splitMentions()
+	var mentions []string
+	for _, id := range ids {
+		mentions = append(mentions, fmt.Sprintf(`<at user_id="%s"></at>`, id))
+	}
+	if len(emails) > 0 {
+		mentions = append(mentions, "审批人："+strings.Join(emails, ", "))
+	}
+	if len(mentions) > 0 {
+		lines = append(lines, strings.Join(mentions, " "))
+	}
+	return strings.Join(append(lines, v.summaryLines()...), "\n")
 }
 
 type CardMessage struct {
@@ -32,33 +186,103 @@ type CardMessage struct {
 	ReceiveType string // "chat_id"、"open_id"、"user_id" 等  ← 重点！
 	TemplateID  string
 	Version     string
-	Variables   *CardVariables
+	Variables   map[string]any // 模板变量，key 与卡片模板中的变量名一致
+}
+
+// 飞书发送消息支持的 receive_id_type
+const (
+	ReceiveIDTypeOpenID  = "open_id"
+	ReceiveIDTypeUserID  = "user_id"
+	ReceiveIDTypeUnionID = "union_id"
+	ReceiveIDTypeChatID  = "chat_id"
+	ReceiveIDTypeEmail   = "email"
+)
+
+// receiveIDPrefixes open_id、union_id、chat_id 的固定前缀，user_id 由企业自定义没有固定格式
+var receiveIDPrefixes = map[string]string{
+	ReceiveIDTypeOpenID:  "ou_",
+	ReceiveIDTypeUserID:  "",
+	ReceiveIDTypeUnionID: "on_",
+	ReceiveIDTypeChatID:  "oc_",
+	ReceiveIDTypeEmail:   "",
+}
+
+// receiveIDSuffix 固定前缀之后的部分只包含字母和数字
+var receiveIDSuffix = regexp.MustCompile(`^[0-9a-zA-Z]+$`)
+
+// ValidateReceiver 检查 receiveType 是否是飞书支持的类型，以及 receiveID 的格式是否与类型相符
+func ValidateReceiver(receiveID, receiveType string) error {
+	prefix, ok := receiveIDPrefixes[receiveType]
+	if !ok {
+		return fmt.Errorf("unsupported receive id type %q, must be one of open_id, user_id, union_id, chat_id, email", receiveType)
+	}
+	if receiveID == "" {
+		return fmt.Errorf("receive id for %s is empty", receiveType)
+	}
+	if rest, ok := strings.CutPrefix(receiveID, prefix); !ok || prefix != "" && !receiveIDSuffix.MatchString(rest) {
+		return fmt.Errorf("receive id %q is not a %s, expected prefix %q followed by letters and digits", receiveID, receiveType, prefix)
+	}
+	if receiveType == ReceiveIDTypeEmail {
+		if addr, err := mail.ParseAddress(receiveID); err != nil || addr.Address != receiveID {
+			return fmt.Errorf("receive id %q is not an email address", receiveID)
+		}
+	}
+	return nil
+}
+
+// 推荐构造函数（一个就够全项目用），receiveType 或 receiveID 无效时返回错误
+func NewCardMessage(receiveID, receiveType, templateID, version string, vars *CardVariables) (*CardMessage, error) {
+	return NewCardMessageWithVariables(receiveID, receiveType, templateID, version, vars.ToMap())
 }
 
-// 推荐构造函数（一个就够全项目用）
-func NewCardMessage(receiveID, receiveType, templateID, version string, vars *CardVariables) *CardMessage {
+// NewCardMessageWithVariables 使用任意模板变量构造卡片，适合字段不固定的模板
+func NewCardMessageWithVariables(receiveID, receiveType, templateID, version string, vars map[string]any) (*CardMessage, error) {
+	if err := ValidateReceiver(receiveID, receiveType); err != nil {
+		return nil, err
+	}
+	if vars == nil {
+		vars = map[string]any{}
+	}
 	return &CardMessage{
 		ReceiveID:   receiveID,
 		ReceiveType: receiveType,
 		TemplateID:  templateID,
 		Version:     version,
 		Variables:   vars,
+	}, nil
+}
+
+// SetVariable 设置额外的模板变量（如 PR 链接、影响范围），返回自身便于链式调用
+func (m *CardMessage) SetVariable(key string, value any) *CardMessage {
+	if m.Variables == nil {
+		m.Variables = map[string]any{}
 	}
+	m.Variables[key] = value
+	return m
 }
 
-// 最终正确的发送函数
-func SendTemplateCard(ctx context.Context, client *lark.Client, msg *CardMessage) error {
-	// 1. 正确生成 content（Variables 是结构体，json tag 自动生效）
+// Content 返回发送给飞书的卡片 content（Variables 是 map，key 即模板变量名）
+func (m *CardMessage) Content() (string, error) {
 	content, err := json.Marshal(map[string]any{
 		"type": "template",
 		"data": map[string]any{
-			"template_id":           msg.TemplateID,
-			"template_version_name": msg.Version,
-			"template_variable":     msg.Variables, // 直接传结构体！不需要 map
+			"template_id":           m.TemplateID,
+			"template_version_name": m.Version,
+			"template_variable":     m.Variables,
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("marshal card content failed: %w", err)
+		return "", fmt.Errorf("marshal card content failed: %w", err)
+	}
+	return string(content), nil
+}
+
+// SendTemplateCard 发送模板卡片，返回消息 ID（用于之后更新卡片状态）
+func SendTemplateCard(ctx context.Context, client *lark.Client, msg *CardMessage) (string, error) {
+	// 1. 生成 content
+	content, err := msg.Content()
+	if err != nil {
+		return "", err
 	}
 
 	// 2. 正确使用 msg 里的字段
@@ -67,18 +291,86 @@ func SendTemplateCard(ctx context.Context, client *lark.Client, msg *CardMessage
 		Body(larkim.NewCreateMessageReqBodyBuilder().
 			ReceiveId(msg.ReceiveID). // 正确：ID 从结构体取
 			MsgType("interactive").
-			Content(string(content)).
+			Content(content).
 			Build()).
 		Build()
 
 	// 3. 新版 SDK 正确的调用方式（v3.0+）
 	resp, err := client.Im.V1.Message.Create(ctx, req)
 	if err != nil {
-		return fmt.Errorf("send card message failed: %w", err)
+		return "", fmt.Errorf("send card message failed: %w", err)
 	}
 	if !resp.Success() {
-		return fmt.Errorf("send card failed: code=%d, msg=%s, request_id=%s", resp.Code, resp.Msg, resp.RequestId())
+		return "", &SendError{Kind: "card", Code: resp.Code, Msg: resp.Msg, RequestID: resp.RequestId()}
 	}
 
-	return nil
+	// 4. 返回消息 ID，用于之后更新卡片
+	if resp.Data == nil || resp.Data.MessageId == nil {
+		return "", nil
+	}
+	return *resp.Data.MessageId, nil
+}
+
+// SendError 飞书接口返回的业务错误
+type SendError struct {
+	Kind      string // card / post / text
+	Code      int
+	Msg       string
+	RequestID string
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("send %s failed: code=%d, msg=%s, request_id=%s", e.Kind, e.Code, e.Msg, e.RequestID)
+}
+
+// templateErrorCodes 卡片模板相关的错误码：模板不存在、版本未发布或变量与模板不匹配时返回
+var templateErrorCodes = map[int]bool{
+	230099: true,
+	11310:  true,
+}
+
+// IsTemplateError 判断发送卡片失败是否由模板引起，此时改发富文本仍能送达
+func IsTemplateError(err error) bool {
+	var sendErr *SendError
+	return errors.As(err, &sendErr) && templateErrorCodes[sendErr.Code]
+}
+
+// SendPostMessage 发送富文本消息，用于卡片无法发送时的兜底通知，content 由 CardVariables.Post 生成，返回消息 ID
+func SendPostMessage(ctx context.Context, client *lark.Client, receiveID, receiveType, content string) (string, error) {
+	return sendMessage(ctx, client, receiveID, receiveType, "post", content)
+}
+
+// SendTextMessage 发送纯文本消息，富文本消息也无法发送时作为最后的兜底通知，返回消息 ID
+func SendTextMessage(ctx context.Context, client *lark.Client, receiveID, receiveType, text string) (string, error) {
+	content, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return "", fmt.Errorf("marshal text content failed: %w", err)
+	}
+	return sendMessage(ctx, client, receiveID, receiveType, "text", string(content))
+}
+
+// sendMessage 按 msgType 发送 content 已经序列化好的消息
+func sendMessage(ctx context.Context, client *lark.Client, receiveID, receiveType, msgType, content string) (string, error) {
+	if err := ValidateReceiver(receiveID, receiveType); err != nil {
+		return "", err
+	}
+
+	resp, err := client.Im.V1.Message.Create(ctx, larkim.NewCreateMessageReqBuilder().
+		ReceiveIdType(receiveType).
+		Body(larkim.NewCreateMessageReqBodyBuilder().
+			ReceiveId(receiveID).
+			MsgType(msgType).
+			Content(content).
+			Build()).
+		Build())
+	if err != nil {
+		return "", fmt.Errorf("send %s message failed: %w", msgType, err)
+	}
+	if !resp.Success() {
+		return "", &SendError{Kind: msgType, Code: resp.Code, Msg: resp.Msg, RequestID: resp.RequestId()}
+	}
+	if resp.Data == nil || resp.Data.MessageId == nil {
+		return "", nil
+	}
+	return *resp.Data.MessageId, nil
 }
@@ -0,0 +1,279 @@
+package feishu
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu/feishutest"
+)
+
+var _ = Describe("CardVariables", func() {
+	vars := &CardVariables{
+		Reason:            "CPU 打满",
+		Patches:           []PatchOp{{Op: "replace", Path: "/spec/replicas", Value: 4}},
+		PatchDiff:         "~ /spec/replicas: 4",
+		ResolveFunction:   "扩容到 4 个副本",
+		Namespace:         "shop",
+		Name:              "app=order",
+		RequestID:         "demo-abc",
+		RiskLevel:         "low",
+		Severity:          "high",
+		SuggestedDuration: "30m",
+		Confidence:        "85%",
+	}
+
+	It("should marshal with the template variable names", func() {
+		raw, err := json.Marshal(vars)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded map[string]any
+		Expect(json.Unmarshal(raw, &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("resolve_function", "扩容到 4 个副本"))
+		Expect(decoded).To(HaveKeyWithValue("risk_level", "low"))
+		Expect(decoded).To(HaveKeyWithValue("severity", "high"))
+		Expect(decoded).To(HaveKeyWithValue("suggested_duration", "30m"))
+		Expect(decoded).To(HaveKeyWithValue("patch_diff", "~ /spec/replicas: 4"))
+		Expect(decoded).To(HaveKeyWithValue("namespace", "shop"))
+		Expect(decoded).To(HaveKeyWithValue("confidence", "85%"))
+		Expect(decoded).NotTo(HaveKey(resolveFunctionAlias))
+
+		// ToMap 与 json tag 保持一致，另外保留旧模板使用的变量名
+		vars := vars.ToMap()
+		for key := range decoded {
+			Expect(vars).To(HaveKey(key))
+		}
+		Expect(vars).To(HaveKeyWithValue(resolveFunctionAlias, "扩容到 4 个副本"))
+	})
+
+	It("should convert every field to a template variable", func() {
+		Expect(vars.ToMap()).To(Equal(map[string]any{
+			"reason":             "CPU 打满",
+			"patch":              "",
+			"patches":            []PatchOp{{Op: "replace", Path: "/spec/replicas", Value: 4}},
+			"patch_diff":         "~ /spec/replicas: 4",
+			"resolve_function":   "扩容到 4 个副本",
+			resolveFunctionAlias: "扩容到 4 个副本",
+			"namespace":          "shop",
+			"name":               "app=order",
+			"request_id":         "demo-abc",
+			"risk_level":         "low",
+			"severity":           "high",
+			"suggested_duration": "30m",
+			"confidence":         "85%",
+			"mentions":           "",
+		}))
+		var empty *CardVariables
+		Expect(empty.ToMap()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("CardMessage", func() {
+	It("should serialize the variables and the extra ones set on the message", func() {
+		msg, err := NewCardMessage("ou_1", "open_id", "tpl", "1.0.0", &CardVariables{
+			Reason:  "CPU 打满",
+			Patches: []PatchOp{{Op: "replace", Path: "/spec/replicas", Value: 4}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		msg.SetVariable("pr_url", "https://github.com/boqier/deploy/pull/8").
+			SetVariable("reason", "CPU 打满，已扩容")
+
+		content, err := msg.Content()
+		Expect(err).NotTo(HaveOccurred())
+		var decoded struct {
+			Type string `json:"type"`
+			Data struct {
+				TemplateID       string         `json:"template_id"`
+				TemplateVersion  string         `json:"template_version_name"`
+				TemplateVariable map[string]any `json:"template_variable"`
+			} `json:"data"`
+		}
+		Expect(json.Unmarshal([]byte(content), &decoded)).To(Succeed())
+		Expect(decoded.Type).To(Equal("template"))
+		Expect(decoded.Data.TemplateID).To(Equal("tpl"))
+		Expect(decoded.Data.TemplateVersion).To(Equal("1.0.0"))
+		Expect(decoded.Data.TemplateVariable).To(HaveKeyWithValue("pr_url", "https://github.com/boqier/deploy/pull/8"))
+		Expect(decoded.Data.TemplateVariable).To(HaveKeyWithValue("reason", "CPU 打满，已扩容"))
+		Expect(decoded.Data.TemplateVariable).To(HaveKeyWithValue("patches", []any{
+			map[string]any{"op": "replace", "path": "/spec/replicas", "value": float64(4)},
+		}))
+	})
+
+	It("should set variables on a message without any", func() {
+		msg := (&CardMessage{}).SetVariable("pr_url", "https://github.com/boqier/deploy/pull/8")
+		Expect(msg.Variables).To(Equal(map[string]any{"pr_url": "https://github.com/boqier/deploy/pull/8"}))
+	})
+})
+
+var _ = Describe("Mentions", func() {
+	It("should render open_id, user_id and email mentions", func() {
+		Expect(MentionTags(nil)).To(BeEmpty())
+		Expect(MentionTags([]string{"ou_1", "4d7a3c6g", "oncall@example.com"})).To(
+			Equal("<at id=ou_1></at> <at id=4d7a3c6g></at> <at email=oncall@example.com></at>"))
+	})
+
+	It("should pass the mentions to the template and the rich-text fallback", func() {
+		vars := &CardVariables{RequestID: "demo-abc", Mentions: []string{"ou_1", "oncall@example.com"}}
+		Expect(vars.ToMap()).To(HaveKeyWithValue("mentions", "<at id=ou_1></at> <at email=oncall@example.com></at>"))
+		content, err := vars.Post()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(postParagraphs(content)[0]).To(Equal([]postElement{
+			{Tag: "at", UserID: "ou_1"},
+			{Tag: "text", Text: " 审批人：oncall@example.com"},
+		}))
+
+		// 只有邮箱审批人时同样列出
+		vars.Mentions = []string{"oncall@example.com", "sre@example.com"}
+		content, err = vars.Post()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(postParagraphs(content)[0]).To(Equal([]postElement{{Tag: "text", Text: "审批人：oncall@example.com, sre@example.com"}}))
+	})
+})
+
+var _ = Describe("NewCardMessage", func() {
+	DescribeTable("validating the receiver",
+		func(receiveID, receiveType, expectErr string) {
+			msg, err := NewCardMessage(receiveID, receiveType, "tpl", "1.0.0", &CardVariables{Reason: "CPU 打满"})
+			if expectErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(expectErr)))
+				Expect(msg).To(BeNil())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg.ReceiveID).To(Equal(receiveID))
+			Expect(msg.ReceiveType).To(Equal(receiveType))
+			Expect(msg.Variables).To(HaveKeyWithValue("reason", "CPU 打满"))
+		},
+		Entry("open_id", "ou_7d8a6e6df7621556ce0d21922b676706", ReceiveIDTypeOpenID, ""),
+		Entry("user_id", "4d7a3c6g", ReceiveIDTypeUserID, ""),
+		Entry("union_id", "on_94a1ee5551019f18cd73d9f111898cf2", ReceiveIDTypeUnionID, ""),
+		Entry("chat_id", "oc_a0553eda9014c201e6969b478895c230", ReceiveIDTypeChatID, ""),
+		Entry("email", "oncall@example.com", ReceiveIDTypeEmail, ""),
+		Entry("unknown type", "oc_a0553eda9014c201e6969b478895c230", "chatid", `unsupported receive id type "chatid"`),
+		Entry("empty id", "", ReceiveIDTypeUserID, "receive id for user_id is empty"),
+		Entry("open_id without prefix", "oc_a0553eda9014c201e6969b478895c230", ReceiveIDTypeOpenID, `expected prefix "ou_"`),
+		Entry("union_id without prefix", "ou_7d8a6e6df7621556ce0d21922b676706", ReceiveIDTypeUnionID, `expected prefix "on_"`),
+		Entry("chat_id without prefix", "ou_7d8a6e6df7621556ce0d21922b676706", ReceiveIDTypeChatID, `expected prefix "oc_"`),
+		Entry("email without @", "oncall", ReceiveIDTypeEmail, "not an email address"),
+		Entry("open_id with other characters", "ou_7d8a-6e6d", ReceiveIDTypeOpenID, `expected prefix "ou_" followed by letters and digits`),
+		Entry("email with a display name", "On Call <oncall@example.com>", ReceiveIDTypeEmail, "not an email address"),
+		Entry("removed user_open_id type", "ou_7d8a6e6df7621556ce0d21922b676706", "user_open_id", `unsupported receive id type "user_open_id"`),
+	)
+})
+
+// postParagraphs 解析富文本消息 content 中的段落
+func postParagraphs(content string) [][]postElement {
+	var post struct {
+		ZhCN struct {
+			Title   string          `json:"title"`
+			Content [][]postElement `json:"content"`
+		} `json:"zh_cn"`
+	}
+	Expect(json.Unmarshal([]byte(content), &post)).To(Succeed())
+	Expect(post.ZhCN.Title).To(Equal("【AIOps 修复建议】"))
+	return post.ZhCN.Content
+}
+
+var _ = Describe("Sending messages", func() {
+	const chatID = "oc_a0553eda9014c201e6969b478895c230"
+
+	var server *feishutest.Server
+
+	BeforeEach(func() {
+		server = feishutest.NewServer()
+		DeferCleanup(server.Close)
+	})
+
+	It("should report template errors from SendTemplateCard", func() {
+		msg, err := NewCardMessage(chatID, ReceiveIDTypeChatID, "tpl", "1.0.0", &CardVariables{})
+		Expect(err).NotTo(HaveOccurred())
+
+		server.RespondTo("interactive", `{"code":230099,"msg":"Failed to create card content, ext=ErrCode: 11310; ErrMsg: template not exist"}`)
+		_, err = SendTemplateCard(context.Background(), server.LarkClient(), msg)
+		Expect(err).To(MatchError(ContainSubstring("send card failed: code=230099")))
+		Expect(IsTemplateError(err)).To(BeTrue())
+
+		server.RespondTo("interactive", `{"code":99991663,"msg":"tenant access token invalid"}`)
+		_, err = SendTemplateCard(context.Background(), server.LarkClient(), msg)
+		Expect(err).To(HaveOccurred())
+		Expect(IsTemplateError(err)).To(BeFalse())
+	})
+
+	It("should send a rich-text message", func() {
+		messageID, err := SendPostMessage(context.Background(), server.LarkClient(), chatID, ReceiveIDTypeChatID, `{"zh_cn":{}}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messageID).To(Equal("om_post"))
+		Expect(server.Messages()).To(Equal([]feishutest.Message{
+			{ReceiveIDType: ReceiveIDTypeChatID, ReceiveID: chatID, MsgType: "post", Content: `{"zh_cn":{}}`},
+		}))
+
+		_, err = SendPostMessage(context.Background(), server.LarkClient(), chatID, "chatid", `{"zh_cn":{}}`)
+		Expect(err).To(MatchError(ContainSubstring("unsupported receive id type")))
+	})
+
+	It("should send a plain-text message", func() {
+		messageID, err := SendTextMessage(context.Background(), server.LarkClient(), chatID, ReceiveIDTypeChatID, "CPU \"打满\"")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messageID).To(Equal("om_text"))
+		Expect(server.Messages()).To(Equal([]feishutest.Message{
+			{ReceiveIDType: ReceiveIDTypeChatID, ReceiveID: chatID, MsgType: "text", Content: `{"text":"CPU \"打满\""}`},
+		}))
+
+		server.RespondTo("text", `{"code":230001,"msg":"invalid receive_id"}`)
+		_, err = SendTextMessage(context.Background(), server.LarkClient(), chatID, ReceiveIDTypeChatID, "CPU 打满")
+		Expect(err).To(MatchError(ContainSubstring("send text failed: code=230001")))
+	})
+
+	It("should summarize the card variables as plain text", func() {
+		Expect((&CardVariables{
+			Reason:     "CPU 打满",
+			Namespace:  "shop",
+			Name:       "app=order",
+			RequestID:  "demo-abc",
+			RiskLevel:  "high",
+			Severity:   "critical",
+			Mentions:   []string{"ou_abc", "ops@example.com"},
+			Confidence: "85%",
+		}).Text()).To(Equal(strings.Join([]string{
+			"【AIOps 修复建议】",
+			`<at user_id="ou_abc"></at> 审批人：ops@example.com`,
+			"审批卡片发送失败，本消息无法审批。请检查卡片模板配置，下次分析时会重新发送审批卡片",
+			"目标：shop/app=order",
+			"原因：CPU 打满",
+			"风险等级：high，严重程度：critical，置信度：85%",
+			"请求 ID：demo-abc",
+		}, "\n")))
+	})
+
+	It("should summarize the card variables as rich text", func() {
+		content, err := (&CardVariables{
+			Reason:          "CPU 打满",
+			PatchDiff:       "~ /spec/replicas: 2 -> 4\n~ /spec/template/spec/containers/0/resources/limits/cpu: 1 -> 2\n",
+			ResolveFunction: "扩容到 4 个副本",
+			Namespace:       "shop",
+			Name:            "app=order",
+			RequestID:       "demo-abc",
+			RiskLevel:       "low",
+			Confidence:      "85%",
+		}).Post()
+		Expect(err).NotTo(HaveOccurred())
+		var lines []string
+		for _, paragraph := range postParagraphs(content) {
+			Expect(paragraph).To(HaveLen(1))
+			lines = append(lines, paragraph[0].Text)
+		}
+		Expect(lines).To(Equal([]string{
+			"审批卡片发送失败，本消息无法审批。请检查卡片模板配置，下次分析时会重新发送审批卡片",
+			"目标：shop/app=order",
+			"原因：CPU 打满",
+			"修复方案：扩容到 4 个副本",
+			"风险等级：low，置信度：85%",
+			"补丁：",
+			"~ /spec/replicas: 2 -> 4",
+			"~ /spec/template/spec/containers/0/resources/limits/cpu: 1 -> 2",
+			"请求 ID：demo-abc",
+		}))
+	})
+})
@@ -0,0 +1,45 @@
+package feishu
+
+import (
+	"context"
+	"fmt"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+// UpdateCardStatus 把审批卡片替换为审批结果，去掉按钮避免重复点击
+// 消息超过可编辑期限（或已无法更新）时改为回复一条结果卡片
+func UpdateCardStatus(ctx context.Context, client *lark.Client, messageID string, decision ApprovalDecision) error {
+	if messageID == "" {
+		return fmt.Errorf("update card status: empty message id")
+	}
+	content, err := DecisionCard(decision).String()
+	if err != nil {
+		return fmt.Errorf("marshal decision card failed: %w", err)
+	}
+
+	patchResp, err := client.Im.V1.Message.Patch(ctx, larkim.NewPatchMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewPatchMessageReqBodyBuilder().Content(content).Build()).
+		Build())
+	if err != nil {
+		return fmt.Errorf("patch card message failed: %w", err)
+	}
+	if patchResp.Success() {
+		return nil
+	}
+
+	replyResp, err := client.Im.V1.Message.Reply(ctx, larkim.NewReplyMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewReplyMessageReqBodyBuilder().MsgType("interactive").Content(content).Build()).
+		Build())
+	if err != nil {
+		return fmt.Errorf("reply card message failed: %w", err)
+	}
+	if !replyResp.Success() {
+		return fmt.Errorf("update card failed: patch code=%d, msg=%s; reply code=%d, msg=%s, request_id=%s",
+			patchResp.Code, patchResp.Msg, replyResp.Code, replyResp.Msg, replyResp.RequestId())
+	}
+	return nil
+}
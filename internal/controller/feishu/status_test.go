@@ -0,0 +1,38 @@
+package feishu
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu/feishutest"
+)
+
+var _ = Describe("UpdateCardStatus", func() {
+	var server *feishutest.Server
+
+	BeforeEach(func() {
+		server = feishutest.NewServer()
+		DeferCleanup(server.Close)
+	})
+
+	decision := ApprovalDecision{RequestID: "demo-abc", Approved: true, Operator: "ou_1", DecidedAt: time.Now()}
+
+	It("should patch the original card", func() {
+		Expect(UpdateCardStatus(context.Background(), server.LarkClient(), "om_1", decision)).To(Succeed())
+		Expect(server.Requests()).To(ContainElement("PATCH /open-apis/im/v1/messages/om_1"))
+		Expect(server.Requests()).NotTo(ContainElement("POST /open-apis/im/v1/messages/om_1/reply"))
+	})
+
+	It("should reply when the card can no longer be edited", func() {
+		server.RespondToPatch(`{"code":230031,"msg":"message can not be updated"}`)
+		Expect(UpdateCardStatus(context.Background(), server.LarkClient(), "om_1", decision)).To(Succeed())
+		Expect(server.Requests()).To(ContainElement("POST /open-apis/im/v1/messages/om_1/reply"))
+	})
+
+	It("should require a message ID", func() {
+		Expect(UpdateCardStatus(context.Background(), server.LarkClient(), "", decision)).To(MatchError(ContainSubstring("empty message id")))
+	})
+})
@@ -0,0 +1,14 @@
+package feishu
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFeishu(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Feishu Suite")
+}
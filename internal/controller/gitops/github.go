@@ -0,0 +1,192 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitHubProvider 通过 GitHub REST API 操作 PR
+type GitHubProvider struct {
+	// API 地址，github.com 为 https://api.github.com，Enterprise 为 https://<host>/api/v3
+	BaseURL string
+	Repo    *Repo
+	Token   string
+
+	HTTPClient *http.Client
+}
+
+// NewGitHubProvider 创建 GitHub provider
+func NewGitHubProvider(repo *Repo, token string, httpClient *http.Client) *GitHubProvider {
+	baseURL := "https://api.github.com"
+	if repo.Host != "github.com" {
+		baseURL = fmt.Sprintf("https://%s/api/v3", repo.Host)
+	}
+	return &GitHubProvider{BaseURL: baseURL, Repo: repo, Token: token, HTTPClient: httpClient}
+}
+
+// header 返回 GitHub API 需要的请求头
+func (g *GitHubProvider) header() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+g.Token)
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return header
+}
+
+// CommentOnPullRequest PR 评论使用 issues 接口
+func (g *GitHubProvider) CommentOnPullRequest(ctx context.Context, number int, body string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/issues/%d/comments", g.BaseURL, g.Repo.FullName, number)
+	return doJSON(ctx, g.HTTPClient, http.MethodPost, endpoint, g.header(), map[string]string{"body": body}, nil)
+}
+
+// ReadFile 通过 contents 接口读取文件
+func (g *GitHubProvider) ReadFile(ctx context.Context, ref, path string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", g.BaseURL, g.Repo.FullName, escapeFilePath(path), url.QueryEscape(ref))
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &file); err != nil {
+		return nil, err
+	}
+	return decodeBase64Content(file.Content, file.Encoding)
+}
+
+// ListFiles 通过 contents 接口列出目录
+func (g *GitHubProvider) ListFiles(ctx context.Context, ref, dir string) ([]string, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", g.BaseURL, g.Repo.FullName, escapeFilePath(dir), url.QueryEscape(ref))
+	var entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &entries); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type == "file" {
+			files = append(files, entry.Path)
+		}
+	}
+	return files, nil
+}
+
+// CreatePullRequest 使用 Git Data 接口把所有改动放进一个提交，再创建 PR
+func (g *GitHubProvider) CreatePullRequest(ctx context.Context, spec PullRequestSpec) (*PullRequest, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s", g.BaseURL, g.Repo.FullName)
+
+	// 1. base 分支的最新提交和 tree
+	var baseRef struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, repoURL+"/git/ref/heads/"+escapeFilePath(spec.BaseBranch), g.header(), nil, &baseRef); err != nil {
+		return nil, err
+	}
+	var baseCommit struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, repoURL+"/git/commits/"+baseRef.Object.SHA, g.header(), nil, &baseCommit); err != nil {
+		return nil, err
+	}
+
+	// 2. 在 base tree 上写入改动并提交
+	entries := make([]map[string]string, 0, len(spec.Changes))
+	for _, change := range spec.Changes {
+		entries = append(entries, map[string]string{
+			"path": change.Path, "mode": "100644", "type": "blob", "content": string(change.Content),
+		})
+	}
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodPost, repoURL+"/git/trees", g.header(),
+		map[string]any{"base_tree": baseCommit.Tree.SHA, "tree": entries}, &tree); err != nil {
+		return nil, err
+	}
+	commitReq := map[string]any{"message": spec.CommitMessage, "tree": tree.SHA, "parents": []string{baseRef.Object.SHA}}
+	if spec.AuthorName != "" && spec.AuthorEmail != "" {
+		commitReq["author"] = map[string]string{"name": spec.AuthorName, "email": spec.AuthorEmail}
+	}
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodPost, repoURL+"/git/commits", g.header(), commitReq, &commit); err != nil {
+		return nil, err
+	}
+
+	// 3. 创建分支，分支已存在（上次创建 PR 失败后重试）时强制指向新提交
+	err := doJSON(ctx, g.HTTPClient, http.MethodPost, repoURL+"/git/refs", g.header(),
+		map[string]string{"ref": "refs/heads/" + spec.HeadBranch, "sha": commit.SHA}, nil)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity {
+		err = doJSON(ctx, g.HTTPClient, http.MethodPatch, repoURL+"/git/refs/heads/"+escapeFilePath(spec.HeadBranch), g.header(),
+			map[string]any{"sha": commit.SHA, "force": true}, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 创建 PR
+	var pr struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		State   string `json:"state"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodPost, repoURL+"/pulls", g.header(), map[string]string{
+		"title": spec.Title, "body": spec.Body, "head": spec.HeadBranch, "base": spec.BaseBranch,
+	}, &pr); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: pr.Number, URL: pr.HTMLURL, Status: pr.State}, nil
+}
+
+// FindOpenPullRequest 按 owner:branch 查询未关闭的 PR
+func (g *GitHubProvider) FindOpenPullRequest(ctx context.Context, headBranch string) (*PullRequest, error) {
+	owner, _, _ := strings.Cut(g.Repo.FullName, "/")
+	query := url.Values{"state": {"open"}, "head": {owner + ":" + headBranch}}
+	endpoint := fmt.Sprintf("%s/repos/%s/pulls?%s", g.BaseURL, g.Repo.FullName, query.Encode())
+	var prs []struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		State   string `json:"state"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &prs); err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &PullRequest{Number: prs[0].Number, URL: prs[0].HTMLURL, Status: prs[0].State}, nil
+}
+
+// GetPullRequest 查询 PR，已合并的 PR 在 GitHub 中 state 为 closed，这里转换为 merged
+func (g *GitHubProvider) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/pulls/%d", g.BaseURL, g.Repo.FullName, number)
+	var pr struct {
+		Number         int        `json:"number"`
+		HTMLURL        string     `json:"html_url"`
+		State          string     `json:"state"`
+		Merged         bool       `json:"merged"`
+		MergedAt       *time.Time `json:"merged_at"`
+		MergeCommitSHA string     `json:"merge_commit_sha"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &pr); err != nil {
+		return nil, err
+	}
+	result := &PullRequest{Number: pr.Number, URL: pr.HTMLURL, Status: pr.State}
+	if pr.Merged {
+		result.Status = "merged"
+		result.MergedAt = pr.MergedAt
+		result.MergeCommitSHA = pr.MergeCommitSHA
+	}
+	return result, nil
+}
@@ -0,0 +1,178 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitLabProvider 通过 GitLab REST API v4 操作 MR
+type GitLabProvider struct {
+	// API 地址，例如 https://gitlab.com/api/v4
+	BaseURL string
+	Repo    *Repo
+	Token   string
+
+	HTTPClient *http.Client
+}
+
+// NewGitLabProvider 创建 GitLab provider
+func NewGitLabProvider(repo *Repo, token string, httpClient *http.Client) *GitLabProvider {
+	return &GitLabProvider{
+		BaseURL:    fmt.Sprintf("https://%s/api/v4", repo.Host),
+		Repo:       repo,
+		Token:      token,
+		HTTPClient: httpClient,
+	}
+}
+
+// projectPath 项目路径需要整体 URL 编码，例如 group%2Frepo
+func (g *GitLabProvider) projectPath() string {
+	return url.PathEscape(g.Repo.FullName)
+}
+
+// header 返回 GitLab API 需要的请求头
+func (g *GitLabProvider) header() http.Header {
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", g.Token)
+	return header
+}
+
+// CommentOnPullRequest 在 MR 上添加 note
+func (g *GitLabProvider) CommentOnPullRequest(ctx context.Context, number int, body string) error {
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%d/notes", g.BaseURL, g.projectPath(), number)
+	return doJSON(ctx, g.HTTPClient, http.MethodPost, endpoint, g.header(), map[string]string{"body": body}, nil)
+}
+
+// ReadFile 通过 repository files 接口读取文件
+func (g *GitLabProvider) ReadFile(ctx context.Context, ref, path string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/repository/files/%s?ref=%s",
+		g.BaseURL, g.projectPath(), url.PathEscape(strings.Trim(path, "/")), url.QueryEscape(ref))
+	var file struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &file); err != nil {
+		return nil, err
+	}
+	return decodeBase64Content(file.Content, file.Encoding)
+}
+
+// ListFiles 通过 repository tree 接口列出目录
+func (g *GitLabProvider) ListFiles(ctx context.Context, ref, dir string) ([]string, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/repository/tree?path=%s&ref=%s&per_page=100",
+		g.BaseURL, g.projectPath(), url.QueryEscape(strings.Trim(dir, "/")), url.QueryEscape(ref))
+	var entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &entries); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type == "blob" {
+			files = append(files, entry.Path)
+		}
+	}
+	return files, nil
+}
+
+// CreatePullRequest 通过 commits 接口从 BaseBranch 新建分支并提交，再创建 MR
+// force 保证重试时分支已存在也会基于 BaseBranch 重新提交
+func (g *GitLabProvider) CreatePullRequest(ctx context.Context, spec PullRequestSpec) (*PullRequest, error) {
+	projectURL := fmt.Sprintf("%s/projects/%s", g.BaseURL, g.projectPath())
+
+	actions := make([]map[string]string, 0, len(spec.Changes))
+	for _, change := range spec.Changes {
+		actions = append(actions, map[string]string{
+			"action": "update", "file_path": change.Path, "content": string(change.Content),
+		})
+	}
+	commitReq := map[string]any{
+		"branch":         spec.HeadBranch,
+		"start_branch":   spec.BaseBranch,
+		"commit_message": spec.CommitMessage,
+		"actions":        actions,
+		"force":          true,
+	}
+	if spec.AuthorName != "" {
+		commitReq["author_name"] = spec.AuthorName
+	}
+	if spec.AuthorEmail != "" {
+		commitReq["author_email"] = spec.AuthorEmail
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodPost, projectURL+"/repository/commits", g.header(), commitReq, nil); err != nil {
+		return nil, err
+	}
+
+	var mr struct {
+		IID    int    `json:"iid"`
+		WebURL string `json:"web_url"`
+		State  string `json:"state"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodPost, projectURL+"/merge_requests", g.header(), map[string]any{
+		"source_branch":        spec.HeadBranch,
+		"target_branch":        spec.BaseBranch,
+		"title":                spec.Title,
+		"description":          spec.Body,
+		"remove_source_branch": true,
+	}, &mr); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: mr.IID, URL: mr.WebURL, Status: gitLabMRStatus(mr.State)}, nil
+}
+
+// FindOpenPullRequest 按源分支查询未关闭的 MR
+func (g *GitLabProvider) FindOpenPullRequest(ctx context.Context, headBranch string) (*PullRequest, error) {
+	query := url.Values{"state": {"opened"}, "source_branch": {headBranch}}
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests?%s", g.BaseURL, g.projectPath(), query.Encode())
+	var mrs []struct {
+		IID    int    `json:"iid"`
+		WebURL string `json:"web_url"`
+		State  string `json:"state"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &mrs); err != nil {
+		return nil, err
+	}
+	if len(mrs) == 0 {
+		return nil, nil
+	}
+	return &PullRequest{Number: mrs[0].IID, URL: mrs[0].WebURL, Status: gitLabMRStatus(mrs[0].State)}, nil
+}
+
+// GetPullRequest 查询 MR，squash 合并时使用 squash 提交作为合并提交
+func (g *GitLabProvider) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%d", g.BaseURL, g.projectPath(), number)
+	var mr struct {
+		IID             int        `json:"iid"`
+		WebURL          string     `json:"web_url"`
+		State           string     `json:"state"`
+		MergedAt        *time.Time `json:"merged_at"`
+		MergeCommitSHA  string     `json:"merge_commit_sha"`
+		SquashCommitSHA string     `json:"squash_commit_sha"`
+	}
+	if err := doJSON(ctx, g.HTTPClient, http.MethodGet, endpoint, g.header(), nil, &mr); err != nil {
+		return nil, err
+	}
+	result := &PullRequest{Number: mr.IID, URL: mr.WebURL, Status: gitLabMRStatus(mr.State)}
+	if result.Merged() {
+		result.MergedAt = mr.MergedAt
+		result.MergeCommitSHA = mr.MergeCommitSHA
+		if result.MergeCommitSHA == "" {
+			result.MergeCommitSHA = mr.SquashCommitSHA
+		}
+	}
+	return result, nil
+}
+
+// gitLabMRStatus 把 MR 的状态转换为与 GitHub 一致的取值
+func gitLabMRStatus(state string) string {
+	if state == "opened" {
+		return "open"
+	}
+	return state
+}
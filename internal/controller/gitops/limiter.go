@@ -0,0 +1,59 @@
+package gitops
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/semaphore"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultMaxConcurrentOps 默认同时进行的 git/PR 操作数
+const DefaultMaxConcurrentOps = 4
+
+var (
+	queuedOps = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "aiops_git_operations_queued",
+		Help: "Number of git/PR operations waiting for a free slot.",
+	})
+	inFlightOps = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "aiops_git_operations_in_flight",
+		Help: "Number of git/PR operations currently running.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(queuedOps, inFlightOps)
+}
+
+// Limiter 限制所有协调共享的 git/PR 并发操作数，避免大量审批同时触发 clone 或触发平台限流
+type Limiter struct {
+	sem *semaphore.Weighted
+}
+
+// NewLimiter 创建并发限制器，max 不大于 0 时使用 DefaultMaxConcurrentOps
+func NewLimiter(max int) *Limiter {
+	if max <= 0 {
+		max = DefaultMaxConcurrentOps
+	}
+	return &Limiter{sem: semaphore.NewWeighted(int64(max))}
+}
+
+// Do 等待空闲名额后执行 fn，ctx 取消时放弃等待；nil Limiter 不做限制
+func (l *Limiter) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if l == nil {
+		return fn(ctx)
+	}
+
+	queuedOps.Inc()
+	err := l.sem.Acquire(ctx, 1)
+	queuedOps.Dec()
+	if err != nil {
+		return err
+	}
+	defer l.sem.Release(1)
+
+	inFlightOps.Inc()
+	defer inFlightOps.Dec()
+	return fn(ctx)
+}
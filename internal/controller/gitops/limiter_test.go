@@ -0,0 +1,59 @@
+package gitops
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	It("should never run more operations than the limit", func() {
+		limiter := NewLimiter(2)
+		var running, peak int32
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				Expect(limiter.Do(context.Background(), func(ctx context.Context) error {
+					n := atomic.AddInt32(&running, 1)
+					for {
+						p := atomic.LoadInt32(&peak)
+						if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+					return nil
+				})).To(Succeed())
+			}()
+		}
+		wg.Wait()
+		Expect(peak).To(BeNumerically("<=", 2))
+	})
+
+	It("should stop waiting when the context is cancelled", func() {
+		limiter := NewLimiter(1)
+		started, release := make(chan struct{}), make(chan struct{})
+		go func() {
+			_ = limiter.Do(context.Background(), func(ctx context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		defer close(release)
+		Eventually(started).Should(BeClosed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := limiter.Do(ctx, func(ctx context.Context) error { return nil })
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
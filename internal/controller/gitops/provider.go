@@ -0,0 +1,180 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provider PR/MR 托管平台的操作
+type Provider interface {
+	// CommentOnPullRequest 在指定的 PR/MR 上发表评论
+	CommentOnPullRequest(ctx context.Context, number int, body string) error
+	// ReadFile 读取 ref 上的文件内容
+	ReadFile(ctx context.Context, ref, path string) ([]byte, error)
+	// ListFiles 列出 ref 上目录中的文件路径（不递归）
+	ListFiles(ctx context.Context, ref, dir string) ([]string, error)
+	// CreatePullRequest 从 BaseBranch 新建 HeadBranch，提交 Changes 并创建 PR/MR
+	CreatePullRequest(ctx context.Context, spec PullRequestSpec) (*PullRequest, error)
+	// GetPullRequest 查询 PR/MR 的当前状态
+	GetPullRequest(ctx context.Context, number int) (*PullRequest, error)
+	// FindOpenPullRequest 查询以 headBranch 为源分支的未关闭 PR/MR，不存在时返回 nil
+	FindOpenPullRequest(ctx context.Context, headBranch string) (*PullRequest, error)
+}
+
+// FileChange 提交中要更新的文件
+type FileChange struct {
+	Path    string
+	Content []byte
+}
+
+// PullRequestSpec 创建 PR/MR 需要的内容
+type PullRequestSpec struct {
+	BaseBranch string
+	HeadBranch string
+	Title      string
+	Body       string
+
+	CommitMessage string
+	// 提交者信息，为空时使用 token 对应的用户
+	AuthorName  string
+	AuthorEmail string
+
+	Changes []FileChange
+}
+
+// PullRequest 创建出的 PR/MR
+type PullRequest struct {
+	Number int
+	URL    string
+	// open / closed / merged
+	Status string
+	// 合并时间和合并提交，未合并时为空
+	MergedAt       *time.Time
+	MergeCommitSHA string
+}
+
+// Merged PR/MR 是否已合并
+func (pr *PullRequest) Merged() bool {
+	return pr.Status == "merged"
+}
+
+// Terminal PR/MR 已合并或关闭，状态不会再变化
+func (pr *PullRequest) Terminal() bool {
+	return pr.Status == "merged" || pr.Status == "closed"
+}
+
+// StatusError 托管平台返回了非 2xx 响应
+type StatusError struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s failed: status %d: %s", e.Method, e.Endpoint, e.StatusCode, e.Body)
+}
+
+// PermanentError 重试也无法成功的错误，如仓库中找不到补丁的目标资源或补丁无法应用到清单
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent 判断创建 PR 的错误是否无法通过重试恢复：PermanentError 或托管平台返回 422（如 PR 已存在、分支无差异）
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	if errors.As(err, &permanent) {
+		return true
+	}
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity
+}
+
+// NewProvider 按仓库地址的主机名选择 GitHub 或 GitLab
+func NewProvider(repoURL, token string) (Provider, error) {
+	repo, err := ParseRepoURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if repo.IsGitHub() {
+		return NewGitHubProvider(repo, token, httpClient), nil
+	}
+	return NewGitLabProvider(repo, token, httpClient), nil
+}
+
+// doJSON 发送 JSON 请求，非 2xx 响应返回错误，out 不为空时解析响应体
+func doJSON(ctx context.Context, httpClient *http.Client, method, endpoint string, header http.Header, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{Method: method, Endpoint: endpoint, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response of %s %s failed: %w", method, endpoint, err)
+	}
+	return nil
+}
+
+// decodeBase64Content 解码 GitHub/GitLab 文件接口返回的 base64 内容（GitHub 每 60 个字符换行）
+func decodeBase64Content(content, encoding string) ([]byte, error) {
+	if encoding != "base64" {
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	return base64.StdEncoding.DecodeString(strings.ReplaceAll(content, "\n", ""))
+}
+
+// escapeFilePath 逐段编码仓库中的文件路径，保留 /
+func escapeFilePath(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,130 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseRepoURL", func() {
+	DescribeTable("parsing repo urls",
+		func(repoURL, host, fullName string) {
+			repo, err := ParseRepoURL(repoURL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.Host).To(Equal(host))
+			Expect(repo.FullName).To(Equal(fullName))
+		},
+		Entry("https", "https://github.com/boqier/deploy.git", "github.com", "boqier/deploy"),
+		Entry("scp style ssh", "git@github.com:boqier/deploy.git", "github.com", "boqier/deploy"),
+		Entry("ssh url", "ssh://git@gitlab.example.com/group/sub/deploy.git", "gitlab.example.com", "group/sub/deploy"),
+	)
+
+	It("should reject urls without owner and repo", func() {
+		_, err := ParseRepoURL("https://github.com/deploy")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Provider comments", func() {
+	var (
+		server   *httptest.Server
+		gotPath  string
+		gotToken string
+		gotBody  map[string]string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.EscapedPath()
+			gotToken = r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")
+			Expect(json.NewDecoder(r.Body).Decode(&gotBody)).To(Succeed())
+			w.WriteHeader(http.StatusCreated)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	It("should post GitHub PR comments through the issues API", func() {
+		provider := NewGitHubProvider(&Repo{Host: "github.com", FullName: "boqier/deploy"}, "t0ken", server.Client())
+		provider.BaseURL = server.URL
+
+		Expect(provider.CommentOnPullRequest(context.Background(), 42, "preview")).To(Succeed())
+		Expect(gotPath).To(Equal("/repos/boqier/deploy/issues/42/comments"))
+		Expect(gotToken).To(Equal("Bearer t0ken"))
+		Expect(gotBody).To(HaveKeyWithValue("body", "preview"))
+	})
+
+	It("should post GitLab MR notes with an encoded project path", func() {
+		provider := NewGitLabProvider(&Repo{Host: "gitlab.example.com", FullName: "group/deploy"}, "t0ken", server.Client())
+		provider.BaseURL = server.URL
+
+		Expect(provider.CommentOnPullRequest(context.Background(), 7, "preview")).To(Succeed())
+		Expect(gotPath).To(Equal("/projects/group%2Fdeploy/merge_requests/7/notes"))
+		Expect(gotToken).To(Equal("t0ken"))
+	})
+})
+
+var _ = Describe("Pull request status", func() {
+	var (
+		server   *httptest.Server
+		gotPath  string
+		response string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.EscapedPath()
+			Expect(r.Method).To(Equal(http.MethodGet))
+			_, _ = w.Write([]byte(response))
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	It("should report a merged GitHub PR as merged with its merge commit", func() {
+		provider := NewGitHubProvider(&Repo{Host: "github.com", FullName: "boqier/deploy"}, "t0ken", server.Client())
+		provider.BaseURL = server.URL
+		response = `{"number":8,"html_url":"https://github.com/boqier/deploy/pull/8","state":"closed","merged":true,` +
+			`"merged_at":"2025-11-26T12:45:55Z","merge_commit_sha":"abc123"}`
+
+		pr, err := provider.GetPullRequest(context.Background(), 8)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gotPath).To(Equal("/repos/boqier/deploy/pulls/8"))
+		Expect(pr.Status).To(Equal("merged"))
+		Expect(pr.Merged()).To(BeTrue())
+		Expect(pr.Terminal()).To(BeTrue())
+		Expect(pr.MergedAt.Equal(time.Date(2025, 11, 26, 12, 45, 55, 0, time.UTC))).To(BeTrue())
+		Expect(pr.MergeCommitSHA).To(Equal("abc123"))
+	})
+
+	It("should keep a closed but unmerged GitHub PR closed", func() {
+		provider := NewGitHubProvider(&Repo{Host: "github.com", FullName: "boqier/deploy"}, "t0ken", server.Client())
+		provider.BaseURL = server.URL
+		response = `{"number":8,"state":"closed","merged":false,"merge_commit_sha":"test-merge"}`
+
+		pr, err := provider.GetPullRequest(context.Background(), 8)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Status).To(Equal("closed"))
+		Expect(pr.MergeCommitSHA).To(BeEmpty())
+	})
+
+	DescribeTable("mapping GitLab MR states",
+		func(body, status, sha string) {
+			provider := NewGitLabProvider(&Repo{Host: "gitlab.example.com", FullName: "group/deploy"}, "t0ken", server.Client())
+			provider.BaseURL = server.URL
+			response = body
+
+			pr, err := provider.GetPullRequest(context.Background(), 7)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gotPath).To(Equal("/projects/group%2Fdeploy/merge_requests/7"))
+			Expect(pr.Status).To(Equal(status))
+			Expect(pr.MergeCommitSHA).To(Equal(sha))
+		},
+		Entry("opened", `{"iid":7,"state":"opened"}`, "open", ""),
+		Entry("merged", `{"iid":7,"state":"merged","merged_at":"2025-11-26T12:45:55Z","merge_commit_sha":"def456"}`, "merged", "def456"),
+		Entry("squash merged", `{"iid":7,"state":"merged","squash_commit_sha":"789abc"}`, "merged", "789abc"),
+	)
+})
@@ -0,0 +1,130 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+)
+
+// RemediationPR 一次修复要提交的内容
+type RemediationPR struct {
+	// 目标分支与清单所在路径（单个 YAML 文件或目录）
+	BaseBranch string
+	Path       string
+
+	HeadBranch  string
+	Title       string
+	Body        string
+	AuthorName  string
+	AuthorEmail string
+
+	// 已批准的补丁，按 TargetRef 匹配清单中的资源
+	Patches []autofixv1.PatchOperation
+}
+
+// OpenRemediationPR 读取 Path 下的清单，应用补丁后提交到新分支并创建 PR/MR
+// 任何补丁找不到对应的资源时返回 PermanentError，不会创建只包含部分改动的 PR
+// HeadBranch 上已有未关闭的 PR 时（上次创建后未能记录到 status）直接返回该 PR
+func OpenRemediationPR(ctx context.Context, provider Provider, pr RemediationPR) (*PullRequest, error) {
+	existing, err := provider.FindOpenPullRequest(ctx, pr.HeadBranch)
+	if err != nil {
+		return nil, fmt.Errorf("find open pull request for %s failed: %w", pr.HeadBranch, err)
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	files, err := manifestFiles(ctx, provider, pr.BaseBranch, pr.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]bool, len(pr.Patches))
+	var changes []FileChange
+	for _, file := range files {
+		content, err := provider.ReadFile(ctx, pr.BaseBranch, file)
+		if err != nil {
+			return nil, fmt.Errorf("read %s failed: %w", file, err)
+		}
+		patched, matched, err := patchManifest(content, pr.Patches)
+		if err != nil {
+			return nil, &PermanentError{Err: fmt.Errorf("patch %s failed: %w", file, err)}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		for _, i := range matched {
+			applied[i] = true
+		}
+		changes = append(changes, FileChange{Path: file, Content: patched})
+	}
+	for i, ok := range applied {
+		if !ok {
+			return nil, &PermanentError{Err: fmt.Errorf("no manifest under %s matches the target of patch %s", pr.Path, pr.Patches[i].Path)}
+		}
+	}
+
+	return provider.CreatePullRequest(ctx, PullRequestSpec{
+		BaseBranch:    pr.BaseBranch,
+		HeadBranch:    pr.HeadBranch,
+		Title:         pr.Title,
+		Body:          pr.Body,
+		CommitMessage: pr.Title,
+		AuthorName:    pr.AuthorName,
+		AuthorEmail:   pr.AuthorEmail,
+		Changes:       changes,
+	})
+}
+
+// manifestFiles Path 是 YAML 文件时直接返回，是目录时返回其中的 YAML 文件
+func manifestFiles(ctx context.Context, provider Provider, ref, p string) ([]string, error) {
+	if isYAMLFile(p) {
+		return []string{p}, nil
+	}
+	entries, err := provider.ListFiles(ctx, ref, p)
+	if err != nil {
+		return nil, fmt.Errorf("list %s failed: %w", p, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if isYAMLFile(entry) {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+func isYAMLFile(p string) bool {
+	ext := path.Ext(p)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// patchManifest 把 TargetRef 命中该文件中资源的补丁应用到文件上，返回新内容和命中的补丁下标
+func patchManifest(content []byte, patches []autofixv1.PatchOperation) ([]byte, []int, error) {
+	objects, err := patch.ManifestObjects(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	var matched []int
+	var ops []autofixv1.PatchOperation
+	for i, op := range patches {
+		for _, object := range objects {
+			if patch.TargetMatches(op.TargetRef, object) {
+				matched = append(matched, i)
+				ops = append(ops, op)
+				break
+			}
+		}
+	}
+	if len(ops) == 0 {
+		return content, nil, nil
+	}
+	patched, err := patch.ApplyPatchesToYAML(content, ops)
+	if err != nil {
+		return nil, nil, err
+	}
+	return patched, matched, nil
+}
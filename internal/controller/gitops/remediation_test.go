@@ -0,0 +1,252 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// fakeProvider 内存中的仓库，记录创建的 PR
+type fakeProvider struct {
+	files   map[string]string
+	created *PullRequestSpec
+	// open FindOpenPullRequest 返回的 PR
+	open *PullRequest
+}
+
+func (f *fakeProvider) CommentOnPullRequest(context.Context, int, string) error { return nil }
+
+func (f *fakeProvider) ReadFile(_ context.Context, _, path string) ([]byte, error) {
+	return []byte(f.files[path]), nil
+}
+
+func (f *fakeProvider) ListFiles(context.Context, string, string) ([]string, error) {
+	var files []string
+	for path := range f.files {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func (f *fakeProvider) CreatePullRequest(_ context.Context, spec PullRequestSpec) (*PullRequest, error) {
+	f.created = &spec
+	return &PullRequest{Number: 1, URL: "https://example.com/pr/1", Status: "open"}, nil
+}
+
+func (f *fakeProvider) FindOpenPullRequest(context.Context, string) (*PullRequest, error) {
+	return f.open, nil
+}
+
+func (f *fakeProvider) GetPullRequest(_ context.Context, number int) (*PullRequest, error) {
+	return &PullRequest{Number: number, Status: "open"}, nil
+}
+
+const multiDocManifest = `apiVersion: v1
+kind: Service
+metadata:
+  name: order
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: order
+spec:
+  replicas: 2
+`
+
+func replicasPatch(kind, name string) autofixv1.PatchOperation {
+	return autofixv1.PatchOperation{
+		Op:        "replace",
+		Path:      "/spec/replicas",
+		Value:     runtime.RawExtension{Raw: []byte("3")},
+		TargetRef: &corev1.ObjectReference{Kind: kind, Name: name},
+	}
+}
+
+var _ = Describe("OpenRemediationPR", func() {
+	It("should patch only the document matching the target", func() {
+		provider := &fakeProvider{files: map[string]string{"apps/order.yaml": multiDocManifest, "apps/README.md": "x"}}
+
+		pr, err := OpenRemediationPR(context.Background(), provider, RemediationPR{
+			BaseBranch: "main",
+			Path:       "apps",
+			HeadBranch: "aiops/req-1",
+			Title:      "扩容 order",
+			Patches:    []autofixv1.PatchOperation{replicasPatch("Deployment", "order")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Number).To(Equal(1))
+
+		Expect(provider.created.HeadBranch).To(Equal("aiops/req-1"))
+		Expect(provider.created.CommitMessage).To(Equal("扩容 order"))
+		Expect(provider.created.Changes).To(HaveLen(1))
+		content := string(provider.created.Changes[0].Content)
+		Expect(content).To(HavePrefix("apiVersion: v1\nkind: Service\nmetadata:\n  name: order\n---\n"))
+		Expect(content).To(ContainSubstring("replicas: 3"))
+	})
+
+	It("should refuse to open a PR when a patch matches no manifest", func() {
+		provider := &fakeProvider{files: map[string]string{"apps/order.yaml": multiDocManifest}}
+
+		_, err := OpenRemediationPR(context.Background(), provider, RemediationPR{
+			BaseBranch: "main",
+			Path:       "apps/order.yaml",
+			Patches: []autofixv1.PatchOperation{
+				replicasPatch("Deployment", "order"),
+				replicasPatch("StatefulSet", "order"),
+			},
+		})
+		Expect(err).To(MatchError(ContainSubstring("no manifest under apps/order.yaml matches")))
+		Expect(IsPermanent(err)).To(BeTrue())
+		Expect(provider.created).To(BeNil())
+	})
+
+	It("should return the open PR of the head branch instead of creating another", func() {
+		open := &PullRequest{Number: 7, URL: "https://example.com/pr/7", Status: "open"}
+		provider := &fakeProvider{files: map[string]string{"apps/order.yaml": multiDocManifest}, open: open}
+
+		pr, err := OpenRemediationPR(context.Background(), provider, RemediationPR{
+			BaseBranch: "main",
+			Path:       "apps/order.yaml",
+			HeadBranch: "aiops/req-1",
+			Patches:    []autofixv1.PatchOperation{replicasPatch("Deployment", "order")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr).To(Equal(open))
+		Expect(provider.created).To(BeNil())
+	})
+})
+
+var _ = Describe("IsPermanent", func() {
+	It("should treat 422 responses as permanent and other statuses as transient", func() {
+		Expect(IsPermanent(&StatusError{StatusCode: http.StatusUnprocessableEntity, Body: "A pull request already exists"})).To(BeTrue())
+		Expect(IsPermanent(fmt.Errorf("open: %w", &StatusError{StatusCode: http.StatusBadGateway}))).To(BeFalse())
+		Expect(IsPermanent(errors.New("connection refused"))).To(BeFalse())
+	})
+})
+
+var _ = Describe("Provider pull requests", func() {
+	var (
+		server   *httptest.Server
+		requests []string
+		bodies   map[string]map[string]any
+		handle   func(w http.ResponseWriter, key string)
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		bodies = map[string]map[string]any{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Method + " " + r.URL.EscapedPath()
+			requests = append(requests, key)
+			if r.Body != nil && r.Method != http.MethodGet {
+				var body map[string]any
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				bodies[key] = body
+			}
+			handle(w, key)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	It("should commit through the GitHub Git Data API and reuse an existing branch", func() {
+		handle = func(w http.ResponseWriter, key string) {
+			switch key {
+			case "GET /repos/boqier/deploy/git/ref/heads/main":
+				_, _ = w.Write([]byte(`{"object":{"sha":"base"}}`))
+			case "GET /repos/boqier/deploy/git/commits/base":
+				_, _ = w.Write([]byte(`{"tree":{"sha":"basetree"}}`))
+			case "POST /repos/boqier/deploy/git/trees":
+				_, _ = w.Write([]byte(`{"sha":"newtree"}`))
+			case "POST /repos/boqier/deploy/git/commits":
+				_, _ = w.Write([]byte(`{"sha":"newcommit"}`))
+			case "POST /repos/boqier/deploy/git/refs":
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			case "PATCH /repos/boqier/deploy/git/refs/heads/aiops/req-1":
+				_, _ = w.Write([]byte(`{}`))
+			case "POST /repos/boqier/deploy/pulls":
+				_, _ = w.Write([]byte(`{"number":12,"html_url":"https://github.com/boqier/deploy/pull/12","state":"open"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+		provider := NewGitHubProvider(&Repo{Host: "github.com", FullName: "boqier/deploy"}, "t0ken", server.Client())
+		provider.BaseURL = server.URL
+
+		pr, err := provider.CreatePullRequest(context.Background(), PullRequestSpec{
+			BaseBranch: "main", HeadBranch: "aiops/req-1", Title: "扩容", CommitMessage: "扩容",
+			AuthorName: "aiops", AuthorEmail: "aiops@example.com",
+			Changes: []FileChange{{Path: "apps/order.yaml", Content: []byte("kind: Deployment\n")}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr).To(Equal(&PullRequest{Number: 12, URL: "https://github.com/boqier/deploy/pull/12", Status: "open"}))
+		Expect(requests).To(ContainElement("PATCH /repos/boqier/deploy/git/refs/heads/aiops/req-1"))
+		Expect(bodies["POST /repos/boqier/deploy/git/trees"]).To(HaveKeyWithValue("base_tree", "basetree"))
+		Expect(bodies["POST /repos/boqier/deploy/git/commits"]).To(HaveKey("author"))
+		Expect(bodies["POST /repos/boqier/deploy/pulls"]).To(HaveKeyWithValue("head", "aiops/req-1"))
+	})
+
+	It("should find the open PR of a head branch", func() {
+		var query string
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery
+			switch r.URL.Path {
+			case "/repos/boqier/deploy/pulls":
+				_, _ = w.Write([]byte(`[{"number":12,"html_url":"https://github.com/boqier/deploy/pull/12","state":"open"}]`))
+			case "/projects/group%2Fdeploy/merge_requests", "/projects/group/deploy/merge_requests":
+				_, _ = w.Write([]byte(`[]`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		github := NewGitHubProvider(&Repo{Host: "github.com", FullName: "boqier/deploy"}, "t0ken", server.Client())
+		github.BaseURL = server.URL
+		pr, err := github.FindOpenPullRequest(context.Background(), "aiops/req-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr).To(Equal(&PullRequest{Number: 12, URL: "https://github.com/boqier/deploy/pull/12", Status: "open"}))
+		Expect(query).To(Equal("GET /repos/boqier/deploy/pulls?head=boqier%3Aaiops%2Freq-1&state=open"))
+
+		gitlab := NewGitLabProvider(&Repo{Host: "gitlab.example.com", FullName: "group/deploy"}, "t0ken", server.Client())
+		gitlab.BaseURL = server.URL
+		pr, err = gitlab.FindOpenPullRequest(context.Background(), "aiops/req-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr).To(BeNil())
+		Expect(query).To(HaveSuffix("?source_branch=aiops%2Freq-1&state=opened"))
+	})
+
+	It("should commit and open a GitLab MR", func() {
+		handle = func(w http.ResponseWriter, key string) {
+			switch key {
+			case "POST /projects/group%2Fdeploy/repository/commits":
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{}`))
+			case "POST /projects/group%2Fdeploy/merge_requests":
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"iid":5,"web_url":"https://gitlab.example.com/group/deploy/-/merge_requests/5","state":"opened"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+		provider := NewGitLabProvider(&Repo{Host: "gitlab.example.com", FullName: "group/deploy"}, "t0ken", server.Client())
+		provider.BaseURL = server.URL
+
+		pr, err := provider.CreatePullRequest(context.Background(), PullRequestSpec{
+			BaseBranch: "main", HeadBranch: "aiops/req-1", Title: "扩容", CommitMessage: "扩容",
+			Changes: []FileChange{{Path: "apps/order.yaml", Content: []byte("kind: Deployment\n")}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Number).To(Equal(5))
+		Expect(pr.Status).To(Equal("open"))
+		Expect(bodies["POST /projects/group%2Fdeploy/repository/commits"]).To(HaveKeyWithValue("start_branch", "main"))
+	})
+})
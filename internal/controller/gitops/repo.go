@@ -0,0 +1,49 @@
+package gitops
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Repo 从仓库地址中解析出的托管平台信息
+type Repo struct {
+	// 托管平台的主机名，例如 github.com
+	Host string
+	// 仓库的完整路径，例如 owner/repo 或 group/subgroup/repo
+	FullName string
+}
+
+// ParseRepoURL 解析 https 或 ssh 形式的仓库地址
+// 支持 https://github.com/owner/repo.git、git@github.com:owner/repo.git、ssh://git@host/owner/repo.git
+func ParseRepoURL(repoURL string) (*Repo, error) {
+	raw := strings.TrimSpace(repoURL)
+	var host, repoPath string
+
+	switch {
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repo url %q: %w", repoURL, err)
+		}
+		host, repoPath = u.Hostname(), u.Path
+	case strings.Contains(raw, "@") && strings.Contains(raw, ":"):
+		// scp 风格：git@github.com:owner/repo.git
+		userHost, p, _ := strings.Cut(raw, ":")
+		_, host, _ = strings.Cut(userHost, "@")
+		repoPath = p
+	default:
+		return nil, fmt.Errorf("invalid repo url %q: unsupported format", repoURL)
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if host == "" || !strings.Contains(repoPath, "/") {
+		return nil, fmt.Errorf("invalid repo url %q: expected <host>/<owner>/<repo>", repoURL)
+	}
+	return &Repo{Host: host, FullName: repoPath}, nil
+}
+
+// IsGitHub 是否托管在 GitHub（含 GitHub Enterprise，按主机名判断）
+func (r *Repo) IsGitHub() bool {
+	return r.Host == "github.com" || strings.HasPrefix(r.Host, "github.")
+}
@@ -0,0 +1,14 @@
+package gitops
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGitOps(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "GitOps Suite")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+)
+
+// maxHistoryEntries status.history 最多保留的记录数
+const maxHistoryEntries = 20
+
+// newRemediationRecord 根据修复建议构造历史记录
+func newRemediationRecord(requestID string, heal *llm.HealAction) autofixv1.RemediationRecord {
+	return autofixv1.RemediationRecord{
+		RequestID:  requestID,
+		ActionType: actionTypeForPatches(heal.PatchContent),
+		RiskLevel:  heal.RiskLevel,
+		Reason:     heal.Reason,
+		ProposedAt: metav1.Now(),
+	}
+}
+
+// actionTypeForPatches 按 patch 路径推断动作类型，取值与 RemediationProposal.ActionType 一致
+func actionTypeForPatches(ops []llm.PatchOp) string {
+	actionType := "config-change"
+	// 修改 ConfigMap 时附带的重启补丁只是为了让配置生效
+	if llm.HasConfigMapPatches(ops) {
+		return actionType
+	}
+	for _, op := range ops {
+		if op.Path == llm.RestartAnnotationPath {
+			return "restart"
+		}
+		switch patch.KindForPath(op.Path) {
+		case patch.KindReplicas:
+			return "scale"
+		case patch.KindQuantity, patch.KindResourceList:
+			actionType = "resource-adjust"
+		}
+	}
+	return actionType
+}
+
+// recordHistory 追加一条修复记录，超出上限时丢弃最旧的记录
+func (r *AIOpsAnalyzerReconciler) recordHistory(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, record autofixv1.RemediationRecord) error {
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		// 产出了修复建议，清空上一次的 noop 原因
+		status.NoopReason = ""
+		status.NoopMessage = ""
+		status.History = append(status.History, record)
+		if overflow := len(status.History) - maxHistoryEntries; overflow > 0 {
+			status.History = status.History[overflow:]
+		}
+	})
+}
+
+// findHistory 按 RequestID 查找历史记录
+func findHistory(status *autofixv1.AIOpsAnalyzerStatus, requestID string) *autofixv1.RemediationRecord {
+	for i := range status.History {
+		if status.History[i].RequestID == requestID {
+			return &status.History[i]
+		}
+	}
+	return nil
+}
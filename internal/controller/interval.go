@@ -0,0 +1,94 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// defaultAnalysisInterval 未配置或无法解析 analysisInterval 时的分析周期
+const defaultAnalysisInterval = 5 * time.Minute
+
+// analysisInterval 解析 spec.analysisInterval，无法解析时回退到 5m
+// 低于 autofixv1.MinAnalysisInterval 时（如绕过了 webhook）按下限处理
+func analysisInterval(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) time.Duration {
+	interval, _ := parseAnalysisInterval(aiopsAnalyzer.Spec.AnalysisInterval)
+	return interval
+}
+
+// parseAnalysisInterval 返回生效的分析周期，回退或按下限处理时同时返回说明
+func parseAnalysisInterval(value string) (time.Duration, string) {
+	if value == "" {
+		return defaultAnalysisInterval, ""
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return defaultAnalysisInterval, fmt.Sprintf("analysisInterval %q 无法解析，使用默认值 %s", value, defaultAnalysisInterval)
+	}
+	if interval < autofixv1.MinAnalysisInterval {
+		return autofixv1.MinAnalysisInterval, fmt.Sprintf("analysisInterval %q 低于下限，按 %s 处理", value, autofixv1.MinAnalysisInterval)
+	}
+	return interval, ""
+}
+
+// intervalWarnings 按 CR 记录已提示过无效 analysisInterval 的 generation，零值可用
+type intervalWarnings struct {
+	mu     sync.Mutex
+	warned map[types.NamespacedName]int64
+}
+
+// first 返回 generation 是否第一次需要提示
+func (w *intervalWarnings) first(key types.NamespacedName, generation int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if warned, ok := w.warned[key]; ok && warned == generation {
+		return false
+	}
+	if w.warned == nil {
+		w.warned = map[types.NamespacedName]int64{}
+	}
+	w.warned[key] = generation
+	return true
+}
+
+// forget 删除 CR 时清除记录
+func (w *intervalWarnings) forget(key types.NamespacedName) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.warned, key)
+}
+
+// nextAnalysisAfter 返回距下一次分析的时间，Once 模式已结束时返回 0（不再重新入队）
+// analysisInterval 无效时每个 generation 只记录一次 Warning 事件
+func (r *AIOpsAnalyzerReconciler) nextAnalysisAfter(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) time.Duration {
+	if aiopsAnalyzer.Spec.RunPolicy == autofixv1.RunPolicyOnce && aiopsAnalyzer.Status.Summary == autofixv1.SummaryCompleted {
+		return 0
+	}
+	interval, warning := parseAnalysisInterval(aiopsAnalyzer.Spec.AnalysisInterval)
+	if warning != "" && r.intervalWarnings.first(client.ObjectKeyFromObject(aiopsAnalyzer), aiopsAnalyzer.Generation) {
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonInvalidAnalysisInterval, "%s", warning)
+	}
+	return interval
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Analysis interval", func() {
+	var recorder *record.FakeRecorder
+
+	nextAnalysisAfter := func(spec autofixv1.AIOpsAnalyzerSpec, status autofixv1.AIOpsAnalyzerStatus) time.Duration {
+		recorder = record.NewFakeRecorder(1)
+		reconciler := &AIOpsAnalyzerReconciler{Recorder: recorder}
+		return reconciler.nextAnalysisAfter(&autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "interval", Namespace: "default"},
+			Spec:       spec,
+			Status:     status,
+		})
+	}
+
+	It("should requeue after the configured interval", func() {
+		Expect(nextAnalysisAfter(autofixv1.AIOpsAnalyzerSpec{AnalysisInterval: "10m"}, autofixv1.AIOpsAnalyzerStatus{})).To(Equal(10 * time.Minute))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should fall back to 5m and warn when the interval cannot be parsed", func() {
+		Expect(nextAnalysisAfter(autofixv1.AIOpsAnalyzerSpec{AnalysisInterval: "5 minutes"}, autofixv1.AIOpsAnalyzerStatus{})).To(Equal(5 * time.Minute))
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonInvalidAnalysisInterval)))
+	})
+
+	It("should clamp an interval below the minimum and warn", func() {
+		Expect(nextAnalysisAfter(autofixv1.AIOpsAnalyzerSpec{AnalysisInterval: "1s"}, autofixv1.AIOpsAnalyzerStatus{})).To(Equal(30 * time.Second))
+		Expect(recorder.Events).To(Receive(ContainSubstring("低于下限")))
+	})
+
+	It("should warn once per generation about an invalid interval", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &AIOpsAnalyzerReconciler{Recorder: recorder}
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "interval", Namespace: "default", Generation: 1},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{AnalysisInterval: "1s"},
+		}
+		for range 3 {
+			Expect(reconciler.nextAnalysisAfter(aiopsAnalyzer)).To(Equal(30 * time.Second))
+		}
+		Expect(analysisInterval(aiopsAnalyzer)).To(Equal(30 * time.Second))
+		Expect(recorder.Events).To(HaveLen(1))
+
+		aiopsAnalyzer.Generation = 2
+		Expect(reconciler.nextAnalysisAfter(aiopsAnalyzer)).To(Equal(30 * time.Second))
+		Expect(recorder.Events).To(HaveLen(2))
+	})
+
+	It("should stop requeueing once a Once run has completed", func() {
+		Expect(nextAnalysisAfter(
+			autofixv1.AIOpsAnalyzerSpec{AnalysisInterval: "1m", RunPolicy: autofixv1.RunPolicyOnce},
+			autofixv1.AIOpsAnalyzerStatus{Summary: autofixv1.SummaryCompleted},
+		)).To(BeZero())
+	})
+})
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PathAllowlist 允许修改的 JSON Path 前缀，* 匹配单个路径段
+type PathAllowlist []string
+
+var (
+	scalePaths    = []string{"/spec/replicas", "/spec/minReplicas", "/spec/maxReplicas", "/spec/metrics"}
+	resourcePaths = []string{"/spec/template/spec/containers/*/resources"}
+	envPaths      = []string{"/spec/template/spec/containers/*/env", "/spec/template/spec/containers/*/envFrom"}
+	restartPaths  = []string{"/spec/template/metadata/annotations"}
+	// ConfigMapPaths 配置变更可以修改的 ConfigMap 路径
+	ConfigMapPaths = []string{"/data", "/binaryData"}
+)
+
+// ActionPaths AutoRemediation.AllowedActions 中每种修复类型允许修改的路径
+var ActionPaths = map[string][]string{
+	"scale":          scalePaths,
+	"resource":       resourcePaths,
+	"restart":        restartPaths,
+	"config":         append(append([]string{}, envPaths...), ConfigMapPaths...),
+	"feature-toggle": envPaths,
+	// 流量调整不修改工作负载
+	"traffic": nil,
+}
+
+// DefaultAllowedActions 未配置 AllowedActions 时允许的修复类型
+var DefaultAllowedActions = []string{"scale", "resource"}
+
+// DefaultPathAllowlist 未配置 AllowedActions 时只允许扩缩容和调整资源
+var DefaultPathAllowlist = PathAllowlist(append(append([]string{}, scalePaths...), resourcePaths...))
+
+// AllActionsAllowlist 所有修复类型允许修改的路径之和
+func AllActionsAllowlist() PathAllowlist {
+	var allowlist PathAllowlist
+	for _, paths := range ActionPaths {
+		allowlist = append(allowlist, paths...)
+	}
+	return allowlist
+}
+
+// ActionsForPath 返回允许修改 path 的修复类型（按名称排序），例如 /spec/replicas 对应 scale
+func ActionsForPath(path string) []string {
+	var actions []string
+	for action, paths := range ActionPaths {
+		if PathAllowlist(paths).Allows(path) {
+			actions = append(actions, action)
+		}
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// allowedOps 允许的 patch 操作
+var allowedOps = map[string]bool{"replace": true, "add": true, "remove": true}
+
+// AllowlistForActions 按修复类型生成路径白名单，actions 为空时使用 DefaultPathAllowlist
+func AllowlistForActions(actions []string) PathAllowlist {
+	if len(actions) == 0 {
+		return DefaultPathAllowlist
+	}
+	var allowlist PathAllowlist
+	for _, action := range actions {
+		allowlist = append(allowlist, ActionPaths[action]...)
+	}
+	return allowlist
+}
+
+// Allows 判断 path 是否位于某个白名单前缀之下
+func (a PathAllowlist) Allows(path string) bool {
+	segments := strings.Split(path, "/")
+	for _, prefix := range a {
+		if matchPathPrefix(strings.Split(prefix, "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathPrefix 按路径段匹配前缀，* 匹配任意单个路径段
+func matchPathPrefix(prefix, segments []string) bool {
+	if len(segments) < len(prefix) {
+		return false
+	}
+	for i, segment := range prefix {
+		if segment != "*" && segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidatePatches 校验每个 patch 的操作类型和路径，返回所有不合法的 patch
+func (a PathAllowlist) ValidatePatches(ops []PatchOp) error {
+	var invalid []string
+	for _, op := range ops {
+		switch {
+		case !allowedOps[op.Op]:
+			invalid = append(invalid, fmt.Sprintf("%s %s (op not allowed)", op.Op, op.Path))
+		case !a.Allows(op.Path):
+			invalid = append(invalid, fmt.Sprintf("%s %s (path not allowed)", op.Op, op.Path))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("patch_content contains disallowed operations: %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
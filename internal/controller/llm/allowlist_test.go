@@ -0,0 +1,47 @@
+package llm
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PathAllowlist", func() {
+	DescribeTable("matching paths against the allowlist",
+		func(allowlist PathAllowlist, path string, allowed bool) {
+			Expect(allowlist.Allows(path)).To(Equal(allowed))
+		},
+		Entry("replicas", DefaultPathAllowlist, "/spec/replicas", true),
+		Entry("container resources", DefaultPathAllowlist, "/spec/template/spec/containers/0/resources/limits/cpu", true),
+		Entry("container image", DefaultPathAllowlist, "/spec/template/spec/containers/0/image", false),
+		Entry("metadata name", DefaultPathAllowlist, "/metadata/name", false),
+		Entry("prefix must end at a segment boundary", DefaultPathAllowlist, "/spec/replicasX", false),
+		Entry("restart annotations when restart is allowed", AllowlistForActions([]string{"restart"}),
+			"/spec/template/metadata/annotations/kubectl.kubernetes.io~1restartedAt", true),
+		Entry("replicas when only restart is allowed", AllowlistForActions([]string{"restart"}), "/spec/replicas", false),
+	)
+
+	DescribeTable("mapping paths to action types",
+		func(path string, actions []string) {
+			Expect(ActionsForPath(path)).To(Equal(actions))
+		},
+		Entry("replicas", "/spec/replicas", []string{"scale"}),
+		Entry("container resources", "/spec/template/spec/containers/1/resources/requests/memory", []string{"resource"}),
+		Entry("env", "/spec/template/spec/containers/0/env/0/value", []string{"config", "feature-toggle"}),
+		Entry("image", "/spec/template/spec/containers/0/image", nil),
+	)
+
+	It("should reject the whole response and list the offending paths", func() {
+		_, err := ParseAutoHealResponse(`{
+  "action": "heal",
+  "reason": "升级镜像",
+  "patch_content": [
+    {"op": "replace", "path": "/spec/replicas", "value": 3},
+    {"op": "replace", "path": "/spec/template/spec/containers/0/image", "value": "nginx:latest"},
+    {"op": "move", "path": "/spec/replicas", "from": "/spec/minReplicas"}
+  ],
+  "risk_level": "low"
+}`, DefaultPathAllowlist)
+		Expect(err).To(MatchError(ContainSubstring("/spec/template/spec/containers/0/image (path not allowed)")))
+		Expect(err).To(MatchError(ContainSubstring("move /spec/replicas (op not allowed)")))
+	})
+})
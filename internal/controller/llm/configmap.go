@@ -0,0 +1,32 @@
+package llm
+
+import "fmt"
+
+// IsConfigMapPath 判断补丁是否修改 ConfigMap 的 /data 或 /binaryData
+func IsConfigMapPath(path string) bool {
+	return PathAllowlist(ConfigMapPaths).Allows(path)
+}
+
+// HasConfigMapPatches 判断补丁中是否包含 ConfigMap 修改
+func HasConfigMapPatches(ops []PatchOp) bool {
+	for _, op := range ops {
+		if IsConfigMapPath(op.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateConfigMapPatches 修改 ConfigMap 时必须给出 config_map，没有 ConfigMap 补丁时不能要求重启
+func validateConfigMapPatches(heal *HealAction) error {
+	hasConfigMap := HasConfigMapPatches(heal.PatchContent)
+	switch {
+	case hasConfigMap && heal.ConfigMap == "":
+		return fmt.Errorf("config_map is required when patching /data or /binaryData")
+	case !hasConfigMap && heal.ConfigMap != "":
+		return fmt.Errorf("config_map %q is set but patch_content does not modify /data or /binaryData", heal.ConfigMap)
+	case !hasConfigMap && heal.RestartWorkload:
+		return fmt.Errorf("restart_workload requires a ConfigMap patch")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConfigMap patches", func() {
+	configAllowlist := AllowlistForActions([]string{"config"})
+
+	It("should append the restart patch when restart_workload is set", func() {
+		result, err := ParseAutoHealResponse(`{
+  "action": "heal",
+  "reason": "连接池过小",
+  "patch_content": [{"op": "replace", "path": "/data/DB_POOL_SIZE", "value": "50"}],
+  "target": {"kind": "Deployment", "labelSelector": "app=order"},
+  "config_map": "order-config",
+  "restart_workload": true,
+  "risk_level": "medium"
+}`, configAllowlist)
+		Expect(err).NotTo(HaveOccurred())
+		heal := result.(*HealAction)
+		Expect(heal.ConfigMap).To(Equal("order-config"))
+		Expect(heal.PatchContent).To(HaveLen(2))
+		Expect(heal.PatchContent[0].Path).To(Equal("/data/DB_POOL_SIZE"))
+		Expect(heal.PatchContent[1].Path).To(Equal(RestartAnnotationPath))
+		Expect(ActionsForPath("/data/DB_POOL_SIZE")).To(Equal([]string{"config"}))
+	})
+
+	It("should not duplicate a restart patch given by the model", func() {
+		result, err := ParseAutoHealResponse(`{
+  "action": "heal",
+  "reason": "连接池过小",
+  "patch_content": [
+    {"op": "replace", "path": "/data/DB_POOL_SIZE", "value": "50"},
+    {"op": "add", "path": "/spec/template/metadata/annotations/kubectl.kubernetes.io~1restartedAt", "value": "2025-01-01T00:00:00Z"}
+  ],
+  "config_map": "order-config",
+  "restart_workload": true,
+  "risk_level": "medium"
+}`, AllowlistForActions([]string{"config", "restart"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.(*HealAction).PatchContent).To(HaveLen(2))
+	})
+
+	DescribeTable("rejecting inconsistent ConfigMap fields",
+		func(response, message string) {
+			_, err := ParseAutoHealResponse(response, configAllowlist)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("missing config_map",
+			`{"action": "heal", "reason": "r", "risk_level": "low", "patch_content": [{"op": "replace", "path": "/data/A", "value": "1"}]}`,
+			"config_map is required"),
+		Entry("config_map without ConfigMap patches",
+			`{"action": "heal", "reason": "r", "risk_level": "low", "config_map": "order-config", "patch_content": [{"op": "add", "path": "/spec/template/spec/containers/0/env/-", "value": {"name": "A", "value": "1"}}]}`,
+			"does not modify /data"),
+		Entry("restart_workload without ConfigMap patches",
+			`{"action": "heal", "reason": "r", "risk_level": "low", "restart_workload": true, "patch_content": [{"op": "add", "path": "/spec/template/spec/containers/0/env/-", "value": {"name": "A", "value": "1"}}]}`,
+			"restart_workload requires"),
+	)
+
+	It("should reject ConfigMap patches when config is not allowed", func() {
+		_, err := ParseAutoHealResponse(`{"action": "heal", "reason": "r", "risk_level": "low", "config_map": "order-config", "patch_content": [{"op": "replace", "path": "/data/A", "value": "1"}]}`, DefaultPathAllowlist)
+		Expect(err).To(MatchError(ContainSubstring("/data/A (path not allowed)")))
+	})
+})
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ContainersPath Pod 模板中容器列表的 JSON Pointer
+const ContainersPath = "/spec/template/spec/containers"
+
+// containerNamePrefix 按名称引用容器的路径段前缀，如 /spec/template/spec/containers/name=app/resources
+// 容器顺序变化（如注入 sidecar）后下标会失效，名称引用由 Operator 在创建 PR 时按被修改的清单换算为下标
+const containerNamePrefix = "name="
+
+var containerSegmentPattern = regexp.MustCompile(`^` + ContainersPath + `/([^/]+)(/.*)?$`)
+
+// validateContainerRefs 容器路径段必须是下标、"-"（追加）或 name=<合法的容器名>
+func validateContainerRefs(ops []PatchOp) error {
+	var invalid []string
+	for _, op := range ops {
+		match := containerSegmentPattern.FindStringSubmatch(op.Path)
+		if match == nil || match[1] == "-" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(match[1], containerNamePrefix); ok {
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				invalid = append(invalid, fmt.Sprintf("%s (invalid container name: %s)", op.Path, strings.Join(errs, ", ")))
+			}
+			continue
+		}
+		if index, err := strconv.Atoi(match[1]); err != nil || index < 0 {
+			invalid = append(invalid, fmt.Sprintf("%s (container must be an index or %s<container>)", op.Path, containerNamePrefix))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid container references: %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package llm
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Container references", func() {
+	DescribeTable("validating container references in heal responses",
+		func(path, message string) {
+			_, err := ParseAutoHealResponse(fmt.Sprintf(
+				`{"action":"heal","reason":"OOM","patch_content":[{"op":"replace","path":%q,"value":"2Gi"}],"risk_level":"low"}`, path), DefaultPathAllowlist)
+			if message == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			}
+		},
+		Entry("index", "/spec/template/spec/containers/1/resources/limits/memory", ""),
+		Entry("name", "/spec/template/spec/containers/name=app/resources/limits/memory", ""),
+		Entry("invalid name", "/spec/template/spec/containers/name=App_1/resources/limits/memory", "invalid container name"),
+		Entry("bare name", "/spec/template/spec/containers/app/resources/limits/memory", "container must be an index or name=<container>"),
+	)
+})
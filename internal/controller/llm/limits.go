@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// HealLimits 修复建议中数值的上限，为零的字段不检查
+type HealLimits struct {
+	MaxReplicas int32
+	MaxCPU      resource.Quantity
+	MaxMemory   resource.Quantity
+	MaxPatches  int
+}
+
+// DefaultHealLimits 生产环境的硬上限，与系统提示词中的要求一致
+var DefaultHealLimits = HealLimits{
+	MaxReplicas: 100,
+	MaxCPU:      resource.MustParse("8"),
+	MaxMemory:   resource.MustParse("16Gi"),
+	MaxPatches:  10,
+}
+
+var (
+	// 副本数类字段，包括 HPA 的 min/maxReplicas
+	replicaCountPathPattern = regexp.MustCompile(`^/spec/(replicas|minReplicas|maxReplicas)$`)
+	// 容器的 resources、resources/limits、resources/limits/cpu 等
+	containerResourcesPathPattern = regexp.MustCompile(`^/spec/template/spec/(containers|initContainers)/[^/]+/resources(/(limits|requests)(/[^/]+)?)?$`)
+	// 相对值（如 "+50%"），由 controller 按线上当前值换算
+	relativeValuePattern = regexp.MustCompile(`^[+-]\d+(\.\d+)?%$`)
+)
+
+// Tighten 返回两者中更严格的上限
+func (l HealLimits) Tighten(other HealLimits) HealLimits {
+	if other.MaxReplicas > 0 && (l.MaxReplicas == 0 || other.MaxReplicas < l.MaxReplicas) {
+		l.MaxReplicas = other.MaxReplicas
+	}
+	if !other.MaxCPU.IsZero() && (l.MaxCPU.IsZero() || other.MaxCPU.Cmp(l.MaxCPU) < 0) {
+		l.MaxCPU = other.MaxCPU
+	}
+	if !other.MaxMemory.IsZero() && (l.MaxMemory.IsZero() || other.MaxMemory.Cmp(l.MaxMemory) < 0) {
+		l.MaxMemory = other.MaxMemory
+	}
+	if other.MaxPatches > 0 && (l.MaxPatches == 0 || other.MaxPatches < l.MaxPatches) {
+		l.MaxPatches = other.MaxPatches
+	}
+	return l
+}
+
+// ValidateHealAction 检查补丁数量、同一路径上的冲突补丁，以及副本数和容器 CPU、内存补丁是否超过上限，返回所有越界的补丁
+// 相对值（如 "+50%"）换算前无法判断，跳过检查；解析阶段不做取值校验，由 controller 在换算相对值后统一调用，越界时记录为 noop
+func ValidateHealAction(heal *HealAction, limits HealLimits) error {
+	if limits.MaxPatches > 0 && len(heal.PatchContent) > limits.MaxPatches {
+		return fmt.Errorf("too many patches: %d exceeds the maximum of %d", len(heal.PatchContent), limits.MaxPatches)
+	}
+	if conflicts := conflictingPatches(heal.PatchContent); len(conflicts) > 0 {
+		return fmt.Errorf("conflicting patches on the same or nested paths: %s", strings.Join(conflicts, "; "))
+	}
+
+	var violations []string
+	for _, op := range heal.PatchContent {
+		if op.Op == "remove" {
+			continue
+		}
+		switch {
+		case replicaCountPathPattern.MatchString(op.Path):
+			if reason := checkReplicas(op.Value, limits.MaxReplicas); reason != "" {
+				violations = append(violations, fmt.Sprintf("%s: %s", op.Path, reason))
+			}
+		case containerResourcesPathPattern.MatchString(op.Path):
+			violations = append(violations, checkResources(op.Path, op.Value, limits)...)
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("patch values out of range: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// conflictingPatches 返回修改同一路径或嵌套路径（如 /spec/template 与 /spec/template/spec/replicas）的补丁及各自的操作，
+// 按首次出现的顺序排列；以 "/-" 结尾的路径是向数组末尾追加，多次追加不冲突，但与修改整个数组的补丁冲突
+func conflictingPatches(ops []PatchOp) []string {
+	var paths []string
+	opsByPath := map[string][]string{}
+	for _, op := range ops {
+		if _, ok := opsByPath[op.Path]; !ok {
+			paths = append(paths, op.Path)
+		}
+		opsByPath[op.Path] = append(opsByPath[op.Path], op.Op)
+	}
+	describe := func(path string) string {
+		return fmt.Sprintf("%s (%s)", path, strings.Join(opsByPath[path], ", "))
+	}
+
+	var conflicts []string
+	for i, path := range paths {
+		if len(opsByPath[path]) > 1 && !strings.HasSuffix(path, "/-") {
+			conflicts = append(conflicts, describe(path))
+		}
+		for _, other := range paths[i+1:] {
+			parent, child := path, other
+			if strings.HasPrefix(parent, child+"/") {
+				parent, child = child, parent
+			}
+			if strings.HasPrefix(child, parent+"/") {
+				conflicts = append(conflicts, describe(parent)+" contains "+describe(child))
+			}
+		}
+	}
+	return conflicts
+}
+
+func checkReplicas(value any, max int32) string {
+	n, ok := value.(float64)
+	if !ok {
+		return fmt.Sprintf("replicas must be a number, got %v", value)
+	}
+	if n < 0 || n != float64(int64(n)) {
+		return fmt.Sprintf("invalid replicas %v", value)
+	}
+	if max > 0 && n > float64(max) {
+		return fmt.Sprintf("replicas %v exceeds the maximum of %d", value, max)
+	}
+	return ""
+}
+
+// checkResources 递归检查 resources 对象或单个资源量
+func checkResources(path string, value any, limits HealLimits) []string {
+	if object, ok := value.(map[string]any); ok {
+		var violations []string
+		for key, child := range object {
+			violations = append(violations, checkResources(path+"/"+key, child, limits)...)
+		}
+		return violations
+	}
+
+	var max resource.Quantity
+	switch path[strings.LastIndex(path, "/")+1:] {
+	case "cpu":
+		max = limits.MaxCPU
+	case "memory":
+		max = limits.MaxMemory
+	default:
+		return nil
+	}
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return []string{fmt.Sprintf("%s: invalid quantity %v", path, value)}
+	}
+	if relativeValuePattern.MatchString(strings.TrimSpace(s)) {
+		return nil
+	}
+	quantity, err := resource.ParseQuantity(s)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: invalid quantity %q", path, s)}
+	}
+	if !max.IsZero() && quantity.Cmp(max) > 0 {
+		return []string{fmt.Sprintf("%s: %s exceeds the maximum of %s", path, s, max.String())}
+	}
+	return nil
+}
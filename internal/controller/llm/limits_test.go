@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("ValidateHealAction", func() {
+	const heal = `{"action":"heal","reason":"调整","patch_content":[{"op":"replace","path":%q,"value":%s}],"risk_level":"low"}`
+	const memoryPath = "/spec/template/spec/containers/0/resources/limits/memory"
+
+	// validate 解析修复建议后按生产硬上限校验，解析阶段不检查取值上限
+	validate := func(path, value string) error {
+		parsed, err := ParseAutoHealResponse(fmt.Sprintf(heal, path, value), DefaultPathAllowlist)
+		Expect(err).NotTo(HaveOccurred())
+		return ValidateHealAction(parsed.(*HealAction), DefaultHealLimits)
+	}
+
+	It("should reject replicas above the production cap", func() {
+		Expect(validate("/spec/replicas", "5000")).To(MatchError(ContainSubstring("replicas 5000 exceeds the maximum of 100")))
+		Expect(validate("/spec/replicas", "100")).To(Succeed())
+	})
+
+	It("should reject memory above the production cap", func() {
+		Expect(validate(memoryPath, `"64Gi"`)).To(MatchError(ContainSubstring("64Gi exceeds the maximum of 16Gi")))
+		Expect(validate("/spec/template/spec/containers/0/resources", `{"limits":{"cpu":16,"memory":"1Gi"}}`)).
+			To(MatchError(ContainSubstring("resources/limits/cpu: 16 exceeds the maximum of 8")))
+	})
+
+	It("should skip relative values until they are resolved", func() {
+		Expect(validate(memoryPath, `"+50%"`)).To(Succeed())
+	})
+
+	It("should apply the tighter of two limits", func() {
+		limits := DefaultHealLimits.Tighten(HealLimits{MaxMemory: resource.MustParse("2Gi"), MaxCPU: resource.MustParse("32")})
+		Expect(limits.MaxReplicas).To(Equal(int32(100)))
+		Expect(limits.MaxCPU.String()).To(Equal("8"))
+		Expect(limits.MaxMemory.String()).To(Equal("2Gi"))
+
+		err := ValidateHealAction(&HealAction{PatchContent: []PatchOp{{Op: "replace", Path: memoryPath, Value: "4Gi"}}}, limits)
+		Expect(err).To(MatchError(ContainSubstring("4Gi exceeds the maximum of 2Gi")))
+	})
+
+	It("should reject more patches than the limit", func() {
+		ops := make([]PatchOp, 0, 11)
+		for i := range 11 {
+			ops = append(ops, PatchOp{Op: "replace", Path: fmt.Sprintf("/spec/template/spec/containers/%d/image", i), Value: "app:v2"})
+		}
+		err := ValidateHealAction(&HealAction{PatchContent: ops}, DefaultHealLimits)
+		Expect(err).To(MatchError("too many patches: 11 exceeds the maximum of 10"))
+
+		limits := DefaultHealLimits.Tighten(HealLimits{MaxPatches: 2})
+		Expect(limits.MaxPatches).To(Equal(2))
+		Expect(ValidateHealAction(&HealAction{PatchContent: ops[:2]}, limits)).To(Succeed())
+		Expect(ValidateHealAction(&HealAction{PatchContent: ops[:3]}, limits)).To(MatchError(ContainSubstring("3 exceeds the maximum of 2")))
+	})
+
+	It("should name the conflicting paths", func() {
+		err := ValidateHealAction(&HealAction{PatchContent: []PatchOp{
+			{Op: "replace", Path: "/spec/replicas", Value: float64(3)},
+			{Op: "replace", Path: memoryPath, Value: "2Gi"},
+			{Op: "replace", Path: "/spec/replicas", Value: float64(5)},
+			{Op: "remove", Path: memoryPath},
+		}}, DefaultHealLimits)
+		Expect(err).To(MatchError("conflicting patches on the same or nested paths: " +
+			"/spec/replicas (replace, replace); " + memoryPath + " (replace, remove)"))
+	})
+
+	It("should reject patches on a path and its parent", func() {
+		err := ValidateHealAction(&HealAction{PatchContent: []PatchOp{
+			{Op: "replace", Path: memoryPath, Value: "2Gi"},
+			{Op: "replace", Path: "/spec/template/spec/containers/0/resources", Value: map[string]any{}},
+		}}, DefaultHealLimits)
+		Expect(err).To(MatchError("conflicting patches on the same or nested paths: " +
+			"/spec/template/spec/containers/0/resources (replace) contains " + memoryPath + " (replace)"))
+
+		err = ValidateHealAction(&HealAction{PatchContent: []PatchOp{
+			{Op: "add", Path: "/spec/template/spec/containers/0/env/-", Value: map[string]any{"name": "A", "value": "1"}},
+			{Op: "replace", Path: "/spec/template/spec/containers/0/env", Value: []any{}},
+		}}, DefaultHealLimits)
+		Expect(err).To(MatchError(ContainSubstring("/spec/template/spec/containers/0/env (replace) contains /spec/template/spec/containers/0/env/- (add)")))
+
+		// 前缀相同但不是父路径的不冲突
+		Expect(ValidateHealAction(&HealAction{PatchContent: []PatchOp{
+			{Op: "replace", Path: "/spec/template/spec/containers/1/image", Value: "app:v2"},
+			{Op: "replace", Path: "/spec/template/spec/containers/10/image", Value: "app:v2"},
+		}}, DefaultHealLimits)).To(Succeed())
+	})
+
+	It("should allow appending to the same array more than once", func() {
+		err := ValidateHealAction(&HealAction{PatchContent: []PatchOp{
+			{Op: "add", Path: "/spec/template/spec/containers/0/env/-", Value: map[string]any{"name": "A", "value": "1"}},
+			{Op: "add", Path: "/spec/template/spec/containers/0/env/-", Value: map[string]any{"name": "B", "value": "2"}},
+		}}, DefaultHealLimits)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
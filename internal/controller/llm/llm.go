@@ -3,46 +3,240 @@ package llm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+// 未配置时使用的默认值
+const (
+	DefaultBaseURL = "https://api.siliconflow.cn/v1"
+	DefaultModel   = "Qwen/Qwen2.5-72B-Instruct"
+	DefaultTimeout = 2 * time.Minute
+)
+
+// OpenAIConfig 大模型服务的配置，Provider 决定使用哪种接口
+type OpenAIConfig struct {
+	// Provider 为空时按 OpenAI 兼容接口处理
+	Provider Provider
+	APIKey   string
+	// azure 时为资源地址（如 https://my-resource.openai.azure.com），ollama 时为服务地址
+	BaseURL string
+	// azure 时为部署名称
+	Model string
+	// APIVersion azure 的 api-version 查询参数，为空时使用 DefaultAzureAPIVersion
+	APIVersion string
+	// 单次请求的超时时间
+	Timeout time.Duration
+	// DisableJSONMode 不发送 response_format: json_object，用于不支持该参数的服务
+	DisableJSONMode bool
+}
+
+// ConfigFromEnv 从环境变量 LLM_PROVIDER、LLM_API_KEY、LLM_BASE_URL、LLM_MODEL、LLM_API_VERSION、LLM_DISABLE_JSON_MODE 读取配置，
+// 未设置的字段使用默认值
+func ConfigFromEnv() OpenAIConfig {
+	disableJSONMode, _ := strconv.ParseBool(os.Getenv("LLM_DISABLE_JSON_MODE"))
+	return OpenAIConfig{
+		Provider:        Provider(os.Getenv("LLM_PROVIDER")),
+		APIVersion:      os.Getenv("LLM_API_VERSION"),
+		APIKey:          os.Getenv("LLM_API_KEY"),
+		BaseURL:         os.Getenv("LLM_BASE_URL"),
+		Model:           os.Getenv("LLM_MODEL"),
+		DisableJSONMode: disableJSONMode,
+	}
+}
+
+// Usage 一次请求消耗的 token 数
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// SendMessageResult 大模型的原始响应和 token 用量
+type SendMessageResult struct {
+	Content string
+	// Usage 服务端未返回用量时为零值
+	Usage Usage
+}
+
+// LLMClient 大模型客户端，controller 只依赖该接口，便于替换实现和单元测试
+type LLMClient interface {
+	// SendMessage 发送分析内容并返回大模型的原始响应
+	SendMessage(ctx context.Context, content string) (SendMessageResult, error)
+}
+
+// StreamingLLMClient 支持流式响应的大模型客户端，分析耗时较长时可以记录进度
+type StreamingLLMClient interface {
+	LLMClient
+	// SendMessageStream 每收到一段内容调用 onDelta，返回完整响应
+	SendMessageStream(ctx context.Context, content string, onDelta func(string)) (SendMessageResult, error)
+}
+
+// SystemPrompter 可以按 CR 替换系统提示词的客户端
+type SystemPrompter interface {
+	// WithSystemPrompt 返回使用 prompt 的客户端副本，不影响原客户端
+	WithSystemPrompt(prompt string) LLMClient
+}
+
+// Pinger 可以低成本检查服务是否可达的客户端，用于依赖健康检查（aiops_dependency_up 指标和可选的就绪检查）
+type Pinger interface {
+	// Ping 不调用模型，只确认服务可达且凭据有效
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ StreamingLLMClient = (*OpenAI)(nil)
+	_ SystemPrompter     = (*OpenAI)(nil)
+	_ Pinger             = (*OpenAI)(nil)
+)
+
 type OpenAI struct {
 	Client *openai.Client
-	ctx    context.Context
+	Model  string
+	// SystemPrompt 系统提示词模板，为空时使用 DefaultSystemPrompt
+	SystemPrompt string
+	// JSONMode 请求 JSON 对象格式的输出，服务端不保证时仍由 RepairJSON 兜底
+	JSONMode bool
+	// 遇到限流或服务端临时错误时的重试策略
+	Retry RetryPolicy
 }
 
-func NewOpenAIClient() (*OpenAI, error) {
-	apiKey := "sk-lqcuebxcbfrtrwlckktalpvvsnwxomdneswvuhytfqoookrw"
-	config := openai.DefaultConfig(apiKey)
-	config.BaseURL = "https://api.siliconflow.cn/v1"
-	client := openai.NewClientWithConfig(config)
+// NewOpenAIClient 创建大模型客户端，APIKey 为空时返回错误
+func NewOpenAIClient(cfg OpenAIConfig) (*OpenAI, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("llm api key is empty: set spec.llm.credentialsRef or LLM_API_KEY")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultModel
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
 
-	ctx := context.Background()
+	config := openai.DefaultConfig(cfg.APIKey)
+	config.BaseURL = cfg.BaseURL
+	config.HTTPClient = &http.Client{Timeout: cfg.Timeout}
+	client := openai.NewClientWithConfig(config)
 
 	return &OpenAI{
-		Client: client,
-		ctx:    ctx,
+		Client:   client,
+		Model:    cfg.Model,
+		JSONMode: !cfg.DisableJSONMode,
+		Retry:    DefaultRetryPolicy,
 	}, nil
 }
 
+// Ping 调用 models 接口，不消耗 token
+func (o *OpenAI) Ping(ctx context.Context) error {
+	_, err := o.Client.ListModels(ctx)
+	return err
+}
+
+// WithSystemPrompt 返回使用 prompt 作为系统提示词的副本
+func (o *OpenAI) WithSystemPrompt(prompt string) LLMClient {
+	c := *o
+	c.SystemPrompt = prompt
+	return &c
+}
+
 // SendMessage 发送消息到 LLM 并返回原始字符串响应
-func (o *OpenAI) SendMessage(content string) (string, error) {
-	prompt := `你是一个拥有 10 年 Kubernetes 生产运维经验的资深 SRE，目前负责一个严格使用 ArgoCD + Kustomize + GitOps 的集群。
-你正在执行全自动 AIOps 自愈闭环，你只能通过生成 JSON 6902 Patch + target 选择器来修改资源，禁止任何其他方式。
-
-### 严格要求（必须 100% 遵守，否则自愈失败）：
-1. 只能使用 RFC6902 JSON Patch 格式
-2. 必须使用 target + labelSelector 定位资源，严禁写死 metadata.name
-3. 只允许修改 Deployment、StatefulSet、HorizontalPodAutoscaler
-4. 扩容时必须同时提升 requests 和 limits，防止 CPU Throttling
-5. 所有数值必须是合理生产值（replicas ≤ 100，CPU ≤ 8，内存 ≤ 16Gi）
-6. patch_file 字段必须使用当前真实时间戳 + 简短英文描述，格式严格为：YYYYMMDD-HHMMSS-short-desc.yaml
-   - 当前时间（北京时间）：20251126-204733
-   - 示例：20251126-204733-cpu-spike.yaml
-7. 输出必须是合法的 JSON，禁止任何解释、markdown、换行符外的文字`
+// ctx 取消或超过 Retry.MaxElapsed 时请求和重试等待都会立即结束
+func (o *OpenAI) SendMessage(ctx context.Context, content string) (SendMessageResult, error) {
+	ctx, cancel := o.Retry.withDeadline(ctx)
+	defer cancel()
+	req, err := o.chatRequest(content)
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+	resp, err := o.createChatCompletion(ctx, req)
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return SendMessageResult{}, errors.New("no response from OpenAI")
+	}
+
+	return SendMessageResult{Content: resp.Choices[0].Message.Content, Usage: toUsage(resp.Usage)}, nil
+}
+
+// SendMessageStream 以流式方式发送消息，每收到一段内容调用 onDelta（可以为空），返回拼接后的完整响应
+// 只在建立连接时按 Retry 重试，已经收到内容后中途出错直接返回错误，整个调用不超过 Retry.MaxElapsed
+// 用量在最后一个分片中返回（stream_options.include_usage）
+func (o *OpenAI) SendMessageStream(ctx context.Context, content string, onDelta func(string)) (SendMessageResult, error) {
+	ctx, cancel := o.Retry.withDeadline(ctx)
+	defer cancel()
+	req, err := o.chatRequest(content)
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := o.createChatCompletionStream(ctx, req)
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+	defer stream.Close()
+
+	var b strings.Builder
+	var usage Usage
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return SendMessageResult{}, fmt.Errorf("receive stream failed after %d bytes: %w", b.Len(), err)
+		}
+		if chunk.Usage != nil {
+			usage = toUsage(*chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		b.WriteString(delta)
+		if onDelta != nil {
+			onDelta(delta)
+		}
+	}
+
+	if b.Len() == 0 {
+		return SendMessageResult{}, errors.New("no response from OpenAI")
+	}
+	return SendMessageResult{Content: b.String(), Usage: usage}, nil
+}
+
+func toUsage(usage openai.Usage) Usage {
+	return Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// chatRequest 构造包含系统提示词的请求，提示词中的 {{.Now}} 渲染为当前时间
+func (o *OpenAI) chatRequest(content string) (openai.ChatCompletionRequest, error) {
+	systemPrompt := o.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+	prompt, err := RenderSystemPrompt(systemPrompt, time.Now())
+	if err != nil {
+		return openai.ChatCompletionRequest{}, err
+	}
 	req := openai.ChatCompletionRequest{
-		Model: "Qwen/Qwen2.5-72B-Instruct",
+		Model: o.Model,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    "system",
@@ -54,15 +248,34 @@ func (o *OpenAI) SendMessage(content string) (string, error) {
 			},
 		},
 	}
-
-	resp, err := o.Client.CreateChatCompletion(o.ctx, req)
-	if err != nil {
-		return "", err
+	if o.JSONMode {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
 	}
+	return req, nil
+}
 
-	if len(resp.Choices) == 0 {
-		return "", errors.New("no response from OpenAI")
+// createChatCompletion 按 Retry 重试 429/5xx 和网络错误，其他错误立即返回
+func (o *OpenAI) createChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := o.Client.CreateChatCompletion(ctx, req)
+		if err == nil || attempt >= o.Retry.MaxAttempts || !isRetryable(err) {
+			return resp, err
+		}
+		if err := sleep(ctx, o.Retry.backoff(attempt)); err != nil {
+			return resp, err
+		}
 	}
+}
 
-	return resp.Choices[0].Message.Content, nil
+// createChatCompletionStream 与 createChatCompletion 相同的重试策略，只作用于建立流
+func (o *OpenAI) createChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error) {
+	for attempt := 1; ; attempt++ {
+		stream, err := o.Client.CreateChatCompletionStream(ctx, req)
+		if err == nil || attempt >= o.Retry.MaxAttempts || !isRetryable(err) {
+			return stream, err
+		}
+		if err := sleep(ctx, o.Retry.backoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
 }
@@ -0,0 +1,44 @@
+package llm
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("NewOpenAIClient", func() {
+	It("should refuse to create a client without an api key", func() {
+		_, err := NewOpenAIClient(OpenAIConfig{})
+		Expect(err).To(MatchError(ContainSubstring("api key is empty")))
+	})
+
+	It("should apply defaults for the base url and model", func() {
+		client, err := NewOpenAIClient(OpenAIConfig{APIKey: "sk-test"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Model).To(Equal(DefaultModel))
+	})
+
+	It("should read the configuration from the environment", func() {
+		GinkgoT().Setenv("LLM_API_KEY", "sk-env")
+		GinkgoT().Setenv("LLM_BASE_URL", "https://llm.example.com/v1")
+		GinkgoT().Setenv("LLM_MODEL", "gpt-4o")
+		Expect(ConfigFromEnv()).To(Equal(OpenAIConfig{APIKey: "sk-env", BaseURL: "https://llm.example.com/v1", Model: "gpt-4o"}))
+	})
+
+	It("should request JSON object output unless disabled", func() {
+		client, err := NewOpenAIClient(OpenAIConfig{APIKey: "sk-test"})
+		Expect(err).NotTo(HaveOccurred())
+		req, err := client.chatRequest("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.ResponseFormat).NotTo(BeNil())
+		Expect(req.ResponseFormat.Type).To(Equal(openai.ChatCompletionResponseFormatTypeJSONObject))
+
+		GinkgoT().Setenv("LLM_API_KEY", "sk-env")
+		GinkgoT().Setenv("LLM_DISABLE_JSON_MODE", "true")
+		client, err = NewOpenAIClient(ConfigFromEnv())
+		Expect(err).NotTo(HaveOccurred())
+		req, err = client.chatRequest("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.ResponseFormat).To(BeNil())
+	})
+})
@@ -0,0 +1,66 @@
+// Package llmtest 提供不访问网络的大模型客户端，用于单元测试
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+var (
+	_ llm.LLMClient      = (*FakeLLMClient)(nil)
+	_ llm.SystemPrompter = (*FakeLLMClient)(nil)
+)
+
+// FakeLLMClient 按顺序返回预设的响应
+type FakeLLMClient struct {
+	mu sync.Mutex
+
+	// Responses 依次返回的响应，用完后一直返回最后一个
+	Responses []string
+	// Err 不为空时 SendMessage 直接返回该错误
+	Err error
+	// Usage 每次响应附带的 token 用量
+	Usage llm.Usage
+	// Requests 记录收到的请求内容
+	Requests []string
+	// SystemPrompt 最近一次 WithSystemPrompt 设置的提示词
+	SystemPrompt string
+}
+
+// WithSystemPrompt 记录提示词并返回自身，便于检查请求
+func (f *FakeLLMClient) WithSystemPrompt(prompt string) llm.LLMClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.SystemPrompt = prompt
+	return f
+}
+
+// NewFakeLLMClient 创建依次返回 responses 的客户端
+func NewFakeLLMClient(responses ...string) *FakeLLMClient {
+	return &FakeLLMClient{Responses: responses}
+}
+
+// SendMessage 记录请求内容并返回下一个预设响应
+func (f *FakeLLMClient) SendMessage(ctx context.Context, content string) (llm.SendMessageResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Requests = append(f.Requests, content)
+	if err := ctx.Err(); err != nil {
+		return llm.SendMessageResult{}, err
+	}
+	if f.Err != nil {
+		return llm.SendMessageResult{}, f.Err
+	}
+	if len(f.Responses) == 0 {
+		return llm.SendMessageResult{}, errors.New("llmtest: no canned response")
+	}
+	return llm.SendMessageResult{
+		Content: f.Responses[min(len(f.Requests), len(f.Responses))-1],
+		Usage:   f.Usage,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ollama 未配置时使用的默认值
+const (
+	DefaultOllamaBaseURL = "http://127.0.0.1:11434"
+	DefaultOllamaModel   = "qwen2.5:14b"
+)
+
+var (
+	_ LLMClient      = (*Ollama)(nil)
+	_ SystemPrompter = (*Ollama)(nil)
+	_ Pinger         = (*Ollama)(nil)
+)
+
+// Ollama 通过 /api/chat 调用本地模型，不需要 API Key
+type Ollama struct {
+	HTTPClient *http.Client
+	BaseURL    string
+	Model      string
+	// SystemPrompt 系统提示词模板，为空时使用 DefaultSystemPrompt
+	SystemPrompt string
+	// JSONMode 请求 format: json，模型不保证时仍由 RepairJSON 兜底
+	JSONMode bool
+	// 遇到限流或服务端临时错误时的重试策略
+	Retry RetryPolicy
+}
+
+// NewOllamaClient 创建 Ollama 客户端，BaseURL、Model 为空时使用默认值
+func NewOllamaClient(cfg OpenAIConfig) (*Ollama, error) {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOllamaBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultOllamaModel
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Ollama{
+		HTTPClient: &http.Client{Timeout: cfg.Timeout},
+		BaseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		Model:      cfg.Model,
+		JSONMode:   !cfg.DisableJSONMode,
+		Retry:      DefaultRetryPolicy,
+	}, nil
+}
+
+// WithSystemPrompt 返回使用 prompt 作为系统提示词的副本
+func (o *Ollama) WithSystemPrompt(prompt string) LLMClient {
+	c := *o
+	c.SystemPrompt = prompt
+	return &c
+}
+
+// Ping 调用 /api/tags 列出本地模型，不加载模型
+func (o *Ollama) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return &statusError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	return nil
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   string          `json:"format,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+// statusError 非 OpenAI SDK 的实现返回的非 200 响应
+type statusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("llm returned %d: %s", e.StatusCode, e.Body)
+}
+
+// SendMessage 调用 /api/chat（非流式）并返回原始字符串响应，按 Retry 重试 429/5xx 和网络错误
+// 所有尝试加起来不超过 Retry.MaxElapsed
+func (o *Ollama) SendMessage(ctx context.Context, content string) (SendMessageResult, error) {
+	systemPrompt := o.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+	prompt, err := RenderSystemPrompt(systemPrompt, time.Now())
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+	req := ollamaChatRequest{
+		Model: o.Model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: content},
+		},
+	}
+	if o.JSONMode {
+		req.Format = "json"
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+
+	ctx, cancel := o.Retry.withDeadline(ctx)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		result, err := o.chat(ctx, body)
+		if err == nil || attempt >= o.Retry.MaxAttempts || !isRetryable(err) {
+			return result, err
+		}
+		if err := sleep(ctx, o.Retry.backoff(attempt)); err != nil {
+			return result, err
+		}
+	}
+}
+
+func (o *Ollama) chat(ctx context.Context, body []byte) (SendMessageResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.BaseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return SendMessageResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return SendMessageResult{}, &statusError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	var chat ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return SendMessageResult{}, fmt.Errorf("decode ollama response failed: %w", err)
+	}
+	if chat.Message.Content == "" {
+		return SendMessageResult{}, errors.New("no response from Ollama")
+	}
+	return SendMessageResult{
+		Content: chat.Message.Content,
+		Usage: Usage{
+			PromptTokens:     chat.PromptEvalCount,
+			CompletionTokens: chat.EvalCount,
+			TotalTokens:      chat.PromptEvalCount + chat.EvalCount,
+		},
+	}, nil
+}
@@ -3,8 +3,13 @@ package llm
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+var parseLog = logf.Log.WithName("llm-parse")
+
 type PatchOp struct {
 	Op    string `json:"op"`
 	Path  string `json:"path"`
@@ -19,7 +24,7 @@ type Target struct {
 // heal 时的完整结构体
 type HealAction struct {
 	Namespace         string    `json:"namespace"`
-	Action            string    `json:"action"` // 一定是 "heal"
+	Action            string    `json:"action"` // "heal" 或 "restart"
 	Reason            string    `json:"reason"`
 	Detail            string    `json:"detail"`
 	PatchFile         string    `json:"patch_file"`
@@ -27,12 +32,21 @@ type HealAction struct {
 	Target            Target    `json:"target"`
 	SuggestedDuration string    `json:"suggested_duration"`
 	RiskLevel         string    `json:"risk_level"`
+	Severity          string    `json:"severity,omitempty"` // 当前问题的严重程度，可选
+	// Confidence 对诊断和修复方案的把握（0~1），可选
+	Confidence *float64 `json:"confidence,omitempty"`
+	// ConfigMap 修改 /data、/binaryData 时补丁作用的 ConfigMap 名称
+	ConfigMap string `json:"config_map,omitempty"`
+	// RestartWorkload 修改 ConfigMap 后是否滚动重启目标工作负载使配置生效
+	RestartWorkload bool `json:"restart_workload,omitempty"`
 }
 
-// noop 时的结构体（只有两个字段）
+// noop 时的结构体，detail 和 severity 可选
 type NoopAction struct {
-	Action string `json:"action"` // 一定是 "noop"
-	Reason string `json:"reason"`
+	Action   string `json:"action"` // 一定是 "noop"
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail,omitempty"`   // 不处理的详细说明
+	Severity string `json:"severity,omitempty"` // 当前问题的严重程度
 }
 
 // ---------- 通用的解析函数 ----------
@@ -44,7 +58,20 @@ func ParseJSONTo(jsonStr string, target any) error {
 }
 
 // ---------- 主解析逻辑 ----------
-func ParseAutoHealResponse(jsonStr string) (any, error) {
+// allowlist 限制 heal 响应中 patch 可以修改的路径
+func ParseAutoHealResponse(jsonStr string, allowlist PathAllowlist) (any, error) {
+	// 严格解析失败时才尝试宽松修复，并记录日志以便发现模型输出漂移
+	if !json.Valid([]byte(jsonStr)) {
+		repaired := RepairJSON(jsonStr)
+		if !json.Valid([]byte(repaired)) {
+			var v any
+			err := json.Unmarshal([]byte(jsonStr), &v)
+			return nil, fmt.Errorf("parse base failed: %w", err)
+		}
+		parseLog.Info("大模型返回的JSON不合法，已自动修复", "original", jsonStr)
+		jsonStr = repaired
+	}
+
 	// 第一步：先只解析 action 和 reason，判断是哪种响应
 	type base struct {
 		Action string `json:"action"`
@@ -57,15 +84,35 @@ func ParseAutoHealResponse(jsonStr string) (any, error) {
 	}
 
 	switch b.Action {
-	case "heal":
+	case "heal", "restart":
 		var heal HealAction
 		if err := ParseJSONTo(jsonStr, &heal); err != nil {
 			return nil, err
 		}
+		// 重启由 Operator 生成修改重启注解的补丁，忽略大模型给出的补丁
+		if b.Action == "restart" {
+			heal.PatchContent = []PatchOp{RestartPatch(time.Now())}
+		}
 		// 可选：在这里做严格校验
 		if heal.RiskLevel != "low" && heal.RiskLevel != "medium" && heal.RiskLevel != "high" {
 			return nil, fmt.Errorf("invalid risk_level: %s", heal.RiskLevel)
 		}
+		if heal.Confidence != nil && (*heal.Confidence < 0 || *heal.Confidence > 1) {
+			return nil, fmt.Errorf("invalid confidence: %v", *heal.Confidence)
+		}
+		if err := allowlist.ValidatePatches(heal.PatchContent); err != nil {
+			return nil, err
+		}
+		if err := validateContainerRefs(heal.PatchContent); err != nil {
+			return nil, err
+		}
+		if err := validateConfigMapPatches(&heal); err != nil {
+			return nil, err
+		}
+		// 重启补丁随配置变更一起生成，不需要 restart 修复类型；大模型已给出重启注解补丁时不再重复添加
+		if heal.RestartWorkload && HasConfigMapPatches(heal.PatchContent) && !hasPatchPath(heal.PatchContent, RestartAnnotationPath) {
+			heal.PatchContent = append(heal.PatchContent, RestartPatch(time.Now()))
+		}
 		return &heal, nil
 
 	case "noop":
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultSystemPrompt 默认的系统提示词，{{.Now}} 渲染为当前北京时间（YYYYMMDD-HHMMSS）
+const DefaultSystemPrompt = `你是一个拥有 10 年 Kubernetes 生产运维经验的资深 SRE，目前负责一个严格使用 ArgoCD + Kustomize + GitOps 的集群。
+你正在执行全自动 AIOps 自愈闭环，你只能通过生成 JSON 6902 Patch + target 选择器来修改资源，禁止任何其他方式。
+
+### 严格要求（必须 100% 遵守，否则自愈失败）：
+1. 只能使用 RFC6902 JSON Patch 格式
+2. 必须使用 target + labelSelector 定位资源，严禁写死 metadata.name
+3. 只允许修改 Deployment、StatefulSet、HorizontalPodAutoscaler；HorizontalPodAutoscaler 只能修改 /spec/minReplicas、/spec/maxReplicas、/spec/metrics
+4. 扩容时必须同时提升 requests 和 limits，防止 CPU Throttling
+5. 所有数值必须是合理生产值（replicas ≤ 100，CPU ≤ 8，内存 ≤ 16Gi）；一次最多 10 个补丁，同一路径只能出现在一个补丁中
+6. patch_file 字段必须使用当前真实时间戳 + 简短英文描述，格式严格为：YYYYMMDD-HHMMSS-short-desc.yaml
+   - 当前时间（北京时间）：{{.Now}}
+   - 示例：{{.Now}}-cpu-spike.yaml
+7. 输出必须是合法的 JSON，禁止任何解释、markdown、换行符外的文字
+8. 调整单个 CPU/内存 requests 或 limits 时可以使用相对值，如 "+50%"、"-20%"，由 Operator 按当前值换算
+9. 不需要自愈时输出 noop，可以附带 detail（不处理的原因和依据）和 severity（none/low/medium/high）
+10. 输出 heal 时给出 confidence（0~1 的小数），表示对诊断结论和修复方案的把握，证据不足时如实给出较低的值
+11. 修改容器时使用 name=<容器名> 引用容器，如 /spec/template/spec/containers/name=app/resources/limits/cpu，避免 sidecar 导致下标错位`
+
+// beijing 提示词中的时间使用北京时间，镜像中不一定带时区数据
+var beijing = time.FixedZone("CST", 8*60*60)
+
+// promptData 系统提示词模板可以使用的变量
+type promptData struct {
+	// Now 当前北京时间，格式 YYYYMMDD-HHMMSS
+	Now string
+}
+
+// RenderSystemPrompt 渲染系统提示词模板
+func RenderSystemPrompt(prompt string, now time.Time) (string, error) {
+	tmpl, err := template.New("system").Option("missingkey=error").Parse(prompt)
+	if err != nil {
+		return "", fmt.Errorf("parse system prompt failed: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, promptData{Now: now.In(beijing).Format("20060102-150405")}); err != nil {
+		return "", fmt.Errorf("render system prompt failed: %w", err)
+	}
+	return b.String(), nil
+}
+
+// ValidateSystemPrompt 自定义系统提示词必须是可渲染的模板，并且要求大模型只输出 JSON
+func ValidateSystemPrompt(prompt string) error {
+	if strings.TrimSpace(prompt) == "" {
+		return errors.New("system prompt is empty")
+	}
+	if _, err := RenderSystemPrompt(prompt, time.Now()); err != nil {
+		return err
+	}
+	if !strings.Contains(strings.ToLower(prompt), "json") {
+		return errors.New("system prompt must ask for JSON-only output")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("System prompt", func() {
+	now := time.Date(2026, 3, 1, 4, 5, 6, 0, time.UTC)
+
+	It("should inject the current Beijing time into the default prompt", func() {
+		prompt, err := RenderSystemPrompt(DefaultSystemPrompt, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompt).To(ContainSubstring("当前时间（北京时间）：20260301-120506"))
+		Expect(prompt).To(ContainSubstring("示例：20260301-120506-cpu-spike.yaml"))
+		Expect(prompt).NotTo(ContainSubstring("20251126"))
+		Expect(ValidateSystemPrompt(DefaultSystemPrompt)).To(Succeed())
+	})
+
+	DescribeTable("validating overrides",
+		func(prompt, expectErr string) {
+			err := ValidateSystemPrompt(prompt)
+			if expectErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectErr)))
+			}
+		},
+		Entry("json-only prompt", "只输出合法的 JSON，当前时间 {{.Now}}", ""),
+		Entry("empty prompt", "  ", "empty"),
+		Entry("prompt without json", "给出修复建议", "JSON-only"),
+		Entry("broken template", "只输出 JSON {{.Now", "parse system prompt"),
+		Entry("unknown variable", "只输出 JSON {{.Cluster}}", "render system prompt"),
+	)
+
+	It("should use the override for requests", func() {
+		client, err := NewOpenAIClient(OpenAIConfig{APIKey: "sk-test"})
+		Expect(err).NotTo(HaveOccurred())
+
+		overridden := client.WithSystemPrompt("只输出 JSON").(*OpenAI)
+		req, err := overridden.chatRequest("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Messages[0].Content).To(Equal("只输出 JSON"))
+
+		req, err = client.chatRequest("hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Messages[0].Content).To(HavePrefix("你是一个拥有 10 年 Kubernetes 生产运维经验的资深 SRE"))
+	})
+})
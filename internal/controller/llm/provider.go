@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Provider 大模型服务的接口类型
+type Provider string
+
+const (
+	// ProviderOpenAI OpenAI 兼容接口（OpenAI、SiliconFlow 等），默认值
+	ProviderOpenAI Provider = "openai-compatible"
+	// ProviderAzure Azure OpenAI，按部署名称拼接地址并带 api-version 参数
+	ProviderAzure Provider = "azure"
+	// ProviderOllama 本地 Ollama 的 /api/chat 接口
+	ProviderOllama Provider = "ollama"
+)
+
+// DefaultAzureAPIVersion 支持 response_format 的 Azure OpenAI API 版本
+const DefaultAzureAPIVersion = "2024-06-01"
+
+// NewLLMClient 按 cfg.Provider 创建大模型客户端，Provider 为空时使用 OpenAI 兼容接口
+func NewLLMClient(cfg OpenAIConfig) (LLMClient, error) {
+	switch cfg.Provider {
+	case "", ProviderOpenAI:
+		return NewOpenAIClient(cfg)
+	case ProviderAzure:
+		return NewAzureOpenAIClient(cfg)
+	case ProviderOllama:
+		return NewOllamaClient(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported llm provider %q: must be one of %s, %s, %s",
+			cfg.Provider, ProviderOpenAI, ProviderAzure, ProviderOllama)
+	}
+}
+
+// NewAzureOpenAIClient 创建 Azure OpenAI 客户端，请求地址为 <BaseURL>/openai/deployments/<Model>/chat/completions?api-version=<APIVersion>
+// 除地址和认证方式外与 OpenAI 兼容接口相同，BaseURL 和 Model（部署名称）必须配置
+func NewAzureOpenAIClient(cfg OpenAIConfig) (*OpenAI, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("llm api key is empty: set spec.llm.credentialsRef or LLM_API_KEY")
+	}
+	if cfg.BaseURL == "" || cfg.Model == "" {
+		return nil, errors.New("azure openai requires LLM_BASE_URL (resource endpoint) and LLM_MODEL (deployment name)")
+	}
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = DefaultAzureAPIVersion
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	config := openai.DefaultAzureConfig(cfg.APIKey, cfg.BaseURL)
+	config.APIVersion = cfg.APIVersion
+	// 部署名称原样使用，SDK 默认会去掉模型名中的 "." 和 ":"
+	config.AzureModelMapperFunc = func(model string) string { return model }
+	config.HTTPClient = &http.Client{Timeout: cfg.Timeout}
+
+	return &OpenAI{
+		Client:   openai.NewClientWithConfig(config),
+		Model:    cfg.Model,
+		JSONMode: !cfg.DisableJSONMode,
+		Retry:    DefaultRetryPolicy,
+	}, nil
+}
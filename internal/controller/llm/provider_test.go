@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewLLMClient", func() {
+	It("should default to the OpenAI-compatible client", func() {
+		client, err := NewLLMClient(OpenAIConfig{APIKey: "sk-test"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client).To(BeAssignableToTypeOf(&OpenAI{}))
+	})
+
+	It("should reject unknown providers", func() {
+		_, err := NewLLMClient(OpenAIConfig{Provider: "bedrock", APIKey: "sk-test"})
+		Expect(err).To(MatchError(ContainSubstring(`unsupported llm provider "bedrock"`)))
+	})
+
+	It("should call the Azure deployment with the api-version", func() {
+		var path, apiVersion, apiKey string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, apiVersion, apiKey = r.URL.Path, r.URL.Query().Get("api-version"), r.Header.Get("api-key")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+		}))
+		DeferCleanup(server.Close)
+
+		_, err := NewLLMClient(OpenAIConfig{Provider: ProviderAzure, APIKey: "az-key"})
+		Expect(err).To(MatchError(ContainSubstring("deployment name")))
+
+		client, err := NewLLMClient(OpenAIConfig{Provider: ProviderAzure, APIKey: "az-key", BaseURL: server.URL, Model: "gpt-4o.prod"})
+		Expect(err).NotTo(HaveOccurred())
+		result, err := client.SendMessage(context.Background(), "hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Content).To(Equal("ok"))
+		Expect(path).To(Equal("/openai/deployments/gpt-4o.prod/chat/completions"))
+		Expect(apiVersion).To(Equal(DefaultAzureAPIVersion))
+		Expect(apiKey).To(Equal("az-key"))
+	})
+
+	It("should call the Ollama chat endpoint without an api key", func() {
+		var req ollamaChatRequest
+		var path string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"{\"action\":\"noop\"}"},"done":true,"prompt_eval_count":120,"eval_count":8}`))
+		}))
+		DeferCleanup(server.Close)
+
+		client, err := NewLLMClient(OpenAIConfig{Provider: ProviderOllama, BaseURL: server.URL + "/", Model: "qwen2.5:7b"})
+		Expect(err).NotTo(HaveOccurred())
+		result, err := client.(SystemPrompter).WithSystemPrompt("只输出 JSON").SendMessage(context.Background(), "hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(SendMessageResult{
+			Content: `{"action":"noop"}`,
+			Usage:   Usage{PromptTokens: 120, CompletionTokens: 8, TotalTokens: 128},
+		}))
+		Expect(path).To(Equal("/api/chat"))
+		Expect(req).To(Equal(ollamaChatRequest{
+			Model:    "qwen2.5:7b",
+			Messages: []ollamaMessage{{Role: "system", Content: "只输出 JSON"}, {Role: "user", Content: "hello"}},
+			Format:   "json",
+		}))
+	})
+
+	It("should retry Ollama server errors", func() {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"ok"}}`))
+		}))
+		DeferCleanup(server.Close)
+
+		client, err := NewOllamaClient(OpenAIConfig{BaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		client.Retry = RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+		result, err := client.SendMessage(context.Background(), "hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Content).To(Equal("ok"))
+		Expect(calls).To(Equal(2))
+	})
+})
+
+var _ = Describe("Ping", func() {
+	It("should list models without calling the chat endpoint", func() {
+		var paths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"object":"list","data":[],"models":[]}`))
+		}))
+		DeferCleanup(server.Close)
+
+		openAI, err := NewOpenAIClient(OpenAIConfig{APIKey: "sk-test", BaseURL: server.URL + "/v1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(openAI.Ping(context.Background())).To(Succeed())
+		ollama, err := NewOllamaClient(OpenAIConfig{BaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ollama.Ping(context.Background())).To(Succeed())
+		Expect(paths).To(Equal([]string{"/v1/models", "/api/tags"}))
+	})
+
+	It("should report non-200 responses as errors", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		DeferCleanup(server.Close)
+
+		ollama, err := NewOllamaClient(OpenAIConfig{BaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ollama.Ping(context.Background())).To(MatchError(ContainSubstring("llm returned 503")))
+	})
+})
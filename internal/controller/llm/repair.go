@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"strings"
+)
+
+// RepairJSON 修复大模型常见的"几乎合法"的 JSON：
+// markdown 代码块、JSON 前后的解释文字、// 和 /* */ 注释、单引号字符串、尾随逗号
+// 只做宽松修复，结果仍需严格解析
+func RepairJSON(s string) string {
+	s = extractJSONObject(s)
+	s = normalizeQuotesAndComments(s)
+	return removeTrailingCommas(s)
+}
+
+// extractJSONObject 截取第一个 { 到最后一个 } 之间的内容，去掉代码块标记和解释文字
+func extractJSONObject(s string) string {
+	start := strings.Index(s, "{")
+	end := strings.LastIndex(s, "}")
+	if start < 0 || end < start {
+		return strings.TrimSpace(s)
+	}
+	return s[start : end+1]
+}
+
+// normalizeQuotesAndComments 删除字符串之外的注释，并把单引号字符串改为双引号
+func normalizeQuotesAndComments(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '"' || c == '\'':
+			i = copyString(&b, runes, i)
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			if i < len(runes) {
+				b.WriteRune('\n')
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// copyString 从 runes[start] 处的引号开始，以双引号字符串的形式写入 b，返回结束引号的位置
+func copyString(b *strings.Builder, runes []rune, start int) int {
+	quote := runes[start]
+	b.WriteRune('"')
+	for i := start + 1; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\' && i+1 < len(runes):
+			// 单引号字符串中的 \' 在 JSON 中不需要转义
+			if quote == '\'' && runes[i+1] == '\'' {
+				b.WriteRune('\'')
+			} else {
+				b.WriteRune(c)
+				b.WriteRune(runes[i+1])
+			}
+			i++
+		case c == quote:
+			b.WriteRune('"')
+			return i
+		case c == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return len(runes)
+}
+
+// removeTrailingCommas 删除 } 或 ] 之前多余的逗号，输入中的字符串必须已是双引号
+func removeTrailingCommas(s string) string {
+	var b strings.Builder
+	inString := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			next := strings.TrimLeft(s[i+1:], " \t\r\n")
+			if strings.HasPrefix(next, "}") || strings.HasPrefix(next, "]") {
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
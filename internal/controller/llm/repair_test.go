@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RepairJSON", func() {
+	DescribeTable("repairing common malformations",
+		func(input string) {
+			result, err := ParseAutoHealResponse(input, DefaultPathAllowlist)
+			Expect(err).NotTo(HaveOccurred())
+			noop, ok := result.(*NoopAction)
+			Expect(ok).To(BeTrue())
+			Expect(noop.Reason).To(Equal("当前指标正常"))
+		},
+		Entry("trailing comma", `{"action": "noop", "reason": "当前指标正常",}`),
+		Entry("single quotes", `{'action': 'noop', 'reason': '当前指标正常'}`),
+		Entry("line comment", "{\n  // 无需处理\n  \"action\": \"noop\",\n  \"reason\": \"当前指标正常\"\n}"),
+		Entry("block comment", `{"action": "noop", /* 无需处理 */ "reason": "当前指标正常"}`),
+		Entry("markdown code fence", "```json\n{\"action\": \"noop\", \"reason\": \"当前指标正常\"}\n```"),
+		Entry("explanation around the JSON", "结论如下：{\"action\": \"noop\", \"reason\": \"当前指标正常\"} 以上。"),
+	)
+
+	It("should keep string contents untouched", func() {
+		repaired := RepairJSON(`{'reason': 'it\'s "fine", // not a comment',}`)
+		Expect(repaired).To(Equal(`{"reason": "it's \"fine\", // not a comment"}`))
+	})
+
+	It("should repair trailing commas inside patch arrays", func() {
+		result, err := ParseAutoHealResponse(`{
+  "action": "heal",
+  "reason": "扩容",
+  "patch_content": [
+    {"op": "replace", "path": "/spec/replicas", "value": 3,},
+  ],
+  "risk_level": "low",
+}`, DefaultPathAllowlist)
+		Expect(err).NotTo(HaveOccurred())
+		heal, ok := result.(*HealAction)
+		Expect(ok).To(BeTrue())
+		Expect(heal.PatchContent).To(HaveLen(1))
+	})
+
+	It("should still fail on input that cannot be repaired", func() {
+		_, err := ParseAutoHealResponse(`not json at all`, DefaultPathAllowlist)
+		Expect(err).To(MatchError(ContainSubstring("parse base failed")))
+	})
+})
+
+var _ = Describe("Noop responses", func() {
+	It("should populate the optional detail and severity", func() {
+		result, err := ParseAutoHealResponse(`{"action":"noop","reason":"流量回落","detail":"CPU 已回到 40%","severity":"low"}`, DefaultPathAllowlist)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(&NoopAction{Action: "noop", Reason: "流量回落", Detail: "CPU 已回到 40%", Severity: "low"}))
+	})
+
+	It("should keep the optional fields empty when omitted", func() {
+		result, err := ParseAutoHealResponse(`{"action":"noop","reason":"指标正常"}`, DefaultPathAllowlist)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(&NoopAction{Action: "noop", Reason: "指标正常"}))
+	})
+})
+
+var _ = Describe("Heal confidence", func() {
+	const heal = `{"action":"heal","reason":"扩容","patch_content":[{"op":"replace","path":"/spec/replicas","value":3}],"risk_level":"low"%s}`
+
+	It("should parse an optional confidence", func() {
+		result, err := ParseAutoHealResponse(fmt.Sprintf(heal, `,"confidence":0.85`), DefaultPathAllowlist)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.(*HealAction).Confidence).To(HaveValue(Equal(0.85)))
+
+		result, err = ParseAutoHealResponse(fmt.Sprintf(heal, ""), DefaultPathAllowlist)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.(*HealAction).Confidence).To(BeNil())
+	})
+
+	It("should reject a confidence outside 0..1", func() {
+		_, err := ParseAutoHealResponse(fmt.Sprintf(heal, `,"confidence":85`), DefaultPathAllowlist)
+		Expect(err).To(MatchError(ContainSubstring("invalid confidence")))
+	})
+})
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"strings"
+	"time"
+)
+
+// RestartedAtAnnotation kubectl rollout restart 使用的 Pod 模板注解，值变化时触发滚动重启
+const RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// RestartAnnotationPath 重启注解的 JSON Pointer
+var RestartAnnotationPath = "/spec/template/metadata/annotations/" + EscapeJSONPointer(RestartedAtAnnotation)
+
+// EscapeJSONPointer 按 RFC6901 转义单个路径段：~ 转为 ~0，/ 转为 ~1
+func EscapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// RestartPatch 把重启注解设置为 now（RFC3339，UTC），通过 GitOps 同步后触发滚动重启
+// 使用 add：注解已存在时覆盖，不存在时新增
+func RestartPatch(now time.Time) PatchOp {
+	return PatchOp{Op: "add", Path: RestartAnnotationPath, Value: now.UTC().Format(time.RFC3339)}
+}
+
+// hasPatchPath 补丁中是否已有修改 path 的操作
+func hasPatchPath(ops []PatchOp, path string) bool {
+	for _, op := range ops {
+		if op.Path == path {
+			return true
+		}
+	}
+	return false
+}
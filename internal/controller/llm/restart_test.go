@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Restart action", func() {
+	It("should escape the annotation key in the JSON pointer", func() {
+		Expect(EscapeJSONPointer("a~b/c")).To(Equal("a~0b~1c"))
+
+		op := RestartPatch(time.Date(2025, 11, 26, 20, 45, 55, 0, beijing))
+		Expect(op).To(Equal(PatchOp{
+			Op:    "add",
+			Path:  "/spec/template/metadata/annotations/kubectl.kubernetes.io~1restartedAt",
+			Value: "2025-11-26T12:45:55Z",
+		}))
+	})
+
+	It("should generate the restart patch and ignore patches from the LLM", func() {
+		result, err := ParseAutoHealResponse(`{
+  "action": "restart",
+  "reason": "连接池耗尽",
+  "patch_content": [{"op": "replace", "path": "/spec/replicas", "value": 3}],
+  "target": {"kind": "Deployment", "labelSelector": "app=order"},
+  "risk_level": "low"
+}`, AllowlistForActions([]string{"restart"}))
+		Expect(err).NotTo(HaveOccurred())
+		heal := result.(*HealAction)
+		Expect(heal.Action).To(Equal("restart"))
+		Expect(heal.PatchContent).To(HaveLen(1))
+		Expect(heal.PatchContent[0].Path).To(Equal(RestartAnnotationPath))
+		_, err = time.Parse(time.RFC3339, heal.PatchContent[0].Value.(string))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ActionsForPath(RestartAnnotationPath)).To(Equal([]string{"restart"}))
+	})
+
+	It("should reject a restart when restart is not allowed", func() {
+		_, err := ParseAutoHealResponse(`{"action": "restart", "reason": "连接池耗尽", "risk_level": "low"}`, DefaultPathAllowlist)
+		Expect(err).To(MatchError(ContainSubstring("kubectl.kubernetes.io~1restartedAt (path not allowed)")))
+	})
+})
@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// RetryPolicy 调用大模型失败时的重试策略
+type RetryPolicy struct {
+	// 最多尝试次数（包含第一次），不大于 1 时不重试
+	MaxAttempts int
+	// 第一次重试前的等待时间，之后每次翻倍
+	InitialBackoff time.Duration
+	// 单次等待时间上限
+	MaxBackoff time.Duration
+	// 所有尝试和等待加起来的时长上限，避免单次超时乘以重试次数长时间占住 worker，不大于 0 时不限制
+	MaxElapsed time.Duration
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	MaxElapsed:     3 * time.Minute,
+}
+
+// withDeadline 按 MaxElapsed 给整个调用（包括所有重试）加上截止时间
+func (p RetryPolicy) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.MaxElapsed <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.MaxElapsed)
+}
+
+// backoff 第 attempt 次重试前的等待时间：指数退避加随机抖动（full jitter）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff << (attempt - 1)
+	if d <= 0 || d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// retryableStatusCodes 限流和服务端临时错误
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+}
+
+// isRetryable 判断错误是否值得重试：429/5xx 以及非超时、非取消的网络错误
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatusCodes[apiErr.HTTPStatusCode]
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return retryableStatusCodes[reqErr.HTTPStatusCode]
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return retryableStatusCodes[statusErr.StatusCode]
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// sleep 等待 d，ctx 取消时立即返回错误
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
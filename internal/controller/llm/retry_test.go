@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SendMessage retries", func() {
+	var (
+		server   *httptest.Server
+		calls    int32
+		failures []int
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&calls, 0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := int(atomic.AddInt32(&calls, 1))
+			w.Header().Set("Content-Type", "application/json")
+			if n <= len(failures) {
+				w.WriteHeader(failures[n-1])
+				_, _ = w.Write([]byte(`{"error":{"message":"try again","type":"server_error"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func() *OpenAI {
+		client, err := NewOpenAIClient(OpenAIConfig{APIKey: "sk-test", BaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		client.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+		return client
+	}
+
+	It("should retry rate limits and server errors", func() {
+		failures = []int{http.StatusTooManyRequests, http.StatusBadGateway}
+		result, err := newClient().SendMessage(context.Background(), "hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Content).To(Equal("ok"))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+	})
+
+	It("should give up after the maximum number of attempts", func() {
+		failures = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+		_, err := newClient().SendMessage(context.Background(), "hello")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+	})
+
+	It("should stop waiting for a retry when the context is cancelled", func() {
+		failures = []int{http.StatusTooManyRequests, http.StatusTooManyRequests}
+		client := newClient()
+		client.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := client.SendMessage(ctx, "hello")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+
+	It("should cap the total time spent on retries", func() {
+		failures = []int{http.StatusTooManyRequests, http.StatusTooManyRequests}
+		client := newClient()
+		client.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour, MaxElapsed: 50 * time.Millisecond}
+
+		_, err := client.SendMessage(context.Background(), "hello")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+
+	It("should not retry client errors", func() {
+		failures = []int{http.StatusUnauthorized}
+		_, err := newClient().SendMessage(context.Background(), "hello")
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+})
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// 副本数类字段，改为 0 会让服务下线
+	replicasPathPattern = regexp.MustCompile(`^/spec/(replicas|minReplicas)$`)
+	// 整个 resources 或 limits 对象
+	resourcesPathPattern = regexp.MustCompile(`^/spec/template/spec/(containers|initContainers)/[^/]+/resources$`)
+	limitsPathPattern    = regexp.MustCompile(`^/spec/template/spec/(containers|initContainers)/[^/]+/resources/limits(/[^/]+)?$`)
+)
+
+// DestructiveReason 判断单个 patch 是否具有破坏性，返回原因
+// 破坏性操作包括：任何 remove、把副本数改为 0、删除或清空资源 limits
+func DestructiveReason(op PatchOp) (string, bool) {
+	if op.Op == "remove" {
+		return fmt.Sprintf("remove %s", op.Path), true
+	}
+	if op.Op != "replace" && op.Op != "add" {
+		return "", false
+	}
+
+	switch {
+	case op.Value == nil:
+		return fmt.Sprintf("%s %s to null", op.Op, op.Path), true
+	case replicasPathPattern.MatchString(op.Path):
+		if n, ok := numericValue(op.Value); ok && n <= 0 {
+			return fmt.Sprintf("%s %s to %v", op.Op, op.Path, op.Value), true
+		}
+	case resourcesPathPattern.MatchString(op.Path):
+		resources, ok := op.Value.(map[string]any)
+		if !ok {
+			break
+		}
+		if limits, ok := resources["limits"].(map[string]any); !ok || len(limits) == 0 {
+			return fmt.Sprintf("%s %s without limits", op.Op, op.Path), true
+		}
+	case limitsPathPattern.MatchString(op.Path):
+		if limits, ok := op.Value.(map[string]any); ok && len(limits) == 0 {
+			return fmt.Sprintf("%s %s to empty limits", op.Op, op.Path), true
+		}
+	}
+	return "", false
+}
+
+// numericValue 把各种形式的数字统一成 float64
+// LLM 返回的 JSON 解码后是 float64，但补丁也可能来自 json.Number、整数或字符串 "0"
+func numericValue(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// FindDestructiveOps 返回所有破坏性 patch 的原因
+func FindDestructiveOps(ops []PatchOp) []string {
+	var reasons []string
+	for _, op := range ops {
+		if reason, ok := DestructiveReason(op); ok {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
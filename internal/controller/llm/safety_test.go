@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DestructiveReason", func() {
+	DescribeTable("detecting destructive patch operations",
+		func(op PatchOp, destructive bool) {
+			_, ok := DestructiveReason(op)
+			Expect(ok).To(Equal(destructive))
+		},
+		Entry("remove is always destructive",
+			PatchOp{Op: "remove", Path: "/spec/template/spec/containers/0/env/1"}, true),
+		Entry("scaling to zero",
+			PatchOp{Op: "replace", Path: "/spec/replicas", Value: float64(0)}, true),
+		Entry("scaling to an integer zero",
+			PatchOp{Op: "replace", Path: "/spec/replicas", Value: 0}, true),
+		Entry("scaling to a json.Number zero",
+			PatchOp{Op: "replace", Path: "/spec/replicas", Value: json.Number("0")}, true),
+		Entry("scaling to a string zero",
+			PatchOp{Op: "replace", Path: "/spec/replicas", Value: "0"}, true),
+		Entry("lowering HPA minReplicas to zero",
+			PatchOp{Op: "replace", Path: "/spec/minReplicas", Value: float64(0)}, true),
+		Entry("replacing a value with null",
+			PatchOp{Op: "replace", Path: "/spec/template/spec/containers/0/resources/limits/cpu", Value: nil}, true),
+		Entry("replacing resources without limits",
+			PatchOp{Op: "replace", Path: "/spec/template/spec/containers/0/resources",
+				Value: map[string]any{"requests": map[string]any{"cpu": "500m"}}}, true),
+		Entry("emptying limits",
+			PatchOp{Op: "replace", Path: "/spec/template/spec/containers/0/resources/limits", Value: map[string]any{}}, true),
+		Entry("scaling up",
+			PatchOp{Op: "replace", Path: "/spec/replicas", Value: float64(3)}, false),
+		Entry("scaling up with a string count",
+			PatchOp{Op: "replace", Path: "/spec/replicas", Value: "3"}, false),
+		Entry("raising a cpu limit",
+			PatchOp{Op: "replace", Path: "/spec/template/spec/containers/0/resources/limits/cpu", Value: "2"}, false),
+		Entry("replacing resources with limits",
+			PatchOp{Op: "replace", Path: "/spec/template/spec/containers/0/resources",
+				Value: map[string]any{"limits": map[string]any{"cpu": "2"}}}, false),
+	)
+
+	It("should collect every destructive operation", func() {
+		reasons := FindDestructiveOps([]PatchOp{
+			{Op: "replace", Path: "/spec/replicas", Value: float64(0)},
+			{Op: "replace", Path: "/spec/template/spec/containers/0/resources/limits/cpu", Value: "2"},
+			{Op: "remove", Path: "/spec/template/spec/containers/0/resources/limits"},
+		})
+		Expect(reasons).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SendMessageStream", func() {
+	var (
+		server *httptest.Server
+		chunks []string
+		done   bool
+	)
+
+	BeforeEach(func() {
+		done = true
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, chunk := range chunks {
+				fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+			}
+			if done {
+				fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":120,\"completion_tokens\":8,\"total_tokens\":128}}\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			} else {
+				fmt.Fprint(w, "data: {\"error\":{\"message\":\"upstream reset\",\"type\":\"server_error\"}}\n\n")
+			}
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func() *OpenAI {
+		client, err := NewOpenAIClient(OpenAIConfig{APIKey: "sk-test", BaseURL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		return client
+	}
+
+	It("should reassemble the full response and report each delta", func() {
+		chunks = []string{`{"action":"noop",`, `"reason":"指标正常"}`}
+		var deltas []string
+		sent, err := newClient().SendMessageStream(context.Background(), "hello", func(delta string) {
+			deltas = append(deltas, delta)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deltas).To(Equal(chunks))
+		Expect(sent.Content).To(Equal(strings.Join(chunks, "")))
+		Expect(sent.Usage).To(Equal(Usage{PromptTokens: 120, CompletionTokens: 8, TotalTokens: 128}))
+
+		result, err := ParseAutoHealResponse(sent.Content, AllActionsAllowlist())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeAssignableToTypeOf(&NoopAction{}))
+	})
+
+	It("should surface errors in the middle of the stream", func() {
+		chunks = []string{`{"action":`}
+		done = false
+		_, err := newClient().SendMessageStream(context.Background(), "hello", nil)
+		Expect(err).To(MatchError(ContainSubstring("upstream reset")))
+	})
+
+	It("should fail on an empty stream", func() {
+		chunks = nil
+		_, err := newClient().SendMessageStream(context.Background(), "hello", nil)
+		Expect(err).To(MatchError("no response from OpenAI"))
+	})
+})
@@ -0,0 +1,115 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// 大模型熔断：连续失败 llmFailureThreshold 次后暂停分析，等待时间从 llmBackoffBase 开始每次翻倍，不超过 llmBackoffMax
+const (
+	llmFailureThreshold = 3
+	llmBackoffBase      = time.Minute
+	llmBackoffMax       = 30 * time.Minute
+)
+
+// llmBreaker 按 CR 记录大模型的连续失败次数，零值可用
+type llmBreaker struct {
+	mu       sync.Mutex
+	failures map[types.NamespacedName]llmFailures
+}
+
+type llmFailures struct {
+	count int
+	last  time.Time
+}
+
+// backoff 连续失败 count 次后的等待时间，未达到阈值时为 0
+func (f llmFailures) backoff() time.Duration {
+	if f.count < llmFailureThreshold {
+		return 0
+	}
+	d := llmBackoffBase << (f.count - llmFailureThreshold)
+	if d <= 0 || d > llmBackoffMax {
+		d = llmBackoffMax
+	}
+	return d
+}
+
+// remaining 熔断打开时距离下次允许调用的时间，未熔断或已到期时为 0
+func (b *llmBreaker) remaining(key types.NamespacedName, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f := b.failures[key]
+	if remaining := f.last.Add(f.backoff()).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// failure 记录一次失败，返回连续失败次数和熔断等待时间
+func (b *llmBreaker) failure(key types.NamespacedName, now time.Time) (int, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures == nil {
+		b.failures = map[types.NamespacedName]llmFailures{}
+	}
+	f := b.failures[key]
+	f.count++
+	f.last = now
+	b.failures[key] = f
+	return f.count, f.backoff()
+}
+
+// reset 调用成功或 CR 删除后清空失败次数
+func (b *llmBreaker) reset(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+}
+
+// recordLLMCondition 熔断打开时把 LLMAvailable 设为 False，恢复后设为 True
+// 从未熔断过（没有该 condition）时成功调用不写 status
+func (r *AIOpsAnalyzerReconciler) recordLLMCondition(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, failures int, backoff time.Duration, err error) error {
+	condition := metav1.Condition{
+		Type:               autofixv1.ConditionLLMAvailable,
+		Status:             metav1.ConditionTrue,
+		Reason:             autofixv1.ReasonLLMAvailable,
+		Message:            "大模型调用正常",
+		ObservedGeneration: aiopsAnalyzer.Generation,
+	}
+	existing := meta.FindStatusCondition(aiopsAnalyzer.Status.Conditions, condition.Type)
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = autofixv1.ReasonLLMUnavailable
+		condition.Message = fmt.Sprintf("大模型连续 %d 次调用失败，%s 后重试: %v", failures, backoff, err)
+	} else if existing == nil || existing.Status == metav1.ConditionTrue {
+		return nil
+	}
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		meta.SetStatusCondition(&status.Conditions, condition)
+	})
+}
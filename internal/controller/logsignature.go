@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+const (
+	// defaultTopSignatures 未配置 spec.loki.topSignatures 时保留的错误签名数
+	defaultTopSignatures = 10
+	// maxSignatureRunes 单个错误签名最多展示的字符数
+	maxSignatureRunes = 200
+	// signaturePlaceholder 签名中被归一化的内容
+	signaturePlaceholder = "<*>"
+	// lokiSignaturesSection event string 中错误签名统计分段的标题
+	lokiSignaturesSection = "Loki Error Signatures"
+)
+
+// defaultSignatureNormalizers 未配置 spec.loki.signatureNormalizers 时去掉的时间戳、ID、地址和数字（包括 "30ms" 等带单位的数字），按顺序替换
+var defaultSignatureNormalizers = []string{
+	`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`,
+	`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`,
+	`(?i)\b(0x[0-9a-f]+|[0-9a-f]*\d[0-9a-f]*[a-f][0-9a-f]*|[0-9a-f]*[a-f][0-9a-f]*\d[0-9a-f]*)\b`,
+	`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`,
+	`\b\d+(\.\d+)?`,
+}
+
+var signatureSpaces = regexp.MustCompile(`\s+`)
+
+// signatureNormalizer 把日志消息归一化为错误签名
+type signatureNormalizer struct {
+	patterns []*regexp.Regexp
+	top      int
+}
+
+// signatureNormalizerFor 读取 spec.loki 中的归一化正则和保留条数，未配置时使用默认值
+func signatureNormalizerFor(cfg *autofixv1.LokiConfig) (signatureNormalizer, error) {
+	exprs, top := defaultSignatureNormalizers, defaultTopSignatures
+	if cfg != nil {
+		if len(cfg.SignatureNormalizers) > 0 {
+			exprs = cfg.SignatureNormalizers
+		}
+		if cfg.TopSignatures > 0 {
+			top = cfg.TopSignatures
+		}
+	}
+	n := signatureNormalizer{top: top}
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return signatureNormalizer{}, fmt.Errorf("invalid spec.loki.signatureNormalizers %q: %w", expr, err)
+		}
+		n.patterns = append(n.patterns, re)
+	}
+	return n, nil
+}
+
+// Signature 去掉日志行开头的时间戳，依次替换归一化正则命中的内容并合并空白
+func (n signatureNormalizer) Signature(line string) string {
+	signature := logMessage(line)
+	for _, re := range n.patterns {
+		signature = re.ReplaceAllString(signature, signaturePlaceholder)
+	}
+	signature = strings.TrimSpace(signatureSpaces.ReplaceAllString(signature, " "))
+	if runes := []rune(signature); len(runes) > maxSignatureRunes {
+		signature = string(runes[:maxSignatureRunes]) + "..."
+	}
+	return signature
+}
+
+// errorSignatureCount 一个错误签名及其出现次数
+type errorSignatureCount struct {
+	Signature string
+	Count     int
+}
+
+// Histogram 按签名统计所有日志流中的日志行，按出现次数从多到少排列，次数相同时按首次出现的顺序
+func (n signatureNormalizer) Histogram(streams []lokiStreamLogs) []errorSignatureCount {
+	var counts []errorSignatureCount
+	index := map[string]int{}
+	for _, stream := range streams {
+		for _, line := range stream.Lines {
+			signature := n.Signature(line)
+			if signature == "" {
+				continue
+			}
+			i, ok := index[signature]
+			if !ok {
+				i = len(counts)
+				index[signature] = i
+				counts = append(counts, errorSignatureCount{Signature: signature})
+			}
+			counts[i].Count++
+		}
+	}
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].Count > counts[j].Count })
+	return counts
+}
+
+// FormatHistogram 输出出现次数最多的 top 个错误签名，其余的合并为一行说明；没有日志时返回空
+func (n signatureNormalizer) FormatHistogram(streams []lokiStreamLogs) string {
+	counts := n.Histogram(streams)
+	if len(counts) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	shown := min(n.top, len(counts))
+	for _, c := range counts[:shown] {
+		fmt.Fprintf(&b, "%s (x%d)\n", c.Signature, c.Count)
+	}
+	if rest := counts[shown:]; len(rest) > 0 {
+		lines := 0
+		for _, c := range rest {
+			lines += c.Count
+		}
+		fmt.Fprintf(&b, "... %d more signatures (%d lines)\n", len(rest), lines)
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Loki error signatures", func() {
+	// burst 生成 count 行 "纳秒时间戳: 日志"，message 中的 {i} 替换为行号
+	burst := func(message string, count int) []string {
+		lines := make([]string, count)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("17000000000%08d: %s", i, strings.ReplaceAll(message, "{i}", strconv.Itoa(i)))
+		}
+		return lines
+	}
+
+	It("should count lines by normalized signature", func() {
+		normalizer, err := signatureNormalizerFor(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var lines []string
+		lines = append(lines, burst("2025-01-02T03:04:0{i}.123Z ERROR dial tcp 10.0.3.{i}:5432: connect: connection refused", 8)...)
+		lines = append(lines, burst("ERROR request {i} failed: java.lang.NullPointerException at OrderService.java:118", 42)...)
+		lines = append(lines, burst("panic: trace 7f3a9c0e{i} runtime error: index out of range [{i}]", 3)...)
+		out := normalizer.FormatHistogram([]lokiStreamLogs{
+			{Selector: `{app="order"}`, Lines: lines[:30]},
+			{Selector: `{app="ingress"}`, Lines: lines[30:]},
+		})
+
+		Expect(out).To(Equal(
+			"ERROR request <*> failed: java.lang.NullPointerException at OrderService.java:<*> (x42)\n" +
+				"<*> ERROR dial tcp <*>: connect: connection refused (x8)\n" +
+				"panic: trace <*> runtime error: index out of range [<*>] (x3)\n"))
+	})
+
+	It("should keep only the top signatures", func() {
+		normalizer, err := signatureNormalizerFor(&autofixv1.LokiConfig{TopSignatures: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		lines := append(burst("ERROR timeout after {i}0ms", 5), burst("ERROR disk full", 2)...)
+		lines = append(lines, burst("FATAL out of memory", 1)...)
+		Expect(normalizer.FormatHistogram([]lokiStreamLogs{{Lines: lines}})).To(Equal(
+			"ERROR timeout after <*>ms (x5)\n" +
+				"... 2 more signatures (3 lines)\n"))
+	})
+
+	It("should use the configured normalizers instead of the built-in ones", func() {
+		normalizer, err := signatureNormalizerFor(&autofixv1.LokiConfig{SignatureNormalizers: []string{`user=\S+`}})
+		Expect(err).NotTo(HaveOccurred())
+
+		lines := []string{"1: ERROR login failed user=alice attempt 1", "2: ERROR login failed user=bob attempt 1", "3: ERROR login failed user=bob attempt 2"}
+		Expect(normalizer.FormatHistogram([]lokiStreamLogs{{Lines: lines}})).To(Equal(
+			"ERROR login failed <*> attempt 1 (x2)\n" +
+				"ERROR login failed <*> attempt 2 (x1)\n"))
+
+		_, err = signatureNormalizerFor(&autofixv1.LokiConfig{SignatureNormalizers: []string{`(`}})
+		Expect(err).To(MatchError(ContainSubstring("invalid spec.loki.signatureNormalizers")))
+	})
+
+	It("should return nothing without logs", func() {
+		normalizer, err := signatureNormalizerFor(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(normalizer.FormatHistogram([]lokiStreamLogs{{Selector: `{app="order"}`}})).To(BeEmpty())
+	})
+})
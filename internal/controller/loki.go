@@ -0,0 +1,213 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+const (
+	// defaultLokiLogFilter 未配置 spec.loki.logFilter 时只保留错误级别的日志
+	defaultLokiLogFilter = `(?i)(error|panic|fatal|critical)`
+	// defaultLokiLookback 未配置 spec.loki.lookback 时查询的时间范围
+	defaultLokiLookback = 48 * time.Minute
+	// defaultLokiMaxLines 未配置 spec.loki.maxLines 时所有日志流合计保留的行数
+	defaultLokiMaxLines = 200
+	// defaultLokiMaxBytes 未配置 spec.loki.maxBytes 时所有日志流合计保留的字节数
+	defaultLokiMaxBytes = 32 * 1024
+)
+
+// lokiLimits 所有日志流合计保留的行数与字节数
+type lokiLimits struct {
+	MaxLines int
+	MaxBytes int
+}
+
+// lokiLimitsFor 读取 spec.loki 中的上限，未配置时使用默认值
+func lokiLimitsFor(cfg *autofixv1.LokiConfig) lokiLimits {
+	limits := lokiLimits{MaxLines: defaultLokiMaxLines, MaxBytes: defaultLokiMaxBytes}
+	if cfg != nil {
+		if cfg.MaxLines > 0 {
+			limits.MaxLines = cfg.MaxLines
+		}
+		if cfg.MaxBytes > 0 {
+			limits.MaxBytes = cfg.MaxBytes
+		}
+	}
+	return limits
+}
+
+// lokiTargetSelector 构建目标命名空间中匹配 target.selector 的日志流选择器，标签值使用双引号
+// 标签名与 Prometheus 一样按采集端（promtail 等）的惯例把 . / - 等字符替换为 _，否则 LogQL 无法解析
+func lokiTargetSelector(target *autofixv1.TargetSelector) string {
+	matchers := append([]string{fmt.Sprintf("namespace=%q", target.Namespace)},
+		labelSelectorMatchers(target.Selector, sanitizeLabelName)...)
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// lokiQuery 查询的时间范围与日志过滤条件
+type lokiQuery struct {
+	Start  time.Time
+	End    time.Time
+	Filter string
+}
+
+// lokiQueryFor 根据 spec.loki 计算截止到 now 的查询范围，并校验过滤正则
+func lokiQueryFor(cfg *autofixv1.LokiConfig, now time.Time) (lokiQuery, error) {
+	lookback := defaultLokiLookback
+	filter := defaultLokiLogFilter
+	if cfg != nil {
+		if cfg.Lookback != "" {
+			d, err := time.ParseDuration(cfg.Lookback)
+			if err != nil {
+				return lokiQuery{}, fmt.Errorf("invalid spec.loki.lookback %q: %w", cfg.Lookback, err)
+			}
+			lookback = d
+		}
+		if cfg.LogFilter != "" {
+			filter = cfg.LogFilter
+		}
+	}
+	// Loki 与 Go 同样使用 RE2，这里能编译的正则 Loki 也能识别
+	if _, err := regexp.Compile(filter); err != nil {
+		return lokiQuery{}, fmt.Errorf("invalid spec.loki.logFilter %q: %w", filter, err)
+	}
+	return lokiQuery{Start: now.Add(-lookback), End: now, Filter: filter}, nil
+}
+
+// LogQL 在日志流选择器后追加行过滤
+func (q lokiQuery) LogQL(selector string) string {
+	return selector + " |~ " + strconv.Quote(q.Filter)
+}
+
+// lokiStream Loki 查询结果中的一个日志流，values 为 ["纳秒时间戳", "日志行"]
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// parseLokiLines 解析 Loki 查询响应，结果必须是 streams，返回 "时间戳: 日志" 形式的行
+func parseLokiLines(body io.Reader) ([]string, error) {
+	var streams []lokiStream
+	if err := decodeQueryResponse(body, "streams", &streams); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, stream := range streams {
+		for _, value := range stream.Values {
+			lines = append(lines, fmt.Sprintf("%s: %s", value[0], value[1]))
+		}
+	}
+	return lines, nil
+}
+
+// lokiStreamLogs 单个日志流的查询结果
+type lokiStreamLogs struct {
+	Selector string
+	Lines    []string
+	Err      error
+}
+
+// formatLokiStreams 按来源分段输出日志，连续重复的日志合并为一行，所有日志流合计不超过 limits
+// 行数和字节数按日志流平均分配，某个日志流用不完的额度留给后面的日志流
+func formatLokiStreams(streams []lokiStreamLogs, limits lokiLimits) string {
+	// 只有目标自身的日志时保持原有的输出格式
+	if len(streams) == 1 && streams[0].Err == nil {
+		lines, omitted := truncateLines(collapseDuplicateLines(streams[0].Lines), limits.MaxLines, limits.MaxBytes)
+		return joinLogLines(lines, omitted)
+	}
+
+	var b strings.Builder
+	remainingLines, remainingBytes := limits.MaxLines, limits.MaxBytes
+	for i, stream := range streams {
+		fmt.Fprintf(&b, "--- stream: %s ---\n", stream.Selector)
+		if stream.Err != nil {
+			fmt.Fprintf(&b, "query failed: %v\n", stream.Err)
+			continue
+		}
+		if len(stream.Lines) == 0 {
+			b.WriteString("No error logs\n")
+			continue
+		}
+
+		lines, omitted := truncateLines(collapseDuplicateLines(stream.Lines),
+			remainingLines/(len(streams)-i), remainingBytes/(len(streams)-i))
+		remainingLines -= len(lines)
+		for _, line := range lines {
+			remainingBytes -= len(line) + 1
+		}
+		b.WriteString(joinLogLines(lines, omitted))
+	}
+	return b.String()
+}
+
+// collapseDuplicateLines 把消息相同的连续日志合并为第一行，并追加 (xN) 后缀
+// 日志行形如 "时间戳: 消息"，比较时忽略时间戳
+func collapseDuplicateLines(lines []string) []string {
+	var collapsed []string
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && logMessage(lines[j]) == logMessage(lines[i]) {
+			j++
+		}
+		if count := j - i; count > 1 {
+			collapsed = append(collapsed, fmt.Sprintf("%s (x%d)", lines[i], count))
+		} else {
+			collapsed = append(collapsed, lines[i])
+		}
+		i = j
+	}
+	return collapsed
+}
+
+// logMessage 去掉日志行开头的时间戳
+func logMessage(line string) string {
+	if _, message, ok := strings.Cut(line, ": "); ok {
+		return message
+	}
+	return line
+}
+
+// truncateLines 保留前 maxLines 行且不超过 maxBytes 字节（Loki 默认按时间倒序返回，即保留最新的日志）
+func truncateLines(lines []string, maxLines, maxBytes int) ([]string, int) {
+	kept, size := 0, 0
+	for kept < len(lines) && kept < maxLines && size+len(lines[kept])+1 <= maxBytes {
+		size += len(lines[kept]) + 1
+		kept++
+	}
+	return lines[:kept], len(lines) - kept
+}
+
+// joinLogLines 拼接日志行，有截断时追加说明
+func joinLogLines(lines []string, omitted int) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, "... %d more lines omitted\n", omitted)
+	}
+	return b.String()
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Loki stream formatting", func() {
+	lines := func(prefix string, n int) []string {
+		result := make([]string, n)
+		for i := range result {
+			result[i] = fmt.Sprintf("%d: %s %d", i, prefix, i)
+		}
+		return result
+	}
+
+	It("should keep the plain format when there is only the target stream", func() {
+		out := formatLokiStreams([]lokiStreamLogs{{Selector: `{app="demo"}`, Lines: []string{"1: boom"}}}, lokiLimits{MaxLines: 10, MaxBytes: defaultLokiMaxBytes})
+		Expect(out).To(Equal("1: boom\n"))
+	})
+
+	It("should label each stream and bound the total number of lines", func() {
+		out := formatLokiStreams([]lokiStreamLogs{
+			{Selector: `{app="demo"}`, Lines: lines("app error", 10)},
+			{Selector: `{app="ingress"}`, Lines: lines("proxy error", 2)},
+			{Selector: `{app="lb"}`, Err: errors.New("timeout")},
+		}, lokiLimits{MaxLines: 9, MaxBytes: defaultLokiMaxBytes})
+
+		Expect(out).To(ContainSubstring(`--- stream: {app="demo"} ---`))
+		Expect(out).To(ContainSubstring(`--- stream: {app="ingress"} ---`))
+		Expect(out).To(ContainSubstring("query failed: timeout"))
+		Expect(strings.Count(out, "app error")).To(Equal(3))
+		Expect(strings.Count(out, "proxy error")).To(Equal(2))
+		Expect(out).To(ContainSubstring("... 7 more lines omitted"))
+	})
+
+	It("should collapse consecutive duplicate messages", func() {
+		out := formatLokiStreams([]lokiStreamLogs{{Selector: `{app="demo"}`, Lines: []string{
+			"3: connection refused", "2: connection refused", "1: connection refused", "0: boom", "0: connection refused",
+		}}}, lokiLimits{MaxLines: 10, MaxBytes: defaultLokiMaxBytes})
+		Expect(out).To(Equal("3: connection refused (x3)\n0: boom\n0: connection refused\n"))
+	})
+
+	It("should keep the most recent lines within the byte budget", func() {
+		out := formatLokiStreams([]lokiStreamLogs{{Selector: `{app="demo"}`, Lines: lines("boom", 5)}},
+			lokiLimits{MaxLines: 10, MaxBytes: 20})
+		Expect(out).To(Equal("0: boom 0\n1: boom 1\n... 3 more lines omitted\n"))
+	})
+
+	It("should split the byte budget between streams", func() {
+		out := formatLokiStreams([]lokiStreamLogs{
+			{Selector: `{app="demo"}`, Lines: lines("app error", 5)},
+			{Selector: `{app="ingress"}`, Lines: lines("proxy error", 5)},
+		}, lokiLimits{MaxLines: 100, MaxBytes: 68})
+		Expect(strings.Count(out, "app error")).To(Equal(2))
+		Expect(strings.Count(out, "proxy error")).To(Equal(2))
+		Expect(strings.Count(out, "... 3 more lines omitted")).To(Equal(2))
+	})
+})
+
+var _ = Describe("Loki query", func() {
+	now := time.Date(2025, 11, 26, 20, 45, 0, 0, time.UTC)
+
+	It("should select the target streams with matchLabels and matchExpressions", func() {
+		target := &autofixv1.TargetSelector{
+			Namespace: "product-a",
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "order", "env": "prod"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "track", Operator: metav1.LabelSelectorOpIn, Values: []string{"stable", "canary"}},
+					{Key: "region", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"us-east-1"}},
+					{Key: "tier", Operator: metav1.LabelSelectorOpExists},
+					{Key: "debug", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+		}
+		Expect(lokiTargetSelector(target)).To(Equal(
+			`{namespace="product-a",app="order",env="prod",track=~"stable|canary",region!~"us-east-1",tier!="",debug=""}`))
+	})
+
+	It("should sanitize label names that are not valid in LogQL", func() {
+		target := &autofixv1.TargetSelector{
+			Namespace: "product-a",
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"app.kubernetes.io/name": "order-service"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app.kubernetes.io/version", Operator: metav1.LabelSelectorOpIn, Values: []string{"v1.2"}},
+				},
+			},
+		}
+		Expect(lokiTargetSelector(target)).To(Equal(
+			`{namespace="product-a",app_kubernetes_io_name="order-service",app_kubernetes_io_version=~"v1\\.2"}`))
+	})
+
+	It("should default to the last 48 minutes of error logs", func() {
+		query, err := lokiQueryFor(nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.Start).To(Equal(now.Add(-48 * time.Minute)))
+		Expect(query.End).To(Equal(now))
+		Expect(query.LogQL(`{app="demo"}`)).To(Equal(`{app="demo"} |~ "(?i)(error|panic|fatal|critical)"`))
+	})
+
+	It("should use the lookback and filter from the spec", func() {
+		query, err := lokiQueryFor(&autofixv1.LokiConfig{Lookback: "2h", LogFilter: `level="(warn|err)"`}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.Start).To(Equal(now.Add(-2 * time.Hour)))
+		Expect(query.LogQL(`{app="demo"}`)).To(Equal(`{app="demo"} |~ "level=\"(warn|err)\""`))
+	})
+
+	It("should reject a filter that does not compile", func() {
+		_, err := lokiQueryFor(&autofixv1.LokiConfig{LogFilter: "(error"}, now)
+		Expect(err).To(MatchError(ContainSubstring(`invalid spec.loki.logFilter "(error"`)))
+	})
+
+	It("should send the range as nanosecond timestamps", func() {
+		query, err := lokiQueryFor(nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(lokiQueryPath))
+			Expect(req.URL.Query().Get("query")).To(Equal(query.LogQL(`{app="demo"}`)))
+			Expect(req.URL.Query().Get("start")).To(Equal(strconv.FormatInt(query.Start.UnixNano(), 10)))
+			Expect(req.URL.Query().Get("end")).To(Equal(strconv.FormatInt(query.End.UnixNano(), 10)))
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{},"values":[["1","boom"]]}]}}`))
+		}))
+		defer server.Close()
+
+		reconciler := &AIOpsAnalyzerReconciler{}
+		lines, err := reconciler.queryLokiLines(context.Background(), datasources{LokiURL: server.URL}, query, `{app="demo"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(Equal([]string{"1: boom"}))
+	})
+
+	It("should keep the raw logs and skip the signatures when a normalizer does not compile", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{},"values":[["1","boom"]]}]}}`))
+		}))
+		defer server.Close()
+
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{Spec: autofixv1.AIOpsAnalyzerSpec{
+			Target: autofixv1.TargetSelector{Namespace: "default", Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}},
+			Loki:   &autofixv1.LokiConfig{SignatureNormalizers: []string{`(`}},
+		}}
+		reconciler := &AIOpsAnalyzerReconciler{}
+		logs, signatures, err := reconciler.GetLokiLogs(context.Background(), datasources{LokiURL: server.URL}, aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs).To(ContainSubstring("boom"))
+		Expect(signatures).To(BeEmpty())
+	})
+
+	DescribeTable("parsing stream responses",
+		func(body string, expected []string, expectErr string) {
+			lines, err := parseLokiLines(strings.NewReader(body))
+			if expectErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(expectErr)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lines).To(Equal(expected))
+		},
+		Entry("lines from every stream",
+			`{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"pod":"order-0"},"values":[["2","panic: nil map"],["1","error: timeout"]]},
+				{"stream":{"pod":"order-1"},"values":[["3","fatal: oom"]]}]}}`,
+			[]string{"2: panic: nil map", "1: error: timeout", "3: fatal: oom"}, ""),
+		Entry("no streams", `{"status":"success","data":{"resultType":"streams","result":[]}}`, nil, ""),
+		Entry("error status", `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			nil, `query failed with status "error": bad_data: parse error`),
+		Entry("metric query result", `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			nil, `unexpected result type "matrix", expected "streams"`),
+		Entry("values are not strings", `{"status":"success","data":{"resultType":"streams","result":[{"stream":{},"values":[[1,2]]}]}}`,
+			nil, "decode streams result failed"),
+	)
+
+	DescribeTable("sending the tenant header",
+		func(orgID string, expectHeader bool) {
+			var values []string
+			var present bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				values, present = req.Header["X-Scope-Orgid"]
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+			}))
+			defer server.Close()
+
+			query, err := lokiQueryFor(nil, now)
+			Expect(err).NotTo(HaveOccurred())
+			reconciler := &AIOpsAnalyzerReconciler{}
+			_, err = reconciler.queryLokiLines(context.Background(), datasources{LokiURL: server.URL, LokiOrgID: orgID}, query, `{app="demo"}`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(present).To(Equal(expectHeader))
+			if expectHeader {
+				Expect(values).To(Equal([]string{orgID}))
+			}
+		},
+		Entry("sets X-Scope-OrgID for a multi-tenant Loki", "team-a", true),
+		Entry("omits X-Scope-OrgID when no org ID is configured", "", false),
+	)
+})
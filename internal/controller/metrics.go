@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+var (
+	llmPromptTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aiops_llm_prompt_tokens_total",
+		Help: "Prompt tokens sent to the LLM, per AIOpsAnalyzer.",
+	}, []string{"namespace", "name"})
+	llmCompletionTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aiops_llm_completion_tokens_total",
+		Help: "Completion tokens returned by the LLM, per AIOpsAnalyzer.",
+	}, []string{"namespace", "name"})
+
+	analysisTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aiops_analysis_total",
+		Help: "Analyses by outcome: heal, noop or error.",
+	}, []string{"result"})
+	approvalPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "aiops_approval_pending",
+		Help: "Whether the AIOpsAnalyzer has an unanswered approval request (1) or not (0).",
+	}, []string{"namespace", "name"})
+	dependencyUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "aiops_dependency_up",
+		Help: "Whether the default LLM, Prometheus or Loki was reachable at the last check (1) or not (0).",
+	}, []string{"dependency"})
+
+	// 大模型响应通常需要数秒到数分钟
+	llmRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "aiops_llm_request_duration_seconds",
+		Help:    "Duration of LLM requests, including retries.",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+	})
+	prometheusQueryDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "aiops_prometheus_query_duration_seconds",
+		Help:    "Duration of Prometheus alert queries.",
+		Buckets: prometheus.DefBuckets,
+	})
+	lokiQueryDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "aiops_loki_query_duration_seconds",
+		Help:    "Duration of Loki log queries, one per log stream.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+// aiops_analysis_total 的 result 取值
+const (
+	analysisResultHeal  = "heal"
+	analysisResultNoop  = "noop"
+	analysisResultError = "error"
+)
+
+func init() {
+	metrics.Registry.MustRegister(llmPromptTokens, llmCompletionTokens, analysisTotal, approvalPending,
+		dependencyUp, llmRequestDuration, prometheusQueryDuration, lokiQueryDuration)
+}
+
+// observeDuration 记录从 start 到现在的耗时，配合 defer 使用
+func observeDuration(histogram prometheus.Observer, start time.Time) {
+	histogram.Observe(time.Since(start).Seconds())
+}
+
+// recordApprovalPending 按 status.pendingApproval 是否仍在等待审批更新指标
+func recordApprovalPending(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) {
+	pending := 0.0
+	if approval := aiopsAnalyzer.Status.PendingApproval; approval != nil && approval.Approved == nil {
+		pending = 1
+	}
+	approvalPending.WithLabelValues(aiopsAnalyzer.Namespace, aiopsAnalyzer.Name).Set(pending)
+}
+
+// recordLLMUsage 累加 AIOpsAnalyzer 的 token 用量
+func recordLLMUsage(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, usage llm.Usage) {
+	llmPromptTokens.WithLabelValues(aiopsAnalyzer.Namespace, aiopsAnalyzer.Name).Add(float64(usage.PromptTokens))
+	llmCompletionTokens.WithLabelValues(aiopsAnalyzer.Namespace, aiopsAnalyzer.Name).Add(float64(usage.CompletionTokens))
+}
+
+// forgetAnalyzerMetrics AIOpsAnalyzer 删除后清理对应的指标
+func forgetAnalyzerMetrics(namespace, name string) {
+	llmPromptTokens.DeleteLabelValues(namespace, name)
+	llmCompletionTokens.DeleteLabelValues(namespace, name)
+	approvalPending.DeleteLabelValues(namespace, name)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// podNodeNameField 是 Pod 按所在节点建立的索引字段
+const podNodeNameField = "spec.nodeName"
+
+// 需要关注的节点压力类型
+var nodePressureConditions = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+}
+
+// GetNodePressure 汇总目标Pod所在节点的压力状态以及资源分配情况
+// 用于让大模型区分"应用需要扩容"和"集群容量不足，扩容无效"；节点已不存在时标注 (not found)
+func (r *AIOpsAnalyzerReconciler) GetNodePressure(ctx context.Context, pods []corev1.Pod) (string, error) {
+	log := log.FromContext(ctx)
+
+	// 只统计实际运行了目标Pod的节点
+	nodeNames := make(map[string]struct{})
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			nodeNames[pod.Spec.NodeName] = struct{}{}
+		}
+	}
+	if len(nodeNames) == 0 {
+		return "", nil
+	}
+
+	names := make([]string, 0, len(nodeNames))
+	for name := range nodeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var nodeBuilder strings.Builder
+	for _, name := range names {
+		var node corev1.Node
+		if err := r.Get(ctx, types.NamespacedName{Name: name}, &node); err != nil {
+			// 节点故障时节点对象常常已被删除，这正是需要分析的时候，不能因此中断
+			if apierrors.IsNotFound(err) {
+				nodeBuilder.WriteString(fmt.Sprintf("Node: %s (not found)\n", name))
+				continue
+			}
+			log.Error(err, "获取节点失败", "node", name)
+			return "", err
+		}
+
+		// 统计节点上所有未结束Pod的 requests
+		var nodePods corev1.PodList
+		if err := r.List(ctx, &nodePods, client.MatchingFields{podNodeNameField: name}); err != nil {
+			log.Error(err, "获取节点Pod列表失败", "node", name)
+			return "", err
+		}
+		requestedCPU, requestedMemory := sumPodRequests(nodePods.Items)
+
+		nodeBuilder.WriteString(fmt.Sprintf("Node: %s\n", name))
+		nodeBuilder.WriteString(fmt.Sprintf("  Conditions: %s\n", formatNodePressureConditions(&node)))
+		nodeBuilder.WriteString(fmt.Sprintf("  CPU: %s\n",
+			formatAllocation(requestedCPU, node.Status.Allocatable[corev1.ResourceCPU])))
+		nodeBuilder.WriteString(fmt.Sprintf("  Memory: %s\n",
+			formatAllocation(requestedMemory, node.Status.Allocatable[corev1.ResourceMemory])))
+	}
+
+	return nodeBuilder.String(), nil
+}
+
+// formatNodePressureConditions 输出 MemoryPressure/DiskPressure/PIDPressure 的状态
+func formatNodePressureConditions(node *corev1.Node) string {
+	parts := make([]string, 0, len(nodePressureConditions))
+	for _, conditionType := range nodePressureConditions {
+		status := corev1.ConditionUnknown
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == conditionType {
+				status = condition.Status
+				break
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", conditionType, status))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sumPodRequests 累加未结束Pod中所有容器的 CPU 和内存 requests
+func sumPodRequests(pods []corev1.Pod) (resource.Quantity, resource.Quantity) {
+	var cpu, memory resource.Quantity
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if q, ok := container.Resources.Requests[corev1.ResourceCPU]; ok {
+				cpu.Add(q)
+			}
+			if q, ok := container.Resources.Requests[corev1.ResourceMemory]; ok {
+				memory.Add(q)
+			}
+		}
+	}
+	return cpu, memory
+}
+
+// formatAllocation 输出 "requested X / allocatable Y (Z%)"
+func formatAllocation(requested, allocatable resource.Quantity) string {
+	if allocatable.IsZero() {
+		return fmt.Sprintf("requested %s / allocatable unknown", requested.String())
+	}
+	percent := float64(requested.MilliValue()) / float64(allocatable.MilliValue()) * 100
+	return fmt.Sprintf("requested %s / allocatable %s (%.0f%%)", requested.String(), allocatable.String(), percent)
+}
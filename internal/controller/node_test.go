@@ -0,0 +1,145 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Node pressure", func() {
+	newNode := func(name string, conditions ...corev1.NodeCondition) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Conditions: conditions,
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		}
+	}
+	newPod := func(name, nodeName string, phase corev1.PodPhase, cpu, memory string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse(memory),
+					}},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	It("should report memory and disk pressure with the requests of running pods", func() {
+		pressured := newNode("node-a",
+			corev1.NodeCondition{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue},
+			corev1.NodeCondition{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+			corev1.NodeCondition{Type: corev1.NodePIDPressure, Status: corev1.ConditionFalse},
+		)
+		healthy := newNode("node-b",
+			corev1.NodeCondition{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+		)
+		target := newPod("order-0", "node-a", corev1.PodRunning, "1", "2Gi")
+		neighbour := newPod("cache-0", "node-a", corev1.PodRunning, "2", "4Gi")
+		finished := newPod("job-0", "node-a", corev1.PodSucceeded, "1", "1Gi")
+		other := newPod("order-1", "node-b", corev1.PodRunning, "500m", "1Gi")
+		reconciler := newFakeReconciler(pressured, healthy, target, neighbour, finished, other)
+
+		out, err := reconciler.GetNodePressure(context.Background(), []corev1.Pod{*other, *target})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("Node: node-a\n" +
+			"  Conditions: MemoryPressure=True, DiskPressure=True, PIDPressure=False\n" +
+			"  CPU: requested 3 / allocatable 4 (75%)\n" +
+			"  Memory: requested 6Gi / allocatable 8Gi (75%)\n" +
+			"Node: node-b\n" +
+			"  Conditions: MemoryPressure=False, DiskPressure=Unknown, PIDPressure=Unknown\n" +
+			"  CPU: requested 500m / allocatable 4 (12%)\n" +
+			"  Memory: requested 1Gi / allocatable 8Gi (12%)\n"))
+	})
+
+	It("should return nothing when no target pod is scheduled", func() {
+		reconciler := newFakeReconciler(newNode("node-a"))
+
+		out, err := reconciler.GetNodePressure(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(BeEmpty())
+
+		pending := newPod("order-0", "", corev1.PodPending, "1", "1Gi")
+		out, err = reconciler.GetNodePressure(context.Background(), []corev1.Pod{*pending})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(BeEmpty())
+	})
+
+	It("should mark a deleted node as not found and keep the others", func() {
+		reconciler := newFakeReconciler(newNode("node-a"))
+
+		out, err := reconciler.GetNodePressure(context.Background(), []corev1.Pod{
+			*newPod("order-0", "gone", corev1.PodRunning, "1", "1Gi"),
+			*newPod("order-1", "node-a", corev1.PodRunning, "1", "1Gi"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HavePrefix("Node: gone (not found)\nNode: node-a\n"))
+	})
+
+	It("should keep analyzing with an unavailable node section when a node cannot be read", func() {
+		empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			resultType := "vector"
+			if req.URL.Path == lokiQueryPath {
+				resultType = "streams"
+			}
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"` + resultType + `","result":[]}}`))
+		}))
+		DeferCleanup(empty.Close)
+		reconciler := newFakeReconciler(newNode("node-a"))
+		reconciler.Client = interceptor.NewClient(reconciler.Client.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*corev1.Node); ok {
+					return errors.New("cache not synced")
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		})
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: autofixv1.TargetSelector{Namespace: "default"}},
+		}
+
+		eventString, err := reconciler.BuildEventString(context.Background(), aiopsAnalyzer,
+			datasources{PrometheusURL: empty.URL, LokiURL: empty.URL}, []corev1.Pod{*newPod("order-0", "node-a", corev1.PodRunning, "1", "1Gi")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventString).To(ContainSubstring("=== Node Pressure ===\nUnavailable: cache not synced\n"))
+	})
+})
@@ -0,0 +1,34 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// recordNoop 记录本轮没有产出修复建议的原因，原因和说明都没有变化时不更新status
+func (r *AIOpsAnalyzerReconciler) recordNoop(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, reason autofixv1.NoopReason, message string) error {
+	if aiopsAnalyzer.Status.NoopReason == reason && aiopsAnalyzer.Status.NoopMessage == message {
+		return nil
+	}
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.NoopReason = reason
+		status.NoopMessage = message
+	})
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+var _ = Describe("Noop reason", func() {
+	It("should record why nothing was proposed and clear it on the next proposal", func() {
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{ObjectMeta: metav1.ObjectMeta{Name: "noop", Namespace: "default"}}
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		Expect(reconciler.recordNoop(context.Background(), aiopsAnalyzer,
+			autofixv1.NoopReasonNoSelector, "未配置 target.selector")).To(Succeed())
+		Expect(aiopsAnalyzer.Status.NoopReason).To(Equal(autofixv1.NoopReasonNoSelector))
+		Expect(aiopsAnalyzer.Status.NoopMessage).To(Equal("未配置 target.selector"))
+
+		heal := &llm.HealAction{Reason: "扩容", RiskLevel: "low",
+			PatchContent: []llm.PatchOp{{Op: "replace", Path: "/spec/replicas", Value: float64(3)}}}
+		Expect(reconciler.recordHistory(context.Background(), aiopsAnalyzer, newRemediationRecord("req-1", heal))).To(Succeed())
+		Expect(aiopsAnalyzer.Status.NoopReason).To(BeEmpty())
+		Expect(aiopsAnalyzer.Status.History).To(HaveLen(1))
+		Expect(aiopsAnalyzer.Status.History[0].ActionType).To(Equal("scale"))
+	})
+})
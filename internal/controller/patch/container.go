@@ -0,0 +1,61 @@
+package patch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// containersPointer Pod 模板中容器列表的位置
+	containersPointer = "/spec/template/spec/containers"
+	// containerNamePrefix 按名称引用容器的路径段前缀，如 /spec/template/spec/containers/name=app/resources
+	// 容器顺序变化（如注入 sidecar）后下标会失效，名称引用在应用补丁时才按被修改的清单换算为下标
+	containerNamePrefix = "name="
+)
+
+// ContainerNameRef 返回路径中按名称引用的容器名，不是名称引用时返回 false
+func ContainerNameRef(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, containersPointer+"/"+containerNamePrefix)
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	return name, true
+}
+
+// ResolveContainerPath 把路径中按名称引用的容器换算为 object 中对应容器的下标，不是名称引用时原样返回
+func ResolveContainerPath(object map[string]any, path string) (string, error) {
+	name, ok := ContainerNameRef(path)
+	if !ok {
+		return path, nil
+	}
+	index, err := ResolveContainerIndex(object, name)
+	if err != nil {
+		return "", err
+	}
+	rest := strings.TrimPrefix(path, containersPointer+"/"+containerNamePrefix+name)
+	return containersPointer + "/" + strconv.Itoa(index) + rest, nil
+}
+
+// ResolveContainerIndex 返回工作负载 Pod 模板中名为 name 的容器的下标，容器不存在时返回错误
+func ResolveContainerIndex(workload map[string]any, name string) (int, error) {
+	raw, ok := LookupPointer(workload, containersPointer)
+	if !ok {
+		return 0, fmt.Errorf("workload has no %s", containersPointer)
+	}
+	containers, ok := raw.([]any)
+	if !ok {
+		return 0, fmt.Errorf("%s is not a list", containersPointer)
+	}
+	var names []string
+	for i, item := range containers {
+		container, _ := item.(map[string]any)
+		containerName, _ := container["name"].(string)
+		if containerName == name {
+			return i, nil
+		}
+		names = append(names, containerName)
+	}
+	return 0, fmt.Errorf("container %q not found, workload has containers %q", name, names)
+}
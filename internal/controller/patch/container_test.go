@@ -0,0 +1,44 @@
+package patch
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolveContainerIndex", func() {
+	workload := func(names ...string) map[string]any {
+		containers := make([]any, len(names))
+		for i, name := range names {
+			containers[i] = map[string]any{"name": name}
+		}
+		return map[string]any{"spec": map[string]any{"template": map[string]any{"spec": map[string]any{"containers": containers}}}}
+	}
+
+	It("should find the container by name", func() {
+		Expect(ResolveContainerIndex(workload("app"), "app")).To(Equal(0))
+		// 注入 sidecar 后业务容器的下标发生变化
+		Expect(ResolveContainerIndex(workload("istio-proxy", "app"), "app")).To(Equal(1))
+	})
+
+	It("should report the containers of the workload when the name is unknown", func() {
+		_, err := ResolveContainerIndex(workload("istio-proxy", "app"), "api")
+		Expect(err).To(MatchError(`container "api" not found, workload has containers ["istio-proxy" "app"]`))
+
+		_, err = ResolveContainerIndex(map[string]any{"spec": map[string]any{}}, "app")
+		Expect(err).To(MatchError("workload has no /spec/template/spec/containers"))
+	})
+
+	It("should resolve name references in paths", func() {
+		object := workload("istio-proxy", "app")
+		Expect(ResolveContainerPath(object, "/spec/template/spec/containers/name=app/resources/limits/cpu")).To(
+			Equal("/spec/template/spec/containers/1/resources/limits/cpu"))
+		Expect(ResolveContainerPath(object, "/spec/template/spec/containers/0/image")).To(
+			Equal("/spec/template/spec/containers/0/image"))
+
+		value, ok := LookupPointer(object, "/spec/template/spec/containers/name=app/name")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("app"))
+		_, ok = LookupPointer(object, "/spec/template/spec/containers/name=api/name")
+		Expect(ok).To(BeFalse())
+	})
+})
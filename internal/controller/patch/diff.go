@@ -0,0 +1,108 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// RenderDiff 按目标资源分组渲染补丁，用于在卡片中展示，每个操作一行：
+// 线上资源中能找到当前值时为 "op path: old -> new"，否则为 "op path = value"
+// live 为线上资源，按 kind、name 与 TargetRef 匹配，可以为空
+func RenderDiff(ops []autofixv1.PatchOperation, live ...*unstructured.Unstructured) string {
+	var groups []string
+	lines := map[string][]string{}
+	for _, op := range ops {
+		group := targetName(op)
+		if _, ok := lines[group]; !ok {
+			groups = append(groups, group)
+		}
+		lines[group] = append(lines[group], renderOp(op, liveObject(op, live)))
+	}
+
+	var b strings.Builder
+	for i, group := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		indent := ""
+		if group != "" {
+			b.WriteString(group + ":\n")
+			indent = "  "
+		}
+		for _, line := range lines[group] {
+			b.WriteString(indent + line + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// targetName 分组标题，例如 Deployment/order
+func targetName(op autofixv1.PatchOperation) string {
+	if op.TargetRef == nil {
+		return ""
+	}
+	return strings.Trim(op.TargetRef.Kind+"/"+op.TargetRef.Name, "/")
+}
+
+// liveObject 找到 TargetRef 对应的线上资源，补丁没有 TargetRef 且只有一个资源时使用该资源
+func liveObject(op autofixv1.PatchOperation, live []*unstructured.Unstructured) map[string]any {
+	for _, object := range live {
+		if object == nil {
+			continue
+		}
+		if op.TargetRef == nil && len(live) == 1 {
+			return object.Object
+		}
+		if TargetMatches(op.TargetRef, ManifestObject{Kind: object.GetKind(), Name: object.GetName()}) {
+			return object.Object
+		}
+	}
+	return nil
+}
+
+func renderOp(op autofixv1.PatchOperation, object map[string]any) string {
+	var old string
+	var known bool
+	if object != nil {
+		var value any
+		if value, known = LookupPointer(object, op.Path); known {
+			old = formatValue(value)
+		}
+	}
+
+	switch {
+	case op.Op == "remove" && known:
+		return fmt.Sprintf("remove %s: %s", op.Path, old)
+	case op.Op == "remove":
+		return "remove " + op.Path
+	case known:
+		return fmt.Sprintf("%s %s: %s -> %s", op.Op, op.Path, old, formatRaw(op.Value.Raw))
+	default:
+		return fmt.Sprintf("%s %s = %s", op.Op, op.Path, formatRaw(op.Value.Raw))
+	}
+}
+
+// formatRaw 补丁中的 JSON 值，字符串去掉引号
+func formatRaw(raw []byte) string {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw)
+	}
+	return formatValue(value)
+}
+
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
@@ -0,0 +1,58 @@
+package patch
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("RenderDiff", func() {
+	deployment := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "order"},
+		"spec": map[string]any{
+			"replicas": int64(2),
+			"template": map[string]any{"spec": map[string]any{"containers": []any{
+				map[string]any{"resources": map[string]any{"limits": map[string]any{"cpu": "500m"}}},
+			}}},
+		},
+	}}
+
+	op := func(kind, name, opType, path, value string) autofixv1.PatchOperation {
+		patch := autofixv1.PatchOperation{Op: opType, Path: path}
+		if kind != "" {
+			patch.TargetRef = &corev1.ObjectReference{Kind: kind, Name: name}
+		}
+		if value != "" {
+			patch.Value = runtime.RawExtension{Raw: []byte(value)}
+		}
+		return patch
+	}
+
+	It("should show current values and group by target", func() {
+		Expect(RenderDiff([]autofixv1.PatchOperation{
+			op("Deployment", "order", "replace", "/spec/replicas", "4"),
+			op("HorizontalPodAutoscaler", "order", "replace", "/spec/maxReplicas", "10"),
+			op("Deployment", "order", "replace", "/spec/template/spec/containers/0/resources/limits/cpu", `"1"`),
+			op("Deployment", "order", "add", "/spec/template/spec/containers/0/resources/limits/memory", `"1Gi"`),
+		}, deployment)).To(Equal("Deployment/order:\n" +
+			"  replace /spec/replicas: 2 -> 4\n" +
+			"  replace /spec/template/spec/containers/0/resources/limits/cpu: 500m -> 1\n" +
+			"  add /spec/template/spec/containers/0/resources/limits/memory = 1Gi\n" +
+			"\n" +
+			"HorizontalPodAutoscaler/order:\n" +
+			"  replace /spec/maxReplicas = 10"))
+	})
+
+	It("should fall back to new values without a live resource", func() {
+		Expect(RenderDiff([]autofixv1.PatchOperation{
+			op("", "", "replace", "/spec/replicas", "4"),
+			op("", "", "remove", "/spec/paused", ""),
+		})).To(Equal("replace /spec/replicas = 4\nremove /spec/paused"))
+	})
+})
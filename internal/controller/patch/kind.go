@@ -0,0 +1,47 @@
+package patch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// kindPaths 每种资源允许打补丁的路径前缀，HPA 只能调整扩缩范围和指标，ConfigMap 只能修改数据
+var kindPaths = map[string][]string{
+	"Deployment":              {"/spec/replicas", "/spec/template", "/spec/strategy"},
+	"StatefulSet":             {"/spec/replicas", "/spec/template", "/spec/updateStrategy"},
+	"HorizontalPodAutoscaler": {"/spec/minReplicas", "/spec/maxReplicas", "/spec/metrics"},
+	"ConfigMap":               {"/data", "/binaryData"},
+}
+
+// SupportedKinds 返回允许打补丁的资源类型，按名称排序
+func SupportedKinds() []string {
+	kinds := make([]string, 0, len(kindPaths))
+	for kind := range kindPaths {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ValidateTarget 校验补丁的 TargetRef.Kind 在允许的类型中，且路径适用于该类型
+// 例如 /spec/minReplicas 只能用于 HPA，/spec/template 不能用于 HPA
+func ValidateTarget(op autofixv1.PatchOperation) error {
+	var kind string
+	if op.TargetRef != nil {
+		kind = op.TargetRef.Kind
+	}
+	prefixes, ok := kindPaths[kind]
+	if !ok {
+		return fmt.Errorf("patch %s %s: unsupported target kind %q, must be one of %s",
+			op.Op, op.Path, kind, strings.Join(SupportedKinds(), ", "))
+	}
+	for _, prefix := range prefixes {
+		if op.Path == prefix || strings.HasPrefix(op.Path, prefix+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("patch %s %s: path does not apply to %s", op.Op, op.Path, kind)
+}
@@ -0,0 +1,36 @@
+package patch
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("ValidateTarget", func() {
+	DescribeTable("kind and path combinations",
+		func(kind, path, expectErr string) {
+			op := newOp("replace", path, "3")
+			if kind != "-" {
+				op.TargetRef = &corev1.ObjectReference{Kind: kind, Name: "order"}
+			}
+			err := ValidateTarget(op)
+			if expectErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectErr)))
+			}
+		},
+		Entry("Deployment replicas", "Deployment", "/spec/replicas", ""),
+		Entry("StatefulSet container resources", "StatefulSet", "/spec/template/spec/containers/0/resources/limits/cpu", ""),
+		Entry("HPA minReplicas", "HorizontalPodAutoscaler", "/spec/minReplicas", ""),
+		Entry("HPA maxReplicas", "HorizontalPodAutoscaler", "/spec/maxReplicas", ""),
+		Entry("HPA metric target", "HorizontalPodAutoscaler", "/spec/metrics/0/resource/target/averageUtilization", ""),
+		Entry("HPA pod template", "HorizontalPodAutoscaler", "/spec/template", "path does not apply to HorizontalPodAutoscaler"),
+		Entry("ConfigMap data key", "ConfigMap", "/data/DB_POOL_SIZE", ""),
+		Entry("ConfigMap replicas", "ConfigMap", "/spec/replicas", "path does not apply to ConfigMap"),
+		Entry("Deployment minReplicas", "Deployment", "/spec/minReplicas", "path does not apply to Deployment"),
+		Entry("prefix is matched by segment", "Deployment", "/spec/replicasOverride", "path does not apply to Deployment"),
+		Entry("unsupported kind", "DaemonSet", "/spec/template", `unsupported target kind "DaemonSet", must be one of ConfigMap, Deployment, HorizontalPodAutoscaler, StatefulSet`),
+		Entry("missing target", "-", "/spec/replicas", `unsupported target kind ""`),
+	)
+})
@@ -0,0 +1,116 @@
+package patch
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// relativePattern 相对值，例如 "+50%"、"-20%"
+var relativePattern = regexp.MustCompile(`^([+-])(\d+(?:\.\d+)?)%$`)
+
+// ParseRelativePercent 解析相对值，返回带符号的百分比，例如 "+50%" 返回 50
+func ParseRelativePercent(value any) (float64, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	match := relativePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return 0, false
+	}
+	if match[1] == "-" {
+		percent = -percent
+	}
+	return percent, true
+}
+
+// ApplyPercent 按百分比调整资源量，保留原来的单位格式
+// 二进制单位（Mi、Gi）取整到字节，十进制单位（m、核数）取整到千分之一
+func ApplyPercent(current resource.Quantity, percent float64) (resource.Quantity, error) {
+	factor := 1 + percent/100
+	if factor < 0 {
+		return resource.Quantity{}, fmt.Errorf("adjusting %s by %v%% results in a negative quantity", current.String(), percent)
+	}
+
+	if current.Format == resource.BinarySI {
+		value := math.Round(float64(current.Value()) * factor)
+		return *resource.NewQuantity(int64(value), resource.BinarySI), nil
+	}
+	milli := math.Round(float64(current.MilliValue()) * factor)
+	return *resource.NewMilliQuantity(int64(milli), current.Format), nil
+}
+
+// ResolveRelativeQuantity 把相对值换算为具体的资源量，max 不为空时结果不超过 max
+// value 不是相对值时返回 false
+func ResolveRelativeQuantity(current resource.Quantity, value any, max *resource.Quantity) (resource.Quantity, bool, error) {
+	percent, ok := ParseRelativePercent(value)
+	if !ok {
+		return resource.Quantity{}, false, nil
+	}
+	resolved, err := ApplyPercent(current, percent)
+	if err != nil {
+		return resource.Quantity{}, true, err
+	}
+	if max != nil && resolved.Cmp(*max) > 0 {
+		resolved = max.DeepCopy()
+	}
+	return resolved, true, nil
+}
+
+// LookupPointer 按 RFC6901 JSON Pointer 在通用 JSON 对象中查找值
+// 数组中的 name=<name> 路径段匹配 name 字段相同的元素，用于按名称引用的容器
+func LookupPointer(object map[string]any, pointer string) (any, bool) {
+	if pointer == "" {
+		return object, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	var current any = object
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]any:
+			next, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []any:
+			if name, ok := strings.CutPrefix(token, containerNamePrefix); ok {
+				if current, ok = elementNamed(node, name); !ok {
+					return nil, false
+				}
+				continue
+			}
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// elementNamed 返回数组中 name 字段等于 name 的元素
+func elementNamed(items []any, name string) (any, bool) {
+	for _, item := range items {
+		if element, ok := item.(map[string]any); ok && element["name"] == name {
+			return element, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,60 @@
+package patch
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("Relative quantities", func() {
+	DescribeTable("resolving percentages against the current value",
+		func(current string, value any, max string, expected string) {
+			var maxQuantity *resource.Quantity
+			if max != "" {
+				q := resource.MustParse(max)
+				maxQuantity = &q
+			}
+			resolved, ok, err := ResolveRelativeQuantity(resource.MustParse(current), value, maxQuantity)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(resolved.Cmp(resource.MustParse(expected))).To(BeZero(), "got %s", resolved.String())
+		},
+		Entry("millicpu increase", "500m", "+50%", "", "750m"),
+		Entry("whole cpu increase", "2", "+50%", "", "3"),
+		Entry("millicpu decrease", "1000m", "-25%", "", "750m"),
+		Entry("Mi increase", "512Mi", "+50%", "", "768Mi"),
+		Entry("Gi increase", "4Gi", "+50%", "", "6Gi"),
+		Entry("fractional percentage", "1Gi", "+12.5%", "", "1152Mi"),
+		Entry("clamped to the configured max", "6Gi", "+100%", "8Gi", "8Gi"),
+		Entry("cpu clamped to the configured max", "6", "+50%", "8", "8"),
+	)
+
+	It("should keep the unit format of the current value", func() {
+		resolved, _, err := ResolveRelativeQuantity(resource.MustParse("1Gi"), "+50%", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.String()).To(Equal("1536Mi"))
+	})
+
+	It("should ignore absolute values", func() {
+		_, ok, err := ResolveRelativeQuantity(resource.MustParse("1Gi"), "2Gi", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should reject decreases below zero", func() {
+		_, _, err := ResolveRelativeQuantity(resource.MustParse("1Gi"), "-150%", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should look up values by JSON pointer", func() {
+		object := map[string]any{"spec": map[string]any{"containers": []any{
+			map[string]any{"resources": map[string]any{"limits": map[string]any{"cpu": "500m"}}},
+		}}}
+		value, ok := LookupPointer(object, "/spec/containers/0/resources/limits/cpu")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("500m"))
+
+		_, ok = LookupPointer(object, "/spec/containers/1/resources")
+		Expect(ok).To(BeFalse())
+	})
+})
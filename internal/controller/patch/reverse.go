@@ -0,0 +1,49 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// ReversePatches 根据修改前的对象生成撤销 ops 的补丁，按相反顺序排列
+// replace 恢复原值，add 在原本不存在时改为 remove，remove 改为 add 原值
+func ReversePatches(original map[string]any, ops []autofixv1.PatchOperation) ([]autofixv1.PatchOperation, error) {
+	reversed := make([]autofixv1.PatchOperation, 0, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if strings.HasSuffix(op.Path, "/-") {
+			return nil, fmt.Errorf("patch %s %s: appending to an array cannot be reversed", op.Op, op.Path)
+		}
+
+		previous, existed := LookupPointer(original, op.Path)
+		reverse := autofixv1.PatchOperation{TargetRef: op.TargetRef, Path: op.Path}
+		switch {
+		case op.Op == "add" && !existed:
+			reverse.Op = "remove"
+		case op.Op == "add", op.Op == "replace":
+			reverse.Op = "replace"
+		case op.Op == "remove":
+			reverse.Op = "add"
+		default:
+			return nil, fmt.Errorf("patch %s %s: unsupported op", op.Op, op.Path)
+		}
+
+		if reverse.Op != "remove" {
+			if !existed {
+				return nil, fmt.Errorf("patch %s %s: path does not exist on the original object", op.Op, op.Path)
+			}
+			raw, err := json.Marshal(previous)
+			if err != nil {
+				return nil, fmt.Errorf("patch %s %s: encode original value failed: %w", op.Op, op.Path, err)
+			}
+			reverse.Value = runtime.RawExtension{Raw: raw}
+		}
+		reversed = append(reversed, reverse)
+	}
+	return reversed, nil
+}
@@ -0,0 +1,56 @@
+package patch
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("ReversePatches", func() {
+	original := map[string]any{"spec": map[string]any{
+		"replicas": float64(2),
+		"template": map[string]any{"spec": map[string]any{"containers": []any{
+			map[string]any{"resources": map[string]any{"limits": map[string]any{"cpu": "500m"}}},
+		}}},
+	}}
+
+	op := func(kind, path, value string) autofixv1.PatchOperation {
+		patch := autofixv1.PatchOperation{Op: kind, Path: path}
+		if value != "" {
+			patch.Value = runtime.RawExtension{Raw: []byte(value)}
+		}
+		return patch
+	}
+
+	It("should restore replaced values and remove added ones in reverse order", func() {
+		reversed, err := ReversePatches(original, []autofixv1.PatchOperation{
+			op("replace", "/spec/replicas", "5"),
+			op("add", "/spec/template/spec/containers/0/resources/limits/memory", `"1Gi"`),
+			op("remove", "/spec/template/spec/containers/0/resources/limits/cpu", ""),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reversed).To(HaveLen(3))
+
+		Expect(reversed[0].Op).To(Equal("add"))
+		Expect(reversed[0].Path).To(Equal("/spec/template/spec/containers/0/resources/limits/cpu"))
+		Expect(string(reversed[0].Value.Raw)).To(Equal(`"500m"`))
+
+		Expect(reversed[1].Op).To(Equal("remove"))
+		Expect(reversed[1].Value.Raw).To(BeNil())
+
+		Expect(reversed[2].Op).To(Equal("replace"))
+		Expect(string(reversed[2].Value.Raw)).To(Equal("2"))
+	})
+
+	It("should refuse to reverse a replace of a missing path", func() {
+		_, err := ReversePatches(original, []autofixv1.PatchOperation{op("replace", "/spec/paused", "true")})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should refuse to reverse an array append", func() {
+		_, err := ReversePatches(original, []autofixv1.PatchOperation{op("add", "/spec/template/spec/containers/-", "{}")})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,14 @@
+package patch
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPatch(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Patch Suite")
+}
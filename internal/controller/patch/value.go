@@ -0,0 +1,167 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// ValueKind 表示已知路径对应的值类型
+type ValueKind string
+
+const (
+	// KindReplicas 副本数（int32，非负）
+	KindReplicas ValueKind = "replicas"
+	// KindQuantity 单个资源量（如 500m、1Gi）
+	KindQuantity ValueKind = "quantity"
+	// KindResourceList 整个 limits/requests 对象
+	KindResourceList ValueKind = "resourceList"
+	// KindUnknown 未知路径，只做 JSON 解析
+	KindUnknown ValueKind = "unknown"
+)
+
+var (
+	replicasPathPattern     = regexp.MustCompile(`^/spec/(replicas|minReplicas|maxReplicas)$`)
+	quantityPathPattern     = regexp.MustCompile(`^/spec/template/spec/(containers|initContainers)/[^/]+/resources/(limits|requests)/[^/]+$`)
+	resourceListPathPattern = regexp.MustCompile(`^/spec/template/spec/(containers|initContainers)/[^/]+/resources/(limits|requests)$`)
+)
+
+// KindForPath 根据 JSON Path 判断值类型
+func KindForPath(path string) ValueKind {
+	switch {
+	case replicasPathPattern.MatchString(path):
+		return KindReplicas
+	case quantityPathPattern.MatchString(path):
+		return KindQuantity
+	case resourceListPathPattern.MatchString(path):
+		return KindResourceList
+	default:
+		return KindUnknown
+	}
+}
+
+// EncodeValue 把大模型补丁中的通用 JSON 值编码为 RawExtension，保留 JSON 类型：数字不会变成字符串，对象保持嵌套结构
+// 已经是 RawExtension 或 json.RawMessage 的值原样使用；不转义 HTML 字符，写入 Git 的内容与大模型给出的一致
+func EncodeValue(value any) (runtime.RawExtension, error) {
+	switch v := value.(type) {
+	case runtime.RawExtension:
+		return v, nil
+	case json.RawMessage:
+		if !json.Valid(v) {
+			return runtime.RawExtension{}, fmt.Errorf("value %q is not valid JSON", string(v))
+		}
+		return runtime.RawExtension{Raw: v}, nil
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return runtime.RawExtension{}, err
+	}
+	return runtime.RawExtension{Raw: bytes.TrimRight(b.Bytes(), "\n")}, nil
+}
+
+// DecodeValue 把 RawExtension 解析成通用 JSON 值，数字保留为 json.Number
+func DecodeValue(op autofixv1.PatchOperation) (any, error) {
+	raw := op.Value.Raw
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if op.Value.Object != nil {
+			return op.Value.Object, nil
+		}
+		return nil, fmt.Errorf("patch %s %s: value is empty", op.Op, op.Path)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("patch %s %s: value %q is not valid JSON: %w", op.Op, op.Path, string(raw), err)
+	}
+	return value, nil
+}
+
+// ExtractValue 按路径类型解析并校验补丁值
+// 副本数返回 int32，资源量返回 resource.Quantity，limits/requests 返回 corev1.ResourceList，其他路径返回通用 JSON 值
+// remove 操作没有值，返回 nil
+func ExtractValue(op autofixv1.PatchOperation) (any, error) {
+	if op.Op == "remove" {
+		return nil, nil
+	}
+
+	value, err := DecodeValue(op)
+	if err != nil {
+		return nil, err
+	}
+
+	switch KindForPath(op.Path) {
+	case KindReplicas:
+		return toReplicas(op.Path, value)
+	case KindQuantity:
+		return toQuantity(op.Path, value)
+	case KindResourceList:
+		return toResourceList(op.Path, value)
+	default:
+		return value, nil
+	}
+}
+
+func toReplicas(path string, value any) (int32, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("patch %s: replicas must be an integer, got %T %v", path, value, value)
+	}
+	replicas, err := number.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("patch %s: replicas must be an integer, got %s", path, number)
+	}
+	if replicas < 0 || replicas > math.MaxInt32 {
+		return 0, fmt.Errorf("patch %s: replicas %d out of range", path, replicas)
+	}
+	return int32(replicas), nil
+}
+
+func toQuantity(path string, value any) (resource.Quantity, error) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case json.Number:
+		// CPU 允许写成数字，例如 1 或 0.5
+		s = v.String()
+	default:
+		return resource.Quantity{}, fmt.Errorf("patch %s: quantity must be a string like \"500m\" or \"1Gi\", got %T", path, value)
+	}
+	quantity, err := resource.ParseQuantity(s)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("patch %s: invalid quantity %q: %w", path, s, err)
+	}
+	if quantity.Sign() < 0 {
+		return resource.Quantity{}, fmt.Errorf("patch %s: quantity %q must not be negative", path, s)
+	}
+	return quantity, nil
+}
+
+func toResourceList(path string, value any) (corev1.ResourceList, error) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("patch %s: resources must be an object like {\"cpu\":\"500m\"}, got %T", path, value)
+	}
+	list := corev1.ResourceList{}
+	for name, v := range object {
+		quantity, err := toQuantity(path+"/"+name, v)
+		if err != nil {
+			return nil, err
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
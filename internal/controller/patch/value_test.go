@@ -0,0 +1,121 @@
+package patch
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+func newOp(op, path, raw string) autofixv1.PatchOperation {
+	return autofixv1.PatchOperation{Op: op, Path: path, Value: runtime.RawExtension{Raw: []byte(raw)}}
+}
+
+var _ = Describe("ExtractValue", func() {
+	It("should extract replicas as int32", func() {
+		replicas, err := ExtractValue(newOp("replace", "/spec/replicas", "3"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replicas).To(Equal(int32(3)))
+	})
+
+	It("should reject non-integer replicas", func() {
+		_, err := ExtractValue(newOp("replace", "/spec/replicas", `"3"`))
+		Expect(err).To(MatchError(ContainSubstring("replicas must be an integer")))
+
+		_, err = ExtractValue(newOp("replace", "/spec/replicas", "2.5"))
+		Expect(err).To(MatchError(ContainSubstring("replicas must be an integer")))
+
+		_, err = ExtractValue(newOp("replace", "/spec/replicas", "-1"))
+		Expect(err).To(MatchError(ContainSubstring("out of range")))
+	})
+
+	It("should extract cpu and memory quantities", func() {
+		cpu, err := ExtractValue(newOp("replace", "/spec/template/spec/containers/0/resources/limits/cpu", `"500m"`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cpu).To(Equal(resource.MustParse("500m")))
+
+		cpu, err = ExtractValue(newOp("replace", "/spec/template/spec/containers/0/resources/requests/cpu", "1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cpu.(resource.Quantity).Equal(resource.MustParse("1"))).To(BeTrue())
+
+		memory, err := ExtractValue(newOp("add", "/spec/template/spec/containers/0/resources/limits/memory", `"1Gi"`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(memory.(resource.Quantity).Equal(resource.MustParse("1Gi"))).To(BeTrue())
+	})
+
+	It("should reject malformed quantities", func() {
+		_, err := ExtractValue(newOp("replace", "/spec/template/spec/containers/0/resources/limits/memory", `"lots"`))
+		Expect(err).To(MatchError(ContainSubstring(`invalid quantity "lots"`)))
+	})
+
+	It("should extract a whole resource list", func() {
+		value, err := ExtractValue(newOp("replace", "/spec/template/spec/containers/0/resources/limits",
+			`{"cpu":"2","memory":"4Gi"}`))
+		Expect(err).NotTo(HaveOccurred())
+		list := value.(corev1.ResourceList)
+		Expect(list.Cpu().MilliValue()).To(Equal(int64(2000)))
+		Expect(list.Memory().Equal(resource.MustParse("4Gi"))).To(BeTrue())
+	})
+
+	It("should report empty and invalid raw values", func() {
+		_, err := ExtractValue(newOp("replace", "/spec/replicas", ""))
+		Expect(err).To(MatchError(ContainSubstring("value is empty")))
+
+		_, err = ExtractValue(newOp("replace", "/spec/replicas", "{"))
+		Expect(err).To(MatchError(ContainSubstring("not valid JSON")))
+	})
+
+	It("should ignore the value of remove operations", func() {
+		value, err := ExtractValue(newOp("remove", "/spec/replicas", ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(BeNil())
+	})
+})
+
+var _ = Describe("EncodeValue", func() {
+	// 大模型响应经 encoding/json 解析后，数字为 float64，对象为 map[string]any
+	DescribeTable("round-tripping values parsed from the LLM response",
+		func(value any, raw string, decoded any) {
+			encoded, err := EncodeValue(value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(encoded.Raw)).To(Equal(raw))
+
+			op := autofixv1.PatchOperation{Op: "replace", Path: "/spec/template/spec/containers/0/env", Value: encoded}
+			Expect(DecodeValue(op)).To(Equal(decoded))
+		},
+		Entry("int", float64(20), `20`, json.Number("20")),
+		Entry("numeric string", "20", `"20"`, "20"),
+		Entry("bool", true, `true`, true),
+		Entry("html characters", "a<b&c", `"a<b&c"`, "a<b&c"),
+		Entry("nested object",
+			map[string]any{"limits": map[string]any{"cpu": float64(2), "memory": "4Gi"}, "ports": []any{float64(8080)}},
+			`{"limits":{"cpu":2,"memory":"4Gi"},"ports":[8080]}`,
+			map[string]any{"limits": map[string]any{"cpu": json.Number("2"), "memory": "4Gi"}, "ports": []any{json.Number("8080")}}),
+	)
+
+	It("should keep raw JSON values as they are", func() {
+		encoded, err := EncodeValue(json.RawMessage(`{"a": 1}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(encoded.Raw)).To(Equal(`{"a": 1}`))
+
+		_, err = EncodeValue(json.RawMessage(`{`))
+		Expect(err).To(MatchError(ContainSubstring("not valid JSON")))
+	})
+
+	It("should write an integer replicas to the manifest", func() {
+		encoded, err := EncodeValue(float64(20))
+		Expect(err).NotTo(HaveOccurred())
+		out, err := ApplyPatchesToYAML([]byte("kind: Deployment\nmetadata:\n  name: order\nspec:\n  replicas: 3\n"),
+			[]autofixv1.PatchOperation{{
+				Op: "replace", Path: "/spec/replicas", Value: encoded,
+				TargetRef: &corev1.ObjectReference{Kind: "Deployment", Name: "order"},
+			}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(HaveSuffix("  replicas: 20\n"))
+	})
+})
@@ -0,0 +1,372 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// ManifestObject 清单中一个文档对应的资源
+type ManifestObject struct {
+	Kind string
+	Name string
+}
+
+// ManifestObjects 列出多文档 YAML 中每个资源的 kind 和名称，跳过空文档
+func ManifestObjects(content []byte) ([]ManifestObject, error) {
+	var objects []ManifestObject
+	for i, doc := range splitDocuments(content) {
+		object, err := manifestObject(doc)
+		if err != nil {
+			return nil, fmt.Errorf("parse document %d failed: %w", i, err)
+		}
+		if object.Kind != "" {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// TargetMatches TargetRef 中设置了的 kind、name 都需要一致，没有 TargetRef 的补丁不匹配任何资源
+func TargetMatches(ref *corev1.ObjectReference, object ManifestObject) bool {
+	if ref == nil || (ref.Kind == "" && ref.Name == "") {
+		return false
+	}
+	return (ref.Kind == "" || ref.Kind == object.Kind) && (ref.Name == "" || ref.Name == object.Name)
+}
+
+// ApplyPatchesToYAML 把补丁应用到 TargetRef 匹配的文档上，没有命中的文档保持原样
+// 补丁先用 RFC6902 库应用到 JSON 上校验结果，再在 YAML 节点树上重放以保留字段顺序和注释；
+// 两者结果不一致时退回库的结果（注释会丢失）。目标类型或路径不受支持、值的类型不对（见 ExtractValue）、
+// 任何补丁找不到对应文档或应用失败时返回错误
+func ApplyPatchesToYAML(fileContent []byte, patches []autofixv1.PatchOperation) ([]byte, error) {
+	for _, op := range patches {
+		if err := ValidateTarget(op); err != nil {
+			return nil, err
+		}
+		// 副本数、资源量等已知路径在写入清单前校验类型，避免把 "3" 或 "lots" 这样的值提交到仓库
+		if _, err := ExtractValue(op); err != nil {
+			return nil, err
+		}
+	}
+	docs := splitDocuments(fileContent)
+	applied := make([]bool, len(patches))
+	for d, doc := range docs {
+		object, err := manifestObject(doc)
+		if err != nil {
+			return nil, fmt.Errorf("parse document %d failed: %w", d, err)
+		}
+		if object.Kind == "" {
+			continue
+		}
+
+		var ops []autofixv1.PatchOperation
+		for i, op := range patches {
+			if TargetMatches(op.TargetRef, object) {
+				ops = append(ops, op)
+				applied[i] = true
+			}
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		if docs[d], err = patchDocument(doc, ops); err != nil {
+			return nil, fmt.Errorf("patch %s/%s failed: %w", object.Kind, object.Name, err)
+		}
+	}
+	for i, ok := range applied {
+		if !ok {
+			return nil, fmt.Errorf("patch %s %s: no document matches the target", patches[i].Op, patches[i].Path)
+		}
+	}
+	patched := bytes.Join(docs, []byte("---\n"))
+	if bytes.HasPrefix(fileContent, []byte("---\n")) {
+		patched = append([]byte("---\n"), patched...)
+	}
+	return patched, nil
+}
+
+// patchDocument 对单个文档应用补丁，按名称引用的容器按该文档中的容器顺序换算为下标
+func patchDocument(doc []byte, ops []autofixv1.PatchOperation) ([]byte, error) {
+	raw, err := sigsyaml.YAMLToJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	if ops, err = resolveContainerPaths(raw, ops); err != nil {
+		return nil, err
+	}
+	expected, err := applyJSONPatch(raw, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+	if err := applyNodePatches(&root, ops); err == nil {
+		if encoded, err := encodeNode(&root); err == nil && sameJSON(encoded, expected) {
+			return encoded, nil
+		}
+	}
+	return sigsyaml.JSONToYAML(expected)
+}
+
+// resolveContainerPaths 返回把名称引用换算为下标后的补丁，不修改传入的 ops
+func resolveContainerPaths(raw []byte, ops []autofixv1.PatchOperation) ([]autofixv1.PatchOperation, error) {
+	var object map[string]any
+	resolved := make([]autofixv1.PatchOperation, len(ops))
+	for i, op := range ops {
+		resolved[i] = op
+		if _, ok := ContainerNameRef(op.Path); !ok {
+			continue
+		}
+		if object == nil {
+			if err := json.Unmarshal(raw, &object); err != nil {
+				return nil, err
+			}
+		}
+		path, err := ResolveContainerPath(object, op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("patch %s %s: %w", op.Op, op.Path, err)
+		}
+		resolved[i].Path = path
+	}
+	return resolved, nil
+}
+
+// applyJSONPatch 使用 json-patch 应用补丁，replace、remove 的路径不存在时返回错误
+// add 的父路径不存在时自动创建（例如 Pod 模板上还没有 annotations 时添加重启注解）
+func applyJSONPatch(doc []byte, ops []autofixv1.PatchOperation) ([]byte, error) {
+	entries := make([]map[string]any, 0, len(ops))
+	for _, op := range ops {
+		entry := map[string]any{"op": op.Op, "path": op.Path}
+		if op.Op != "remove" {
+			entry["value"] = json.RawMessage(op.Value.Raw)
+		}
+		entries = append(entries, entry)
+	}
+	rawOps, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := jsonpatch.DecodePatch(rawOps)
+	if err != nil {
+		return nil, err
+	}
+	options := jsonpatch.NewApplyOptions()
+	options.EnsurePathExistsOnAdd = true
+	return decoded.ApplyWithOptions(doc, options)
+}
+
+// applyNodePatches 在 YAML 节点树上执行补丁
+func applyNodePatches(root *yaml.Node, ops []autofixv1.PatchOperation) error {
+	if root.Kind != yaml.DocumentNode || len(root.Content) != 1 {
+		return fmt.Errorf("not a yaml document")
+	}
+	for _, op := range ops {
+		tokens, err := pointerTokens(op.Path)
+		if err != nil || len(tokens) == 0 {
+			return fmt.Errorf("invalid path %q", op.Path)
+		}
+		parent, err := lookupNode(root.Content[0], tokens[:len(tokens)-1], op.Op == "add")
+		if err != nil {
+			return err
+		}
+		var value *yaml.Node
+		if op.Op != "remove" {
+			if value, err = valueNode(op.Value.Raw); err != nil {
+				return err
+			}
+		}
+		if err := setNode(parent, tokens[len(tokens)-1], op.Op, value); err != nil {
+			return fmt.Errorf("%s %s: %w", op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+// pointerTokens 按 RFC6901 拆分 JSON Pointer
+func pointerTokens(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// lookupNode 沿路径找到节点，create 时把缺少的 key 创建为空的 mapping
+func lookupNode(node *yaml.Node, tokens []string, create bool) (*yaml.Node, error) {
+	for _, token := range tokens {
+		switch node.Kind {
+		case yaml.MappingNode:
+			i := mappingIndex(node, token)
+			if i < 0 && create {
+				node.Content = append(node.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token},
+					&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+				i = len(node.Content) - 2
+			}
+			if i < 0 {
+				return nil, fmt.Errorf("key %q not found", token)
+			}
+			node = node.Content[i+1]
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return nil, fmt.Errorf("index %q out of range", token)
+			}
+			node = node.Content[index]
+		default:
+			return nil, fmt.Errorf("cannot descend into scalar at %q", token)
+		}
+	}
+	return node, nil
+}
+
+// setNode 在 parent 的 token 位置执行 add、replace、remove
+func setNode(parent *yaml.Node, token, op string, value *yaml.Node) error {
+	switch parent.Kind {
+	case yaml.MappingNode:
+		i := mappingIndex(parent, token)
+		switch {
+		case op == "remove" && i >= 0:
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+		case op == "add" && i < 0:
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}, value)
+		case i >= 0:
+			keepComments(parent.Content[i+1], value)
+			parent.Content[i+1] = value
+		default:
+			return fmt.Errorf("key %q not found", token)
+		}
+	case yaml.SequenceNode:
+		if op == "add" && token == "-" {
+			parent.Content = append(parent.Content, value)
+			return nil
+		}
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index > len(parent.Content) || (op != "add" && index == len(parent.Content)) {
+			return fmt.Errorf("index %q out of range", token)
+		}
+		switch op {
+		case "add":
+			parent.Content = append(parent.Content[:index], append([]*yaml.Node{value}, parent.Content[index:]...)...)
+		case "remove":
+			parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+		default:
+			keepComments(parent.Content[index], value)
+			parent.Content[index] = value
+		}
+	default:
+		return fmt.Errorf("parent of %q is a scalar", token)
+	}
+	return nil
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// keepComments 替换值时保留原值上的注释
+func keepComments(old, value *yaml.Node) {
+	value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+}
+
+// valueNode 把 JSON 值转换为块风格的 YAML 节点
+func valueNode(raw []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("decode value failed: %w", err)
+	}
+	if len(doc.Content) != 1 {
+		return nil, fmt.Errorf("empty value")
+	}
+	clearStyle(doc.Content[0])
+	return doc.Content[0], nil
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+func encodeNode(root *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sameJSON 比较 YAML 文档与 JSON 的内容是否一致
+func sameJSON(doc, expected []byte) bool {
+	raw, err := sigsyaml.YAMLToJSON(doc)
+	if err != nil {
+		return false
+	}
+	var a, b any
+	if json.Unmarshal(raw, &a) != nil || json.Unmarshal(expected, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// manifestObject 读取文档的 kind 和 metadata.name，空文档返回零值
+func manifestObject(doc []byte) (ManifestObject, error) {
+	var meta struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err := sigsyaml.Unmarshal(doc, &meta); err != nil {
+		return ManifestObject{}, err
+	}
+	return ManifestObject{Kind: meta.Kind, Name: meta.Metadata.Name}, nil
+}
+
+// splitDocuments 按 --- 分隔符拆分多文档 YAML，每个文档保留末尾换行
+func splitDocuments(content []byte) [][]byte {
+	var docs [][]byte
+	var current bytes.Buffer
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if strings.TrimRight(line, " \r\n") == "---" {
+			if current.Len() > 0 {
+				docs = append(docs, bytes.Clone(current.Bytes()))
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteString(line)
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		docs = append(docs, current.Bytes())
+	}
+	return docs
+}
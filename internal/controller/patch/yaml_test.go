@@ -0,0 +1,184 @@
+package patch
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("ApplyPatchesToYAML", func() {
+	const manifest = `# 订单服务
+apiVersion: v1
+kind: Service
+metadata:
+  name: order
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: order
+spec:
+  replicas: 2 # 由 HPA 之外的流程管理
+  template:
+    spec:
+      containers:
+        - name: app
+          image: order:v1
+          resources:
+            limits:
+              cpu: 500m
+`
+
+	targeted := func(kind, path, value string) autofixv1.PatchOperation {
+		op := newOp("replace", path, value)
+		op.TargetRef = &corev1.ObjectReference{Kind: kind, Name: "order"}
+		return op
+	}
+
+	It("should patch only the matching document and keep comments and field order", func() {
+		patched, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/replicas", "3"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(patched)).To(HavePrefix("# 订单服务\napiVersion: v1\nkind: Service\nmetadata:\n  name: order\n---\n"))
+		Expect(string(patched)).To(ContainSubstring("replicas: 3 # 由 HPA 之外的流程管理\n  template:"))
+		Expect(string(patched)).To(ContainSubstring("cpu: 500m"))
+	})
+
+	It("should add and remove fields", func() {
+		add := targeted("Deployment", "/spec/template/spec/containers/0/resources/limits/memory", `"1Gi"`)
+		add.Op = "add"
+		remove := targeted("Deployment", "/spec/template/spec/containers/0/resources/limits/cpu", "")
+		remove.Op = "remove"
+
+		patched, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{add, remove})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(patched)).To(ContainSubstring("limits:\n              memory: 1Gi\n"))
+		Expect(string(patched)).NotTo(ContainSubstring("cpu:"))
+	})
+
+	It("should keep string values quoted when they look like numbers", func() {
+		patched, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/template/spec/containers/0/image", `"1"`),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(patched)).To(ContainSubstring(`image: "1"`))
+	})
+
+	It("should resolve container names against the containers of the manifest", func() {
+		// 线上注入了 sidecar，但清单中业务容器仍是第一个
+		patched, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/template/spec/containers/name=app/resources/limits/cpu", `"750m"`),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(patched)).To(ContainSubstring("cpu: 750m"))
+
+		_, err = ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/template/spec/containers/name=istio-proxy/resources/limits/cpu", `"750m"`),
+		})
+		Expect(err).To(MatchError(ContainSubstring(`container "istio-proxy" not found, workload has containers ["app"]`)))
+	})
+
+	It("should fail when replace targets a missing path", func() {
+		_, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/strategy/type", `"Recreate"`),
+		})
+		Expect(err).To(MatchError(ContainSubstring("patch Deployment/order failed")))
+	})
+
+	It("should reject values of the wrong type before touching the manifest", func() {
+		_, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/replicas", `"3"`),
+		})
+		Expect(err).To(MatchError(ContainSubstring("replicas must be an integer")))
+
+		_, err = ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/template/spec/containers/0/resources/limits/memory", `"lots"`),
+		})
+		Expect(err).To(MatchError(ContainSubstring(`invalid quantity "lots"`)))
+	})
+
+	It("should fail when no document matches the target", func() {
+		_, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("StatefulSet", "/spec/replicas", "3"),
+		})
+		Expect(err).To(MatchError("patch replace /spec/replicas: no document matches the target"))
+	})
+
+	It("should bump minReplicas on the HPA next to its Deployment", func() {
+		hpa := manifest + `---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: order
+spec:
+  minReplicas: 2 # 大促前手动调高
+  maxReplicas: 10
+  metrics:
+    - type: Resource
+      resource:
+        name: cpu
+        target:
+          type: Utilization
+          averageUtilization: 80
+`
+		patched, err := ApplyPatchesToYAML([]byte(hpa), []autofixv1.PatchOperation{
+			targeted("HorizontalPodAutoscaler", "/spec/minReplicas", "4"),
+			targeted("HorizontalPodAutoscaler", "/spec/metrics/0/resource/target/averageUtilization", "60"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(patched)).To(HavePrefix(manifest + "---\n"))
+		Expect(string(patched)).To(ContainSubstring("minReplicas: 4 # 大促前手动调高\n  maxReplicas: 10\n"))
+		Expect(string(patched)).To(ContainSubstring("averageUtilization: 60\n"))
+	})
+
+	It("should reject patches whose path does not apply to the target kind", func() {
+		_, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{
+			targeted("Deployment", "/spec/minReplicas", "4"),
+		})
+		Expect(err).To(MatchError("patch replace /spec/minReplicas: path does not apply to Deployment"))
+	})
+
+	It("should create the pod template annotations for a restart", func() {
+		restart := targeted("Deployment", "/spec/template/metadata/annotations/kubectl.kubernetes.io~1restartedAt", `"2025-11-26T12:45:55Z"`)
+		restart.Op = "add"
+
+		patched, err := ApplyPatchesToYAML([]byte(manifest), []autofixv1.PatchOperation{restart})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(patched)).To(ContainSubstring("replicas: 2 # 由 HPA 之外的流程管理\n"))
+		Expect(string(patched)).To(ContainSubstring(
+			"  template:\n    spec:\n      containers:\n        - name: app\n          image: order:v1\n"))
+		Expect(string(patched)).To(ContainSubstring(
+			"    metadata:\n      annotations:\n        kubectl.kubernetes.io/restartedAt: \"2025-11-26T12:45:55Z\"\n"))
+	})
+
+	It("should patch a ConfigMap and bump the restart annotation of its Deployment", func() {
+		const withConfig = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: order
+data:
+  DB_POOL_SIZE: "20" # 连接池大小
+---
+` + manifest
+		restart := targeted("Deployment", "/spec/template/metadata/annotations/kubectl.kubernetes.io~1restartedAt", `"2025-11-26T12:45:55Z"`)
+		restart.Op = "add"
+
+		patched, err := ApplyPatchesToYAML([]byte(withConfig), []autofixv1.PatchOperation{
+			targeted("ConfigMap", "/data/DB_POOL_SIZE", `"50"`),
+			restart,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(patched)).To(HavePrefix("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: order\ndata:\n  DB_POOL_SIZE: \"50\" # 连接池大小\n---\n"))
+		Expect(string(patched)).To(ContainSubstring(
+			"    metadata:\n      annotations:\n        kubectl.kubernetes.io/restartedAt: \"2025-11-26T12:45:55Z\"\n"))
+	})
+
+	It("should list the objects in a manifest", func() {
+		objects, err := ManifestObjects([]byte(manifest))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(Equal([]ManifestObject{{Kind: "Service", Name: "order"}, {Kind: "Deployment", Name: "order"}}))
+	})
+})
@@ -0,0 +1,81 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("FilterPodFields", func() {
+	newPod := func(status corev1.PodStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "order-0", Namespace: "product-a", UID: "uid", ResourceVersion: "42",
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubelet"}},
+			},
+			Status: status,
+		}
+	}
+
+	It("should handle a freshly created Pending pod", func() {
+		filtered := FilterPodFields(newPod(corev1.PodStatus{Phase: corev1.PodPending}))
+		Expect(filtered.Status.Phase).To(Equal(corev1.PodPending))
+		Expect(filtered.Status.Conditions).To(BeNil())
+		Expect(filtered.Status.ContainerStatuses).To(BeNil())
+		Expect(filtered.ManagedFields).To(BeNil())
+		Expect(filtered.UID).To(BeEmpty())
+	})
+
+	It("should keep the Ready condition regardless of its position", func() {
+		filtered := FilterPodFields(newPod(corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+			},
+		}))
+		Expect(filtered.Status.Conditions).To(Equal([]corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}))
+	})
+
+	It("should handle a pod with multiple containers", func() {
+		filtered := FilterPodFields(newPod(corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", Ready: true, ImageID: "sha256:app", Image: "order:v1"},
+				{Name: "istio-proxy", Ready: true, ImageID: "sha256:proxy", Image: "proxyv2:1.20"},
+			},
+		}))
+		Expect(filtered.Status.ContainerStatuses).To(Equal([]corev1.ContainerStatus{
+			{Name: "app", Ready: true},
+			{Name: "istio-proxy", Ready: true},
+		}))
+	})
+
+	It("should keep the restart count and state of a crash-looping pod", func() {
+		waiting := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+		filtered := FilterPodFields(newPod(corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: 7, State: waiting}},
+		}))
+		Expect(filtered.Status.ContainerStatuses[0].RestartCount).To(Equal(int32(7)))
+		Expect(filtered.Status.ContainerStatuses[0].State).To(Equal(waiting))
+	})
+})
@@ -0,0 +1,60 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podLabelsField 是 Pod 按标签建立的索引字段，每个标签对应一个 "key=value" 索引值
+const podLabelsField = "metadata.labels"
+
+// indexPodLabels 返回 Pod 在 podLabelsField 索引中的取值
+func indexPodLabels(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || len(pod.Labels) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(pod.Labels))
+	for key, value := range pod.Labels {
+		values = append(values, podLabelIndexValue(key, value))
+	}
+	return values
+}
+
+// podLabelIndexValue 标签在 podLabelsField 索引中的取值
+func podLabelIndexValue(key, value string) string {
+	return key + "=" + value
+}
+
+// podLabelIndexSelector 从 matchLabels 中选出一个标签作为索引查询条件，没有 matchLabels 时返回 nil
+// 缓存只能按单个索引值查询，其余 matchLabels 和 matchExpressions 仍由标签选择器在索引结果上过滤
+func podLabelIndexSelector(selector *metav1.LabelSelector) client.MatchingFields {
+	if len(selector.MatchLabels) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(selector.MatchLabels))
+	for key := range selector.MatchLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return client.MatchingFields{podLabelsField: podLabelIndexValue(keys[0], selector.MatchLabels[keys[0]])}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// newLabeledPod 创建带标签的 Pod
+func newLabeledPod(namespace, name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: podLabels}}
+}
+
+var _ = Describe("Pod label index", func() {
+	It("should index every label as key=value", func() {
+		pod := newLabeledPod("default", "order-0", map[string]string{"app": "order", "track": "canary"})
+		Expect(indexPodLabels(pod)).To(ConsistOf("app=order", "track=canary"))
+		Expect(indexPodLabels(newLabeledPod("default", "bare", nil))).To(BeEmpty())
+	})
+
+	It("should query the index by the first matchLabel", func() {
+		Expect(podLabelIndexSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web", "app": "order"}})).
+			To(Equal(client.MatchingFields{podLabelsField: "app=order"}))
+		Expect(podLabelIndexSelector(&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: metav1.LabelSelectorOpExists},
+		}})).To(BeNil())
+	})
+
+	It("should list target pods through the index and still apply the whole selector", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		reconciler := &AIOpsAnalyzerReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).
+				WithIndex(&corev1.Pod{}, podLabelsField, indexPodLabels).
+				WithObjects(
+					newLabeledPod("shop", "order-stable", map[string]string{"app": "order", "track": "stable"}),
+					newLabeledPod("shop", "order-canary", map[string]string{"app": "order", "track": "canary"}),
+					newLabeledPod("shop", "payment", map[string]string{"app": "payment", "track": "stable"}),
+					newLabeledPod("other", "order-other", map[string]string{"app": "order", "track": "stable"}),
+				).Build(),
+			podLabelsIndexed: true,
+		}
+
+		pods, err := reconciler.GetTargetPods(context.Background(), &autofixv1.TargetSelector{
+			Namespace: "shop",
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "order"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "track", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"canary"}},
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods).To(HaveLen(1))
+		Expect(pods[0].Name).To(Equal("order-stable"))
+	})
+})
+
+// BenchmarkTargetPodLookup 对比在 10k Pod 的命名空间中按命名空间扫描和按标签索引查找目标 Pod，
+// 索引函数与控制器缓存中注册的相同
+func BenchmarkTargetPodLookup(b *testing.B) {
+	const namespaceIndex, labelsIndex = "namespace", "field:" + podLabelsField
+	indexer := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc, toolscache.Indexers{
+		namespaceIndex: toolscache.MetaNamespaceIndexFunc,
+		labelsIndex: func(obj interface{}) ([]string, error) {
+			pod := obj.(*corev1.Pod)
+			var keys []string
+			for _, value := range indexPodLabels(pod) {
+				keys = append(keys, pod.Namespace+"/"+value)
+			}
+			return keys, nil
+		},
+	})
+	for i := 0; i < 10000; i++ {
+		pod := newLabeledPod("shop", fmt.Sprintf("pod-%d", i), map[string]string{
+			"app": fmt.Sprintf("app-%d", i%100), "pod-template-hash": fmt.Sprintf("%x", i%7),
+		})
+		if err := indexer.Add(pod); err != nil {
+			b.Fatal(err)
+		}
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app-42"}}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		b.Fatal(err)
+	}
+	match := func(b *testing.B, objs []interface{}) {
+		matched := 0
+		for _, obj := range objs {
+			if labelSelector.Matches(labels.Set(obj.(*corev1.Pod).Labels)) {
+				matched++
+			}
+		}
+		if matched != 100 {
+			b.Fatalf("matched %d pods, want 100", matched)
+		}
+	}
+
+	b.Run("namespace scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			objs, err := indexer.ByIndex(namespaceIndex, "shop")
+			if err != nil {
+				b.Fatal(err)
+			}
+			match(b, objs)
+		}
+	})
+	b.Run("label index", func(b *testing.B) {
+		value := podLabelIndexSelector(selector)[podLabelsField]
+		for i := 0; i < b.N; i++ {
+			objs, err := indexer.ByIndex(labelsIndex, "shop/"+value)
+			if err != nil {
+				b.Fatal(err)
+			}
+			match(b, objs)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// postPreviewComment 预览模式下把修复建议作为评论发到 spec.gitOps.previewMode.trackingPR
+func (r *AIOpsAnalyzerReconciler) postPreviewComment(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) error {
+	provider, err := r.gitProvider(ctx, aiopsAnalyzer)
+	if err != nil {
+		return err
+	}
+	return r.GitLimiter.Do(ctx, func(ctx context.Context) error {
+		return provider.CommentOnPullRequest(ctx, aiopsAnalyzer.Spec.GitOps.PreviewMode.TrackingPR, formatPreviewComment(aiopsAnalyzer, heal))
+	})
+}
+
+// formatPreviewComment 生成 markdown 格式的评论内容
+func formatPreviewComment(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) string {
+	patches, err := json.MarshalIndent(heal.PatchContent, "", "  ")
+	if err != nil {
+		patches = []byte(fmt.Sprintf("%v", heal.PatchContent))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### AIOps 修复建议预览（%s/%s）\n\n", aiopsAnalyzer.Namespace, aiopsAnalyzer.Name)
+	fmt.Fprintf(&b, "- 原因：%s\n", heal.Reason)
+	fmt.Fprintf(&b, "- 风险：%s\n", heal.RiskLevel)
+	fmt.Fprintf(&b, "- 目标：%s `%s`\n", heal.Target.Kind, heal.Target.LabelSelector)
+	fmt.Fprintf(&b, "- 补丁文件：`%s`\n\n", heal.PatchFile)
+	if heal.Detail != "" {
+		fmt.Fprintf(&b, "%s\n\n", heal.Detail)
+	}
+	fmt.Fprintf(&b, "```json\n%s\n```\n\n", patches)
+	b.WriteString("_预览模式：仅供评审，不会自动提交或创建 PR。_\n")
+	return b.String()
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// Prometheus 标签名只允许字母、数字和下划线
+var invalidPrometheusLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// 单独展示、不再重复出现在 Labels 中的标签
+var prometheusAlertLabelsShownSeparately = map[string]bool{
+	"__name__":  true,
+	"alertname": true,
+	"severity":  true,
+	"namespace": true,
+	"pod":       true,
+}
+
+// prometheusAlertsQuery 构建查询目标命名空间中正在触发的告警的 PromQL
+// 标签按名称排序保证查询稳定，标签值使用双引号
+// Kubernetes 标签名中的 . / - 等字符在 Prometheus 中不合法，按服务发现的惯例替换为 _
+func prometheusAlertsQuery(target *autofixv1.TargetSelector) string {
+	matchers := []string{
+		fmt.Sprintf("namespace=%q", target.Namespace),
+		`alertstate="firing"`,
+	}
+	matchers = append(matchers, labelSelectorMatchers(target.Selector, sanitizeLabelName)...)
+	return fmt.Sprintf("ALERTS{%s}", strings.Join(matchers, ","))
+}
+
+// sanitizeLabelName 把 Kubernetes 标签名中不合法的字符替换为 _，Prometheus 与 Loki 的标签名规则相同
+func sanitizeLabelName(key string) string {
+	return invalidPrometheusLabelChars.ReplaceAllString(key, "_")
+}
+
+// labelSelectorMatchers 把标签选择器转换为 PromQL/LogQL 的标签匹配器，labelName 转换标签名
+// matchLabels 按名称排序在前，matchExpressions 按声明顺序在后：
+// In、NotIn 转换为正则 =~、!~（取值转义后用 | 连接），Exists、DoesNotExist 转换为 !=""、=""
+func labelSelectorMatchers(selector metav1.LabelSelector, labelName func(string) string) []string {
+	var matchers []string
+	for _, key := range sortedKeys(selector.MatchLabels) {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", labelName(key), selector.MatchLabels[key]))
+	}
+	for _, expr := range selector.MatchExpressions {
+		name := labelName(expr.Key)
+		switch expr.Operator {
+		case metav1.LabelSelectorOpIn:
+			matchers = append(matchers, fmt.Sprintf("%s=~%q", name, valuesRegexp(expr.Values)))
+		case metav1.LabelSelectorOpNotIn:
+			matchers = append(matchers, fmt.Sprintf("%s!~%q", name, valuesRegexp(expr.Values)))
+		case metav1.LabelSelectorOpExists:
+			matchers = append(matchers, fmt.Sprintf("%s!=\"\"", name))
+		case metav1.LabelSelectorOpDoesNotExist:
+			matchers = append(matchers, fmt.Sprintf("%s=\"\"", name))
+		}
+	}
+	return matchers
+}
+
+// valuesRegexp 返回完整匹配任意一个取值的正则
+func valuesRegexp(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = regexp.QuoteMeta(value)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// PrometheusAlert 单条告警，Labels 来自 ALERTS 的 metric，结果中带有注解时同时解析注解
+type PrometheusAlert struct {
+	Labels      map[string]string `json:"metric"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// queryResponse Prometheus /api/v1/query、/api/v1/query_range 和 Loki query_range 共用的响应结构
+// 查询失败时 status 为 "error"，并带有 errorType 和 error
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// decodeQueryResponse 解析查询响应并把 data.result 解析到 result，
+// status 不是 success 或 resultType 与预期不符时返回错误
+func decodeQueryResponse(body io.Reader, resultType string, result any) error {
+	var resp queryResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return fmt.Errorf("decode query response failed: %w", err)
+	}
+	if resp.Status != "success" {
+		return fmt.Errorf("query failed with status %q: %s: %s", resp.Status, resp.ErrorType, resp.Error)
+	}
+	if resp.Data.ResultType != resultType {
+		return fmt.Errorf("unexpected result type %q, expected %q", resp.Data.ResultType, resultType)
+	}
+	if err := json.Unmarshal(resp.Data.Result, result); err != nil {
+		return fmt.Errorf("decode %s result failed: %w", resultType, err)
+	}
+	return nil
+}
+
+// parsePrometheusAlerts 解析即时查询响应，结果必须是 vector
+func parsePrometheusAlerts(body io.Reader) ([]PrometheusAlert, error) {
+	var alerts []PrometheusAlert
+	if err := decodeQueryResponse(body, "vector", &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// Format 输出告警名称、严重程度、命名空间、Pod、其余标签和注解，缺少的字段直接跳过
+func (a PrometheusAlert) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Alert: %s\n", a.Labels["alertname"])
+	if severity := a.Labels["severity"]; severity != "" {
+		fmt.Fprintf(&b, "  Severity: %s\n", severity)
+	}
+	if namespace := a.Labels["namespace"]; namespace != "" {
+		fmt.Fprintf(&b, "  Namespace: %s\n", namespace)
+	}
+	if pod := a.Labels["pod"]; pod != "" {
+		fmt.Fprintf(&b, "  Pod: %s\n", pod)
+	}
+
+	var labels []string
+	for _, key := range sortedKeys(a.Labels) {
+		if !prometheusAlertLabelsShownSeparately[key] {
+			labels = append(labels, fmt.Sprintf("%s=%s", key, a.Labels[key]))
+		}
+	}
+	if len(labels) > 0 {
+		fmt.Fprintf(&b, "  Labels: %s\n", strings.Join(labels, ", "))
+	}
+
+	// summary、description 优先展示
+	annotations := sortedKeys(a.Annotations)
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotationOrder(annotations[i]) < annotationOrder(annotations[j])
+	})
+	for _, key := range annotations {
+		fmt.Fprintf(&b, "  %s: %s\n", key, a.Annotations[key])
+	}
+	return b.String()
+}
+
+// annotationOrder 返回注解的展示顺序
+func annotationOrder(key string) int {
+	switch key {
+	case "summary":
+		return 0
+	case "description":
+		return 1
+	default:
+		return 2
+	}
+}
+
+const (
+	// defaultPrometheusRangeWindow 未配置 window 时区间查询的时间范围
+	defaultPrometheusRangeWindow = 30 * time.Minute
+	// defaultPrometheusRangeStep 未配置 step 时区间查询的采样步长
+	defaultPrometheusRangeStep = time.Minute
+	// prometheusTrendPoints 每条序列最多展示的采样点数，多余的点均匀抽样
+	prometheusTrendPoints = 12
+)
+
+// prometheusRangeQuery 一次区间查询的 PromQL 与时间范围
+type prometheusRangeQuery struct {
+	Name  string
+	Expr  string
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
+// prometheusRangeQueryFor 替换 expr 中的 $namespace、$pod，并根据 window、step 计算截止到 now 的查询范围
+func prometheusRangeQueryFor(cfg autofixv1.PrometheusRangeQuery, target *autofixv1.TargetSelector, pods []corev1.Pod, now time.Time) (prometheusRangeQuery, error) {
+	window, step := defaultPrometheusRangeWindow, defaultPrometheusRangeStep
+	if cfg.Window != "" {
+		d, err := time.ParseDuration(cfg.Window)
+		if err != nil || d <= 0 {
+			return prometheusRangeQuery{}, fmt.Errorf("invalid window %q", cfg.Window)
+		}
+		window = d
+	}
+	if cfg.Step != "" {
+		d, err := time.ParseDuration(cfg.Step)
+		if err != nil || d <= 0 {
+			return prometheusRangeQuery{}, fmt.Errorf("invalid step %q", cfg.Step)
+		}
+		step = d
+	}
+
+	podNames := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		podNames = append(podNames, regexp.QuoteMeta(pod.Name))
+	}
+	expr := strings.NewReplacer(
+		"$namespace", target.Namespace,
+		"$pod", strings.Join(podNames, "|"),
+	).Replace(cfg.Expr)
+
+	return prometheusRangeQuery{Name: cfg.Name, Expr: expr, Start: now.Add(-window), End: now, Step: step}, nil
+}
+
+// PrometheusSample 区间查询结果中的一个采样点
+type PrometheusSample struct {
+	Time  time.Time
+	Value float64
+}
+
+// PrometheusSeries 区间查询结果中的一条序列
+type PrometheusSeries struct {
+	Labels  map[string]string
+	Samples []PrometheusSample
+}
+
+// prometheusMatrixSeries 区间查询结果中的一条序列，values 为 [时间戳, "值"]
+type prometheusMatrixSeries struct {
+	Metric map[string]string    `json:"metric"`
+	Values [][2]json.RawMessage `json:"values"`
+}
+
+// parsePrometheusSeries 解析区间查询响应，结果必须是 matrix
+func parsePrometheusSeries(body io.Reader) ([]PrometheusSeries, error) {
+	var matrix []prometheusMatrixSeries
+	if err := decodeQueryResponse(body, "matrix", &matrix); err != nil {
+		return nil, err
+	}
+
+	series := make([]PrometheusSeries, 0, len(matrix))
+	for _, result := range matrix {
+		s := PrometheusSeries{Labels: result.Metric}
+		for _, value := range result.Values {
+			var timestamp float64
+			var raw string
+			if err := json.Unmarshal(value[0], &timestamp); err != nil {
+				return nil, fmt.Errorf("invalid sample timestamp %s: %w", value[0], err)
+			}
+			if err := json.Unmarshal(value[1], &raw); err != nil {
+				return nil, fmt.Errorf("invalid sample value %s: %w", value[1], err)
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sample value %q: %w", raw, err)
+			}
+			sec, frac := math.Modf(timestamp)
+			s.Samples = append(s.Samples, PrometheusSample{Time: time.Unix(int64(sec), int64(frac*1e9)).UTC(), Value: v})
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+// Format 输出序列标签、最小/最大/最新值，以及均匀抽样后的 "时间=值"
+func (s PrometheusSeries) Format() string {
+	var b strings.Builder
+	labels := make([]string, 0, len(s.Labels))
+	for _, key := range sortedKeys(s.Labels) {
+		labels = append(labels, fmt.Sprintf("%s=%s", key, s.Labels[key]))
+	}
+	fmt.Fprintf(&b, "{%s}", strings.Join(labels, ", "))
+	if len(s.Samples) == 0 {
+		b.WriteString(" no data\n")
+		return b.String()
+	}
+
+	minValue, maxValue := s.Samples[0].Value, s.Samples[0].Value
+	for _, sample := range s.Samples[1:] {
+		minValue = math.Min(minValue, sample.Value)
+		maxValue = math.Max(maxValue, sample.Value)
+	}
+	fmt.Fprintf(&b, " min=%s max=%s last=%s\n ", formatSampleValue(minValue), formatSampleValue(maxValue),
+		formatSampleValue(s.Samples[len(s.Samples)-1].Value))
+	for _, sample := range downsample(s.Samples, prometheusTrendPoints) {
+		fmt.Fprintf(&b, " %s=%s", sample.Time.Format("15:04"), formatSampleValue(sample.Value))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// formatSampleValue 保留 4 位有效数字
+func formatSampleValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// downsample 均匀抽取最多 n 个采样点，始终保留第一个和最后一个
+func downsample(samples []PrometheusSample, n int) []PrometheusSample {
+	if len(samples) <= n {
+		return samples
+	}
+	picked := make([]PrometheusSample, 0, n)
+	for i := 0; i < n; i++ {
+		picked = append(picked, samples[i*(len(samples)-1)/(n-1)])
+	}
+	return picked
+}
+
+// sortedKeys 返回按名称排序的 key
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
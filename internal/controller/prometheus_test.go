@@ -0,0 +1,246 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Prometheus alerts", func() {
+	target := &autofixv1.TargetSelector{Namespace: "product-a"}
+
+	query := func(status int, body string) (string, error) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(prometheusQueryPath))
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		defer server.Close()
+
+		reconciler := &AIOpsAnalyzerReconciler{}
+		return reconciler.GetPrometheusAlerts(context.Background(), datasources{PrometheusURL: server.URL}, target)
+	}
+
+	It("should format firing alerts from a vector result", func() {
+		alerts, err := query(http.StatusOK, `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"alertname":"HighCPU","namespace":"product-a","pod":"order-0"},"value":[1700000000,"1"]}]}}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(alerts).To(ContainSubstring("Alert: HighCPU"))
+		Expect(alerts).To(ContainSubstring("Pod: order-0"))
+	})
+
+	It("should return the body on non-200 responses", func() {
+		_, err := query(http.StatusServiceUnavailable, "prometheus is restarting")
+		Expect(err).To(MatchError(ContainSubstring("prometheus returned 503: prometheus is restarting")))
+	})
+
+	It("should render severity, remaining labels and annotations", func() {
+		alerts, err := query(http.StatusOK, `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"__name__":"ALERTS","alertname":"HighCPU","alertstate":"firing","severity":"critical","namespace":"product-a","container":"app"},
+			 "annotations":{"runbook_url":"https://runbooks/cpu","description":"CPU 超过 90% 持续 5 分钟","summary":"CPU 飙高"},
+			 "value":[1700000000,"1"]}]}}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(alerts).To(Equal("Alert: HighCPU\n" +
+			"  Severity: critical\n" +
+			"  Namespace: product-a\n" +
+			"  Labels: alertstate=firing, container=app\n" +
+			"  summary: CPU 飙高\n" +
+			"  description: CPU 超过 90% 持续 5 分钟\n" +
+			"  runbook_url: https://runbooks/cpu\n\n"))
+	})
+
+	It("should not panic on alerts without optional fields", func() {
+		Expect(PrometheusAlert{}.Format()).To(Equal("Alert: \n"))
+		alerts, err := query(http.StatusOK, `{"status":"success","data":{"resultType":"vector","result":[{"value":[1700000000,"1"]}]}}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(alerts).To(Equal("Alert: \n\n"))
+	})
+
+	It("should reject non-vector results", func() {
+		_, err := query(http.StatusOK, `{"status":"success","data":{"resultType":"matrix","result":[]}}`)
+		Expect(err).To(MatchError(`unexpected result type "matrix", expected "vector"`))
+	})
+
+	DescribeTable("decoding query responses",
+		func(body string, expected []PrometheusAlert, expectErr string) {
+			alerts, err := parsePrometheusAlerts(strings.NewReader(body))
+			if expectErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(expectErr)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(alerts).To(Equal(expected))
+		},
+		Entry("vector with labels and annotations",
+			`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"alertname":"HighCPU"},"annotations":{"summary":"CPU 飙高"},"value":[1700000000,"1"]}]}}`,
+			[]PrometheusAlert{{Labels: map[string]string{"alertname": "HighCPU"}, Annotations: map[string]string{"summary": "CPU 飙高"}}}, ""),
+		Entry("empty vector", `{"status":"success","data":{"resultType":"vector","result":[]}}`, []PrometheusAlert{}, ""),
+		Entry("error status", `{"status":"error","errorType":"bad_data","error":"parse error at char 5"}`,
+			nil, `query failed with status "error": bad_data: parse error at char 5`),
+		Entry("result is not a list", `{"status":"success","data":{"resultType":"vector","result":{"metric":{}}}}`,
+			nil, "decode vector result failed"),
+		Entry("labels are not strings", `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"alertname":1}}]}}`,
+			nil, "decode vector result failed"),
+		Entry("body is not JSON", `<html>bad gateway</html>`, nil, "decode query response failed"),
+	)
+
+	It("should select firing alerts with label matchers", func() {
+		target := &autofixv1.TargetSelector{
+			Namespace: "product-a",
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{
+				"app.kubernetes.io/name": "order-service",
+				"app":                    "order",
+			}},
+		}
+		Expect(prometheusAlertsQuery(target)).To(Equal(
+			`ALERTS{namespace="product-a",alertstate="firing",app="order",app_kubernetes_io_name="order-service"}`))
+	})
+
+	DescribeTable("translating matchExpressions",
+		func(expr metav1.LabelSelectorRequirement, matcher string) {
+			target := &autofixv1.TargetSelector{
+				Namespace: "product-a",
+				Selector: metav1.LabelSelector{
+					MatchLabels:      map[string]string{"app": "order"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{expr},
+				},
+			}
+			Expect(prometheusAlertsQuery(target)).To(Equal(
+				`ALERTS{namespace="product-a",alertstate="firing",app="order",` + matcher + `}`))
+		},
+		Entry("In", metav1.LabelSelectorRequirement{
+			Key: "app.kubernetes.io/version", Operator: metav1.LabelSelectorOpIn, Values: []string{"v1.2", "canary"},
+		}, `app_kubernetes_io_version=~"v1\\.2|canary"`),
+		Entry("NotIn", metav1.LabelSelectorRequirement{
+			Key: "track", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"canary"},
+		}, `track!~"canary"`),
+		Entry("Exists", metav1.LabelSelectorRequirement{
+			Key: "tier", Operator: metav1.LabelSelectorOpExists,
+		}, `tier!=""`),
+		Entry("DoesNotExist", metav1.LabelSelectorRequirement{
+			Key: "debug", Operator: metav1.LabelSelectorOpDoesNotExist,
+		}, `debug=""`),
+	)
+})
+
+var _ = Describe("Prometheus range queries", func() {
+	const cpuMatrix = `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"pod":"order-0"},"values":[[1700000000,"0.12"],[1700000060,"0.5"],[1700000120,"0.95"]]}]}}`
+
+	It("should query /api/v1/query_range with the window and step", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(prometheusQueryRangePath))
+			Expect(req.URL.Query().Get("query")).To(Equal(`rate(container_cpu_usage_seconds_total[5m])`))
+			Expect(req.URL.Query().Get("start")).To(Equal("1700000000"))
+			Expect(req.URL.Query().Get("end")).To(Equal("1700001800"))
+			Expect(req.URL.Query().Get("step")).To(Equal("60"))
+			_, _ = w.Write([]byte(cpuMatrix))
+		}))
+		defer server.Close()
+
+		reconciler := &AIOpsAnalyzerReconciler{}
+		end := time.Unix(1700001800, 0)
+		series, err := reconciler.QueryRange(context.Background(), datasources{PrometheusURL: server.URL},
+			`rate(container_cpu_usage_seconds_total[5m])`, end.Add(-30*time.Minute), end, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(series).To(HaveLen(1))
+		Expect(series[0].Labels).To(Equal(map[string]string{"pod": "order-0"}))
+		Expect(series[0].Samples).To(HaveLen(3))
+		Expect(series[0].Samples[2]).To(Equal(PrometheusSample{Time: time.Unix(1700000120, 0).UTC(), Value: 0.95}))
+	})
+
+	It("should format a series as a compact trend", func() {
+		series, err := parsePrometheusSeries(strings.NewReader(cpuMatrix))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(series[0].Format()).To(Equal("{pod=order-0} min=0.12 max=0.95 last=0.95\n  22:13=0.12 22:14=0.5 22:15=0.95\n"))
+		Expect(PrometheusSeries{}.Format()).To(Equal("{} no data\n"))
+	})
+
+	It("should keep at most prometheusTrendPoints samples including both ends", func() {
+		var samples []PrometheusSample
+		for i := 0; i < 61; i++ {
+			samples = append(samples, PrometheusSample{Time: time.Unix(int64(i*60), 0), Value: float64(i)})
+		}
+		picked := downsample(samples, prometheusTrendPoints)
+		Expect(picked).To(HaveLen(prometheusTrendPoints))
+		Expect(picked[0].Value).To(Equal(0.0))
+		Expect(picked[len(picked)-1].Value).To(Equal(60.0))
+	})
+
+	It("should substitute the target namespace and pods into the expression", func() {
+		target := &autofixv1.TargetSelector{Namespace: "product-a"}
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "order-0"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "order.1"}},
+		}
+		now := time.Unix(1700001800, 0)
+		query, err := prometheusRangeQueryFor(autofixv1.PrometheusRangeQuery{
+			Name: "cpu",
+			Expr: `sum by (pod) (rate(container_cpu_usage_seconds_total{namespace="$namespace",pod=~"$pod"}[5m]))`,
+		}, target, pods, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query.Expr).To(Equal(`sum by (pod) (rate(container_cpu_usage_seconds_total{namespace="product-a",pod=~"order-0|order\.1"}[5m]))`))
+		Expect(query.Start).To(Equal(now.Add(-defaultPrometheusRangeWindow)))
+		Expect(query.Step).To(Equal(defaultPrometheusRangeStep))
+
+		_, err = prometheusRangeQueryFor(autofixv1.PrometheusRangeQuery{Name: "cpu", Expr: "up", Step: "0s"}, target, pods, now)
+		Expect(err).To(MatchError(ContainSubstring(`invalid step "0s"`)))
+	})
+
+	It("should render each named query in the event string and keep going on failures", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.URL.Path == prometheusQueryPath:
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			case req.URL.Query().Get("query") == "broken":
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte("parse error"))
+			default:
+				_, _ = w.Write([]byte(cpuMatrix))
+			}
+		}))
+		defer server.Close()
+		loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+		}))
+		defer loki.Close()
+
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{Spec: autofixv1.AIOpsAnalyzerSpec{
+			Target: autofixv1.TargetSelector{Namespace: "product-a"},
+			Prometheus: &autofixv1.PrometheusConfig{RangeQueries: []autofixv1.PrometheusRangeQuery{
+				{Name: "cpu", Expr: "rate(container_cpu_usage_seconds_total[5m])"},
+				{Name: "memory", Expr: "broken"},
+			}},
+		}}
+		eventString, err := newFakeReconciler().BuildEventString(context.Background(), aiopsAnalyzer,
+			datasources{PrometheusURL: server.URL, LokiURL: loki.URL}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventString).To(ContainSubstring("=== Prometheus Trends ===\n--- cpu ---\n{pod=order-0} min=0.12"))
+		Expect(eventString).To(ContainSubstring("--- memory ---\nUnavailable: prometheus returned 400: parse error\n"))
+	})
+})
@@ -0,0 +1,168 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// analysisTemplateName 分析提示词模板的名称，出错时出现在错误信息中
+const analysisTemplateName = "analysis"
+
+// defaultAnalysisTemplate 内置的分析提示词模板
+//
+//go:embed prompts/analysis.tmpl
+var defaultAnalysisTemplate string
+
+// defaultPromptKey 未指定 promptTemplateRef.key 时读取的键
+const defaultPromptKey = "analysis.tmpl"
+
+// promptContext 分析提示词模板可以使用的变量
+type promptContext struct {
+	// AppInfo 目标工作负载，{{.AppInfo}} 渲染为 formatWorkloadInfo 的输出
+	AppInfo *workloadInfo
+	// EventString 经过脱敏和截断的监控数据
+	EventString string
+	// CurrentTime 当前时间，格式 YYYYMMDD-HHMMSS
+	CurrentTime string
+	// Thresholds spec.thresholds，未配置时为 nil
+	Thresholds *autofixv1.Thresholds
+	// AllowedActions 允许的修复类型，未配置时为默认的 scale、resource
+	AllowedActions []string
+}
+
+// parseAnalysisTemplate 解析分析提示词模板，模板中引用不存在的变量时渲染失败
+func parseAnalysisTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New(analysisTemplateName).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt template failed: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderAnalysisPrompt 用 tmpl 渲染大模型请求内容
+func renderAnalysisPrompt(tmpl *template.Template, data promptContext) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render prompt template failed: %w", err)
+	}
+	return b.String(), nil
+}
+
+// newPromptContext 用工作负载、监控数据和 CR 的修复策略构建模板变量
+func newPromptContext(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, workload *workloadInfo, eventString string, now time.Time) promptContext {
+	allowed := aiopsAnalyzer.Spec.AutoRemediation.AllowedActions
+	if len(allowed) == 0 {
+		allowed = llm.DefaultAllowedActions
+	}
+	return promptContext{
+		AppInfo:        workload,
+		EventString:    eventString,
+		CurrentTime:    now.Format("20060102-150405"),
+		Thresholds:     aiopsAnalyzer.Spec.Thresholds,
+		AllowedActions: allowed,
+	}
+}
+
+// analysisTemplate 返回 spec.llm.promptTemplateRef 指定的模板，未配置时使用内置模板
+func (r *AIOpsAnalyzerReconciler) analysisTemplate(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (*template.Template, error) {
+	if aiopsAnalyzer.Spec.LLM == nil || aiopsAnalyzer.Spec.LLM.PromptTemplateRef == nil {
+		return parseAnalysisTemplate(defaultAnalysisTemplate)
+	}
+	ref := aiopsAnalyzer.Spec.LLM.PromptTemplateRef
+	key := ref.Key
+	if key == "" {
+		key = defaultPromptKey
+	}
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, types.NamespacedName{Namespace: aiopsAnalyzer.Namespace, Name: ref.Name}, &configMap); err != nil {
+		return nil, fmt.Errorf("get prompt template ConfigMap %s failed: %w", ref.Name, err)
+	}
+	text, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("prompt template ConfigMap %s has no %q key", ref.Name, key)
+	}
+	return parseAnalysisTemplate(text)
+}
+
+// buildAnalysisPrompt 用工作负载的当前配置和监控数据渲染大模型请求内容
+func (r *AIOpsAnalyzerReconciler) buildAnalysisPrompt(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, workload *workloadInfo, eventString string, now time.Time) (string, error) {
+	tmpl, err := r.analysisTemplate(ctx, aiopsAnalyzer)
+	if err != nil {
+		return "", err
+	}
+	return renderAnalysisPrompt(tmpl, newPromptContext(aiopsAnalyzer, workload, eventString, now))
+}
+
+// String 在提示词模板中以 {{.AppInfo}} 输出工作负载信息
+func (w *workloadInfo) String() string {
+	return formatWorkloadInfo(w)
+}
+
+// formatWorkloadInfo 输出工作负载的标签选择器、命名空间、副本数、HPA 和每个容器的资源配置
+func formatWorkloadInfo(workload *workloadInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- 工作负载：%s/%s\n", workload.Kind, workload.Name)
+	fmt.Fprintf(&b, "- 应用标签选择器：%s\n", workload.LabelSelector)
+	fmt.Fprintf(&b, "- 命名空间：%s\n", workload.Namespace)
+	if workload.Replicas != nil {
+		fmt.Fprintf(&b, "- 当前副本数：%d\n", *workload.Replicas)
+	} else {
+		b.WriteString("- 当前副本数：未设置（默认 1）\n")
+	}
+	if hpa := workload.HPA; hpa != nil {
+		minReplicas := int64(1)
+		if hpa.MinReplicas != nil {
+			minReplicas = *hpa.MinReplicas
+		}
+		fmt.Fprintf(&b, "- HPA %s：minReplicas %d，maxReplicas %d\n", hpa.Name, minReplicas, hpa.MaxReplicas)
+	}
+	if len(workload.ConfigMaps) > 0 {
+		fmt.Fprintf(&b, "- 引用的 ConfigMap：%s\n", strings.Join(workload.ConfigMaps, ", "))
+	}
+	for _, container := range workload.Containers {
+		fmt.Fprintf(&b, "- 容器 %s：CPU requests %s，CPU limits %s，内存 requests %s，内存 limits %s\n",
+			container.Name,
+			formatQuantity(container.Resources.Requests, corev1.ResourceCPU),
+			formatQuantity(container.Resources.Limits, corev1.ResourceCPU),
+			formatQuantity(container.Resources.Requests, corev1.ResourceMemory),
+			formatQuantity(container.Resources.Limits, corev1.ResourceMemory))
+	}
+	return b.String()
+}
+
+// formatQuantity 返回资源量，未设置时返回 "未设置"
+func formatQuantity(resources corev1.ResourceList, name corev1.ResourceName) string {
+	if quantity, ok := resources[name]; ok {
+		return quantity.String()
+	}
+	return "未设置"
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Analysis prompt template", func() {
+	var (
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		workload      *workloadInfo
+		now           time.Time
+	)
+
+	BeforeEach(func() {
+		restartCount := int32(5)
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "shop"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Thresholds:      &autofixv1.Thresholds{CPU: "4", RestartCount: &restartCount},
+				AutoRemediation: autofixv1.AutoRemediationSpec{AllowedActions: []string{"scale", "restart"}},
+			},
+		}
+		replicas := int64(2)
+		workload = &workloadInfo{
+			Kind:          "Deployment",
+			Name:          "order",
+			Namespace:     "shop",
+			LabelSelector: "app=order",
+			Replicas:      &replicas,
+		}
+		now = time.Date(2025, 11, 26, 20, 45, 55, 0, time.UTC)
+	})
+
+	It("should render the built-in template with the sample context", func() {
+		prompt, err := newFakeReconciler().buildAnalysisPrompt(context.Background(), aiopsAnalyzer, workload, "=== Prometheus Alerts ===\nHighCPU firing\n", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompt).To(HavePrefix("### 当前应用信息（请原样使用）：\n" +
+			"- 工作负载：Deployment/order\n" +
+			"- 应用标签选择器：app=order\n" +
+			"- 命名空间：shop\n" +
+			"- 当前副本数：2\n" +
+			"- 当前时间: 20251126-204555\n\n" +
+			"### 修复约束：\n" +
+			"- 允许的修复类型：scale, restart\n" +
+			"- CPU 阈值：4\n" +
+			"- 重启次数阈值：5\n\n" +
+			"### 告警/监控数据：\n=== Prometheus Alerts ===\nHighCPU firing\n"))
+		Expect(prompt).To(ContainSubstring("请立即决定是否需要自愈"))
+		Expect(prompt).To(ContainSubstring(`"namespace": "shop",`))
+		Expect(prompt).To(ContainSubstring("\"kind\": \"Deployment\",\n    \"labelSelector\": \"app=order\""))
+		Expect(prompt).To(ContainSubstring(`"action": "restart"`))
+		Expect(prompt).NotTo(ContainSubstring("<no value>"))
+	})
+
+	It("should list the default actions and skip unset thresholds", func() {
+		aiopsAnalyzer.Spec.Thresholds = nil
+		aiopsAnalyzer.Spec.AutoRemediation.AllowedActions = nil
+		prompt, err := newFakeReconciler().buildAnalysisPrompt(context.Background(), aiopsAnalyzer, workload, "", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompt).To(ContainSubstring("- 允许的修复类型：scale, resource\n\n### 告警/监控数据："))
+	})
+
+	It("should use the template from the referenced ConfigMap", func() {
+		aiopsAnalyzer.Spec.LLM = &autofixv1.LLMSpec{PromptTemplateRef: &autofixv1.ConfigMapKeyRef{Name: "prompts"}}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "prompts", Namespace: "shop"},
+			Data: map[string]string{
+				"analysis.tmpl": "{{.AppInfo.Kind}}/{{.AppInfo.Name}} @ {{.CurrentTime}}: {{.EventString}}",
+				"broken.tmpl":   "{{.Unknown}}",
+			},
+		}
+		reconciler := newFakeReconciler(configMap)
+
+		prompt, err := reconciler.buildAnalysisPrompt(context.Background(), aiopsAnalyzer, workload, "HighCPU", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompt).To(Equal("Deployment/order @ 20251126-204555: HighCPU"))
+
+		aiopsAnalyzer.Spec.LLM.PromptTemplateRef.Key = "broken.tmpl"
+		_, err = reconciler.buildAnalysisPrompt(context.Background(), aiopsAnalyzer, workload, "HighCPU", now)
+		Expect(err).To(MatchError(ContainSubstring("render prompt template failed")))
+
+		aiopsAnalyzer.Spec.LLM.PromptTemplateRef.Key = "missing.tmpl"
+		_, err = reconciler.buildAnalysisPrompt(context.Background(), aiopsAnalyzer, workload, "HighCPU", now)
+		Expect(err).To(MatchError(`prompt template ConfigMap prompts has no "missing.tmpl" key`))
+	})
+})
@@ -0,0 +1,139 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
+)
+
+// defaultPRPollInterval 未配置或无法解析 prPollInterval 时查询 PR 状态的间隔
+const defaultPRPollInterval = 5 * time.Minute
+
+// prPollInterval 返回 spec.gitOps.prPollInterval
+func prPollInterval(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) time.Duration {
+	interval, err := time.ParseDuration(aiopsAnalyzer.Spec.GitOps.PRPollInterval)
+	if err != nil || interval <= 0 {
+		return defaultPRPollInterval
+	}
+	return interval
+}
+
+// prTerminal PR 已合并或关闭，不再需要查询
+func prTerminal(pr autofixv1.PRStatus) bool {
+	return pr.Merged || pr.Status == "merged" || pr.Status == "closed"
+}
+
+// pollPullRequest 查询 status.gitOps.pr 的最新状态并写回 status
+// PR 合并时记录合并 commit、更新历史记录并把审批卡片更新为已合并
+// 返回下次查询前的等待时间，没有需要跟踪的 PR 时返回 0；查询失败只记录日志，按间隔重试
+func (r *AIOpsAnalyzerReconciler) pollPullRequest(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) time.Duration {
+	logger := log.FromContext(ctx)
+
+	tracked := aiopsAnalyzer.Status.GitOps.PR
+	if tracked.Number == 0 || prTerminal(tracked) {
+		return 0
+	}
+	interval := prPollInterval(aiopsAnalyzer)
+
+	pr, err := r.fetchPullRequest(ctx, aiopsAnalyzer, tracked.Number)
+	if err != nil {
+		logger.Error(err, "查询 PR 状态失败", "number", tracked.Number)
+		return interval
+	}
+
+	var record autofixv1.RemediationRecord
+	if err := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		// 查询期间已创建了新的 PR
+		if status.GitOps.PR.Number != tracked.Number {
+			return
+		}
+		status.GitOps.PR.Status = pr.Status
+		if !pr.Merged() {
+			return
+		}
+		status.GitOps.PR.Merged = true
+		if pr.MergedAt != nil {
+			mergedAt := metav1.NewTime(*pr.MergedAt)
+			status.GitOps.PR.MergedAt = &mergedAt
+		}
+		if pr.MergeCommitSHA != "" {
+			status.GitOps.LastCommitSHA = pr.MergeCommitSHA
+		}
+		for i := range status.History {
+			if status.History[i].PRNumber == tracked.Number {
+				status.History[i].PRMerged = true
+				record = status.History[i]
+			}
+		}
+	}); err != nil {
+		logger.Error(err, "更新 PR 状态失败", "number", tracked.Number)
+		return interval
+	}
+
+	if !pr.Terminal() {
+		return interval
+	}
+	logger.Info("PR 已结束", "number", tracked.Number, "status", pr.Status)
+	if pr.Merged() {
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonPullRequestMerged, "PR #%d 已合并: %s", tracked.Number, tracked.URL)
+		// 配置了 ArgoCD 时等同步完成后再记录 Applied
+		if aiopsAnalyzer.Spec.GitOps.ArgoCD == nil {
+			r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonApplied, "PR #%d 的修复已合并到 GitOps 仓库", tracked.Number)
+		}
+		if record.MessageID != "" {
+			mergedAt := time.Now()
+			if pr.MergedAt != nil {
+				mergedAt = *pr.MergedAt
+			}
+			r.updateApprovalCard(ctx, aiopsAnalyzer, feishu.ApprovalDecision{
+				RequestID: record.RequestID,
+				MessageID: record.MessageID,
+				DecidedAt: mergedAt,
+				Merged:    true,
+				PRURL:     tracked.URL,
+			})
+		}
+	}
+	return 0
+}
+
+// fetchPullRequest 使用 spec.gitOps 的 token 查询 PR
+func (r *AIOpsAnalyzerReconciler) fetchPullRequest(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, number int) (*gitops.PullRequest, error) {
+	provider, err := r.gitProvider(ctx, aiopsAnalyzer)
+	if err != nil {
+		return nil, err
+	}
+	var pr *gitops.PullRequest
+	if err := r.GitLimiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		pr, err = provider.GetPullRequest(ctx, number)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("get pull request #%d failed: %w", number, err)
+	}
+	return pr, nil
+}
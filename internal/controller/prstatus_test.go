@@ -0,0 +1,143 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
+)
+
+var _ = Describe("Pull request status", func() {
+	var (
+		ctx           context.Context
+		reconciler    *AIOpsAnalyzerReconciler
+		provider      *fakeGitProvider
+		recorder      *record.FakeRecorder
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		updatedCards  []feishu.ApprovalDecision
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				GitOps: autofixv1.GitOpsConfig{
+					RepoURL:        "https://github.com/boqier/deploy.git",
+					TokenSecretRef: autofixv1.SecretRef{Provider: autofixv1.SecretProviderKubernetes, Name: "git"},
+					PRPollInterval: "2m",
+				},
+			},
+		}
+		gitSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "git", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("t0ken")},
+		}
+		reconciler = newFakeReconciler(aiopsAnalyzer, gitSecret)
+		recorder = record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		reconciler.Feishu = lark.NewClient("cli_test", "secret")
+		provider = &fakeGitProvider{}
+		reconciler.NewGitProvider = func(string, string) (gitops.Provider, error) { return provider, nil }
+		updatedCards = nil
+		reconciler.UpdateCardStatus = func(_ context.Context, _ *lark.Client, _ string, decision feishu.ApprovalDecision) error {
+			updatedCards = append(updatedCards, decision)
+			return nil
+		}
+		Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.GitOps.PR = autofixv1.PRStatus{Number: 8, URL: "https://github.com/boqier/deploy/pull/8", Status: "open"}
+			status.History = []autofixv1.RemediationRecord{{RequestID: "req-1", ProposedAt: metav1.Now(), PRNumber: 8, MessageID: "om_1"}}
+		})).To(Succeed())
+	})
+
+	latest := func() autofixv1.AIOpsAnalyzer {
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		return latest
+	}
+
+	It("should parse the poll interval and fall back to the default", func() {
+		Expect(prPollInterval(aiopsAnalyzer)).To(Equal(2 * time.Minute))
+		aiopsAnalyzer.Spec.GitOps.PRPollInterval = ""
+		Expect(prPollInterval(aiopsAnalyzer)).To(Equal(defaultPRPollInterval))
+	})
+
+	It("should keep polling while the PR is open", func() {
+		Expect(reconciler.pollPullRequest(ctx, aiopsAnalyzer)).To(Equal(2 * time.Minute))
+		Expect(latest().Status.GitOps.PR.Merged).To(BeFalse())
+		Expect(updatedCards).To(BeEmpty())
+	})
+
+	It("should mark the PR merged, record the merge commit and update the card", func() {
+		mergedAt := time.Date(2025, 11, 26, 12, 45, 55, 0, time.UTC)
+		provider.pr = &gitops.PullRequest{Number: 8, Status: "merged", MergedAt: &mergedAt, MergeCommitSHA: "abc123"}
+
+		Expect(reconciler.pollPullRequest(ctx, aiopsAnalyzer)).To(BeZero())
+
+		status := latest().Status
+		Expect(status.GitOps.PR.Status).To(Equal("merged"))
+		Expect(status.GitOps.PR.Merged).To(BeTrue())
+		Expect(status.GitOps.PR.MergedAt.Time.Equal(mergedAt)).To(BeTrue())
+		Expect(status.GitOps.LastCommitSHA).To(Equal("abc123"))
+		Expect(status.History[0].PRMerged).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonPullRequestMerged)))
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonApplied)))
+		Expect(updatedCards).To(HaveLen(1))
+		Expect(updatedCards[0]).To(Equal(feishu.ApprovalDecision{
+			RequestID: "req-1", MessageID: "om_1", DecidedAt: mergedAt, Merged: true,
+			PRURL: "https://github.com/boqier/deploy/pull/8",
+		}))
+
+		// PR 已结束，不再查询
+		provider.pr = nil
+		Expect(reconciler.pollPullRequest(ctx, aiopsAnalyzer)).To(BeZero())
+		Expect(latest().Status.GitOps.PR.Status).To(Equal("merged"))
+	})
+
+	It("should stop polling a PR closed without merging", func() {
+		provider.pr = &gitops.PullRequest{Number: 8, Status: "closed"}
+
+		Expect(reconciler.pollPullRequest(ctx, aiopsAnalyzer)).To(BeZero())
+		status := latest().Status
+		Expect(status.GitOps.PR.Status).To(Equal("closed"))
+		Expect(status.GitOps.PR.Merged).To(BeFalse())
+		Expect(status.History[0].PRMerged).To(BeFalse())
+		Expect(updatedCards).To(BeEmpty())
+	})
+
+	It("should requeue at the poll interval when it is sooner than the next analysis", func() {
+		aiopsAnalyzer.Spec.AnalysisInterval = "10m"
+		Expect(reconciler.Update(ctx, aiopsAnalyzer)).To(Succeed())
+
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(aiopsAnalyzer)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(2 * time.Minute))
+	})
+})
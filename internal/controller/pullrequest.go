@@ -0,0 +1,182 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+)
+
+// 分支名中不允许出现的字符
+var invalidBranchChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// gitProvider 使用 spec.gitOps.tokenSecretRef 中的 token 创建托管平台客户端
+func (r *AIOpsAnalyzerReconciler) gitProvider(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (gitops.Provider, error) {
+	gitOps := aiopsAnalyzer.Spec.GitOps
+	credential, err := r.resolveGitToken(ctx, gitOps.TokenSecretRef, aiopsAnalyzer.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	// 评论与创建 PR 都通过托管平台 API 完成，SSH 地址也需要 token
+	if credential.Token == "" {
+		return nil, fmt.Errorf("git credentials %q has no %q key: the hosting API needs an access token even for ssh repo URLs",
+			gitOps.TokenSecretRef.Name, gitTokenKey)
+	}
+	newProvider := r.NewGitProvider
+	if newProvider == nil {
+		newProvider = gitops.NewProvider
+	}
+	return newProvider(gitOps.RepoURL, credential.Token)
+}
+
+// newRemediationProposal 把修复建议转换为 status.proposedRemediation
+// TargetRef 指向线上匹配的工作负载或 ConfigMap，创建 PR 时按它找到仓库中对应的清单
+func (r *AIOpsAnalyzerReconciler) newRemediationProposal(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (*autofixv1.RemediationProposal, error) {
+	patches, _, err := r.healPatches(ctx, aiopsAnalyzer, heal)
+	if err != nil {
+		return nil, err
+	}
+	// 目标类型和路径不匹配时（如对 Deployment 修改 /spec/minReplicas）不产出修复建议
+	for _, op := range patches {
+		if err := patch.ValidateTarget(op); err != nil {
+			return nil, err
+		}
+	}
+
+	return &autofixv1.RemediationProposal{
+		ActionType:  actionTypeForPatches(heal.PatchContent),
+		Patches:     patches,
+		Reason:      heal.Reason,
+		Severity:    proposalSeverity(heal),
+		GeneratedAt: metav1.Now(),
+	}, nil
+}
+
+// openApprovedPullRequest 审批通过后把 status.proposedRemediation 提交到 GitOps 仓库并创建 PR
+// 创建成功后清空待审批请求，避免重复创建；返回是否创建了 PR
+// dry-run 和预览模式下不做 Git 写操作；重试也无法成功的错误记录到 history 并清空待审批请求，不再重试
+func (r *AIOpsAnalyzerReconciler) openApprovedPullRequest(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (bool, error) {
+	log := log.FromContext(ctx)
+
+	pending := aiopsAnalyzer.Status.PendingApproval
+	proposal := aiopsAnalyzer.Status.ProposedRemediation
+	if pending == nil || pending.Approved == nil || !*pending.Approved || proposal == nil ||
+		aiopsAnalyzer.Spec.AutoRemediation.DryRun || aiopsAnalyzer.Spec.GitOps.PreviewMode != nil {
+		return false, nil
+	}
+
+	provider, err := r.gitProvider(ctx, aiopsAnalyzer)
+	if err != nil {
+		return false, err
+	}
+	gitOps := aiopsAnalyzer.Spec.GitOps
+	requestID := pending.RequestID
+	var pr *gitops.PullRequest
+	if err := r.GitLimiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		pr, err = gitops.OpenRemediationPR(ctx, provider, gitops.RemediationPR{
+			BaseBranch:  gitOps.Branch,
+			Path:        gitOps.Path,
+			HeadBranch:  remediationBranch(requestID),
+			Title:       proposal.Reason,
+			Body:        formatPullRequestBody(aiopsAnalyzer, requestID, proposal),
+			AuthorName:  gitOps.CommitAuthorName,
+			AuthorEmail: gitOps.CommitAuthorEmail,
+			Patches:     proposal.Patches,
+		})
+		return err
+	}); err != nil {
+		if gitops.IsPermanent(err) {
+			return false, r.recordPullRequestFailure(ctx, aiopsAnalyzer, requestID, err)
+		}
+		return false, fmt.Errorf("open pull request for %s failed: %w", requestID, err)
+	}
+
+	log.Info("PR 创建成功", "requestID", requestID, "number", pr.Number, "url", pr.URL)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeNormal, EventReasonPullRequestOpened, "修复建议 %s 已创建 PR #%d: %s", requestID, pr.Number, pr.URL)
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.GitOps.PR = autofixv1.PRStatus{Number: pr.Number, URL: pr.URL, Status: pr.Status}
+		if record := findHistory(status, requestID); record != nil {
+			record.PRNumber = pr.Number
+			record.MessageID = pending.MessageID
+		}
+		if status.PendingApproval != nil && status.PendingApproval.RequestID == requestID {
+			status.PendingApproval = nil
+		}
+	})
+}
+
+// remediationBranch 修复 PR 的分支名，由 RequestID 生成
+func remediationBranch(requestID string) string {
+	return "aiops/" + strings.Trim(invalidBranchChars.ReplaceAllString(requestID, "-"), "-.")
+}
+
+// recordPullRequestFailure 记录无法创建 PR 的原因并清空待审批请求，之后的协调可以继续分析
+func (r *AIOpsAnalyzerReconciler) recordPullRequestFailure(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID string, err error) error {
+	log.FromContext(ctx).Error(err, "无法为已批准的修复建议创建 PR，不再重试", "requestID", requestID)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonPullRequestFailed, "修复建议 %s 无法创建 PR: %v", requestID, err)
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.Summary = autofixv1.SummaryPullRequestFailed
+		status.Insights = fmt.Sprintf("修复建议 %s 无法创建 PR：%v", requestID, err)
+		if record := findHistory(status, requestID); record != nil {
+			record.Failure = err.Error()
+		}
+		if status.PendingApproval != nil && status.PendingApproval.RequestID == requestID {
+			status.PendingApproval = nil
+		}
+	})
+}
+
+// healNamespace 修复建议作用的命名空间，大模型未返回时使用 spec.target.namespace
+func healNamespace(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) string {
+	if heal.Namespace != "" {
+		return heal.Namespace
+	}
+	return aiopsAnalyzer.Spec.Target.Namespace
+}
+
+// formatPullRequestBody 生成 PR 描述
+func formatPullRequestBody(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID string, proposal *autofixv1.RemediationProposal) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### AIOps 修复（%s/%s）\n\n", aiopsAnalyzer.Namespace, aiopsAnalyzer.Name)
+	fmt.Fprintf(&b, "- 请求 ID：`%s`\n", requestID)
+	fmt.Fprintf(&b, "- 动作类型：%s\n", proposal.ActionType)
+	if proposal.Severity != "" {
+		fmt.Fprintf(&b, "- 风险：%s\n", proposal.Severity)
+	}
+	fmt.Fprintf(&b, "- 原因：%s\n\n", proposal.Reason)
+	b.WriteString("| op | target | path | value |\n|---|---|---|---|\n")
+	for _, op := range proposal.Patches {
+		target := ""
+		if op.TargetRef != nil {
+			target = op.TargetRef.Kind + "/" + op.TargetRef.Name
+		}
+		fmt.Fprintf(&b, "| %s | %s | `%s` | `%s` |\n", op.Op, target, op.Path, string(op.Value.Raw))
+	}
+	return b.String()
+}
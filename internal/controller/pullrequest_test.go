@@ -0,0 +1,333 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// fakeGitProvider 内存中的 GitOps 仓库
+type fakeGitProvider struct {
+	files   map[string]string
+	created []gitops.PullRequestSpec
+	// pr GetPullRequest 返回的 PR，为空时返回 open
+	pr *gitops.PullRequest
+	// createErr 不为空时 CreatePullRequest 返回该错误
+	createErr error
+}
+
+func (f *fakeGitProvider) CommentOnPullRequest(context.Context, int, string) error { return nil }
+
+func (f *fakeGitProvider) ReadFile(_ context.Context, _, path string) ([]byte, error) {
+	return []byte(f.files[path]), nil
+}
+
+func (f *fakeGitProvider) ListFiles(context.Context, string, string) ([]string, error) {
+	var files []string
+	for path := range f.files {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func (f *fakeGitProvider) GetPullRequest(_ context.Context, number int) (*gitops.PullRequest, error) {
+	if f.pr != nil {
+		return f.pr, nil
+	}
+	return &gitops.PullRequest{Number: number, Status: "open"}, nil
+}
+
+func (f *fakeGitProvider) FindOpenPullRequest(context.Context, string) (*gitops.PullRequest, error) {
+	return nil, nil
+}
+
+func (f *fakeGitProvider) CreatePullRequest(_ context.Context, spec gitops.PullRequestSpec) (*gitops.PullRequest, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	f.created = append(f.created, spec)
+	return &gitops.PullRequest{Number: 8, URL: "https://github.com/boqier/deploy/pull/8", Status: "open"}, nil
+}
+
+var _ = Describe("GitOps pull requests", func() {
+	var (
+		ctx           context.Context
+		reconciler    *AIOpsAnalyzerReconciler
+		provider      *fakeGitProvider
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		heal          *llm.HealAction
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+				GitOps: autofixv1.GitOpsConfig{
+					RepoURL:        "https://github.com/boqier/deploy.git",
+					Branch:         "main",
+					Path:           "apps/order.yaml",
+					TokenSecretRef: autofixv1.SecretRef{Provider: autofixv1.SecretProviderKubernetes, Name: "git"},
+				},
+			},
+		}
+		replicas := int32(2)
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+		gitSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "git", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("t0ken")},
+		}
+		reconciler = newFakeReconciler(aiopsAnalyzer, deployment, gitSecret)
+		provider = &fakeGitProvider{files: map[string]string{
+			"apps/order.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: order\nspec:\n  replicas: 2\n",
+		}}
+		reconciler.NewGitProvider = func(repoURL, token string) (gitops.Provider, error) {
+			Expect(token).To(Equal("t0ken"))
+			return provider, nil
+		}
+		heal = &llm.HealAction{
+			Action:       "heal",
+			Reason:       "扩容 order",
+			Target:       llm.Target{Kind: "Deployment", LabelSelector: "app=order"},
+			PatchContent: []llm.PatchOp{{Op: "replace", Path: "/spec/replicas", Value: 3}},
+			RiskLevel:    "low",
+		}
+	})
+
+	It("should point the proposal at the live workload", func() {
+		proposal, err := reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposal.ActionType).To(Equal("scale"))
+		Expect(proposal.Severity).To(Equal("low"))
+		Expect(proposal.Patches).To(HaveLen(1))
+		Expect(proposal.Patches[0].TargetRef).To(Equal(&corev1.ObjectReference{
+			APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "order",
+		}))
+	})
+
+	It("should propose a restart as its own action type", func() {
+		heal.PatchContent = []llm.PatchOp{llm.RestartPatch(time.Now())}
+		proposal, err := reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposal.ActionType).To(Equal("restart"))
+		Expect(proposal.Patches[0].Path).To(Equal("/spec/template/metadata/annotations/kubectl.kubernetes.io~1restartedAt"))
+	})
+
+	It("should point HPA patches at the live HPA and reject paths it does not have", func() {
+		minReplicas := int32(2)
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec:       autoscalingv2.HorizontalPodAutoscalerSpec{MinReplicas: &minReplicas, MaxReplicas: 10},
+		}
+		Expect(reconciler.Create(ctx, hpa)).To(Succeed())
+		heal.Target.Kind = "HorizontalPodAutoscaler"
+		heal.PatchContent = []llm.PatchOp{{Op: "replace", Path: "/spec/minReplicas", Value: 4}}
+
+		proposal, err := reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proposal.Patches[0].TargetRef.Kind).To(Equal("HorizontalPodAutoscaler"))
+		Expect(proposal.Patches[0].TargetRef.APIVersion).To(Equal("autoscaling/v2"))
+
+		heal.Target.Kind = "Deployment"
+		_, err = reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+		Expect(err).To(MatchError(ContainSubstring("path does not apply to Deployment")))
+	})
+
+	It("should record the proposal and summary in status", func() {
+		heal.Detail = "副本数不足"
+		Expect(reconciler.recordProposal(ctx, aiopsAnalyzer, heal)).To(Succeed())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.Summary).To(Equal(autofixv1.SummaryRemediationProposed))
+		Expect(latest.Status.Insights).To(Equal("扩容 order：副本数不足"))
+		Expect(latest.Status.LastAnalysisTime).NotTo(BeNil())
+		Expect(latest.Status.ProposedRemediation).NotTo(BeNil())
+		Expect(latest.Status.ProposedRemediation.Reason).To(Equal("扩容 order"))
+	})
+
+	It("should wait for approval before opening a PR", func() {
+		proposal, err := reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		aiopsAnalyzer.Status.ProposedRemediation = proposal
+		aiopsAnalyzer.Status.PendingApproval = &autofixv1.ApprovalRequest{RequestID: "req-1"}
+
+		opened, err := reconciler.openApprovedPullRequest(ctx, aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opened).To(BeFalse())
+		Expect(provider.created).To(BeEmpty())
+	})
+
+	It("should open a PR once the proposal is approved", func() {
+		proposal, err := reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		approved := true
+		Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.ProposedRemediation = proposal
+			status.PendingApproval = &autofixv1.ApprovalRequest{RequestID: "req-1", Approved: &approved, MessageID: "om_1"}
+			status.History = []autofixv1.RemediationRecord{{RequestID: "req-1", ProposedAt: metav1.Now()}}
+		})).To(Succeed())
+
+		opened, err := reconciler.openApprovedPullRequest(ctx, aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opened).To(BeTrue())
+
+		Expect(provider.created).To(HaveLen(1))
+		Expect(provider.created[0].HeadBranch).To(Equal("aiops/req-1"))
+		Expect(provider.created[0].BaseBranch).To(Equal("main"))
+		Expect(string(provider.created[0].Changes[0].Content)).To(ContainSubstring("replicas: 3"))
+		Expect(provider.created[0].Body).To(ContainSubstring("`req-1`"))
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Status.GitOps.PR).To(Equal(autofixv1.PRStatus{Number: 8, URL: "https://github.com/boqier/deploy/pull/8", Status: "open"}))
+		Expect(latest.Status.History[0].PRNumber).To(Equal(8))
+		Expect(latest.Status.History[0].MessageID).To(Equal("om_1"))
+		Expect(latest.Status.PendingApproval).To(BeNil())
+	})
+
+	Context("with an approved proposal", func() {
+		BeforeEach(func() {
+			proposal, err := reconciler.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+			Expect(err).NotTo(HaveOccurred())
+			approved := true
+			Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+				status.ProposedRemediation = proposal
+				status.PendingApproval = &autofixv1.ApprovalRequest{RequestID: "req-1", Approved: &approved}
+				status.History = []autofixv1.RemediationRecord{{RequestID: "req-1", ProposedAt: metav1.Now()}}
+			})).To(Succeed())
+		})
+
+		It("should not open a PR in preview mode", func() {
+			aiopsAnalyzer.Spec.GitOps.PreviewMode = &autofixv1.PreviewModeSpec{TrackingPR: 3}
+
+			opened, err := reconciler.openApprovedPullRequest(ctx, aiopsAnalyzer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opened).To(BeFalse())
+			Expect(provider.created).To(BeEmpty())
+		})
+
+		It("should record permanent failures and release the approval", func() {
+			recorder := record.NewFakeRecorder(10)
+			reconciler.Recorder = recorder
+			provider.files["apps/order.yaml"] = "apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: order\n"
+
+			opened, err := reconciler.openApprovedPullRequest(ctx, aiopsAnalyzer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opened).To(BeFalse())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning " + EventReasonPullRequestFailed)))
+
+			var latest autofixv1.AIOpsAnalyzer
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+			Expect(latest.Status.PendingApproval).To(BeNil())
+			Expect(latest.Status.Summary).To(Equal(autofixv1.SummaryPullRequestFailed))
+			Expect(latest.Status.History[0].Failure).To(ContainSubstring("no manifest under apps/order.yaml matches"))
+		})
+
+		It("should treat a rejected PR creation as permanent and other errors as retryable", func() {
+			provider.createErr = &gitops.StatusError{Method: "POST", Endpoint: "/pulls", StatusCode: 422, Body: "A pull request already exists"}
+			_, err := reconciler.openApprovedPullRequest(ctx, aiopsAnalyzer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aiopsAnalyzer.Status.PendingApproval).To(BeNil())
+
+			approved := true
+			Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+				status.PendingApproval = &autofixv1.ApprovalRequest{RequestID: "req-2", Approved: &approved}
+			})).To(Succeed())
+			provider.createErr = &gitops.StatusError{Method: "POST", Endpoint: "/pulls", StatusCode: 502}
+			_, err = reconciler.openApprovedPullRequest(ctx, aiopsAnalyzer)
+			Expect(err).To(MatchError(ContainSubstring("status 502")))
+			Expect(aiopsAnalyzer.Status.PendingApproval).NotTo(BeNil())
+		})
+	})
+	Context("with a merged remediation", func() {
+		BeforeEach(func() {
+			rollback, err := reconciler.rollbackPatchesFor(ctx, aiopsAnalyzer, heal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rollback).To(HaveLen(1))
+			Expect(rollback[0].Op).To(Equal("replace"))
+			Expect(rollback[0].Path).To(Equal("/spec/replicas"))
+			Expect(rollback[0].Value.Raw).To(MatchJSON("2"))
+
+			// 修复已合并，仓库中的副本数为 3
+			provider.files["apps/order.yaml"] = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: order\nspec:\n  replicas: 3\n"
+			Expect(reconciler.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+				status.History = []autofixv1.RemediationRecord{{
+					RequestID: "req-1", Reason: "扩容 order", ProposedAt: metav1.Now(),
+					PRNumber: 7, PRMerged: true, RollbackPatches: rollback,
+				}}
+			})).To(Succeed())
+			aiopsAnalyzer.Spec.AutoRemediation.AutoRollback = true
+		})
+
+		It("should open a rollback PR once when the problem persists", func() {
+			recorder := record.NewFakeRecorder(10)
+			reconciler.Recorder = recorder
+
+			rolledBack, err := reconciler.rollBackFailedRemediation(ctx, aiopsAnalyzer, "req-2", heal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rolledBack).To(BeTrue())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning " + EventReasonRolledBack)))
+			Expect(provider.created).To(HaveLen(1))
+			Expect(provider.created[0].HeadBranch).To(Equal("aiops/req-2"))
+			Expect(string(provider.created[0].Changes[0].Content)).To(ContainSubstring("replicas: 2"))
+
+			var latest autofixv1.AIOpsAnalyzer
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+			Expect(latest.Status.History).To(HaveLen(2))
+			Expect(latest.Status.History[1].RollbackOf).To(Equal("req-1"))
+			Expect(latest.Status.History[1].PRNumber).To(Equal(8))
+			Expect(latest.Status.GitOps.PR.Number).To(Equal(8))
+
+			// 回滚记录成为最近一条历史，不再重复回滚
+			rolledBack, err = reconciler.rollBackFailedRemediation(ctx, aiopsAnalyzer, "req-3", heal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rolledBack).To(BeFalse())
+			Expect(provider.created).To(HaveLen(1))
+		})
+
+		It("should not roll back when autoRollback is disabled", func() {
+			aiopsAnalyzer.Spec.AutoRemediation.AutoRollback = false
+
+			rolledBack, err := reconciler.rollBackFailedRemediation(ctx, aiopsAnalyzer, "req-2", heal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rolledBack).To(BeFalse())
+			Expect(provider.created).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,82 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+)
+
+// resolveRelativeResources 把资源量补丁中的相对值（如 "+50%"）按线上工作负载的当前值换算为具体值
+// 结果不超过 spec.autoRemediation.maxResources 中对应资源的上限
+func (r *AIOpsAnalyzerReconciler) resolveRelativeResources(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) error {
+	log := log.FromContext(ctx)
+
+	var workload map[string]any
+	for i, op := range heal.PatchContent {
+		if patch.KindForPath(op.Path) != patch.KindQuantity {
+			continue
+		}
+		if _, ok := patch.ParseRelativePercent(op.Value); !ok {
+			continue
+		}
+
+		// 只有存在相对值时才读取线上工作负载
+		if workload == nil {
+			namespace := heal.Namespace
+			if namespace == "" {
+				namespace = aiopsAnalyzer.Spec.Target.Namespace
+			}
+			obj, err := r.getTargetWorkload(ctx, namespace, heal.Target)
+			if err != nil {
+				return err
+			}
+			workload = obj.Object
+		}
+
+		raw, ok := patch.LookupPointer(workload, op.Path)
+		if !ok {
+			return fmt.Errorf("patch %s: relative value %v requires a current value on the live workload", op.Path, op.Value)
+		}
+		current, err := resource.ParseQuantity(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("patch %s: invalid current quantity %v: %w", op.Path, raw, err)
+		}
+
+		var max *resource.Quantity
+		if limit, ok := aiopsAnalyzer.Spec.AutoRemediation.MaxResources[corev1.ResourceName(path.Base(op.Path))]; ok {
+			max = &limit
+		}
+		resolved, _, err := patch.ResolveRelativeQuantity(current, op.Value, max)
+		if err != nil {
+			return fmt.Errorf("patch %s: %w", op.Path, err)
+		}
+
+		log.Info("相对值已换算", "path", op.Path, "relative", op.Value, "current", current.String(), "resolved", resolved.String())
+		heal.PatchContent[i].Value = resolved.String()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+var _ = Describe("Relative resource patches", func() {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "prod", Labels: map[string]string{"app": "order"}},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("6Gi"),
+				}},
+			}}}},
+		},
+	}
+
+	It("should resolve percentages against the live workload and clamp to the max", func() {
+		scheme := runtime.NewScheme()
+		Expect(appsv1.AddToScheme(scheme)).To(Succeed())
+		reconciler := &AIOpsAnalyzerReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(deployment.DeepCopy()).Build(),
+			Scheme: scheme,
+		}
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{Spec: autofixv1.AIOpsAnalyzerSpec{
+			AutoRemediation: autofixv1.AutoRemediationSpec{
+				MaxResources: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+			},
+		}}
+		heal := &llm.HealAction{
+			Namespace: "prod",
+			Target:    llm.Target{Kind: "Deployment", LabelSelector: "app=order"},
+			PatchContent: []llm.PatchOp{
+				{Op: "replace", Path: "/spec/template/spec/containers/0/resources/limits/cpu", Value: "+50%"},
+				{Op: "replace", Path: "/spec/template/spec/containers/0/resources/limits/memory", Value: "+50%"},
+				{Op: "replace", Path: "/spec/replicas", Value: float64(3)},
+			},
+		}
+
+		Expect(reconciler.resolveRelativeResources(context.Background(), aiopsAnalyzer, heal)).To(Succeed())
+		Expect(heal.PatchContent[0].Value).To(Equal("750m"))
+		Expect(heal.PatchContent[1].Value).To(Equal("8Gi"))
+		Expect(heal.PatchContent[2].Value).To(Equal(float64(3)))
+	})
+
+	It("should keep container names in the proposal and reject unknown containers without an error", func() {
+		// 注入的 sidecar 排在业务容器之前，业务容器的下标从 0 变为 1
+		injected := deployment.DeepCopy()
+		injected.Spec.Template.Spec.Containers = append([]corev1.Container{{
+			Name: "istio-proxy",
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			}},
+		}}, injected.Spec.Template.Spec.Containers...)
+		aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "prod"},
+			Spec:       autofixv1.AIOpsAnalyzerSpec{Target: autofixv1.TargetSelector{Namespace: "prod"}},
+		}
+		reconciler := newFakeReconciler(injected, aiopsAnalyzer)
+		heal := &llm.HealAction{
+			Reason: "OOM",
+			Target: llm.Target{Kind: "Deployment", LabelSelector: "app=order"},
+			PatchContent: []llm.PatchOp{
+				{Op: "replace", Path: "/spec/template/spec/containers/name=app/resources/limits/memory", Value: "+50%"},
+				{Op: "replace", Path: "/spec/replicas", Value: float64(3)},
+			},
+		}
+
+		Expect(reconciler.rejectUnknownContainers(context.Background(), aiopsAnalyzer, heal)).To(BeFalse())
+		Expect(reconciler.resolveRelativeResources(context.Background(), aiopsAnalyzer, heal)).To(Succeed())
+		Expect(heal.PatchContent).To(Equal([]llm.PatchOp{
+			{Op: "replace", Path: "/spec/template/spec/containers/name=app/resources/limits/memory", Value: "9Gi"},
+			{Op: "replace", Path: "/spec/replicas", Value: float64(3)},
+		}))
+
+		heal.PatchContent = []llm.PatchOp{{Op: "replace", Path: "/spec/template/spec/containers/name=api/resources/limits/memory", Value: "2Gi"}}
+		Expect(reconciler.rejectUnknownContainers(context.Background(), aiopsAnalyzer, heal)).To(BeTrue())
+		Expect(aiopsAnalyzer.Status.NoopReason).To(Equal(autofixv1.NoopReasonPolicyRejected))
+		Expect(aiopsAnalyzer.Status.NoopMessage).To(ContainSubstring(`container "api" not found, workload has containers ["istio-proxy" "app"]`))
+	})
+})
@@ -0,0 +1,176 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/patch"
+)
+
+// toPatchOperations 把大模型返回的补丁转换为 status 中使用的 PatchOperation
+func toPatchOperations(ops []llm.PatchOp) ([]autofixv1.PatchOperation, error) {
+	result := make([]autofixv1.PatchOperation, 0, len(ops))
+	for _, op := range ops {
+		patchOp := autofixv1.PatchOperation{Op: op.Op, Path: op.Path}
+		if op.Op != "remove" {
+			value, err := patch.EncodeValue(op.Value)
+			if err != nil {
+				return nil, fmt.Errorf("patch %s %s: encode value failed: %w", op.Op, op.Path, err)
+			}
+			patchOp.Value = value
+		}
+		result = append(result, patchOp)
+	}
+	return result, nil
+}
+
+// rollbackPatchesFor 提出修复建议时根据线上工作负载和 ConfigMap 生成撤销补丁
+// 修复合并后导致情况恶化时，用这些补丁创建回滚 PR
+func (r *AIOpsAnalyzerReconciler) rollbackPatchesFor(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) ([]autofixv1.PatchOperation, error) {
+	ops, live, err := r.healPatches(ctx, aiopsAnalyzer, heal)
+	if err != nil {
+		return nil, err
+	}
+	// 不同资源的补丁互不影响，按资源分别撤销
+	var rollback []autofixv1.PatchOperation
+	for i := len(live) - 1; i >= 0; i-- {
+		reversed, err := patch.ReversePatches(live[i].Object, patchesFor(ops, live[i]))
+		if err != nil {
+			return nil, err
+		}
+		rollback = append(rollback, reversed...)
+	}
+	return rollback, nil
+}
+
+// failedRemediation 返回需要回滚的修复：最近一条历史记录是已合并、记录了撤销补丁的修复，且本身不是回滚
+// 冷却期结束后大模型仍给出修复建议，说明合并的修复没有解决问题，视为验证失败
+func failedRemediation(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) *autofixv1.RemediationRecord {
+	history := aiopsAnalyzer.Status.History
+	if !aiopsAnalyzer.Spec.AutoRemediation.AutoRollback || len(history) == 0 {
+		return nil
+	}
+	last := history[len(history)-1]
+	if !last.PRMerged || len(last.RollbackPatches) == 0 || last.RollbackOf != "" {
+		return nil
+	}
+	return &last
+}
+
+// rollBackFailedRemediation 合并的修复未能解决问题时用撤销补丁创建回滚 PR，记录到修复历史并更新原审批卡片
+// 返回 true 时本轮不再发起新的修复建议；回滚记录成为最近一条历史，之后的分析不会重复回滚
+func (r *AIOpsAnalyzerReconciler) rollBackFailedRemediation(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID string, heal *llm.HealAction) (bool, error) {
+	failed := failedRemediation(aiopsAnalyzer)
+	if failed == nil || aiopsAnalyzer.Spec.AutoRemediation.DryRun || aiopsAnalyzer.Spec.GitOps.PreviewMode != nil {
+		return false, nil
+	}
+	logger := log.FromContext(ctx)
+
+	provider, err := r.gitProvider(ctx, aiopsAnalyzer)
+	if err != nil {
+		return false, err
+	}
+	gitOps := aiopsAnalyzer.Spec.GitOps
+	var pr *gitops.PullRequest
+	if err := r.GitLimiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		pr, err = gitops.OpenRemediationPR(ctx, provider, gitops.RemediationPR{
+			BaseBranch:  gitOps.Branch,
+			Path:        gitOps.Path,
+			HeadBranch:  remediationBranch(requestID),
+			Title:       "回滚：" + failed.Reason,
+			Body:        formatRollbackBody(aiopsAnalyzer, requestID, failed, heal),
+			AuthorName:  gitOps.CommitAuthorName,
+			AuthorEmail: gitOps.CommitAuthorEmail,
+			Patches:     failed.RollbackPatches,
+		})
+		return err
+	}); err != nil {
+		if !gitops.IsPermanent(err) {
+			return false, fmt.Errorf("open rollback pull request for %s failed: %w", failed.RequestID, err)
+		}
+		// 无法回滚时同样记录到历史，不再重复尝试
+		logger.Error(err, "无法为修复创建回滚 PR", "requestID", failed.RequestID)
+		r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonPullRequestFailed, "修复 %s 无法创建回滚 PR: %v", failed.RequestID, err)
+		record := newRollbackRecord(requestID, failed, heal)
+		record.Failure = err.Error()
+		return true, r.recordHistory(ctx, aiopsAnalyzer, record)
+	}
+
+	logger.Info("合并的修复未能解决问题，已创建回滚 PR", "requestID", failed.RequestID, "number", pr.Number, "url", pr.URL)
+	r.recordEvent(aiopsAnalyzer, corev1.EventTypeWarning, EventReasonRolledBack,
+		"修复 %s 合并后问题仍未解决，已创建回滚 PR #%d: %s", failed.RequestID, pr.Number, pr.URL)
+	record := newRollbackRecord(requestID, failed, heal)
+	record.PRNumber = pr.Number
+	if err := r.recordHistory(ctx, aiopsAnalyzer, record); err != nil {
+		return true, err
+	}
+	if err := r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		status.GitOps.PR = autofixv1.PRStatus{Number: pr.Number, URL: pr.URL, Status: pr.Status}
+		status.Insights = fmt.Sprintf("修复 %s 合并后问题仍未解决，已创建回滚 PR：%s", failed.RequestID, heal.Reason)
+	}); err != nil {
+		return true, err
+	}
+	if failed.MessageID != "" {
+		r.updateApprovalCard(ctx, aiopsAnalyzer, feishu.ApprovalDecision{
+			RequestID:  failed.RequestID,
+			MessageID:  failed.MessageID,
+			DecidedAt:  time.Now(),
+			RolledBack: true,
+			PRURL:      pr.URL,
+			Reason:     heal.Reason,
+		})
+	}
+	return true, nil
+}
+
+// newRollbackRecord 回滚对应的历史记录，回滚由 autoRollback 自动批准
+func newRollbackRecord(requestID string, failed *autofixv1.RemediationRecord, heal *llm.HealAction) autofixv1.RemediationRecord {
+	approved := true
+	return autofixv1.RemediationRecord{
+		RequestID:  requestID,
+		ActionType: failed.ActionType,
+		RiskLevel:  failed.RiskLevel,
+		Reason:     fmt.Sprintf("回滚 %s：%s", failed.RequestID, heal.Reason),
+		ProposedAt: metav1.Now(),
+		Approved:   &approved,
+		RollbackOf: failed.RequestID,
+	}
+}
+
+// formatRollbackBody 生成回滚 PR 的描述
+func formatRollbackBody(aiopsAnalyzer *autofixv1.AIOpsAnalyzer, requestID string, failed *autofixv1.RemediationRecord, heal *llm.HealAction) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### AIOps 自动回滚（%s/%s）\n\n", aiopsAnalyzer.Namespace, aiopsAnalyzer.Name)
+	fmt.Fprintf(&b, "- 请求 ID：`%s`\n", requestID)
+	fmt.Fprintf(&b, "- 回滚的修复：`%s`（PR #%d）\n", failed.RequestID, failed.PRNumber)
+	fmt.Fprintf(&b, "- 原修复原因：%s\n", failed.Reason)
+	fmt.Fprintf(&b, "- 回滚原因：冷却期结束后问题仍未解决，%s\n", heal.Reason)
+	return b.String()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
+)
+
+// feishuReceiver 按风险等级选择接收者，第一条匹配的路由生效，没有匹配时使用默认接收者
+func feishuReceiver(feishu *autofixv1.FeishuNotification, riskLevel string) (autofixv1.FeishuReceiveIDType, string) {
+	for _, route := range feishu.Routes {
+		if route.RiskLevel == riskLevel {
+			return route.ReceiveIDType, route.ReceiveID
+		}
+	}
+	return feishu.ReceiveIDType, feishu.ReceiveID
+}
+
+// feishuTemplate 返回审批卡片的模板 ID 和版本，未配置时依次使用控制器配置 defaults 和内置模板
+func feishuTemplate(feishu *autofixv1.FeishuNotification, defaults config.FeishuConfig) (string, string) {
+	templateID, version := feishu.TemplateID, feishu.TemplateVersion
+	if templateID == "" {
+		templateID = defaults.TemplateID
+	}
+	if templateID == "" {
+		templateID = autofixv1.DefaultFeishuTemplateID
+	}
+	if version == "" {
+		version = defaults.TemplateVersion
+	}
+	if version == "" {
+		version = autofixv1.DefaultFeishuTemplateVersion
+	}
+	return templateID, version
+}
+
+// feishuMentions 汇总需要 @ 的审批人：mentionUsers 加上 mentionRoles 按 roleMembers 解析出的成员，去重并保持顺序
+// allRoles 时（升级审批）再按名称顺序加上 roleMembers 中其余角色的成员
+// 无法解析的角色只记录日志，不影响卡片发送
+func feishuMentions(ctx context.Context, feishu *autofixv1.FeishuNotification, allRoles bool) []string {
+	var mentions []string
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			mentions = append(mentions, id)
+		}
+	}
+
+	for _, user := range feishu.MentionUsers {
+		add(user)
+	}
+	roles := feishu.MentionRoles
+	if allRoles {
+		roles = append(slices.Clone(roles), slices.Sorted(maps.Keys(feishu.RoleMembers))...)
+	}
+	for _, role := range roles {
+		members, ok := feishu.RoleMembers[role]
+		if !ok || len(members) == 0 {
+			log.FromContext(ctx).Info("无法解析审批角色，跳过", "role", role)
+			continue
+		}
+		for _, member := range members {
+			add(member)
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/config"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+var _ = Describe("Feishu receiver routing", func() {
+	feishu := &autofixv1.FeishuNotification{
+		ReceiveIDType: autofixv1.FeishuChatID,
+		ReceiveID:     "oc_team",
+		Routes: []autofixv1.FeishuRoute{
+			{RiskLevel: "high", ReceiveIDType: autofixv1.FeishuUserID, ReceiveID: "oncall"},
+			{RiskLevel: "medium", ReceiveIDType: autofixv1.FeishuChatID, ReceiveID: "oc_sre"},
+		},
+	}
+
+	DescribeTable("selecting the receiver by risk level",
+		func(riskLevel string, idType autofixv1.FeishuReceiveIDType, id string) {
+			gotType, gotID := feishuReceiver(feishu, riskLevel)
+			Expect(gotType).To(Equal(idType))
+			Expect(gotID).To(Equal(id))
+		},
+		Entry("high risk pages on-call", "high", autofixv1.FeishuUserID, "oncall"),
+		Entry("medium risk goes to the SRE chat", "medium", autofixv1.FeishuChatID, "oc_sre"),
+		Entry("unrouted risk falls back to the default receiver", "low", autofixv1.FeishuChatID, "oc_team"),
+	)
+})
+
+var _ = Describe("Feishu mentions", func() {
+	It("should resolve roles through roleMembers and skip unknown roles", func() {
+		feishu := &autofixv1.FeishuNotification{
+			MentionUsers: []string{"ou_lead", "ou_alice"},
+			MentionRoles: []string{"oncall-sre", "dba", "empty"},
+			RoleMembers: map[string][]string{
+				"oncall-sre": {"ou_alice", "ou_bob"},
+				"empty":      {},
+			},
+		}
+		Expect(feishuMentions(context.Background(), feishu, false)).To(Equal([]string{"ou_lead", "ou_alice", "ou_bob"}))
+		Expect(feishuMentions(context.Background(), &autofixv1.FeishuNotification{}, false)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Approval card template", func() {
+	heal := &llm.HealAction{
+		Reason:       "CPU 打满",
+		Target:       llm.Target{Kind: "Deployment", LabelSelector: "app=order"},
+		PatchContent: []llm.PatchOp{{Op: "replace", Path: "/spec/replicas", Value: 4}},
+		RiskLevel:    "low",
+	}
+	newAnalyzer := func(templateID, version string) *autofixv1.AIOpsAnalyzer {
+		return &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "shop"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{Feishu: autofixv1.FeishuNotification{
+				ReceiveIDType:   autofixv1.FeishuChatID,
+				ReceiveID:       "oc_a0553eda9014c201e6969b478895c230",
+				TemplateID:      templateID,
+				TemplateVersion: version,
+				MentionUsers:    []string{"ou_lead"},
+			}},
+		}
+	}
+
+	It("should build the card from the spec template", func() {
+		cardMsg, vars, err := newFakeReconciler().buildApprovalCard(context.Background(), newAnalyzer("ctp_team_card", "1.2.0"), heal, "analyze-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cardMsg.TemplateID).To(Equal("ctp_team_card"))
+		Expect(cardMsg.Version).To(Equal("1.2.0"))
+		Expect(cardMsg.ReceiveType).To(Equal("chat_id"))
+		Expect(cardMsg.Variables).To(HaveKeyWithValue("request_id", "analyze-1"))
+		Expect(vars.Reason).To(Equal("CPU 打满"))
+		Expect(cardMsg.Variables).To(HaveKeyWithValue("mentions", "<at id=ou_lead></at>"))
+	})
+
+	It("should fall back to the template from the controller config", func() {
+		reconciler := newFakeReconciler()
+		reconciler.Defaults.Feishu = config.FeishuConfig{TemplateID: "ctp_platform_card", TemplateVersion: "2.0.0"}
+		cardMsg, _, err := reconciler.buildApprovalCard(context.Background(), newAnalyzer("", ""), heal, "analyze-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cardMsg.TemplateID).To(Equal("ctp_platform_card"))
+		Expect(cardMsg.Version).To(Equal("2.0.0"))
+
+		cardMsg, _, err = reconciler.buildApprovalCard(context.Background(), newAnalyzer("ctp_team_card", ""), heal, "analyze-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cardMsg.TemplateID).To(Equal("ctp_team_card"))
+		Expect(cardMsg.Version).To(Equal("2.0.0"))
+	})
+
+	It("should fall back to the built-in template", func() {
+		cardMsg, _, err := newFakeReconciler().buildApprovalCard(context.Background(), newAnalyzer("", ""), heal, "analyze-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cardMsg.TemplateID).To(Equal(autofixv1.DefaultFeishuTemplateID))
+		Expect(cardMsg.Version).To(Equal(autofixv1.DefaultFeishuTemplateVersion))
+	})
+})
@@ -0,0 +1,73 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// isRunCompleted 判断 Once 模式的分析是否已经结束
+// 修改 spec（generation 变化）或设置 RerunAnnotation 都会重新开始一次分析
+func (r *AIOpsAnalyzerReconciler) isRunCompleted(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if aiopsAnalyzer.Spec.RunPolicy != autofixv1.RunPolicyOnce {
+		return false, nil
+	}
+
+	if _, ok := aiopsAnalyzer.Annotations[autofixv1.RerunAnnotation]; ok {
+		// 移除注解后重新分析
+		log.Info("检测到重置注解，重新开始分析", "annotation", autofixv1.RerunAnnotation)
+		delete(aiopsAnalyzer.Annotations, autofixv1.RerunAnnotation)
+		if err := r.Update(ctx, aiopsAnalyzer); err != nil {
+			log.Error(err, "移除重置注解失败")
+			return false, err
+		}
+		// 清空指纹，即使情况没有变化也立即重新分析
+		return false, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.LastEventHash = ""
+		})
+	}
+
+	completed := aiopsAnalyzer.Status.Summary == autofixv1.SummaryCompleted &&
+		aiopsAnalyzer.Status.ObservedGeneration == aiopsAnalyzer.Generation
+	if completed {
+		log.Info("Once 模式已产出修复建议，跳过分析", "generation", aiopsAnalyzer.Generation)
+	}
+	return completed, nil
+}
+
+// markRunCompleted 在 Once 模式下产出修复建议后写入终止状态
+func (r *AIOpsAnalyzerReconciler) markRunCompleted(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, insights string) error {
+	if aiopsAnalyzer.Spec.RunPolicy != autofixv1.RunPolicyOnce {
+		return nil
+	}
+
+	generation := aiopsAnalyzer.Generation
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryCompleted
+		status.Insights = insights
+		status.ObservedGeneration = generation
+	})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("Run policy", func() {
+	var (
+		reconciler    *AIOpsAnalyzerReconciler
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		fake          *llmtest.FakeLLMClient
+	)
+
+	BeforeEach(func() {
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "once", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				RunPolicy:        autofixv1.RunPolicyOnce,
+				AnalysisInterval: "10m",
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+			},
+		}
+		reconciler = newFakeReconciler(aiopsAnalyzer)
+		fake = llmtest.NewFakeLLMClient()
+		reconciler.LLM = fake
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), aiopsAnalyzer)).To(Succeed())
+	})
+
+	It("should stop analyzing and requeueing once a Once run has completed", func() {
+		Expect(reconciler.markRunCompleted(context.Background(), aiopsAnalyzer, "已提交修复建议")).To(Succeed())
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryCompleted))
+		Expect(aiopsAnalyzer.Status.ObservedGeneration).To(Equal(aiopsAnalyzer.Generation))
+
+		result, err := reconciler.reconcile(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(reconciler.nextAnalysisAfter(aiopsAnalyzer)).To(BeZero())
+		Expect(fake.Requests).To(BeEmpty())
+		Expect(aiopsAnalyzer.Status.NoopReason).To(Equal(autofixv1.NoopReasonRunCompleted))
+	})
+
+	It("should stay completed while the proposal awaits approval or a later reconcile fails", func() {
+		aiopsAnalyzer.Spec.AutoRemediation.RequireApproval = true
+		Expect(reconciler.Update(context.Background(), aiopsAnalyzer)).To(Succeed())
+		Expect(reconciler.updateStatus(context.Background(), aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.PendingApproval = &autofixv1.ApprovalRequest{RequestID: "req-1", ExpiresAt: metav1.NewTime(time.Now().Add(time.Hour))}
+		})).To(Succeed())
+		Expect(reconciler.markRunCompleted(context.Background(), aiopsAnalyzer, "已提交修复建议")).To(Succeed())
+
+		Expect(reconciler.recordConditions(context.Background(), aiopsAnalyzer, nil)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionApprovalPending)).To(BeTrue())
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryCompleted))
+
+		Expect(reconciler.recordConditions(context.Background(), aiopsAnalyzer, errors.New("list pods failed"))).To(Succeed())
+		Expect(meta.IsStatusConditionFalse(aiopsAnalyzer.Status.Conditions, autofixv1.ConditionReady)).To(BeTrue())
+		Expect(aiopsAnalyzer.Status.Summary).To(Equal(autofixv1.SummaryCompleted))
+
+		completed, err := reconciler.isRunCompleted(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeTrue())
+	})
+
+	It("should analyze again after the spec changes", func() {
+		Expect(reconciler.markRunCompleted(context.Background(), aiopsAnalyzer, "已提交修复建议")).To(Succeed())
+
+		aiopsAnalyzer.Generation++
+		completed, err := reconciler.isRunCompleted(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeFalse())
+	})
+
+	It("should analyze again and forget the fingerprint when the rerun annotation is set", func() {
+		Expect(reconciler.markRunCompleted(context.Background(), aiopsAnalyzer, "已提交修复建议")).To(Succeed())
+		Expect(reconciler.updateStatus(context.Background(), aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+			status.LastEventHash = "abc"
+		})).To(Succeed())
+		aiopsAnalyzer.Annotations = map[string]string{autofixv1.RerunAnnotation: "true"}
+		Expect(reconciler.Update(context.Background(), aiopsAnalyzer)).To(Succeed())
+
+		completed, err := reconciler.isRunCompleted(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeFalse())
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &latest)).To(Succeed())
+		Expect(latest.Annotations).NotTo(HaveKey(autofixv1.RerunAnnotation))
+		Expect(latest.Status.LastEventHash).To(BeEmpty())
+	})
+
+	It("should keep analyzing in Continuous mode", func() {
+		aiopsAnalyzer.Spec.RunPolicy = autofixv1.RunPolicyContinuous
+		Expect(reconciler.markRunCompleted(context.Background(), aiopsAnalyzer, "已提交修复建议")).To(Succeed())
+		Expect(aiopsAnalyzer.Status.Summary).NotTo(Equal(autofixv1.SummaryCompleted))
+
+		completed, err := reconciler.isRunCompleted(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeFalse())
+		Expect(reconciler.nextAnalysisAfter(aiopsAnalyzer)).To(Equal(10 * time.Minute))
+	})
+})
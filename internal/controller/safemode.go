@@ -0,0 +1,52 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// blockedBySafeMode 开启安全模式时检查修复建议，包含破坏性操作则记录到status并返回 true
+func (r *AIOpsAnalyzerReconciler) blockedBySafeMode(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if !aiopsAnalyzer.Spec.AutoRemediation.SafeMode {
+		return false, nil
+	}
+	reasons := llm.FindDestructiveOps(heal.PatchContent)
+	if len(reasons) == 0 {
+		return false, nil
+	}
+
+	log.Info("安全模式拒绝了破坏性操作", "ops", reasons)
+	return true, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryBlockedBySafeMode
+		status.Insights = fmt.Sprintf("%s（安全模式拒绝：%s）", heal.Reason, strings.Join(reasons, "; "))
+		status.NoopReason = autofixv1.NoopReasonPolicyRejected
+		status.NoopMessage = "安全模式拒绝: " + strings.Join(reasons, "; ")
+	})
+}
@@ -0,0 +1,90 @@
+package sanitize
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Rule 一条脱敏规则，命中的内容会被替换为 Replacement（支持 $1 引用分组）
+type Rule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// DefaultRules 内置的脱敏规则：token/密钥、邮箱、IP、内部域名
+// 顺序有意义：先替换带上下文的密钥，避免被后面的规则拆散
+var DefaultRules = []Rule{
+	{
+		Name:        "bearer-token",
+		Pattern:     regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		Replacement: "${1}[REDACTED_TOKEN]",
+	},
+	{
+		Name:        "jwt",
+		Pattern:     regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+		Replacement: "[REDACTED_TOKEN]",
+	},
+	{
+		Name:        "api-key",
+		Pattern:     regexp.MustCompile(`\b(sk|ghp|gho|glpat|xox[bp])[-_][A-Za-z0-9_-]{16,}`),
+		Replacement: "[REDACTED_TOKEN]",
+	},
+	{
+		Name:        "key-value-secret",
+		Pattern:     regexp.MustCompile(`(?i)((?:api[_-]?key|access[_-]?key|token|secret|password|passwd)["']?\s*[:=]\s*["']?)[^\s"',}]+`),
+		Replacement: "${1}[REDACTED_SECRET]",
+	},
+	{
+		Name:        "email",
+		Pattern:     regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		Replacement: "[REDACTED_EMAIL]",
+	},
+	{
+		Name:        "ipv4",
+		Pattern:     regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+		Replacement: "[REDACTED_IP]",
+	},
+	{
+		Name:        "internal-hostname",
+		Pattern:     regexp.MustCompile(`(?i)\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:internal|local|corp|lan|intranet)\b`),
+		Replacement: "[REDACTED_HOST]",
+	},
+}
+
+// Sanitizer 在内容发送给大模型前按规则脱敏
+type Sanitizer struct {
+	rules []Rule
+}
+
+// New 创建脱敏器，patterns 为额外的正则，命中内容替换为 [REDACTED]
+func New(patterns []string, includeDefaults bool) (*Sanitizer, error) {
+	var rules []Rule
+	if includeDefaults {
+		rules = append(rules, DefaultRules...)
+	}
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sanitizer pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, Rule{
+			Name:        fmt.Sprintf("custom-%d", i),
+			Pattern:     re,
+			Replacement: "[REDACTED]",
+		})
+	}
+	return &Sanitizer{rules: rules}, nil
+}
+
+// Sanitize 依次应用所有规则，返回脱敏后的内容以及替换次数
+func (s *Sanitizer) Sanitize(content string) (string, int) {
+	redactions := 0
+	for _, rule := range s.rules {
+		content = rule.Pattern.ReplaceAllStringFunc(content, func(match string) string {
+			redactions++
+			return rule.Pattern.ReplaceAllString(match, rule.Replacement)
+		})
+	}
+	return content, redactions
+}
@@ -0,0 +1,39 @@
+package sanitize
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sanitizer", func() {
+	It("should redact emails, tokens, IPs and internal hostnames with the default rules", func() {
+		s, err := New(nil, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		content := "user ops@example.com called db.prod.internal from 10.0.3.17 with Authorization: Bearer abc.def-123 password=hunter2"
+		sanitized, redactions := s.Sanitize(content)
+
+		Expect(sanitized).NotTo(ContainSubstring("ops@example.com"))
+		Expect(sanitized).NotTo(ContainSubstring("db.prod.internal"))
+		Expect(sanitized).NotTo(ContainSubstring("10.0.3.17"))
+		Expect(sanitized).NotTo(ContainSubstring("abc.def-123"))
+		Expect(sanitized).NotTo(ContainSubstring("hunter2"))
+		Expect(sanitized).To(ContainSubstring("Bearer [REDACTED_TOKEN]"))
+		Expect(sanitized).To(ContainSubstring("password=[REDACTED_SECRET]"))
+		Expect(redactions).To(Equal(5))
+	})
+
+	It("should apply custom patterns", func() {
+		s, err := New([]string{`order-\d+`}, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		sanitized, redactions := s.Sanitize("failed to load order-42 and order-43 from 10.0.0.1")
+		Expect(sanitized).To(Equal("failed to load [REDACTED] and [REDACTED] from 10.0.0.1"))
+		Expect(redactions).To(Equal(2))
+	})
+
+	It("should reject invalid patterns", func() {
+		_, err := New([]string{"("}, true)
+		Expect(err).To(MatchError(ContainSubstring("invalid sanitizer pattern")))
+	})
+})
@@ -0,0 +1,14 @@
+package sanitize
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSanitize(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Sanitize Suite")
+}
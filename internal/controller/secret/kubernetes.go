@@ -0,0 +1,24 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KubernetesResolver 从 CR 所在命名空间的 Secret 中读取凭据
+type KubernetesResolver struct {
+	Client client.Reader
+}
+
+// Resolve 读取 Secret 的全部数据
+func (k *KubernetesResolver) Resolve(ctx context.Context, namespace, name string) (map[string][]byte, error) {
+	var secret corev1.Secret
+	if err := k.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &secret); err != nil {
+		return nil, fmt.Errorf("get secret %s/%s failed: %w", namespace, name, err)
+	}
+	return secret.Data, nil
+}
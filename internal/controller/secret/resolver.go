@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// SecretResolver 按名称解析一组凭据（键值对）
+type SecretResolver interface {
+	Resolve(ctx context.Context, namespace, name string) (map[string][]byte, error)
+}
+
+// Resolvers 按 SecretRef.Provider 分发到对应的后端
+type Resolvers map[autofixv1.SecretProvider]SecretResolver
+
+// Resolve 解析 SecretRef，未指定 Provider 时使用 Kubernetes Secret
+func (r Resolvers) Resolve(ctx context.Context, namespace string, ref autofixv1.SecretRef) (map[string][]byte, error) {
+	provider := ref.Provider
+	if provider == "" {
+		provider = autofixv1.SecretProviderKubernetes
+	}
+	resolver, ok := r[provider]
+	if !ok {
+		return nil, fmt.Errorf("secret provider %q is not configured", provider)
+	}
+	return resolver.Resolve(ctx, namespace, ref.Name)
+}
+
+// ResolveKey 解析 SecretRef 并读取指定键，键不存在或为空时返回错误
+func (r Resolvers) ResolveKey(ctx context.Context, namespace string, ref autofixv1.SecretRef, key string) (string, error) {
+	data, err := r.Resolve(ctx, namespace, ref)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[key]
+	if !ok || len(value) == 0 {
+		return "", fmt.Errorf("secret %q (provider %s) has no key %q", ref.Name, ref.Provider, key)
+	}
+	return string(value), nil
+}
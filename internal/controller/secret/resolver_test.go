@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Resolvers", func() {
+	It("should dispatch by provider and default to kubernetes", func() {
+		resolvers := Resolvers{
+			autofixv1.SecretProviderKubernetes: resolverFunc(func(namespace, name string) map[string][]byte {
+				return map[string][]byte{"source": []byte("kubernetes/" + namespace + "/" + name)}
+			}),
+			autofixv1.SecretProviderVault: resolverFunc(func(namespace, name string) map[string][]byte {
+				return map[string][]byte{"source": []byte("vault/" + namespace + "/" + name)}
+			}),
+		}
+
+		value, err := resolvers.ResolveKey(context.Background(), "team-a", autofixv1.SecretRef{Name: "llm"}, "source")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal("kubernetes/team-a/llm"))
+
+		value, err = resolvers.ResolveKey(context.Background(), "team-a",
+			autofixv1.SecretRef{Name: "llm", Provider: autofixv1.SecretProviderVault}, "source")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal("vault/team-a/llm"))
+	})
+
+	It("should reject a provider that is not configured", func() {
+		resolvers := Resolvers{autofixv1.SecretProviderKubernetes: resolverFunc(func(string, string) map[string][]byte { return nil })}
+
+		_, err := resolvers.Resolve(context.Background(), "team-a",
+			autofixv1.SecretRef{Name: "llm", Provider: autofixv1.SecretProviderVault})
+		Expect(err).To(MatchError(`secret provider "vault" is not configured`))
+
+		_, err = resolvers.Resolve(context.Background(), "team-a",
+			autofixv1.SecretRef{Name: "llm", Provider: "aws"})
+		Expect(err).To(MatchError(`secret provider "aws" is not configured`))
+	})
+})
+
+// resolverFunc 把函数适配为 SecretResolver
+type resolverFunc func(namespace, name string) map[string][]byte
+
+func (f resolverFunc) Resolve(_ context.Context, namespace, name string) (map[string][]byte, error) {
+	return f(namespace, name), nil
+}
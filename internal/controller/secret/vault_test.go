@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("VaultResolver", func() {
+	var (
+		paths  []string
+		tokens []string
+		server *httptest.Server
+	)
+
+	// serve 按路径返回响应体，未配置的路径返回 404
+	serve := func(bodies map[string]string) {
+		paths, tokens = nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			paths = append(paths, req.URL.Path)
+			tokens = append(tokens, req.Header.Get("X-Vault-Token"))
+			body, ok := bodies[req.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(body))
+		}))
+		DeferCleanup(server.Close)
+	}
+
+	It("should read a KV v2 record under the data path", func() {
+		serve(map[string]string{
+			"/v1/kv/data/team-a/feishu": `{"data":{"data":{"app_id":"cli_a1","retries":3},"metadata":{"version":2}}}`,
+		})
+		resolver, err := NewVaultResolver(server.URL+"/", "s.token", "/kv/", 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := resolver.Resolve(context.Background(), "team-a", "feishu")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(map[string][]byte{"app_id": []byte("cli_a1"), "retries": []byte("3")}))
+		Expect(paths).To(Equal([]string{"/v1/kv/data/team-a/feishu"}))
+		Expect(tokens).To(Equal([]string{"s.token"}))
+	})
+
+	It("should read a KV v1 record without the data path", func() {
+		serve(map[string]string{
+			"/v1/secret/team-a/feishu": `{"data":{"app_id":"cli_a1","app_secret":"s3cret"}}`,
+		})
+		resolver, err := NewVaultResolver(server.URL, "s.token", "", 1)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := resolver.Resolve(context.Background(), "team-a", "feishu")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(map[string][]byte{"app_id": []byte("cli_a1"), "app_secret": []byte("s3cret")}))
+		Expect(paths).To(Equal([]string{"/v1/secret/team-a/feishu"}))
+	})
+
+	It("should refuse names that leave the namespace", func() {
+		serve(map[string]string{
+			"/v1/secret/data/other-ns/llm": `{"data":{"data":{"api_key":"sk-other"}}}`,
+		})
+		resolver, err := NewVaultResolver(server.URL, "s.token", "", 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range []string{"../other-ns/llm", "llm/../../other-ns/llm", "/other-ns/llm", "."} {
+			_, err := resolver.Resolve(context.Background(), "team-a", name)
+			Expect(err).To(MatchError(ContainSubstring("invalid vault secret name")), name)
+		}
+		Expect(paths).To(BeEmpty())
+	})
+
+	It("should report a missing record and a missing key", func() {
+		serve(map[string]string{
+			"/v1/secret/data/team-a/feishu": `{"data":{"data":{"app_id":"cli_a1"}}}`,
+		})
+		resolver, err := NewVaultResolver(server.URL, "s.token", "", 2)
+		Expect(err).NotTo(HaveOccurred())
+		resolvers := Resolvers{autofixv1.SecretProviderVault: resolver}
+
+		_, err = resolvers.ResolveKey(context.Background(), "team-a",
+			autofixv1.SecretRef{Name: "gitlab", Provider: autofixv1.SecretProviderVault}, "token")
+		Expect(err).To(MatchError(ContainSubstring("read vault secret secret/data/team-a/gitlab failed: status 404")))
+
+		_, err = resolvers.ResolveKey(context.Background(), "team-a",
+			autofixv1.SecretRef{Name: "feishu", Provider: autofixv1.SecretProviderVault}, "app_secret")
+		Expect(err).To(MatchError(`secret "feishu" (provider vault) has no key "app_secret"`))
+
+		value, err := resolvers.ResolveKey(context.Background(), "team-a",
+			autofixv1.SecretRef{Name: "feishu", Provider: autofixv1.SecretProviderVault}, "app_id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal("cli_a1"))
+	})
+
+	It("should reject incomplete configuration", func() {
+		_, err := NewVaultResolver("", "s.token", "", 0)
+		Expect(err).To(MatchError("vault address is empty"))
+		_, err = NewVaultResolver("https://vault.example.com", "", "", 0)
+		Expect(err).To(MatchError("vault token is empty"))
+		_, err = NewVaultResolver("https://vault.example.com", "s.token", "", 3)
+		Expect(err).To(MatchError(ContainSubstring("unsupported vault kv version 3")))
+	})
+})
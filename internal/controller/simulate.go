@@ -0,0 +1,272 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// simulateMaxBodyBytes 模拟请求体的大小上限
+const simulateMaxBodyBytes = 1 << 20
+
+// SimulateRequest 模拟分析的输入：按 namespace/name 读取集群中的 CR，或直接提供 CR 的内容
+type SimulateRequest struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Analyzer 尚未创建或修改后未应用的 CR，设置后忽略 namespace/name
+	Analyzer *autofixv1.AIOpsAnalyzer `json:"analyzer,omitempty"`
+	// EventString 粘贴的监控数据，为空时按 CR 实时采集
+	EventString string `json:"eventString,omitempty"`
+}
+
+// SimulateResult 模拟分析的结果，不写 status、不发送卡片、不做任何 Git 操作
+type SimulateResult struct {
+	// Prompt 发送给大模型的完整内容
+	Prompt string `json:"prompt"`
+	// Response 大模型的原始响应
+	Response string `json:"response"`
+	// Action 解析并校验后的动作
+	Action any `json:"action,omitempty"`
+	// Decision 控制器对该响应的处理结果，取值与 status.summary 相同
+	Decision string `json:"decision"`
+	// Rejections 修复建议未通过的策略检查
+	Rejections []string `json:"rejections,omitempty"`
+	// RequireApproval 修复建议是否需要人工审批
+	RequireApproval bool `json:"requireApproval,omitempty"`
+	// Card 审批卡片的 content，与发送给飞书的内容相同
+	Card json.RawMessage `json:"card,omitempty"`
+	// Error 响应无法解析或卡片无法构造的原因
+	Error string `json:"error,omitempty"`
+}
+
+// simulateTarget 返回请求中的 CR，未直接提供时从集群读取
+func (r *AIOpsAnalyzerReconciler) simulateTarget(ctx context.Context, req *SimulateRequest) (*autofixv1.AIOpsAnalyzer, error) {
+	if req.Analyzer != nil {
+		aiopsAnalyzer := req.Analyzer.DeepCopy()
+		if aiopsAnalyzer.Namespace == "" {
+			aiopsAnalyzer.Namespace = corev1.NamespaceDefault
+		}
+		// 凭据引用按 CR 所在命名空间解析，内联的 CR 只能分析自己命名空间中的 Pod，
+		// 避免借用一个命名空间的凭据读取另一个命名空间的日志和事件
+		if target := aiopsAnalyzer.Spec.Target.Namespace; target != "" && target != aiopsAnalyzer.Namespace {
+			return nil, fmt.Errorf("inline analyzer in namespace %s cannot target namespace %s", aiopsAnalyzer.Namespace, target)
+		}
+		return aiopsAnalyzer, nil
+	}
+	if req.Name == "" {
+		return nil, errors.New("either analyzer or name must be set")
+	}
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+	var aiopsAnalyzer autofixv1.AIOpsAnalyzer
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: req.Name}, &aiopsAnalyzer); err != nil {
+		return nil, fmt.Errorf("get AIOpsAnalyzer %s/%s failed: %w", namespace, req.Name, err)
+	}
+	return &aiopsAnalyzer, nil
+}
+
+// simulateEventString 未粘贴监控数据时按 CR 实时采集，之后与协调时一样脱敏并按 token 预算截断
+func (r *AIOpsAnalyzerReconciler) simulateEventString(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, eventString string) (string, error) {
+	if eventString == "" {
+		pods, err := r.GetContextPods(ctx, aiopsAnalyzer)
+		if err != nil {
+			return "", err
+		}
+		datasources, err := r.resolveDatasources(ctx, aiopsAnalyzer)
+		if err != nil {
+			return "", err
+		}
+		// 只有一个数据源失败时仍然继续分析
+		if eventString, err = r.BuildEventString(ctx, aiopsAnalyzer, datasources, pods); eventString == "" {
+			return "", err
+		}
+	}
+	eventString, err := r.SanitizeEventString(ctx, aiopsAnalyzer, eventString)
+	if err != nil {
+		return "", err
+	}
+	if maxTokens := contextMaxTokens(aiopsAnalyzer.Spec.Context); estimateTokens(eventString) > maxTokens {
+		eventString = TrimEventString(eventString, maxTokens)
+	}
+	return eventString, nil
+}
+
+// Simulate 用 CR 的配置和给定的监控数据走一遍大模型调用、解析、校验和策略检查，返回将作出的处理和审批卡片
+// 观察期和冷却期只与时间有关，模拟时不检查
+func (r *AIOpsAnalyzerReconciler) Simulate(ctx context.Context, req *SimulateRequest) (*SimulateResult, error) {
+	aiopsAnalyzer, err := r.simulateTarget(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	eventString, err := r.simulateEventString(ctx, aiopsAnalyzer, req.EventString)
+	if err != nil {
+		return nil, fmt.Errorf("build event string failed: %w", err)
+	}
+	llmClient, err := r.llmClientFor(ctx, aiopsAnalyzer)
+	if err != nil {
+		return nil, err
+	}
+	workload, err := r.describeTargetWorkload(ctx, aiopsAnalyzer)
+	if err != nil {
+		return nil, err
+	}
+	result := &SimulateResult{}
+	if result.Prompt, err = r.buildAnalysisPrompt(ctx, aiopsAnalyzer, workload, eventString, time.Now()); err != nil {
+		return nil, err
+	}
+	sent, err := sendAnalysis(ctx, llmClient, result.Prompt)
+	if err != nil {
+		return nil, fmt.Errorf("call llm failed: %w", err)
+	}
+	result.Response = sent.Content
+
+	action, err := llm.ParseAutoHealResponse(sent.Content, llm.AllActionsAllowlist())
+	if err != nil {
+		result.Decision = autofixv1.SummaryAnalysisFailed
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Action = action
+	switch v := action.(type) {
+	case *llm.HealAction:
+		if err := r.simulateHeal(ctx, aiopsAnalyzer, v, result); err != nil {
+			return nil, err
+		}
+	case *llm.NoopAction:
+		result.Decision = autofixv1.SummaryHealthy
+	}
+	return result, nil
+}
+
+// simulateHeal 按 handleResponse 的顺序检查修复建议，只记录结论，不写 status、不发事件
+func (r *AIOpsAnalyzerReconciler) simulateHeal(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction, result *SimulateResult) error {
+	remediation := aiopsAnalyzer.Spec.AutoRemediation
+	if !remediation.Enabled {
+		result.Decision = autofixv1.SummaryRemediationDisabled
+		result.Rejections = []string{"autoRemediation.enabled 为 false"}
+		return nil
+	}
+
+	if rejected := disallowedActions(remediation, heal.PatchContent); len(rejected) > 0 {
+		result.Rejections = append(result.Rejections, "修复类型未允许: "+strings.Join(rejected, "; "))
+	}
+	if heal.ConfigMap != "" {
+		workload, err := r.getTargetWorkload(ctx, healNamespace(aiopsAnalyzer, heal), heal.Target)
+		if err != nil {
+			return err
+		}
+		referenced, err := workloadConfigMaps(workload)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(referenced, heal.ConfigMap) {
+			result.Rejections = append(result.Rejections,
+				fmt.Sprintf("ConfigMap %s 未被 %s/%s 引用", heal.ConfigMap, workload.GetKind(), workload.GetName()))
+		}
+	}
+	if threshold := minConfidence(remediation); threshold > 0 && (heal.Confidence == nil || *heal.Confidence < threshold) {
+		result.Rejections = append(result.Rejections,
+			fmt.Sprintf("置信度 %s 低于 minConfidence %s", formatConfidence(heal.Confidence), remediation.MinConfidence))
+	}
+	unknown, err := r.unknownContainerRefs(ctx, aiopsAnalyzer, heal)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		result.Decision = autofixv1.SummaryPolicyRejected
+		result.Rejections = append(result.Rejections, "引用的容器不存在: "+strings.Join(unknown, "; "))
+		return nil
+	}
+	if err := r.resolveRelativeResources(ctx, aiopsAnalyzer, heal); err != nil {
+		return err
+	}
+	if err := llm.ValidateHealAction(heal, healLimits(aiopsAnalyzer)); err != nil {
+		result.Rejections = append(result.Rejections, err.Error())
+	}
+	if len(result.Rejections) > 0 {
+		result.Decision = autofixv1.SummaryPolicyRejected
+		return nil
+	}
+
+	if remediation.SafeMode {
+		if reasons := llm.FindDestructiveOps(heal.PatchContent); len(reasons) > 0 {
+			result.Decision = autofixv1.SummaryBlockedBySafeMode
+			result.Rejections = []string{"安全模式拒绝: " + strings.Join(reasons, "; ")}
+			return nil
+		}
+	}
+	if remediation.DryRun {
+		result.Decision = autofixv1.SummaryDryRun
+		return nil
+	}
+
+	result.Decision = autofixv1.SummaryRemediationProposed
+	result.RequireApproval = remediation.RequireApproval || EscalationPolicy(proposalSeverity(heal), heal.RiskLevel).RequireApproval
+	cardMsg, _, err := r.buildApprovalCard(ctx, aiopsAnalyzer, heal, GenerateRequestID(aiopsAnalyzer))
+	if err == nil {
+		var content string
+		if content, err = cardMsg.Content(); err == nil {
+			result.Card = json.RawMessage(content)
+		}
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("build approval card failed: %v", err)
+	}
+	return nil
+}
+
+// SimulateHandler 处理 POST 的 SimulateRequest，以 JSON 返回 SimulateResult
+func (r *AIOpsAnalyzerReconciler) SimulateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var simulate SimulateRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, simulateMaxBodyBytes)).Decode(&simulate); err != nil {
+			http.Error(w, fmt.Sprintf("decode request failed: %v", err), http.StatusBadRequest)
+			return
+		}
+		result, err := r.Simulate(req.Context(), &simulate)
+		if err != nil {
+			log.FromContext(req.Context()).Error(err, "模拟分析失败")
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(result)
+	})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm/llmtest"
+)
+
+var _ = Describe("Simulate", func() {
+	const (
+		healResponse = `{"action":"heal","reason":"CPU 飙高","patch_file":"cpu.yaml","patch_content":[{"op":"replace","path":"/spec/replicas","value":3}],"target":{"kind":"Deployment","labelSelector":"app=order"},"risk_level":"low"}`
+		eventString  = "=== Prometheus Alerts ===\nAlert: HighCPU\n"
+	)
+
+	var (
+		reconciler    *AIOpsAnalyzerReconciler
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+		recorder      *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		replicas := int32(2)
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{Name: "simulate", Namespace: "default"},
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target: autofixv1.TargetSelector{
+					Namespace: "default",
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+				},
+				Feishu: autofixv1.FeishuNotification{
+					ReceiveIDType: autofixv1.FeishuChatID, ReceiveID: "oc_team",
+					TemplateID: "tpl", TemplateVersion: "1.0.0",
+				},
+				AutoRemediation: autofixv1.AutoRemediationSpec{Enabled: true, RequireApproval: true},
+			},
+		}
+		reconciler = newFakeReconciler(aiopsAnalyzer, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", Labels: map[string]string{"app": "order"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		})
+		recorder = record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+	})
+
+	It("should return the action and approval card without touching status", func() {
+		fake := llmtest.NewFakeLLMClient(healResponse)
+		reconciler.LLM = fake
+
+		result, err := reconciler.Simulate(context.Background(), &SimulateRequest{Name: "simulate", EventString: eventString})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.Requests).To(ConsistOf(ContainSubstring("Alert: HighCPU")))
+		Expect(result.Prompt).To(Equal(fake.Requests[0]))
+		Expect(result.Response).To(Equal(healResponse))
+		Expect(result.Decision).To(Equal(autofixv1.SummaryRemediationProposed))
+		Expect(result.RequireApproval).To(BeTrue())
+		Expect(result.Error).To(BeEmpty())
+		Expect(string(result.Card)).To(ContainSubstring(`"template_id":"tpl"`))
+		Expect(string(result.Card)).To(ContainSubstring(`"request_id":"default.simulate.`))
+
+		var latest autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "simulate"}, &latest)).To(Succeed())
+		Expect(latest.Status).To(Equal(autofixv1.AIOpsAnalyzerStatus{}))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should report the policy checks the proposal would fail", func() {
+		reconciler.LLM = llmtest.NewFakeLLMClient(healResponse)
+		analyzer := aiopsAnalyzer.DeepCopy()
+		analyzer.Spec.AutoRemediation.AllowedActions = []string{"restart"}
+		analyzer.Spec.AutoRemediation.MinConfidence = "0.5"
+
+		result, err := reconciler.Simulate(context.Background(), &SimulateRequest{Analyzer: analyzer, EventString: eventString})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Decision).To(Equal(autofixv1.SummaryPolicyRejected))
+		Expect(result.Rejections).To(Equal([]string{
+			"修复类型未允许: replace /spec/replicas (scale)",
+			"置信度 未知 低于 minConfidence 0.5",
+		}))
+		Expect(result.Card).To(BeEmpty())
+	})
+
+	It("should not let inline analyzers target another namespace", func() {
+		fake := llmtest.NewFakeLLMClient(healResponse)
+		reconciler.LLM = fake
+		analyzer := aiopsAnalyzer.DeepCopy()
+		analyzer.Namespace = "team-a"
+		analyzer.Spec.Target.Namespace = "payments"
+
+		_, err := reconciler.Simulate(context.Background(), &SimulateRequest{Analyzer: analyzer, EventString: eventString})
+		Expect(err).To(MatchError("inline analyzer in namespace team-a cannot target namespace payments"))
+		Expect(fake.Requests).To(BeEmpty())
+	})
+
+	It("should report unusable responses", func() {
+		reconciler.LLM = llmtest.NewFakeLLMClient(`{"action":"reboot"}`)
+
+		result, err := reconciler.Simulate(context.Background(), &SimulateRequest{Name: "simulate", EventString: eventString})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Decision).To(Equal(autofixv1.SummaryAnalysisFailed))
+		Expect(result.Error).To(ContainSubstring("unknown action: reboot"))
+	})
+
+	It("should serve simulations over HTTP", func() {
+		reconciler.LLM = llmtest.NewFakeLLMClient(`{"action":"noop","reason":"指标正常"}`)
+		handler := reconciler.SimulateHandler()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/simulate",
+			strings.NewReader(`{"namespace":"default","name":"simulate","eventString":"=== Prometheus Alerts ===\nNo firing alerts\n"}`)))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var result SimulateResult
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		Expect(result.Decision).To(Equal(autofixv1.SummaryHealthy))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/simulate", strings.NewReader(`{"name":"missing"}`)))
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/simulate", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
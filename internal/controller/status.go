@@ -0,0 +1,95 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// lastErrorMaxLength status.lastError 中保留的最大错误长度
+const lastErrorMaxLength = 1024
+
+// updateStatus 获取最新的对象后应用 mutate 并更新status，冲突时自动重试
+// 更新成功后会把最新的对象写回 aiopsAnalyzer
+func (r *AIOpsAnalyzerReconciler) updateStatus(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer,
+	mutate func(status *autofixv1.AIOpsAnalyzerStatus)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var latest autofixv1.AIOpsAnalyzer
+		if err := r.Get(ctx, client.ObjectKeyFromObject(aiopsAnalyzer), &latest); err != nil {
+			return err
+		}
+		mutate(&latest.Status)
+		if err := r.Status().Update(ctx, &latest); err != nil {
+			return err
+		}
+		latest.DeepCopyInto(aiopsAnalyzer)
+		return nil
+	})
+}
+
+// recordProposal 把修复结论写入status：Summary 为 RemediationProposed，并记录完整的修复建议
+func (r *AIOpsAnalyzerReconciler) recordProposal(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) error {
+	proposal, err := r.newRemediationProposal(ctx, aiopsAnalyzer, heal)
+	if err != nil {
+		return err
+	}
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		status.LastAnalysisTime = &now
+		status.Summary = autofixv1.SummaryRemediationProposed
+		status.Insights = heal.Reason
+		if heal.Detail != "" {
+			status.Insights = fmt.Sprintf("%s：%s", heal.Reason, heal.Detail)
+		}
+		status.ProposedRemediation = proposal
+	})
+}
+
+// recordLastError 把协调错误（截断后）写入 status.lastError，成功时清空
+func (r *AIOpsAnalyzerReconciler) recordLastError(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, reconcileErr error) error {
+	if reconcileErr == nil && aiopsAnalyzer.Status.LastError == nil {
+		return nil
+	}
+
+	return r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		if reconcileErr == nil {
+			status.LastError = nil
+			return
+		}
+		status.LastError = &autofixv1.ReconcileError{
+			Message: truncateError(reconcileErr),
+			Time:    metav1.Now(),
+		}
+	})
+}
+
+// truncateError 返回截断到 lastErrorMaxLength 的错误信息
+func truncateError(err error) string {
+	message := err.Error()
+	if len(message) > lastErrorMaxLength {
+		message = message[:lastErrorMaxLength] + "...(truncated)"
+	}
+	return message
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Last error", func() {
+	var (
+		reconciler    *AIOpsAnalyzerReconciler
+		aiopsAnalyzer *autofixv1.AIOpsAnalyzer
+	)
+
+	BeforeEach(func() {
+		aiopsAnalyzer = &autofixv1.AIOpsAnalyzer{ObjectMeta: metav1.ObjectMeta{Name: "last-error", Namespace: "default"}}
+		reconciler = newFakeReconciler(aiopsAnalyzer)
+	})
+
+	latest := func() *autofixv1.ReconcileError {
+		var stored autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(aiopsAnalyzer), &stored)).To(Succeed())
+		return stored.Status.LastError
+	}
+
+	It("should record a reconcile error and clear it after a successful reconcile", func() {
+		Expect(reconciler.recordLastError(context.Background(), aiopsAnalyzer, errors.New("loki returned 502"))).To(Succeed())
+		lastError := latest()
+		Expect(lastError).NotTo(BeNil())
+		Expect(lastError.Message).To(Equal("loki returned 502"))
+		Expect(lastError.Time.IsZero()).To(BeFalse())
+
+		Expect(reconciler.recordLastError(context.Background(), aiopsAnalyzer, nil)).To(Succeed())
+		Expect(latest()).To(BeNil())
+		Expect(aiopsAnalyzer.Status.LastError).To(BeNil())
+	})
+
+	It("should truncate long errors", func() {
+		Expect(reconciler.recordLastError(context.Background(), aiopsAnalyzer, errors.New(strings.Repeat("x", 2*lastErrorMaxLength)))).To(Succeed())
+		Expect(latest().Message).To(Equal(strings.Repeat("x", lastErrorMaxLength) + "...(truncated)"))
+	})
+
+	It("should not update the status when there is no error to record or clear", func() {
+		key := client.ObjectKeyFromObject(aiopsAnalyzer)
+		Expect(reconciler.Get(context.Background(), key, aiopsAnalyzer)).To(Succeed())
+		resourceVersion := aiopsAnalyzer.ResourceVersion
+		Expect(reconciler.recordLastError(context.Background(), aiopsAnalyzer, nil)).To(Succeed())
+
+		var stored autofixv1.AIOpsAnalyzer
+		Expect(reconciler.Get(context.Background(), key, &stored)).To(Succeed())
+		Expect(stored.ResourceVersion).To(Equal(resourceVersion))
+	})
+})
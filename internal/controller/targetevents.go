@@ -0,0 +1,161 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// maxTargetEvents event string 中最多保留的 Warning Event 数量
+const maxTargetEvents = 20
+
+// eventInvolvedObjectNameField API Server 支持的 Event 字段选择器
+const eventInvolvedObjectNameField = "involvedObject.name"
+
+// involvedObjectKey Event 关联对象的类型和名称
+type involvedObjectKey struct {
+	Kind string
+	Name string
+}
+
+// targetInvolvedObjects 返回目标Pod及其直接 owner（如 ReplicaSet），
+// FailedScheduling、BackOff 记录在 Pod 上，FailedCreate 记录在 ReplicaSet 上
+func targetInvolvedObjects(pods []corev1.Pod) map[involvedObjectKey]struct{} {
+	objects := make(map[involvedObjectKey]struct{})
+	for _, pod := range pods {
+		objects[involvedObjectKey{Kind: "Pod", Name: pod.Name}] = struct{}{}
+		for _, owner := range pod.OwnerReferences {
+			objects[involvedObjectKey{Kind: owner.Kind, Name: owner.Name}] = struct{}{}
+		}
+	}
+	return objects
+}
+
+// eventLookback 与 Loki 日志使用相同的时间范围，未配置或无法解析时使用默认值
+func eventLookback(cfg *autofixv1.LokiConfig) time.Duration {
+	if cfg != nil && cfg.Lookback != "" {
+		if d, err := time.ParseDuration(cfg.Lookback); err == nil {
+			return d
+		}
+	}
+	return defaultLokiLookback
+}
+
+// eventTime 返回 Event 最后一次发生的时间
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// filterTargetEvents 保留 since 之后关联到目标对象的 Warning Event，按时间从新到旧最多返回 maxTargetEvents 条
+func filterTargetEvents(events []corev1.Event, objects map[involvedObjectKey]struct{}, since time.Time) []corev1.Event {
+	var matched []corev1.Event
+	for _, event := range events {
+		if event.Type != corev1.EventTypeWarning || eventTime(&event).Before(since) {
+			continue
+		}
+		if _, ok := objects[involvedObjectKey{Kind: event.InvolvedObject.Kind, Name: event.InvolvedObject.Name}]; !ok {
+			continue
+		}
+		matched = append(matched, event)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return eventTime(&matched[i]).After(eventTime(&matched[j]))
+	})
+	if len(matched) > maxTargetEvents {
+		matched = matched[:maxTargetEvents]
+	}
+	return matched
+}
+
+// formatTargetEvents 每条 Event 输出一行：时间、关联对象、原因、次数和消息
+func formatTargetEvents(events []corev1.Event) string {
+	var b strings.Builder
+	for i := range events {
+		event := &events[i]
+		count := event.Count
+		if count == 0 {
+			count = 1
+		}
+		fmt.Fprintf(&b, "- %s %s/%s %s (x%d): %s\n",
+			eventTime(event).UTC().Format(time.RFC3339),
+			event.InvolvedObject.Kind, event.InvolvedObject.Name,
+			event.Reason, count, strings.TrimSpace(event.Message))
+	}
+	return b.String()
+}
+
+// GetTargetEvents 获取目标Pod及其 owner 在回溯时间范围内的 Warning Event，
+// 让大模型看到 OOMKilled、FailedScheduling、BackOff 等调度和运行时信息
+func (r *AIOpsAnalyzerReconciler) GetTargetEvents(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, pods []corev1.Pod) (string, error) {
+	return r.getTargetEvents(ctx, aiopsAnalyzer, pods, time.Now())
+}
+
+func (r *AIOpsAnalyzerReconciler) getTargetEvents(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, pods []corev1.Pod, now time.Time) (string, error) {
+	log := log.FromContext(ctx)
+	if len(pods) == 0 {
+		return "", nil
+	}
+
+	namespace := aiopsAnalyzer.Spec.Target.Namespace
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+	// 按关联对象名称逐个查询，不使用缓存，避免 watch 全集群的 Event
+	reader := r.eventReader()
+	objects := targetInvolvedObjects(pods)
+	var events []corev1.Event
+	for object := range objects {
+		var list corev1.EventList
+		if err := reader.List(ctx, &list, client.InNamespace(namespace),
+			client.MatchingFields{eventInvolvedObjectNameField: object.Name}); err != nil {
+			log.Error(err, "获取Event列表失败", "namespace", namespace, "involvedObject", object.Name)
+			return "", err
+		}
+		events = append(events, list.Items...)
+	}
+
+	since := now.Add(-eventLookback(aiopsAnalyzer.Spec.Loki))
+	matched := filterTargetEvents(events, objects, since)
+	log.V(1).Info("获取目标Warning Event", "count", len(matched), "namespace", namespace)
+	return formatTargetEvents(matched), nil
+}
+
+// eventReader Event 不进入缓存，配置了 APIReader 时直接读取 API Server
+func (r *AIOpsAnalyzerReconciler) eventReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Target warning events", func() {
+	now := time.Date(2025, 11, 26, 12, 0, 0, 0, time.UTC)
+
+	aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+		ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "shop"},
+		Spec: autofixv1.AIOpsAnalyzerSpec{
+			Target: autofixv1.TargetSelector{Namespace: "shop"},
+			Loki:   &autofixv1.LokiConfig{Lookback: "30m"},
+		},
+	}
+	pods := []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{
+		Name: "order-7c9f-abc", Namespace: "shop",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "order-7c9f"}},
+	}}}
+
+	// warning 构造关联到 kind/name 的 Event，ago 为最后一次发生距 now 的时间
+	warning := func(name, eventType, kind, objectName, reason string, ago time.Duration) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: objectName, Namespace: "shop"},
+			Type:           eventType,
+			Reason:         reason,
+			Message:        reason + " message",
+			Count:          2,
+			LastTimestamp:  metav1.NewTime(now.Add(-ago)),
+		}
+	}
+
+	It("should keep recent warnings of the target pods and their owners, newest first", func() {
+		reconciler := newFakeReconciler(
+			warning("backoff", corev1.EventTypeWarning, "Pod", "order-7c9f-abc", "BackOff", time.Minute),
+			warning("failed-create", corev1.EventTypeWarning, "ReplicaSet", "order-7c9f", "FailedCreate", 5*time.Minute),
+			warning("pulled", corev1.EventTypeNormal, "Pod", "order-7c9f-abc", "Pulled", time.Minute),
+			warning("stale", corev1.EventTypeWarning, "Pod", "order-7c9f-abc", "FailedScheduling", time.Hour),
+			warning("other-pod", corev1.EventTypeWarning, "Pod", "payment-0", "BackOff", time.Minute),
+		)
+
+		events, err := reconciler.getTargetEvents(context.Background(), aiopsAnalyzer, pods, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(Equal(
+			"- 2025-11-26T11:59:00Z Pod/order-7c9f-abc BackOff (x2): BackOff message\n" +
+				"- 2025-11-26T11:55:00Z ReplicaSet/order-7c9f FailedCreate (x2): FailedCreate message\n"))
+	})
+
+	It("should read events through the API reader instead of the cache", func() {
+		reconciler := newFakeReconciler()
+		reconciler.APIReader = newFakeReconciler(
+			warning("backoff", corev1.EventTypeWarning, "Pod", "order-7c9f-abc", "BackOff", time.Minute),
+		).Client
+
+		events, err := reconciler.getTargetEvents(context.Background(), aiopsAnalyzer, pods, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(Equal("- 2025-11-26T11:59:00Z Pod/order-7c9f-abc BackOff (x2): BackOff message\n"))
+	})
+
+	It("should cap the number of events", func() {
+		var objs []corev1.Event
+		for i := 0; i < maxTargetEvents+5; i++ {
+			objs = append(objs, *warning(fmt.Sprintf("oom-%d", i), corev1.EventTypeWarning, "Pod", "order-7c9f-abc", "OOMKilling", time.Duration(i)*time.Second))
+		}
+		matched := filterTargetEvents(objs, targetInvolvedObjects(pods), now.Add(-time.Hour))
+		Expect(matched).To(HaveLen(maxTargetEvents))
+		Expect(matched[0].Name).To(Equal("oom-0"))
+		Expect(strings.Count(formatTargetEvents(matched), "\n")).To(Equal(maxTargetEvents))
+	})
+
+	It("should add a warning events section to the event string", func() {
+		scheduling := warning("scheduling", corev1.EventTypeWarning, "Pod", "order-7c9f-abc", "FailedScheduling", 0)
+		scheduling.LastTimestamp = metav1.Now()
+		empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			resultType := "vector"
+			if req.URL.Path == lokiQueryPath {
+				resultType = "streams"
+			}
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"` + resultType + `","result":[]}}`))
+		}))
+		DeferCleanup(empty.Close)
+
+		eventString, err := newFakeReconciler(scheduling).BuildEventString(context.Background(), aiopsAnalyzer,
+			datasources{PrometheusURL: empty.URL, LokiURL: empty.URL}, pods)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventString).To(ContainSubstring(
+			"=== Kubernetes Warning Events ===\n- " + scheduling.LastTimestamp.UTC().Format(time.RFC3339) +
+				" Pod/order-7c9f-abc FailedScheduling (x2): FailedScheduling message\n"))
+	})
+})
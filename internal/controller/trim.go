@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// defaultContextMaxTokens 未配置 spec.context.maxTokens 时 event string 的 token 预算
+const defaultContextMaxTokens = 24000
+
+// trimmableSections 超出预算时依次截断的分段，告警和节点压力始终保留
+var trimmableSections = []string{
+	"Loki Error Logs",
+	"Prometheus Trends",
+	"Target Resource Information",
+}
+
+// contextMaxTokens 读取 spec.context.maxTokens，未配置时使用默认值
+func contextMaxTokens(cfg *autofixv1.ContextSpec) int {
+	if cfg != nil && cfg.MaxTokens > 0 {
+		return cfg.MaxTokens
+	}
+	return defaultContextMaxTokens
+}
+
+// estimateTokens 粗略估算 token 数：ASCII 约 4 个字符一个 token，其余字符（如中文）各算一个
+func estimateTokens(s string) int {
+	ascii, other := countRunes(s)
+	return (ascii+3)/4 + other
+}
+
+// countRunes 分别统计 ASCII 字符和其余字符的数量
+func countRunes(s string) (ascii, other int) {
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return ascii, other
+}
+
+// eventSection event string 中以 "=== 标题 ===" 开头的一段
+type eventSection struct {
+	Title string
+	Lines []string
+}
+
+// splitEventSections 按 "=== 标题 ===" 拆分 event string，标题之前的内容放在标题为空的分段中
+func splitEventSections(s string) []eventSection {
+	sections := []eventSection{{}}
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line == "" {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") && strings.HasSuffix(trimmed, " ===") && len(trimmed) > 8 {
+			sections = append(sections, eventSection{Title: trimmed[4 : len(trimmed)-4]})
+		}
+		sections[len(sections)-1].Lines = append(sections[len(sections)-1].Lines, line)
+	}
+	return sections
+}
+
+// TrimEventString 估算的 token 数超过 maxTokens 时，按日志、指标趋势、资源 YAML 的顺序
+// 截断分段末尾的行，每段只截掉超出的部分，并在末尾说明截断了哪些内容
+// 只截断这些分段仍超出预算时原样保留其余内容
+func TrimEventString(s string, maxTokens int) string {
+	excess := estimateTokens(s) - maxTokens
+	if maxTokens <= 0 || excess <= 0 {
+		return s
+	}
+
+	// 截断说明本身也占用预算
+	header := fmt.Sprintf("\n=== Trimmed to fit %d tokens ===\n", maxTokens)
+	excess += estimateTokens(header)
+
+	sections := splitEventSections(s)
+	var notes []string
+	for _, title := range trimmableSections {
+		if excess <= 0 {
+			break
+		}
+		for i := range sections {
+			if sections[i].Title != title {
+				continue
+			}
+			// 第一行是标题，末尾的空行用于分隔下一段，都保留
+			body := sections[i].Lines[1:]
+			var tail []string
+			if n := len(body); n > 0 && body[n-1] == "\n" {
+				body, tail = body[:n-1], []string{"\n"}
+			}
+			if len(body) == 0 {
+				continue
+			}
+			marker := fmt.Sprintf("... %d more lines trimmed\n", len(body))
+			note := fmt.Sprintf("%s: kept %d/%d lines\n", title, len(body), len(body))
+			// 按删除的字符总数估算，逐行向上取整会高估删除的 token 数
+			kept, removed, removedASCII, removedOther := len(body), 0, 0, 0
+			for kept > 0 && removed < excess+estimateTokens(marker)+estimateTokens(note) {
+				kept--
+				ascii, other := countRunes(body[kept])
+				removedASCII, removedOther = removedASCII+ascii, removedOther+other
+				removed = removedASCII/4 + removedOther
+			}
+			excess -= removed - estimateTokens(marker) - estimateTokens(note)
+			lines := append(sections[i].Lines[:1+kept:1+kept], fmt.Sprintf("... %d more lines trimmed\n", len(body)-kept))
+			sections[i].Lines = append(lines, tail...)
+			notes = append(notes, fmt.Sprintf("%s: kept %d/%d lines", title, kept, len(body)))
+		}
+	}
+
+	var b strings.Builder
+	for _, section := range sections {
+		for _, line := range section.Lines {
+			b.WriteString(line)
+		}
+	}
+	if len(notes) > 0 {
+		b.WriteString(header)
+		b.WriteString(strings.Join(notes, "\n"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("TrimEventString", func() {
+	// eventString 按 BuildEventString 的格式拼出各分段，yamlLines、logLines 为对应分段的行数
+	eventString := func(yamlLines, logLines int) string {
+		var b strings.Builder
+		b.WriteString("=== Target Resource Information ===\n")
+		for i := 0; i < yamlLines; i++ {
+			fmt.Fprintf(&b, "    reason: CrashLoopBackOff restart %04d\n", i)
+		}
+		b.WriteString("\n=== Node Pressure ===\nNo scheduled nodes\n")
+		b.WriteString("\n=== Prometheus Alerts ===\nAlert: HighCPU\n  Pod: order-0\n\n")
+		b.WriteString("\n=== Loki Error Logs ===\n")
+		for i := 0; i < logLines; i++ {
+			fmt.Fprintf(&b, "1700000000%04d: error connecting to database\n", i)
+		}
+		return b.String()
+	}
+
+	It("should estimate ASCII at four characters per token and other characters as one", func() {
+		Expect(estimateTokens("")).To(Equal(0))
+		Expect(estimateTokens("abcdefgh")).To(Equal(2))
+		Expect(estimateTokens("abcde")).To(Equal(2))
+		Expect(estimateTokens("内存不足")).To(Equal(4))
+	})
+
+	It("should leave an event string within the budget untouched", func() {
+		s := eventString(10, 10)
+		Expect(TrimEventString(s, estimateTokens(s))).To(Equal(s))
+		Expect(TrimEventString(s, 0)).To(Equal(s))
+	})
+
+	It("should trim logs before the resource YAML and keep the alerts", func() {
+		s := eventString(50, 200)
+		maxTokens := estimateTokens(s) - 1000
+
+		trimmed := TrimEventString(s, maxTokens)
+		Expect(estimateTokens(trimmed)).To(BeNumerically("<=", maxTokens))
+		Expect(trimmed).To(ContainSubstring("=== Prometheus Alerts ===\nAlert: HighCPU\n  Pod: order-0\n"))
+		Expect(trimmed).To(ContainSubstring("reason: CrashLoopBackOff restart 0049\n\n=== Node Pressure ==="))
+		Expect(trimmed).To(ContainSubstring("1700000000" + "0000: error connecting to database\n"))
+		Expect(trimmed).NotTo(ContainSubstring("1700000000" + "0199"))
+		Expect(trimmed).To(MatchRegexp(`\.\.\. \d+ more lines trimmed\n`))
+		Expect(trimmed).To(MatchRegexp(`=== Trimmed to fit \d+ tokens ===\nLoki Error Logs: kept \d+/200 lines\n$`))
+	})
+
+	It("should move on to the resource YAML once the logs are exhausted", func() {
+		s := eventString(200, 20)
+		maxTokens := estimateTokens(s) - 1200
+
+		trimmed := TrimEventString(s, maxTokens)
+		Expect(trimmed).To(ContainSubstring("=== Loki Error Logs ===\n... 20 more lines trimmed\n"))
+		Expect(trimmed).To(ContainSubstring("Loki Error Logs: kept 0/20 lines\nTarget Resource Information: kept "))
+		Expect(trimmed).To(MatchRegexp(`more lines trimmed\n\n=== Node Pressure ===`))
+		Expect(trimmed).To(ContainSubstring("Alert: HighCPU"))
+	})
+
+	It("should use the configured budget", func() {
+		Expect(contextMaxTokens(nil)).To(Equal(defaultContextMaxTokens))
+		Expect(contextMaxTokens(&autofixv1.ContextSpec{MaxTokens: 8000})).To(Equal(8000))
+	})
+})
@@ -0,0 +1,64 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// warmupEndsAt 返回观察期结束时间，未配置 warmupPeriod 时返回 nil
+func warmupEndsAt(aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (*time.Time, error) {
+	if aiopsAnalyzer.Spec.WarmupPeriod == "" {
+		return nil, nil
+	}
+	period, err := time.ParseDuration(aiopsAnalyzer.Spec.WarmupPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid warmupPeriod %q: %w", aiopsAnalyzer.Spec.WarmupPeriod, err)
+	}
+	end := aiopsAnalyzer.CreationTimestamp.Add(period)
+	return &end, nil
+}
+
+// holdDuringWarmup 观察期内只记录分析结论，不发起修复建议
+// 返回 true 时调用方应在返回的时间后重新分析
+func (r *AIOpsAnalyzerReconciler) holdDuringWarmup(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer, heal *llm.HealAction) (bool, time.Duration, error) {
+	end, err := warmupEndsAt(aiopsAnalyzer)
+	if err != nil || end == nil {
+		return false, 0, err
+	}
+	remaining := time.Until(*end)
+	if remaining <= 0 {
+		return false, 0, nil
+	}
+
+	return true, remaining, r.updateStatus(ctx, aiopsAnalyzer, func(status *autofixv1.AIOpsAnalyzerStatus) {
+		now := metav1.Now()
+		endsAt := metav1.NewTime(*end)
+		status.LastAnalysisTime = &now
+		status.WarmupEndsAt = &endsAt
+		status.Insights = fmt.Sprintf("%s（观察期内，仅记录：%v）", heal.Reason, heal.PatchContent)
+		status.NoopReason = autofixv1.NoopReasonWarmingUp
+		status.NoopMessage = fmt.Sprintf("观察期到 %s 结束", end.Format(time.RFC3339))
+	})
+}
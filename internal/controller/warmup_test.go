@@ -0,0 +1,65 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+var _ = Describe("Warmup period", func() {
+	heal := &llm.HealAction{Reason: "CPU 飙高", PatchContent: []llm.PatchOp{{Op: "replace", Path: "/spec/replicas", Value: float64(3)}}}
+
+	newAnalyzer := func(age time.Duration) *autofixv1.AIOpsAnalyzer {
+		return &autofixv1.AIOpsAnalyzer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "warmup", Namespace: "default",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+			Spec: autofixv1.AIOpsAnalyzerSpec{WarmupPeriod: "1h"},
+		}
+	}
+
+	It("should only record insights while warming up", func() {
+		aiopsAnalyzer := newAnalyzer(10 * time.Minute)
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		hold, remaining, err := reconciler.holdDuringWarmup(context.Background(), aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hold).To(BeTrue())
+		Expect(remaining).To(BeNumerically("~", 50*time.Minute, time.Minute))
+		Expect(aiopsAnalyzer.Status.NoopReason).To(Equal(autofixv1.NoopReasonWarmingUp))
+		Expect(aiopsAnalyzer.Status.WarmupEndsAt).NotTo(BeNil())
+		Expect(aiopsAnalyzer.Status.Insights).To(ContainSubstring("CPU 飙高"))
+	})
+
+	It("should resume full behavior after the warmup period", func() {
+		aiopsAnalyzer := newAnalyzer(2 * time.Hour)
+		reconciler := newFakeReconciler(aiopsAnalyzer)
+
+		hold, _, err := reconciler.holdDuringWarmup(context.Background(), aiopsAnalyzer, heal)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hold).To(BeFalse())
+	})
+})
@@ -0,0 +1,271 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch
+
+// workloadKinds 大模型允许修改的工作负载类型
+var workloadKinds = map[string]schema.GroupVersionKind{
+	"Deployment":              {Group: "apps", Version: "v1", Kind: "Deployment"},
+	"StatefulSet":             {Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	"HorizontalPodAutoscaler": {Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"},
+}
+
+// replicaSetGVK 从 Pod 查找 Deployment 时经过的 ReplicaSet
+var replicaSetGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+
+// getTargetWorkload 按修复建议中的 kind 和 labelSelector 查找线上的工作负载
+func (r *AIOpsAnalyzerReconciler) getTargetWorkload(ctx context.Context, namespace string, target llm.Target) (*unstructured.Unstructured, error) {
+	gvk, ok := workloadKinds[target.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported workload kind %q", target.Kind)
+	}
+	selector, err := labels.Parse(target.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", target.LabelSelector, err)
+	}
+
+	items, err := r.listWorkloads(ctx, gvk, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	switch len(items) {
+	case 0:
+		return nil, fmt.Errorf("no %s matches %q in namespace %s", target.Kind, target.LabelSelector, namespace)
+	case 1:
+		return &items[0], nil
+	default:
+		return nil, fmt.Errorf("%d %s objects match %q in namespace %s, expected exactly one",
+			len(items), target.Kind, target.LabelSelector, namespace)
+	}
+}
+
+// listWorkloads 列出命名空间中标签匹配 selector 的工作负载
+func (r *AIOpsAnalyzerReconciler) listWorkloads(ctx context.Context, gvk schema.GroupVersionKind, namespace string, selector labels.Selector) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, list, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("list %s failed: %w", gvk.Kind, err)
+	}
+	return list.Items, nil
+}
+
+// targetWorkloadKinds 按顺序查找的被监控工作负载类型
+var targetWorkloadKinds = []string{"Deployment", "StatefulSet"}
+
+// hpaInfo 扩缩被监控工作负载的 HPA
+type hpaInfo struct {
+	Name string
+	// 未设置时为 nil（apiserver 默认为 1）
+	MinReplicas *int64
+	MaxReplicas int64
+}
+
+// workloadInfo 被监控工作负载的当前配置，用于构建大模型请求内容
+type workloadInfo struct {
+	Kind          string
+	Name          string
+	Namespace     string
+	LabelSelector string
+	// 未设置时为 nil（apiserver 默认为 1）
+	Replicas   *int64
+	Containers []corev1.Container
+	// Pod 模板引用的 ConfigMap 名称
+	ConfigMaps []string
+	// 没有 HPA 时为 nil
+	HPA *hpaInfo
+}
+
+// GetTargetWorkloads 把 spec.target 解析为管理目标 Pod 的 Deployment/StatefulSet，以及扩缩它们的 HPA
+// 除了按标签选择器查询，还沿目标 Pod 的 ownerReferences（Pod → ReplicaSet → Deployment、Pod → StatefulSet）查找，
+// 工作负载本身没有打上 Pod 标签时也能找到；返回结果中 HPA 排在最后
+func (r *AIOpsAnalyzerReconciler) GetTargetWorkloads(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) ([]unstructured.Unstructured, error) {
+	target := &aiopsAnalyzer.Spec.Target
+	namespace := target.Namespace
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&target.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target selector: %w", err)
+	}
+
+	var workloads []unstructured.Unstructured
+	seen := map[string]bool{}
+	add := func(obj unstructured.Unstructured) {
+		key := obj.GetKind() + "/" + obj.GetName()
+		if !seen[key] {
+			seen[key] = true
+			workloads = append(workloads, obj)
+		}
+	}
+
+	for _, kind := range targetWorkloadKinds {
+		items, err := r.listWorkloads(ctx, workloadKinds[kind], namespace, selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			add(item)
+		}
+	}
+
+	pods, err := r.GetTargetPods(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods {
+		owner, err := r.podWorkload(ctx, &pods[i])
+		if err != nil {
+			return nil, err
+		}
+		if owner != nil {
+			add(*owner)
+		}
+	}
+
+	hpas, err := r.listWorkloads(ctx, workloadKinds["HorizontalPodAutoscaler"], namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, hpa := range hpas {
+		kind, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "kind")
+		name, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "name")
+		if seen[kind+"/"+name] {
+			workloads = append(workloads, hpa)
+		}
+	}
+	return workloads, nil
+}
+
+// podWorkload 沿 ownerReferences 查找管理 Pod 的 Deployment 或 StatefulSet，没有或已被删除时返回 nil
+func (r *AIOpsAnalyzerReconciler) podWorkload(ctx context.Context, pod *corev1.Pod) (*unstructured.Unstructured, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil, nil
+	}
+	switch owner.Kind {
+	case "StatefulSet":
+		return r.getWorkload(ctx, workloadKinds["StatefulSet"], pod.Namespace, owner.Name)
+	case "ReplicaSet":
+		replicaSet, err := r.getWorkload(ctx, replicaSetGVK, pod.Namespace, owner.Name)
+		if err != nil || replicaSet == nil {
+			return nil, err
+		}
+		for _, ref := range replicaSet.GetOwnerReferences() {
+			if ref.Controller != nil && *ref.Controller && ref.Kind == "Deployment" {
+				return r.getWorkload(ctx, workloadKinds["Deployment"], pod.Namespace, ref.Name)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// getWorkload 按名称读取工作负载，不存在时返回 nil
+func (r *AIOpsAnalyzerReconciler) getWorkload(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get %s %s/%s failed: %w", gvk.Kind, namespace, name, err)
+	}
+	return obj, nil
+}
+
+// describeTargetWorkload 按 spec.target 查找被监控的 Deployment 或 StatefulSet 及其 HPA 并读取当前配置
+// 找不到或匹配到多个时返回错误，避免把虚假的数据交给大模型
+func (r *AIOpsAnalyzerReconciler) describeTargetWorkload(ctx context.Context, aiopsAnalyzer *autofixv1.AIOpsAnalyzer) (*workloadInfo, error) {
+	target := &aiopsAnalyzer.Spec.Target
+	namespace := target.Namespace
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&target.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target selector: %w", err)
+	}
+
+	workloads, err := r.GetTargetWorkloads(ctx, aiopsAnalyzer)
+	if err != nil {
+		return nil, err
+	}
+	var matched, hpas []unstructured.Unstructured
+	for _, obj := range workloads {
+		if obj.GetKind() == "HorizontalPodAutoscaler" {
+			hpas = append(hpas, obj)
+		} else {
+			matched = append(matched, obj)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("target workload not found: no Deployment or StatefulSet matches %q in namespace %s", selector, namespace)
+	case 1:
+	default:
+		return nil, fmt.Errorf("target workload is ambiguous: %d Deployments/StatefulSets match %q in namespace %s",
+			len(matched), selector, namespace)
+	}
+
+	obj := matched[0]
+	info := &workloadInfo{
+		Kind:          obj.GetKind(),
+		Name:          obj.GetName(),
+		Namespace:     namespace,
+		LabelSelector: selector.String(),
+	}
+	if replicas, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); ok {
+		info.Replicas = &replicas
+	}
+	if template, ok, _ := unstructured.NestedMap(obj.Object, "spec", "template"); ok {
+		var podTemplate corev1.PodTemplateSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(template, &podTemplate); err != nil {
+			return nil, fmt.Errorf("decode pod template of %s/%s failed: %w", info.Kind, info.Name, err)
+		}
+		info.Containers = podTemplate.Spec.Containers
+		info.ConfigMaps = configMapReferences(&podTemplate.Spec)
+	}
+	if len(hpas) > 0 {
+		hpa := hpas[0]
+		info.HPA = &hpaInfo{Name: hpa.GetName()}
+		if minReplicas, ok, _ := unstructured.NestedInt64(hpa.Object, "spec", "minReplicas"); ok {
+			info.HPA.MinReplicas = &minReplicas
+		}
+		info.HPA.MaxReplicas, _, _ = unstructured.NestedInt64(hpa.Object, "spec", "maxReplicas")
+	}
+	return info, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Target workloads", func() {
+	replicas, minReplicas, isController := int32(3), int32(2), true
+	// Deployment 本身没有打 app 标签，只能从 Pod 的 ownerReferences 找到
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default", UID: "deploy-uid"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}},
+		},
+	}
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "order-5d8f", Namespace: "default", UID: "rs-uid",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "order", UID: "deploy-uid", Controller: &isController}}},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "order-5d8f-x2k", Namespace: "default", Labels: map[string]string{"app": "order"},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "order-5d8f", UID: "rs-uid", Controller: &isController}}},
+	}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "order", Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "order"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    10,
+		},
+	}
+	otherHPA := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "payment", Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "payment"},
+			MaxReplicas:    5,
+		},
+	}
+	aiopsAnalyzer := &autofixv1.AIOpsAnalyzer{
+		ObjectMeta: metav1.ObjectMeta{Name: "analyze", Namespace: "default"},
+		Spec: autofixv1.AIOpsAnalyzerSpec{Target: autofixv1.TargetSelector{
+			Namespace: "default",
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}},
+		}},
+	}
+
+	It("should resolve the Deployment through the pods' owner references along with its HPA", func() {
+		reconciler := newFakeReconciler(deployment.DeepCopy(), replicaSet.DeepCopy(), pod.DeepCopy(), hpa.DeepCopy(), otherHPA.DeepCopy())
+
+		workloads, err := reconciler.GetTargetWorkloads(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, obj := range workloads {
+			names = append(names, obj.GetKind()+"/"+obj.GetName())
+		}
+		Expect(names).To(Equal([]string{"Deployment/order", "HorizontalPodAutoscaler/order"}))
+
+		info, err := reconciler.describeTargetWorkload(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(formatWorkloadInfo(info)).To(Equal("- 工作负载：Deployment/order\n" +
+			"- 应用标签选择器：app=order\n" +
+			"- 命名空间：default\n" +
+			"- 当前副本数：3\n" +
+			"- HPA order：minReplicas 2，maxReplicas 10\n" +
+			"- 容器 app：CPU requests 未设置，CPU limits 未设置，内存 requests 未设置，内存 limits 未设置\n"))
+	})
+
+	It("should ignore pods whose owners no longer exist", func() {
+		reconciler := newFakeReconciler(pod.DeepCopy())
+
+		workloads, err := reconciler.GetTargetWorkloads(context.Background(), aiopsAnalyzer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workloads).To(BeEmpty())
+	})
+})
@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package report 汇总集群内所有 AIOpsAnalyzer 的修复历史
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+// FleetReport 集群维度的修复统计
+type FleetReport struct {
+	// 统计的起始时间
+	Since time.Time
+	// AIOpsAnalyzer 数量
+	Analyzers int
+	// 统计范围内的修复建议数量
+	Remediations int
+	// 按动作类型计数
+	ByAction map[string]int
+
+	// 审批结果
+	Approved int
+	Rejected int
+	Pending  int
+
+	// PR 情况
+	PRsOpened int
+	PRsMerged int
+}
+
+// ApprovalRate 已决定的审批中通过的比例
+func (r *FleetReport) ApprovalRate() float64 {
+	return ratio(r.Approved, r.Approved+r.Rejected)
+}
+
+// MergeRate 已创建的 PR 中合并的比例
+func (r *FleetReport) MergeRate() float64 {
+	return ratio(r.PRsMerged, r.PRsOpened)
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// Collect 通过 client 读取所有命名空间的 AIOpsAnalyzer 并汇总 since 之后的修复记录
+func Collect(ctx context.Context, c client.Reader, since time.Time) (*FleetReport, error) {
+	var list autofixv1.AIOpsAnalyzerList
+	if err := c.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("list AIOpsAnalyzers failed: %w", err)
+	}
+	return Summarize(list.Items, since), nil
+}
+
+// Summarize 汇总 since 之后的修复记录
+func Summarize(analyzers []autofixv1.AIOpsAnalyzer, since time.Time) *FleetReport {
+	report := &FleetReport{
+		Since:     since,
+		Analyzers: len(analyzers),
+		ByAction:  map[string]int{},
+	}
+	for _, analyzer := range analyzers {
+		for _, record := range analyzer.Status.History {
+			if record.ProposedAt.Time.Before(since) {
+				continue
+			}
+			report.Remediations++
+
+			action := record.ActionType
+			if action == "" {
+				action = "unknown"
+			}
+			report.ByAction[action]++
+
+			switch {
+			case record.Approved == nil:
+				report.Pending++
+			case *record.Approved:
+				report.Approved++
+			default:
+				report.Rejected++
+			}
+
+			if record.PRNumber > 0 {
+				report.PRsOpened++
+				if record.PRMerged {
+					report.PRsMerged++
+				}
+			}
+		}
+	}
+	return report
+}
+
+// String 输出便于阅读的文本报告
+func (r *FleetReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Remediations since %s: %d (across %d analyzers)\n", r.Since.Format(time.RFC3339), r.Remediations, r.Analyzers)
+
+	actions := make([]string, 0, len(r.ByAction))
+	for action := range r.ByAction {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		fmt.Fprintf(&b, "  %-16s %d\n", action, r.ByAction[action])
+	}
+
+	fmt.Fprintf(&b, "Approval: %d approved, %d rejected, %d pending (rate %.0f%%)\n",
+		r.Approved, r.Rejected, r.Pending, r.ApprovalRate()*100)
+	fmt.Fprintf(&b, "Pull requests: %d opened, %d merged (rate %.0f%%)\n",
+		r.PRsOpened, r.PRsMerged, r.MergeRate()*100)
+	return b.String()
+}
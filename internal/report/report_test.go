@@ -0,0 +1,94 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+)
+
+var _ = Describe("Remediation report", func() {
+	now := time.Now()
+	approved, rejected := true, false
+
+	record := func(action string, age time.Duration, decision *bool, prNumber int, merged bool) autofixv1.RemediationRecord {
+		return autofixv1.RemediationRecord{
+			RequestID:  action,
+			ActionType: action,
+			ProposedAt: metav1.NewTime(now.Add(-age)),
+			Approved:   decision,
+			PRNumber:   prNumber,
+			PRMerged:   merged,
+		}
+	}
+
+	analyzers := []autofixv1.AIOpsAnalyzer{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "team-a"},
+			Status: autofixv1.AIOpsAnalyzerStatus{History: []autofixv1.RemediationRecord{
+				record("scale", 48*time.Hour, &approved, 1, true),
+				record("scale", time.Hour, &approved, 2, true),
+				record("resource-adjust", time.Hour, &rejected, 0, false),
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "team-b"},
+			Status: autofixv1.AIOpsAnalyzerStatus{History: []autofixv1.RemediationRecord{
+				record("scale", 2*time.Hour, &approved, 3, false),
+				record("config-change", time.Minute, nil, 0, false),
+			}},
+		},
+	}
+
+	It("should summarize records newer than the window", func() {
+		report := Summarize(analyzers, now.Add(-24*time.Hour))
+		Expect(report.Analyzers).To(Equal(2))
+		Expect(report.Remediations).To(Equal(4))
+		Expect(report.ByAction).To(Equal(map[string]int{"scale": 2, "resource-adjust": 1, "config-change": 1}))
+		Expect(report.Approved).To(Equal(2))
+		Expect(report.Rejected).To(Equal(1))
+		Expect(report.Pending).To(Equal(1))
+		Expect(report.ApprovalRate()).To(BeNumerically("~", 2.0/3.0))
+		Expect(report.PRsOpened).To(Equal(2))
+		Expect(report.MergeRate()).To(BeNumerically("~", 0.5))
+		Expect(report.String()).To(ContainSubstring("Pull requests: 2 opened, 1 merged (rate 50%)"))
+	})
+
+	It("should collect analyzers from all namespaces through the client", func() {
+		scheme := runtime.NewScheme()
+		Expect(autofixv1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&analyzers[0], &analyzers[1]).Build()
+
+		report, err := Collect(context.Background(), c, now.Add(-24*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Remediations).To(Equal(4))
+	})
+
+	It("should report zero rates when nothing was decided", func() {
+		report := Summarize(nil, now)
+		Expect(report.ApprovalRate()).To(BeZero())
+		Expect(report.MergeRate()).To(BeZero())
+	})
+})
@@ -0,0 +1,227 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/feishu"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/gitops"
+	"github.com/boqier/AIOpsAnalyzer/internal/controller/llm"
+)
+
+// log is for logging in this package.
+var aiopsanalyzerlog = logf.Log.WithName("aiopsanalyzer-resource")
+
+// SetupAIOpsAnalyzerWebhookWithManager registers the webhook for AIOpsAnalyzer in the manager.
+func SetupAIOpsAnalyzerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&autofixv1.AIOpsAnalyzer{}).
+		WithValidator(&AIOpsAnalyzerCustomValidator{}).
+		WithDefaulter(&AIOpsAnalyzerCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-autofix-aiops-com-v1-aiopsanalyzer,mutating=true,failurePolicy=fail,sideEffects=None,groups=autofix.aiops.com,resources=aiopsanalyzers,verbs=create;update,versions=v1,name=maiopsanalyzer-v1.kb.io,admissionReviewVersions=v1
+
+// AIOpsAnalyzerCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind AIOpsAnalyzer when those are created or updated.
+type AIOpsAnalyzerCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &AIOpsAnalyzerCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind AIOpsAnalyzer.
+func (d *AIOpsAnalyzerCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	aiopsanalyzer, ok := obj.(*autofixv1.AIOpsAnalyzer)
+	if !ok {
+		return fmt.Errorf("expected an AIOpsAnalyzer object but got %T", obj)
+	}
+	aiopsanalyzerlog.Info("Defaulting for AIOpsAnalyzer", "name", aiopsanalyzer.GetName())
+
+	// 规整分析周期的单位，非法值留给校验 webhook 拒绝
+	if normalized, err := autofixv1.NormalizeAnalysisInterval(aiopsanalyzer.Spec.AnalysisInterval); err == nil {
+		aiopsanalyzer.Spec.AnalysisInterval = normalized
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-autofix-aiops-com-v1-aiopsanalyzer,mutating=false,failurePolicy=fail,sideEffects=None,groups=autofix.aiops.com,resources=aiopsanalyzers,verbs=create;update,versions=v1,name=vaiopsanalyzer-v1.kb.io,admissionReviewVersions=v1
+
+// AIOpsAnalyzerCustomValidator struct is responsible for validating the AIOpsAnalyzer resource
+// when it is created, updated, or deleted.
+type AIOpsAnalyzerCustomValidator struct{}
+
+var _ webhook.CustomValidator = &AIOpsAnalyzerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type AIOpsAnalyzer.
+func (v *AIOpsAnalyzerCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	aiopsanalyzer, ok := obj.(*autofixv1.AIOpsAnalyzer)
+	if !ok {
+		return nil, fmt.Errorf("expected a AIOpsAnalyzer object but got %T", obj)
+	}
+	aiopsanalyzerlog.Info("Validation for AIOpsAnalyzer upon creation", "name", aiopsanalyzer.GetName())
+
+	return nil, validateAIOpsAnalyzer(aiopsanalyzer)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type AIOpsAnalyzer.
+func (v *AIOpsAnalyzerCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	aiopsanalyzer, ok := newObj.(*autofixv1.AIOpsAnalyzer)
+	if !ok {
+		return nil, fmt.Errorf("expected a AIOpsAnalyzer object for the newObj but got %T", newObj)
+	}
+	aiopsanalyzerlog.Info("Validation for AIOpsAnalyzer upon update", "name", aiopsanalyzer.GetName())
+
+	return nil, validateAIOpsAnalyzer(aiopsanalyzer)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type AIOpsAnalyzer.
+func (v *AIOpsAnalyzerCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateAIOpsAnalyzer 汇总所有字段错误，返回标准的 Invalid 错误
+func validateAIOpsAnalyzer(aiopsanalyzer *autofixv1.AIOpsAnalyzer) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if _, err := autofixv1.ParseAnalysisInterval(aiopsanalyzer.Spec.AnalysisInterval); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("analysisInterval"),
+			aiopsanalyzer.Spec.AnalysisInterval,
+			fmt.Sprintf("must be a duration of at least %s, e.g. 30s, 5m or 1h", autofixv1.MinAnalysisInterval)))
+	}
+
+	allErrs = append(allErrs, validateTargetSelector(&aiopsanalyzer.Spec.Target.Selector, specPath.Child("target", "selector"))...)
+
+	feishuPath := specPath.Child("feishu")
+	if timeout := aiopsanalyzer.Spec.Feishu.ApprovalTimeout; timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			allErrs = append(allErrs, field.Invalid(feishuPath.Child("approvalTimeout"), timeout,
+				"must be a positive duration, e.g. 10m or 1h"))
+		}
+	}
+	allErrs = append(allErrs, validateReceiveID(aiopsanalyzer.Spec.Feishu.ReceiveIDType, aiopsanalyzer.Spec.Feishu.ReceiveID, feishuPath)...)
+	for i, route := range aiopsanalyzer.Spec.Feishu.Routes {
+		allErrs = append(allErrs, validateReceiveID(route.ReceiveIDType, route.ReceiveID, feishuPath.Child("routes").Index(i))...)
+	}
+
+	repoURLPath := specPath.Child("gitOps", "repoURL")
+	if aiopsanalyzer.Spec.GitOps.RepoURL == "" {
+		allErrs = append(allErrs, field.Required(repoURLPath, "must be a git repository url"))
+	} else if _, err := gitops.ParseRepoURL(aiopsanalyzer.Spec.GitOps.RepoURL); err != nil {
+		allErrs = append(allErrs, field.Invalid(repoURLPath, aiopsanalyzer.Spec.GitOps.RepoURL,
+			"must be a git repository url, e.g. https://github.com/owner/repo.git or git@github.com:owner/repo.git"))
+	}
+
+	actionsPath := specPath.Child("autoRemediation", "allowedActions")
+	for i, action := range aiopsanalyzer.Spec.AutoRemediation.AllowedActions {
+		if _, ok := llm.ActionPaths[action]; !ok {
+			allErrs = append(allErrs, field.NotSupported(actionsPath.Index(i), action, supportedActions()))
+		}
+	}
+
+	if cfg := aiopsanalyzer.Spec.Prometheus; cfg != nil {
+		for i, query := range cfg.RangeQueries {
+			allErrs = append(allErrs, validateRangeQuery(query, specPath.Child("prometheus", "rangeQueries").Index(i))...)
+		}
+	}
+
+	if cfg := aiopsanalyzer.Spec.Loki; cfg != nil {
+		normalizersPath := specPath.Child("loki", "signatureNormalizers")
+		for i, expr := range cfg.SignatureNormalizers {
+			if _, err := regexp.Compile(expr); err != nil {
+				allErrs = append(allErrs, field.Invalid(normalizersPath.Index(i), expr, err.Error()))
+			}
+		}
+	}
+
+	if llmSpec := aiopsanalyzer.Spec.LLM; llmSpec != nil && llmSpec.SystemPromptOverride != "" {
+		if err := llm.ValidateSystemPrompt(llmSpec.SystemPromptOverride); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("llm", "systemPromptOverride"),
+				"<prompt>", err.Error()))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: autofixv1.GroupVersion.Group, Kind: "AIOpsAnalyzer"},
+		aiopsanalyzer.Name, allErrs)
+}
+
+// validateTargetSelector 选择器不能为空，否则会分析命名空间内的所有 Pod
+func validateTargetSelector(selector *metav1.LabelSelector, fldPath *field.Path) field.ErrorList {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return field.ErrorList{field.Required(fldPath, "matchLabels or matchExpressions must be set")}
+	}
+	if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, selector.String(), err.Error())}
+	}
+	return nil
+}
+
+// validateRangeQuery step 不能大于 window，否则区间内最多只有一个采样点
+func validateRangeQuery(query autofixv1.PrometheusRangeQuery, fldPath *field.Path) field.ErrorList {
+	if query.Window == "" || query.Step == "" {
+		return nil
+	}
+	window, windowErr := time.ParseDuration(query.Window)
+	step, stepErr := time.ParseDuration(query.Step)
+	if windowErr != nil || stepErr != nil || step <= 0 || step > window {
+		return field.ErrorList{field.Invalid(fldPath.Child("step"), query.Step,
+			fmt.Sprintf("must be a positive duration no longer than window %s", query.Window))}
+	}
+	return nil
+}
+
+// validateReceiveID 检查 receiveId 是否符合 receiveIdType 的格式，与发送卡片时使用同一套校验
+func validateReceiveID(idType autofixv1.FeishuReceiveIDType, id string, fldPath *field.Path) field.ErrorList {
+	idPath := fldPath.Child("receiveId")
+	if id == "" {
+		return field.ErrorList{field.Required(idPath, "")}
+	}
+	if err := feishu.ValidateReceiver(id, string(idType)); err != nil {
+		return field.ErrorList{field.Invalid(idPath, id, err.Error())}
+	}
+	return nil
+}
+
+// supportedActions 按名称排序的修复类型
+func supportedActions() []string {
+	actions := make([]string, 0, len(llm.ActionPaths))
+	for action := range llm.ActionPaths {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
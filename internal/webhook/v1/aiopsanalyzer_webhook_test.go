@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autofixv1 "github.com/boqier/AIOpsAnalyzer/api/v1"
+	// TODO (user): Add any additional imports if needed
+)
+
+var _ = Describe("AIOpsAnalyzer Webhook", func() {
+	var (
+		obj       *autofixv1.AIOpsAnalyzer
+		oldObj    *autofixv1.AIOpsAnalyzer
+		validator AIOpsAnalyzerCustomValidator
+		defaulter AIOpsAnalyzerCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		// 只包含必填字段的合法对象，各用例在此基础上修改
+		obj = &autofixv1.AIOpsAnalyzer{
+			Spec: autofixv1.AIOpsAnalyzerSpec{
+				Target:           autofixv1.TargetSelector{Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "order"}}},
+				AnalysisInterval: "5m",
+				Feishu:           autofixv1.FeishuNotification{ReceiveIDType: autofixv1.FeishuChatID, ReceiveID: "oc_a0553eda9014c201e6969b478895c230"},
+				GitOps:           autofixv1.GitOpsConfig{RepoURL: "https://github.com/acme/deploy.git", Path: "apps/order"},
+			},
+		}
+		oldObj = obj.DeepCopy()
+		validator = AIOpsAnalyzerCustomValidator{}
+		Expect(validator).NotTo(BeNil(), "Expected validator to be initialized")
+		defaulter = AIOpsAnalyzerCustomDefaulter{}
+		Expect(defaulter).NotTo(BeNil(), "Expected defaulter to be initialized")
+		Expect(oldObj).NotTo(BeNil(), "Expected oldObj to be initialized")
+		Expect(obj).NotTo(BeNil(), "Expected obj to be initialized")
+	})
+
+	Context("When creating AIOpsAnalyzer under Defaulting Webhook", func() {
+		It("Should normalize the analysisInterval to the largest exact unit", func() {
+			obj.Spec.AnalysisInterval = "120s"
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.AnalysisInterval).To(Equal("2m"))
+
+			obj.Spec.AnalysisInterval = "3600s"
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.AnalysisInterval).To(Equal("1h"))
+		})
+
+		It("Should leave an invalid analysisInterval for the validator", func() {
+			obj.Spec.AnalysisInterval = "1s"
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.AnalysisInterval).To(Equal("1s"))
+		})
+	})
+
+	Context("When creating or updating AIOpsAnalyzer under Validating Webhook", func() {
+		It("Should deny creation if the analysisInterval is below the minimum", func() {
+			obj.Spec.AnalysisInterval = "0s"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.analysisInterval"))
+
+			obj.Spec.AnalysisInterval = "10s"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should admit creation if the analysisInterval is valid", func() {
+			obj.Spec.AnalysisInterval = "30s"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a system prompt override that does not ask for JSON", func() {
+			obj.Spec.AnalysisInterval = "5m"
+			obj.Spec.LLM = &autofixv1.LLMSpec{SystemPromptOverride: "你是资深 SRE，请给出修复建议"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.llm.systemPromptOverride"))
+
+			obj.Spec.LLM.SystemPromptOverride = "你是资深 SRE，当前时间 {{.Now}}，只输出 JSON"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny an empty target selector", func() {
+			obj.Spec.Target.Selector = metav1.LabelSelector{}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.target.selector: Required value")))
+		})
+
+		It("Should deny an approvalTimeout that is not a duration", func() {
+			obj.Spec.Feishu.ApprovalTimeout = "ten minutes"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.feishu.approvalTimeout")))
+
+			obj.Spec.Feishu.ApprovalTimeout = "1h30m"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a repoURL that is not a git repository", func() {
+			obj.Spec.GitOps.RepoURL = "github.com/acme"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.gitOps.repoURL")))
+
+			obj.Spec.GitOps.RepoURL = "git@gitlab.example.com:acme/deploy.git"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a receiveId that does not match its receiveIdType", func() {
+			obj.Spec.Feishu.ReceiveIDType = autofixv1.FeishuOpenID
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.feishu.receiveId")))
+
+			obj.Spec.Feishu.ReceiveID = "ou_7d8a6e6df7621556ce0d21922b676706"
+			obj.Spec.Feishu.Routes = []autofixv1.FeishuRoute{{RiskLevel: "high", ReceiveIDType: autofixv1.FeishuEmail, ReceiveID: "oncall"}}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.feishu.routes[0].receiveId")))
+
+			obj.Spec.Feishu.Routes[0].ReceiveID = "oncall@example.com"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny allowedActions outside the supported actions", func() {
+			obj.Spec.AutoRemediation.AllowedActions = []string{"scale", "delete"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring(`spec.autoRemediation.allowedActions[1]: Unsupported value: "delete"`)))
+		})
+
+		It("Should deny a range query step longer than its window", func() {
+			obj.Spec.Prometheus = &autofixv1.PrometheusConfig{RangeQueries: []autofixv1.PrometheusRangeQuery{
+				{Name: "cpu", Expr: "up", Window: "5m", Step: "10m"},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.prometheus.rangeQueries[0].step")))
+
+			obj.Spec.Prometheus.RangeQueries[0].Step = "30s"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a signature normalizer that does not compile", func() {
+			obj.Spec.Loki = &autofixv1.LokiConfig{SignatureNormalizers: []string{`user=\S+`, `(`}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.loki.signatureNormalizers[1]")))
+
+			obj.Spec.Loki.SignatureNormalizers = []string{`user=\S+`}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should allow approval without a card template so the controller default applies", func() {
+			obj.Spec.AutoRemediation.RequireApproval = true
+			obj.Spec.Feishu.TemplateID = ""
+			obj.Spec.Feishu.TemplateVersion = ""
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should validate updates correctly", func() {
+			oldObj.Spec.AnalysisInterval = "5m"
+			obj.Spec.AnalysisInterval = "1s"
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})